@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"context"
+	"crypto"
+	stded25519 "crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// maxAgentMessageSize is the largest message OpenSSH's agent will sign,
+// leaving headroom for the rest of the request.
+const maxAgentMessageSize = 256*1024 - 1024
+
+// agentSigner signs messages using an ed25519 key held by a running
+// ssh-agent, so that the private key never has to exist on disk. Each request
+// connects to the agent, so that no connection is left open once it's done.
+type agentSigner struct {
+	ctx  context.Context
+	sock string
+	key  ssh.PublicKey
+}
+
+// newAgentSigner connects to the ssh-agent at $SSH_AUTH_SOCK and selects the
+// ed25519 key matching the given fingerprint, or the only ed25519 key held by
+// the agent when the fingerprint is empty. Requests to the agent are aborted
+// once ctx is done.
+func newAgentSigner(ctx context.Context, fingerprint string) (*agentSigner, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, errors.New("ssh-agent is not available ($SSH_AUTH_SOCK is not set)")
+	}
+
+	s := &agentSigner{ctx: ctx, sock: sock}
+
+	var keys []*agent.Key
+
+	err := s.do(func(client agent.ExtendedAgent) error {
+		var err error
+
+		keys, err = client.List()
+		if err != nil {
+			return fmt.Errorf("ssh-agent keys could not be listed (%s)", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*agent.Key
+
+	for _, key := range keys {
+		if key.Type() != ssh.KeyAlgoED25519 {
+			continue
+		}
+
+		if fingerprint != "" && ssh.FingerprintSHA256(key) != fingerprint && ssh.FingerprintLegacyMD5(key) != strings.TrimPrefix(fingerprint, "MD5:") {
+			continue
+		}
+
+		matches = append(matches, key)
+	}
+
+	switch {
+	case len(matches) == 0 && fingerprint != "":
+		return nil, fmt.Errorf(`ssh-agent has no ed25519 key with fingerprint "%s"`, fingerprint)
+	case len(matches) == 0:
+		return nil, errors.New("ssh-agent has no ed25519 keys")
+	case len(matches) > 1:
+		fingerprints := []string{}
+		for _, key := range matches {
+			fingerprints = append(fingerprints, ssh.FingerprintSHA256(key))
+		}
+
+		return nil, fmt.Errorf("ssh-agent has multiple ed25519 keys, choose one using agent://<fingerprint> (one of: %s)", strings.Join(fingerprints, ", "))
+	}
+
+	key, err := ssh.ParsePublicKey(matches[0].Blob)
+	if err != nil {
+		return nil, fmt.Errorf("ssh-agent key could not be parsed (%s)", err)
+	}
+
+	s.key = key
+
+	return s, nil
+}
+
+// do makes requests to the agent using a new connection, which is closed once
+// fn returns, or as soon as the signer's context is done.
+func (s *agentSigner) do(fn func(agent.ExtendedAgent) error) error {
+	conn, err := (&net.Dialer{}).DialContext(s.ctx, "unix", s.sock)
+	if err != nil {
+		if e := s.ctx.Err(); e != nil {
+			return e
+		}
+
+		return fmt.Errorf("ssh-agent is not available (%s)", err)
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	err = fn(agent.NewClient(conn))
+
+	// Errors of requests aborted by closing the connection aren't helpful
+	if e := s.ctx.Err(); e != nil {
+		return e
+	}
+
+	return err
+}
+
+func (s *agentSigner) Public() crypto.PublicKey {
+	if key, ok := s.key.(ssh.CryptoPublicKey); ok {
+		if k, ok := key.CryptoPublicKey().(stded25519.PublicKey); ok {
+			return ed25519.PublicKey(k)
+		}
+	}
+
+	return nil
+}
+
+// Sign signs the message using the agent. The SSH agent protocol only supports
+// pure ed25519 without a context, so ed25519ph signatures are not possible.
+func (s *agentSigner) Sign(_ io.Reader, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts != nil && opts.HashFunc() != crypto.Hash(0) {
+		return nil, errors.New("ssh-agent does not support ed25519ph signatures (use --signing-algorithm ed25519)")
+	}
+
+	if o, ok := opts.(*ed25519.Options); ok && o.Context != "" {
+		return nil, errors.New("ssh-agent does not support ed25519 signature contexts")
+	}
+
+	if l := len(message); l > maxAgentMessageSize {
+		return nil, fmt.Errorf("file is too large to be signed by ssh-agent (got %d bytes max %d)", l, maxAgentMessageSize)
+	}
+
+	var sig *ssh.Signature
+
+	err := s.do(func(client agent.ExtendedAgent) error {
+		var err error
+
+		sig, err = client.Sign(s.key, message)
+		if err != nil {
+			return fmt.Errorf("ssh-agent failed to sign (%s)", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if sig.Format != ssh.KeyAlgoED25519 || len(sig.Blob) != ed25519.SignatureSize {
+		return nil, fmt.Errorf(`ssh-agent returned an unexpected signature format "%s"`, sig.Format)
+	}
+
+	return sig.Blob, nil
+}
@@ -0,0 +1,310 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+// defaultAnnouncementTemplate is used to announce releases without a
+// --template.
+const defaultAnnouncementTemplate = `*{{if .Name}}{{.Name}} {{end}}{{.Version}}* has been released to the {{.Channel}} channel.
+{{if .Notes}}
+{{.Notes}}
+{{end}}
+{{range .Artifacts}}• {{.Filename}}{{if .Platform}} ({{.Platform}}){{end}}: {{.URL}}
+{{end}}`
+
+// maxDiscordContent is the longest message Discord's webhooks accept.
+const maxDiscordContent = 2000
+
+// announcement is the data announcement templates are rendered with.
+type announcement struct {
+	ID        string                  `json:"id"`
+	Name      string                  `json:"name"`
+	Version   string                  `json:"version"`
+	Channel   string                  `json:"channel"`
+	Notes     string                  `json:"notes"`
+	Product   string                  `json:"product"`
+	Artifacts []*announcementArtifact `json:"artifacts"`
+}
+
+// announcementArtifact is a release of the announced version, along with its
+// download link.
+type announcementArtifact struct {
+	ID        string `json:"id"`
+	Filename  string `json:"filename"`
+	Platform  string `json:"platform"`
+	Filetype  string `json:"filetype"`
+	Filesize  int64  `json:"filesize"`
+	Checksum  string `json:"checksum"`
+	Signature string `json:"signature"`
+	URL       string `json:"url"`
+}
+
+func newAnnounceCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "announce",
+		Short: "post an announcement of a published release to Slack, Discord, Teams or a webhook",
+		Example: `  keygen announce --release <id> --slack-webhook "$SLACK_WEBHOOK_URL" --template announcement.md
+
+Templates:
+  Go templates rendered with .ID, .Name, .Version, .Channel, .Notes (the
+  release's description), .Product and .Artifacts, the releases of the same
+  version, each with .Filename, .Platform, .Filetype, .Filesize, .Checksum,
+  .Signature and .URL (its download link).
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return announceRun(opts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+
+	cmd.Flags().StringVar(&opts.release, "release", "", "release to announce, by ID, along with the other releases of its version (required)")
+	cmd.Flags().StringVar(&opts.template, "template", "", "path to a Go template for the announcement (default a summary with download links)")
+	cmd.Flags().StringVar(&opts.slackWebhook, "slack-webhook", "", "Slack incoming webhook URL to post the announcement to [$KEYGEN_SLACK_WEBHOOK]")
+	cmd.Flags().StringVar(&opts.discordWebhook, "discord-webhook", "", "Discord webhook URL to post the announcement to [$KEYGEN_DISCORD_WEBHOOK]")
+	cmd.Flags().StringVar(&opts.teamsWebhook, "teams-webhook", "", "Microsoft Teams incoming webhook URL to post the announcement to [$KEYGEN_TEAMS_WEBHOOK]")
+	cmd.Flags().StringVar(&opts.webhook, "webhook", "", "URL to POST the announcement to as JSON, along with the release and its artifacts [$KEYGEN_ANNOUNCE_WEBHOOK]")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "print the announcement instead of posting it")
+
+	bindEnv(cmd.Flags(), "slack-webhook", "KEYGEN_SLACK_WEBHOOK")
+	bindEnv(cmd.Flags(), "discord-webhook", "KEYGEN_DISCORD_WEBHOOK")
+	bindEnv(cmd.Flags(), "teams-webhook", "KEYGEN_TEAMS_WEBHOOK")
+	bindEnv(cmd.Flags(), "webhook", "KEYGEN_ANNOUNCE_WEBHOOK")
+
+	cmd.MarkFlagRequired("release")
+
+	return cmd
+}
+
+func announceRun(opts *CommandOptions) error {
+	if err := opts.checkArtifactHost(); err != nil {
+		return err
+	}
+
+	// Webhooks are keyed by a name for error messages, with payloads built from
+	// the rendered announcement
+	webhooks := map[string]string{
+		"slack":   opts.slackWebhook,
+		"discord": opts.discordWebhook,
+		"teams":   opts.teamsWebhook,
+		"webhook": opts.webhook,
+	}
+
+	targets := []string{}
+	for name, u := range webhooks {
+		if u != "" {
+			targets = append(targets, name)
+		}
+	}
+
+	sort.Strings(targets)
+
+	if len(targets) == 0 && !opts.dryRun {
+		return errors.New(`one of "--slack-webhook", "--discord-webhook", "--teams-webhook" or "--webhook" is required (or use --dry-run)`)
+	}
+
+	tmpl, err := loadAnnouncementTemplate(opts.template)
+	if err != nil {
+		return err
+	}
+
+	release, err := opts.client.GetRelease(opts.ctx, opts.release)
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	// Announce every artifact of the version, e.g. one per platform
+	siblings, err := opts.client.ListReleases(opts.ctx, &keygenext.ReleaseFilter{
+		Product: release.ProductID,
+		Version: release.Version,
+		Paging:  keygenext.Paging{All: true},
+	})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	a := newAnnouncement(opts, release, siblings)
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, a); err != nil {
+		return fmt.Errorf(`announcement template could not be rendered (%s)`, err)
+	}
+
+	message := strings.TrimSpace(b.String())
+
+	if opts.dryRun {
+		fmt.Println(message)
+
+		return nil
+	}
+
+	failed := []string{}
+	for _, name := range targets {
+		if err := postAnnouncement(name, webhooks[name], message, a); err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%s)", name, err))
+		}
+	}
+
+	if len(failed) != 0 {
+		return fmt.Errorf("announcement could not be posted to: %s", strings.Join(failed, ", "))
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("announced release " + italic(a.Version) + " to " + strings.Join(targets, ", "))
+
+	return nil
+}
+
+// loadAnnouncementTemplate parses the template at path, or the default
+// template when no path is given.
+func loadAnnouncementTemplate(path string) (*template.Template, error) {
+	text := defaultAnnouncementTemplate
+
+	if path != "" {
+		p, err := homedir.Expand(path)
+		if err != nil {
+			return nil, fmt.Errorf(`template path "%s" is not expandable (%s)`, path, err)
+		}
+
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf(`template path "%s" is not readable (%s)`, path, err)
+		}
+
+		text = string(b)
+	}
+
+	tmpl, err := template.New("announcement").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf(`announcement template is not valid (%s)`, err)
+	}
+
+	return tmpl, nil
+}
+
+// newAnnouncement returns the announcement of a release, where siblings are
+// the releases of the same version, including the release itself. Yanked
+// releases aren't announced.
+func newAnnouncement(opts *CommandOptions, release *keygenext.Release, siblings keygenext.Releases) *announcement {
+	a := &announcement{
+		ID:        release.ID,
+		Version:   release.Version,
+		Channel:   release.Channel,
+		Product:   release.ProductID,
+		Artifacts: []*announcementArtifact{},
+	}
+
+	if n := release.Name; n != nil && *n != "" {
+		a.Name = *n
+	}
+
+	if d := release.Description; d != nil {
+		a.Notes = strings.TrimSpace(*d)
+	}
+
+	found := false
+	for i := range siblings {
+		r := &siblings[i]
+		if r.Yanked != nil || r.Channel != release.Channel {
+			continue
+		}
+
+		if r.ID == release.ID {
+			found = true
+		}
+
+		a.Artifacts = append(a.Artifacts, newAnnouncementArtifact(opts, r))
+	}
+
+	if !found {
+		a.Artifacts = append([]*announcementArtifact{newAnnouncementArtifact(opts, release)}, a.Artifacts...)
+	}
+
+	sort.SliceStable(a.Artifacts, func(i, j int) bool {
+		return a.Artifacts[i].Filename < a.Artifacts[j].Filename
+	})
+
+	return a
+}
+
+func newAnnouncementArtifact(opts *CommandOptions, r *keygenext.Release) *announcementArtifact {
+	return &announcementArtifact{
+		ID:        r.ID,
+		Filename:  r.Filename,
+		Platform:  r.Platform,
+		Filetype:  r.Filetype,
+		Filesize:  r.Filesize,
+		Checksum:  r.Checksum,
+		Signature: r.Signature,
+		URL:       opts.client.ReleaseArtifactURL(r),
+	}
+}
+
+// postAnnouncement posts the message to a webhook, using the payload its
+// service expects.
+func postAnnouncement(name string, url string, message string, a *announcement) error {
+	var payload interface{}
+
+	switch name {
+	case "slack", "teams":
+		payload = map[string]interface{}{"text": message}
+	case "discord":
+		if r := []rune(message); len(r) > maxDiscordContent {
+			message = string(r[:maxDiscordContent-1]) + "…"
+		}
+
+		payload = map[string]interface{}{"content": message}
+	default:
+		payload = map[string]interface{}{"text": message, "release": a}
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := newExternalClient(30 * time.Second)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %d", res.StatusCode)
+	}
+
+	return nil
+}
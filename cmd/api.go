@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+// apiMethods are the HTTP methods supported by the api command.
+var apiMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+func newAPICmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "api <method> <path>",
+		Short: "make an authenticated request to any API endpoint",
+		Example: `  keygen api GET '/releases?limit=5'
+  keygen api PATCH /releases/1fddcec8-8dd3-4d8d-9b16-215cac0f9b52 --data @payload.json
+  keygen api GET /licenses -H 'Keygen-Environment: staging'
+
+Docs:
+  https://keygen.sh/docs/api/`,
+		Args: apiArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return apiRun(opts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+
+	cmd.Flags().StringVarP(&opts.data, "data", "d", "", "request body, or @<path> to read it from a file (@- for stdin)")
+	cmd.Flags().StringArrayVarP(&opts.headers, "header", "H", []string{}, "additional request header, e.g. 'Keygen-Environment: staging'; may be repeated")
+	cmd.Flags().BoolVarP(&opts.include, "include", "i", false, "print the response status and headers to stderr")
+
+	return cmd
+}
+
+func apiArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("method and path are required")
+	}
+
+	return nil
+}
+
+func apiRun(opts *CommandOptions, args []string) error {
+	method := strings.ToUpper(args[0])
+
+	supported := false
+	for _, m := range apiMethods {
+		if m == method {
+			supported = true
+		}
+	}
+
+	if !supported {
+		return fmt.Errorf(`method "%s" is not supported (must be one of: %s)`, args[0], strings.Join(apiMethods, ", "))
+	}
+
+	header := http.Header{}
+	for _, h := range opts.headers {
+		kv := strings.SplitN(h, ":", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return fmt.Errorf(`header "%s" is not acceptable (must be <name>: <value>)`, h)
+		}
+
+		header.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	}
+
+	body, err := readAPIData(opts.data)
+	if err != nil {
+		return err
+	}
+
+	if len(body) != 0 && method == http.MethodGet {
+		return errors.New(`data is not acceptable for GET requests (pass query parameters in the path)`)
+	}
+
+	res, err := opts.client.Do(opts.ctx, method, args[1], header, body)
+	if err != nil {
+		return err
+	}
+
+	if opts.include {
+		fmt.Fprintf(os.Stderr, "%d %s\n", res.Status, http.StatusText(res.Status))
+		res.Headers.Write(os.Stderr)
+		fmt.Fprintln(os.Stderr)
+	}
+
+	// Pretty print JSON responses, leaving anything else as-is
+	var out bytes.Buffer
+	if json.Indent(&out, res.Body, "", "  ") == nil {
+		out.WriteString("\n")
+	} else {
+		out.Reset()
+		out.Write(res.Body)
+	}
+
+	os.Stdout.Write(out.Bytes())
+
+	if res.Status >= http.StatusBadRequest {
+		return fmt.Errorf("request failed (status %d)", res.Status)
+	}
+
+	return nil
+}
+
+// readAPIData reads a --data value, which is either the body itself or a path
+// to it prefixed with @, where @- reads from stdin.
+func readAPIData(data string) ([]byte, error) {
+	switch {
+	case data == "@-":
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf(`data is not readable (%s)`, err)
+		}
+
+		return b, nil
+	case strings.HasPrefix(data, "@"):
+		p, err := homedir.Expand(data[1:])
+		if err != nil {
+			return nil, fmt.Errorf(`data path "%s" is not expandable (%s)`, data[1:], err)
+		}
+
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf(`data path "%s" is not readable (%s)`, data[1:], err.(*os.PathError).Err)
+		}
+
+		return b, nil
+	}
+
+	return []byte(data), nil
+}
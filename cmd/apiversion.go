@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// supportedAPIVersion is the oldest API version the CLI supports. Newer 1.x
+// versions are backwards compatible, so they're supported too.
+const supportedAPIVersion = "1.0"
+
+var apiVersionRegex = regexp.MustCompile(`^\d+\.\d+$`)
+
+// configureAPIVersion pins requests to the --api-version, and warns once when
+// the API serves a version other than what's pinned or, when nothing is
+// pinned, older than the supported version.
+func (s *session) configureAPIVersion() error {
+	pinned := s.root.apiVersion
+	if pinned != "" && !apiVersionRegex.MatchString(pinned) {
+		return fmt.Errorf(`api version "%s" is not acceptable (must be major.minor, e.g. 1.7)`, pinned)
+	}
+
+	warned := false
+
+	s.client.APIVersion = pinned
+	s.client.VersionReported = func(version string) {
+		if warned {
+			return
+		}
+
+		if pinned != "" && compareAPIVersions(version, pinned) == 0 {
+			return
+		}
+
+		if pinned == "" && compareAPIVersions(version, supportedAPIVersion) >= 0 {
+			return
+		}
+
+		warned = true
+
+		yellow := color.New(color.FgYellow).SprintFunc()
+
+		if pinned != "" {
+			fmt.Fprintln(os.Stderr, yellow("warning:")+" the API served version "+version+" instead of the pinned version "+pinned)
+
+			return
+		}
+
+		fmt.Fprintln(os.Stderr, yellow("warning:")+" the account's default API version "+version+" is older than "+supportedAPIVersion+", which the CLI may not work with (pin a version using --api-version)")
+	}
+
+	return nil
+}
+
+// compareAPIVersions compares two major.minor versions numerically.
+func compareAPIVersions(a, b string) int {
+	pa, pb := strings.SplitN(a, ".", 2), strings.SplitN(b, ".", 2)
+
+	for i := 0; i < 2; i++ {
+		var x, y int
+		if i < len(pa) {
+			x, _ = strconv.Atoi(pa[i])
+		}
+
+		if i < len(pb) {
+			y, _ = strconv.Atoi(pb[i])
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+func newArtifactsCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	urlCmd := &cobra.Command{
+		Use:   "url <id>",
+		Short: "generate a temporary download URL for an artifact",
+		Example: `  keygen artifacts url 1fddcec8-8dd3-4d8d-9b16-215cac0f9b52 \
+      --account '1fddcec8-8dd3-4d8d-9b16-215cac0f9b52' \
+      --token 'prod-xxx' \
+      --ttl 1h
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: artifactsURLArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return artifactsURLRun(opts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(urlCmd, s)
+
+	urlCmd.Flags().DurationVar(&opts.ttl, "ttl", time.Hour, "how long the download URL is valid for, between 1m and 168h")
+	urlCmd.Flags().StringVarP(&opts.output, "output", "o", "text", "output format, one of: text, json, yaml, go-template=<template>")
+
+	updateCmd := &cobra.Command{
+		Use:   "update <id>",
+		Short: "update an artifact's metadata",
+		Example: `  keygen artifacts update 1fddcec8-8dd3-4d8d-9b16-215cac0f9b52 \
+      --account '1fddcec8-8dd3-4d8d-9b16-215cac0f9b52' \
+      --token 'prod-xxx' \
+      --metadata buildId=2f1c9a7 \
+      --metadata symbols=
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: artifactsURLArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return artifactsUpdateRun(opts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(updateCmd, s)
+
+	updateCmd.Flags().StringArrayVar(&opts.metadataPairs, "metadata", []string{}, "key=value to set in the artifact's metadata, or key= to remove it; may be repeated (required)")
+	updateCmd.Flags().StringVarP(&opts.output, "output", "o", "text", "output format, one of: text, json, yaml, go-template=<template>")
+
+	updateCmd.MarkFlagRequired("metadata")
+
+	cmd := &cobra.Command{
+		Use:   "artifacts",
+		Short: "manage release artifacts",
+	}
+
+	cmd.AddCommand(urlCmd)
+	cmd.AddCommand(updateCmd)
+	cmd.AddCommand(newArtifactsGCCmd(s))
+
+	return cmd
+}
+
+func artifactsURLArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("artifact ID is required")
+	}
+
+	return nil
+}
+
+func artifactsURLRun(opts *CommandOptions, args []string) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	ttl := opts.ttl
+	if ttl < time.Minute || ttl > 7*24*time.Hour {
+		return fmt.Errorf(`ttl "%s" is not acceptable (must be between 1m and 168h)`, ttl)
+	}
+
+	if err := opts.checkArtifactHost(); err != nil {
+		return err
+	}
+
+	artifact, err := opts.client.GetArtifact(opts.ctx, args[0], ttl)
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	if artifact.Location == "" {
+		return keygenext.ErrArtifactLocationMissing
+	}
+
+	expiry := time.Now().Add(ttl).UTC()
+
+	if isStructuredOutput(opts.output) {
+		return render(opts.output, rendering{value: map[string]interface{}{
+			"id":      artifact.ID,
+			"key":     artifact.Key,
+			"url":     opts.client.ArtifactDownloadURL(artifact),
+			"ttl":     int64(ttl.Seconds()),
+			"expires": expiry.Format(time.RFC3339),
+		}})
+	}
+
+	fmt.Println(opts.client.ArtifactDownloadURL(artifact))
+
+	return nil
+}
+
+// artifactsUpdateRun merges metadata into an artifact's. Each artifact belongs
+// to its own release, so the metadata is stored on the artifact's release.
+func artifactsUpdateRun(opts *CommandOptions, args []string) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	changes, err := parseMetadata(opts.metadataPairs)
+	if err != nil {
+		return err
+	}
+
+	artifact, err := opts.client.GetArtifact(opts.ctx, args[0], 0)
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	if artifact.ReleaseID == "" {
+		return fmt.Errorf(`artifact "%s" is not acceptable (it has no release)`, args[0])
+	}
+
+	release, err := opts.client.GetRelease(opts.ctx, artifact.ReleaseID)
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	metadata := map[string]interface{}{}
+	for k, v := range release.Metadata {
+		metadata[k] = v
+	}
+
+	for k, v := range changes {
+		if v == "" {
+			delete(metadata, k)
+
+			continue
+		}
+
+		metadata[k] = v
+	}
+
+	if err := opts.client.UpdateReleaseMetadata(opts.ctx, release, metadata); err != nil {
+		return formatAPIError(err)
+	}
+
+	if isStructuredOutput(opts.output) {
+		return render(opts.output, rendering{value: map[string]interface{}{
+			"id":       artifact.ID,
+			"release":  release.ID,
+			"metadata": metadata,
+		}})
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("updated artifact " + italic(artifact.ID))
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// orphanedArtifact is an artifact whose release doesn't exist, or whose upload
+// never finished, along with why it's considered orphaned.
+type orphanedArtifact struct {
+	artifact *keygenext.Artifact
+	reason   string
+}
+
+func newArtifactsGCCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "delete artifacts which no release references, e.g. from failed publishes",
+		Example: `  keygen artifacts gc \
+      --account '1fddcec8-8dd3-4d8d-9b16-215cac0f9b52' \
+      --token 'admin-xxx'
+  keygen artifacts gc --older-than 720h --dry-run=false
+
+Artifacts are orphaned when they have no release, when their release was
+deleted, or when their upload never finished. Recent artifacts are left alone,
+since they may belong to a publish which is still in progress. Nothing is
+deleted unless given --dry-run=false.
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return artifactsGCRun(opts)
+		},
+		Annotations: map[string]string{adminTokenAnnotation: "true"},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", true, "report the orphaned artifacts and reclaimable storage without deleting them, or delete them after confirming with --dry-run=false")
+	cmd.Flags().DurationVar(&opts.olderThan, "older-than", 24*time.Hour, "only collect artifacts created at least this long ago")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "table", renderOutputUsage)
+
+	return cmd
+}
+
+func artifactsGCRun(opts *CommandOptions) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	if opts.olderThan < 0 {
+		return fmt.Errorf(`age "%s" is not acceptable (must not be negative)`, opts.olderThan)
+	}
+
+	orphans, err := findOrphanedArtifacts(opts)
+	if err != nil {
+		return err
+	}
+
+	var reclaimable int64
+
+	rows := [][]string{}
+	values := []map[string]interface{}{}
+
+	for _, o := range orphans {
+		a := o.artifact
+		reclaimable += a.Filesize
+
+		rows = append(rows, []string{a.ID, a.Filename, formatBytes(a.Filesize), o.reason, a.Created.Format(time.RFC3339)})
+		values = append(values, map[string]interface{}{
+			"id":       a.ID,
+			"filename": a.Filename,
+			"filesize": a.Filesize,
+			"reason":   o.reason,
+			"created":  a.Created.Format(time.RFC3339),
+		})
+	}
+
+	if isStructuredOutput(opts.output) {
+		if err := render(opts.output, rendering{value: map[string]interface{}{
+			"artifacts":   values,
+			"reclaimable": reclaimable,
+			"deleted":     !opts.dryRun && len(orphans) != 0,
+		}}); err != nil {
+			return err
+		}
+	} else {
+		if len(orphans) == 0 {
+			fmt.Println("no orphaned artifacts found")
+
+			return nil
+		}
+
+		if err := render(opts.output, rendering{
+			headers: []string{"ID", "FILENAME", "SIZE", "REASON", "CREATED"},
+			rows:    rows,
+		}); err != nil {
+			return err
+		}
+
+		fmt.Println()
+		fmt.Println(strconv.Itoa(len(orphans)) + " orphaned artifacts, " + formatBytes(reclaimable) + " reclaimable")
+	}
+
+	if opts.dryRun || len(orphans) == 0 {
+		return nil
+	}
+
+	affected := []string{}
+	for _, o := range orphans {
+		affected = append(affected, o.artifact.ID+" ("+o.artifact.Filename+", "+o.reason+")")
+	}
+
+	if err := opts.confirmAction("delete "+strconv.Itoa(len(orphans))+" orphaned artifacts", affected, ""); err != nil {
+		return err
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	for _, o := range orphans {
+		if err := opts.client.DeleteArtifact(opts.ctx, o.artifact); err != nil {
+			return fmt.Errorf("artifact %s could not be deleted (%w)", o.artifact.ID, formatAPIError(err))
+		}
+
+		// Status lines would corrupt structured output
+		if isStructuredOutput(opts.output) {
+			fmt.Fprintln(os.Stderr, "deleted orphaned artifact "+o.artifact.ID)
+		} else {
+			fmt.Println("deleted orphaned artifact " + italic(o.artifact.ID) + " (" + o.artifact.Filename + ")")
+		}
+	}
+
+	return nil
+}
+
+// findOrphanedArtifacts returns the account's artifacts which are older than
+// --older-than and whose release doesn't exist, or whose upload never finished.
+func findOrphanedArtifacts(opts *CommandOptions) ([]*orphanedArtifact, error) {
+	artifacts, err := opts.client.ListArtifacts(opts.ctx, &keygenext.ListParams{Paging: keygenext.Paging{All: true}})
+	if err != nil {
+		return nil, formatAPIError(err)
+	}
+
+	return classifyOrphanedArtifacts(artifacts, time.Now().Add(-opts.olderThan), func(id string) (bool, error) {
+		_, err := opts.client.GetRelease(opts.ctx, id)
+		if err == nil {
+			return true, nil
+		}
+
+		if e, ok := err.(*keygenext.APIError); ok && e.Status == http.StatusNotFound {
+			return false, nil
+		}
+
+		return false, fmt.Errorf(`release "%s" could not be checked (%w)`, id, formatAPIError(err))
+	})
+}
+
+// classifyOrphanedArtifacts returns the artifacts created before cutoff which
+// are orphaned. Releases are looked up one by one, rather than listed, since a
+// listing only includes the releases the token can see, and an artifact of a
+// release which merely isn't listed would be deleted for good. Only a release
+// which doesn't exist orphans its artifact.
+func classifyOrphanedArtifacts(artifacts keygenext.Artifacts, cutoff time.Time, releaseExists func(id string) (bool, error)) ([]*orphanedArtifact, error) {
+	exists := map[string]bool{}
+	orphans := []*orphanedArtifact{}
+
+	for i := range artifacts {
+		a := &artifacts[i]
+		if a.Created.After(cutoff) {
+			continue
+		}
+
+		if a.ReleaseID != "" {
+			if _, ok := exists[a.ReleaseID]; !ok {
+				ok, err := releaseExists(a.ReleaseID)
+				if err != nil {
+					return nil, err
+				}
+
+				exists[a.ReleaseID] = ok
+			}
+		}
+
+		var reason string
+
+		switch {
+		case a.ReleaseID == "":
+			reason = "no release"
+		case !exists[a.ReleaseID]:
+			reason = "release deleted"
+		case a.Status == "WAITING" || a.Status == "FAILED":
+			reason = "upload incomplete"
+		default:
+			continue
+		}
+
+		orphans = append(orphans, &orphanedArtifact{artifact: a, reason: reason})
+	}
+
+	return orphans, nil
+}
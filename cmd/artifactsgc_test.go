@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keygen-sh/keygen-cli/keygenext"
+)
+
+func TestClassifyOrphanedArtifacts(t *testing.T) {
+	now := time.Date(2021, 11, 30, 0, 0, 0, 0, time.UTC)
+	cutoff := now.Add(-24 * time.Hour)
+	old := now.Add(-48 * time.Hour)
+
+	artifacts := keygenext.Artifacts{
+		{ID: "a1", Created: old},
+		{ID: "a2", Created: old, ReleaseID: "deleted"},
+		{ID: "a3", Created: old, ReleaseID: "live", Status: "UPLOADED"},
+		{ID: "a4", Created: old, ReleaseID: "live", Status: "WAITING"},
+		{ID: "a5", Created: old, ReleaseID: "live", Status: "FAILED"},
+		{ID: "a6", Created: now},
+		{ID: "a7", Created: old, ReleaseID: "deleted", Status: "WAITING"},
+	}
+
+	lookups := map[string]int{}
+
+	orphans, err := classifyOrphanedArtifacts(artifacts, cutoff, func(id string) (bool, error) {
+		lookups[id]++
+
+		return id == "live", nil
+	})
+	if err != nil {
+		t.Fatalf("classifyOrphanedArtifacts() error = %v", err)
+	}
+
+	got := map[string]string{}
+	for _, o := range orphans {
+		got[o.artifact.ID] = o.reason
+	}
+
+	want := map[string]string{
+		"a1": "no release",
+		"a2": "release deleted",
+		"a4": "upload incomplete",
+		"a5": "upload incomplete",
+		"a7": "release deleted",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orphans = %v, want %v", got, want)
+	}
+
+	// Each release is only looked up once
+	if want := map[string]int{"deleted": 1, "live": 1}; !reflect.DeepEqual(lookups, want) {
+		t.Errorf("lookups = %v, want %v", lookups, want)
+	}
+}
+
+func TestClassifyOrphanedArtifactsLookupError(t *testing.T) {
+	artifacts := keygenext.Artifacts{{ID: "a1", ReleaseID: "r1"}}
+
+	_, err := classifyOrphanedArtifacts(artifacts, time.Now(), func(id string) (bool, error) {
+		return false, errors.New("unreachable")
+	})
+	if err == nil {
+		t.Fatal("classifyOrphanedArtifacts() deleted artifacts whose release couldn't be checked")
+	}
+}
+
+func TestFindOrphanedArtifacts(t *testing.T) {
+	tests := []struct {
+		name    string
+		release int
+		want    map[string]string
+		err     bool
+	}{
+		{name: "release exists", release: http.StatusOK, want: map[string]string{"a1": "no release"}},
+		{name: "release deleted", release: http.StatusNotFound, want: map[string]string{"a1": "no release", "a2": "release deleted"}},
+		{name: "release forbidden", release: http.StatusForbidden, err: true},
+		{name: "server error", release: http.StatusInternalServerError, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := newTestOptions(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/accounts/acct/artifacts":
+					writeJSONAPI(w, http.StatusOK, `{"data":[`+
+						`{"id":"a1","type":"artifacts","attributes":{"filename":"a.zip","status":"UPLOADED","created":"2021-01-01T00:00:00Z"}},`+
+						`{"id":"a2","type":"artifacts","attributes":{"filename":"b.zip","status":"UPLOADED","created":"2021-01-01T00:00:00Z"},"relationships":{"release":{"data":{"type":"releases","id":"r1"}}}}`+
+						`],"links":{"next":null}}`)
+				case "/v1/accounts/acct/releases/r1":
+					switch tt.release {
+					case http.StatusOK:
+						writeJSONAPI(w, tt.release, `{"data":{"id":"r1","type":"releases","attributes":{"version":"1.0.0"}}}`)
+					case http.StatusNotFound:
+						writeJSONAPI(w, tt.release, `{"errors":[{"title":"Not found","code":"NOT_FOUND"}]}`)
+					case http.StatusForbidden:
+						writeJSONAPI(w, tt.release, `{"errors":[{"title":"Access denied","code":"FORBIDDEN"}]}`)
+					default:
+						w.WriteHeader(tt.release)
+					}
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+
+			opts.olderThan = time.Hour
+
+			orphans, err := findOrphanedArtifacts(opts)
+			if (err != nil) != tt.err {
+				t.Fatalf("findOrphanedArtifacts() error = %v, want error %v", err, tt.err)
+			}
+
+			if err != nil {
+				if !strings.Contains(err.Error(), `release "r1" could not be checked`) {
+					t.Errorf("findOrphanedArtifacts() error = %q, want it to name the release", err)
+				}
+
+				return
+			}
+
+			got := map[string]string{}
+			for _, o := range orphans {
+				got[o.artifact.ID] = o.reason
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("orphans = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
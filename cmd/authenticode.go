@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const defaultAuthenticodeTimestampURL = "http://timestamp.digicert.com"
+
+// authenticodeSign code-signs an exe, msi or dll in place using signtool,
+// osslsigncode or Azure Trusted Signing (via signtool's dlib support). Other
+// files are skipped, e.g. the macOS and Linux artifacts of a multi-artifact
+// release. Certificate passwords are never passed as arguments, since those
+// are visible to every user, e.g. using ps.
+func authenticodeSign(opts *CommandOptions, path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".exe", ".msi", ".dll":
+	default:
+		if opts.output != "json" {
+			fmt.Fprintln(os.Stderr, "skipped code signing "+filepath.Base(path)+" (not an exe, msi or dll)")
+		}
+
+		return nil
+	}
+
+	cert := os.Getenv("KEYGEN_AUTHENTICODE_CERT")
+	password := os.Getenv("KEYGEN_AUTHENTICODE_PASSWORD")
+	timestamp := opts.timestampURL
+
+	var name string
+	var args []string
+
+	switch tool := opts.authenticode; tool {
+	case "signtool":
+		name = "signtool"
+		args = []string{"sign", "/fd", "SHA256", "/tr", timestamp, "/td", "SHA256"}
+
+		switch thumbprint := os.Getenv("KEYGEN_AUTHENTICODE_THUMBPRINT"); {
+		case thumbprint != "":
+			args = append(args, "/sha1", thumbprint)
+		case cert != "" && password != "":
+			// signtool only takes a certificate's password as an argument
+			return errors.New("authenticode certificate is password protected (import it into the certificate store and use $KEYGEN_AUTHENTICODE_THUMBPRINT, or use osslsigncode)")
+		case cert != "":
+			args = append(args, "/f", cert)
+		default:
+			return errors.New("authenticode certificate is missing (use $KEYGEN_AUTHENTICODE_CERT or $KEYGEN_AUTHENTICODE_THUMBPRINT)")
+		}
+
+		if n := opts.name; n != "" {
+			args = append(args, "/d", n)
+		}
+
+		args = append(args, path)
+	case "osslsigncode":
+		if cert == "" {
+			return errors.New("authenticode certificate is missing (use $KEYGEN_AUTHENTICODE_CERT)")
+		}
+
+		name = "osslsigncode"
+		args = []string{"sign", "-pkcs12", cert, "-h", "sha256", "-ts", timestamp}
+		if password != "" {
+			passfile, err := writeAuthenticodePassword(password)
+			if err != nil {
+				return err
+			}
+			defer os.Remove(passfile)
+
+			args = append(args, "-readpass", passfile)
+		}
+
+		if n := opts.name; n != "" {
+			args = append(args, "-n", n)
+		}
+
+		// osslsigncode can't sign in place, so sign into a temporary file next
+		// to the original and then replace it
+		args = append(args, "-in", path, "-out", path+".signed")
+	case "azure":
+		dlib := os.Getenv("KEYGEN_AZURE_CODESIGNING_DLIB")
+		metadata := os.Getenv("KEYGEN_AZURE_CODESIGNING_METADATA")
+		if dlib == "" || metadata == "" {
+			return errors.New("azure trusted signing is not configured (use $KEYGEN_AZURE_CODESIGNING_DLIB and $KEYGEN_AZURE_CODESIGNING_METADATA)")
+		}
+
+		name = "signtool"
+		args = []string{"sign", "/fd", "SHA256", "/tr", timestamp, "/td", "SHA256", "/dlib", dlib, "/dmdf", metadata, path}
+	default:
+		return fmt.Errorf(`authenticode tool "%s" is not supported`, tool)
+	}
+
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		os.Remove(path + ".signed")
+
+		return fmt.Errorf("code signing failed (%s)", strings.TrimSpace(string(out)+" "+err.Error()))
+	}
+
+	if opts.authenticode == "osslsigncode" {
+		if err := os.Rename(path+".signed", path); err != nil {
+			return fmt.Errorf("code signing failed (%s)", err)
+		}
+	}
+
+	if opts.output != "json" {
+		fmt.Fprintln(os.Stderr, "code-signed "+filepath.Base(path)+" using "+opts.authenticode)
+	}
+
+	return nil
+}
+
+// writeAuthenticodePassword writes a certificate's password to a temporary
+// file only readable by the current user, for osslsigncode's -readpass.
+func writeAuthenticodePassword(password string) (string, error) {
+	f, err := ioutil.TempFile("", "keygen-authenticode-")
+	if err != nil {
+		return "", fmt.Errorf("authenticode password could not be written (%s)", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(password); err != nil {
+		os.Remove(f.Name())
+
+		return "", fmt.Errorf("authenticode password could not be written (%s)", err)
+	}
+
+	return f.Name(), nil
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-go"
+	"github.com/mattn/go-isatty"
+	"github.com/mitchellh/go-homedir"
+)
+
+const (
+	// defaultUpgradeCheckCache is where the result of the last automatic
+	// upgrade check is cached, so that dist only checks once per interval.
+	defaultUpgradeCheckCache = "~/.keygen/upgrade-check.json"
+
+	// upgradeCheckGrace is how long a finished command waits for an upgrade
+	// check which is still in-flight.
+	upgradeCheckGrace = 500 * time.Millisecond
+)
+
+// upgradeCheckResult is the cached result of an upgrade check, which is only
+// used by the CLI version which made it.
+type upgradeCheckResult struct {
+	Checked time.Time `json:"checked"`
+	Current string    `json:"current"`
+	Latest  string    `json:"latest,omitempty"`
+}
+
+// upgradeCheck is an automatic upgrade check, which runs in the background so
+// that it never delays or fails the command it's run for.
+type upgradeCheck struct {
+	done   chan struct{}
+	result *upgradeCheckResult
+}
+
+// startUpgradeCheck checks for a CLI upgrade in the background, unless the last
+// check is more recent than the interval. Checks only run in a terminal, since
+// nobody is there to upgrade in CI.
+func startUpgradeCheck(interval time.Duration) *upgradeCheck {
+	c := &upgradeCheck{done: make(chan struct{})}
+
+	if !isatty.IsTerminal(os.Stdout.Fd()) && !isatty.IsCygwinTerminal(os.Stdout.Fd()) {
+		close(c.done)
+
+		return c
+	}
+
+	// Self-hosted instances only serve upgrades when they mirror the CLI
+	if isSelfHosted() && (keygen.Account == "" || keygen.Product == "") {
+		close(c.done)
+
+		return c
+	}
+
+	if cached := readUpgradeCheck(); cached != nil && cached.Current == Version && time.Since(cached.Checked) < interval {
+		c.result = cached
+		close(c.done)
+
+		return c
+	}
+
+	go func() {
+		defer close(c.done)
+
+		result := &upgradeCheckResult{Checked: time.Now().UTC(), Current: Version}
+
+		release, err := keygen.Upgrade(Version)
+		switch {
+		case err == keygen.ErrUpgradeNotAvailable:
+		case err != nil:
+			// Try again next time rather than caching a failed check
+			return
+		default:
+			result.Latest = release.Version
+		}
+
+		writeUpgradeCheck(result)
+
+		c.result = result
+	}()
+
+	return c
+}
+
+// notify prints a notice when an upgrade is available, waiting briefly for the
+// check to finish. Checks which haven't finished by then are abandoned.
+func (c *upgradeCheck) notify() {
+	select {
+	case <-c.done:
+	case <-time.After(upgradeCheckGrace):
+		return
+	}
+
+	if c.result == nil || c.result.Latest == "" {
+		return
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Fprintln(os.Stderr, "an upgrade is available: "+italic("v"+c.result.Latest)+" (run `keygen upgrade` to install)")
+}
+
+func readUpgradeCheck() *upgradeCheckResult {
+	p, err := homedir.Expand(defaultUpgradeCheckCache)
+	if err != nil {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil
+	}
+
+	result := &upgradeCheckResult{}
+	if err := json.Unmarshal(b, result); err != nil {
+		return nil
+	}
+
+	return result
+}
+
+// writeUpgradeCheck caches the result of a check. Failing to cache is ignored,
+// since it only means checking again next time.
+func writeUpgradeCheck(result *upgradeCheckResult) {
+	p, err := homedir.Expand(defaultUpgradeCheckCache)
+	if err != nil {
+		return
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return
+	}
+
+	// Write atomically, so that concurrent runs never read a partial result
+	tmp, err := ioutil.TempFile(filepath.Dir(p), ".upgrade-check-*.json")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+
+		return
+	}
+
+	if err := tmp.Close(); err != nil {
+		return
+	}
+
+	os.Rename(tmp.Name(), p)
+}
@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/Masterminds/semver"
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// brewPlatforms maps Keygen platforms onto Homebrew's OS and CPU blocks.
+var brewPlatforms = map[string][2]string{
+	"darwin/amd64": {"macos", "intel"},
+	"darwin/arm64": {"macos", "arm"},
+	"linux/amd64":  {"linux", "intel"},
+	"linux/arm64":  {"linux", "arm"},
+}
+
+var brewTemplate = template.Must(template.New("formula").Funcs(template.FuncMap{"ruby": rubyString}).Parse(`class {{ .Class }} < Formula
+  desc "{{ ruby .Description }}"
+  homepage "{{ ruby .Homepage }}"
+  version "{{ .Version }}"
+{{ range $os, $bottles := .Bottles }}
+  on_{{ $os }} do
+{{- range $bottles }}
+    if Hardware::CPU.{{ .CPU }}?
+      url "{{ .URL }}",
+          headers: ["Authorization: License #{ENV["HOMEBREW_KEYGEN_LICENSE_KEY"]}"]
+      sha256 "{{ .SHA256 }}"
+    end
+{{- end }}
+  end
+{{ end }}
+  def install
+    bin.install Dir["*"].first => "{{ ruby .Binary }}"
+  end
+
+  def caveats
+    <<~EOS
+      Downloading {{ .Formula }} requires a valid license key. Before installing,
+      export your license key so that Homebrew can authenticate the download:
+
+        export HOMEBREW_KEYGEN_LICENSE_KEY="<license-key>"
+    EOS
+  end
+end
+`))
+
+type brewBottle struct {
+	CPU    string
+	URL    string
+	SHA256 string
+}
+
+func newBrewCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "brew",
+		Short: "generate a homebrew formula for a published version",
+		Example: `  keygen brew \
+      --account '1fddcec8-8dd3-4d8d-9b16-215cac0f9b52' \
+      --product '2313b7e7-1ea6-4a01-901e-2931de6bb1e2' \
+      --token 'prod-xxx' \
+      --formula 'my-program' \
+      --version '1.0.0' \
+      --tap 'my-org/homebrew-tap' \
+      --open-pr
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return brewRun(opts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+	addProductFlag(cmd, s)
+
+	cmd.Flags().StringVar(&opts.version, "version", "", "version of the releases to package (required)")
+	cmd.Flags().StringVar(&opts.channel, "channel", "stable", "channel of the releases to package")
+	cmd.Flags().StringVar(&opts.formula, "formula", "", "name of the formula (required)")
+	cmd.Flags().StringVar(&opts.description, "description", "", "description for the formula")
+	cmd.Flags().StringVar(&opts.homepage, "homepage", "", "homepage for the formula")
+	cmd.Flags().StringVar(&opts.binary, "binary", "", "name of the installed executable (defaults to the formula name)")
+	cmd.Flags().StringVar(&opts.out, "out", "", "write the formula to the specified file (defaults to stdout)")
+	cmd.Flags().StringVar(&opts.tap, "tap", "", "homebrew tap repository to open a pull request against (e.g. my-org/homebrew-tap)")
+	cmd.Flags().BoolVar(&opts.openPR, "open-pr", false, "open a pull request against --tap with the formula (requires git and gh)")
+
+	cmd.MarkFlagRequired("version")
+	cmd.MarkFlagRequired("formula")
+
+	return cmd
+}
+
+func brewRun(opts *CommandOptions) error {
+	if opts.openPR && opts.tap == "" {
+		return errors.New("--tap is required when opening a pull request")
+	}
+
+	if err := opts.checkArtifactHost(); err != nil {
+		return err
+	}
+
+	version, err := semver.NewVersion(opts.version)
+	if err != nil {
+		return fmt.Errorf(`version "%s" is not acceptable (%s)`, opts.version, strings.ToLower(err.Error()))
+	}
+
+	releases, err := opts.client.ListReleases(opts.ctx, &keygenext.ReleaseFilter{
+		Product: opts.productID,
+		Version: version.String(),
+		Channel: opts.channel,
+		Paging:  keygenext.Paging{All: true},
+	})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	bottles := map[string][]brewBottle{}
+	bottled := map[string]keygenext.Release{}
+
+	for _, release := range releases {
+		platform, ok := brewPlatforms[release.Platform]
+		if !ok {
+			continue
+		}
+
+		// Only the artifacts Homebrew could install, i.e. not drafts, yanked
+		// releases, or GPG signature and symbols companions
+		if release.Status != "PUBLISHED" || release.Yanked != nil || isCompanionRelease(release) {
+			continue
+		}
+
+		if other, ok := bottled[release.Platform]; ok {
+			return fmt.Errorf(`version "%s" has more than one release for platform "%s" (%s and %s)`, version, release.Platform, other.Filename, release.Filename)
+		}
+
+		bottled[release.Platform] = release
+
+		checksum, err := opts.calculateRemoteSHA256(&release)
+		if err != nil {
+			return fmt.Errorf(`release "%s" could not be downloaded (%s)`, release.ID, err)
+		}
+
+		goos, cpu := platform[0], platform[1]
+		bottles[goos] = append(bottles[goos], brewBottle{CPU: cpu, URL: opts.client.ReleaseArtifactURL(&release), SHA256: checksum})
+	}
+
+	if len(bottles) == 0 {
+		return fmt.Errorf(`version "%s" has no macOS or Linux releases`, version)
+	}
+
+	for _, b := range bottles {
+		sort.Slice(b, func(i, j int) bool { return b[i].CPU < b[j].CPU })
+	}
+
+	binary := opts.binary
+	if binary == "" {
+		binary = opts.formula
+	}
+
+	var buf bytes.Buffer
+
+	err = brewTemplate.Execute(&buf, map[string]interface{}{
+		"Class":       brewClassName(opts.formula),
+		"Formula":     opts.formula,
+		"Description": opts.description,
+		"Homepage":    opts.homepage,
+		"Version":     version.String(),
+		"Binary":      binary,
+		"Bottles":     bottles,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case opts.openPR:
+		return openBrewPullRequest(opts, version.String(), buf.Bytes())
+	case opts.out != "":
+		if err := ioutil.WriteFile(opts.out, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf(`formula could not be written (%s)`, err)
+		}
+
+		fmt.Println("wrote formula to " + opts.out)
+	default:
+		fmt.Print(buf.String())
+	}
+
+	return nil
+}
+
+// calculateRemoteSHA256 streams a release's artifact to calculate its SHA-256
+// checksum, since Homebrew does not support SHA-512.
+func (s *session) calculateRemoteSHA256(release *keygenext.Release) (string, error) {
+	// Use the checksum recorded by `keygen dist --extra-checksums sha256`
+	if checksums, ok := release.Metadata["checksums"].(map[string]interface{}); ok {
+		if sum, ok := checksums["sha256"].(string); ok && sum != "" {
+			return sum, nil
+		}
+	}
+
+	artifact, err := s.client.GetReleaseArtifact(s.ctx, release)
+	if err != nil {
+		return "", err
+	}
+
+	body, _, err := artifact.Download(s.ctx, 0, 0)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	h := sha256.New()
+
+	if _, err := io.Copy(h, body); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// rubyString escapes s for use within a double-quoted Ruby string, including
+// "#", which would otherwise start an interpolation.
+func rubyString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "#", `\#`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s)
+}
+
+// brewClassName converts a formula name into Homebrew's class name convention,
+// e.g. "my-program" becomes "MyProgram".
+func brewClassName(formula string) string {
+	var b strings.Builder
+
+	upper := true
+	for _, r := range formula {
+		switch {
+		case r == '@':
+			b.WriteString("AT")
+			upper = false
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+func openBrewPullRequest(opts *CommandOptions, version string, formula []byte) error {
+	dir, err := ioutil.TempDir("", "keygen-brew-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	branch := opts.formula + "-" + version
+	title := opts.formula + " " + version
+	steps := [][]string{
+		{"gh", "repo", "clone", opts.tap, dir},
+		{"git", "-C", dir, "checkout", "-b", branch},
+	}
+
+	for _, step := range steps {
+		if err := runBrewStep(step); err != nil {
+			return err
+		}
+	}
+
+	path := filepath.Join(dir, "Formula", opts.formula+".rb")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, formula, 0644); err != nil {
+		return err
+	}
+
+	steps = [][]string{
+		{"git", "-C", dir, "add", path},
+		{"git", "-C", dir, "commit", "-m", title},
+		{"git", "-C", dir, "push", "-u", "origin", branch},
+		{"gh", "pr", "create", "--repo", opts.tap, "--head", branch, "--title", title, "--body", "Generated by keygen-cli."},
+	}
+
+	for _, step := range steps {
+		if err := runBrewStep(step); err != nil {
+			return err
+		}
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("opened pull request for " + italic(title))
+
+	return nil
+}
+
+func runBrewStep(args []string) error {
+	c := exec.Command(args[0], args[1:]...)
+	c.Stdout = os.Stderr
+	c.Stderr = os.Stderr
+
+	if err := c.Run(); err != nil {
+		return fmt.Errorf(`command "%s" failed (%s)`, strings.Join(args[:2], " "), err)
+	}
+
+	return nil
+}
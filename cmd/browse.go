@@ -0,0 +1,442 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eiannone/keyboard"
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+type browseView int
+
+const (
+	browseViewProducts browseView = iota
+	browseViewReleases
+	browseViewRelease
+)
+
+// browseChannels are cycled through with tab when listing releases, where an
+// empty channel lists releases for every channel.
+var browseChannels = []string{"", "stable", "rc", "beta", "alpha", "dev"}
+
+func newBrowseCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "browse",
+		Short: "interactively browse products, releases and artifacts",
+		Example: `  keygen browse
+
+Keys:
+  ↑/↓ or k/j   move            enter   open
+  tab          cycle channel   esc     back
+  y            yank release    d       delete release
+  c            copy URL        r       refresh
+  q            quit
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return browseRun(opts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+
+	cmd.Flags().StringVar(&s.productID, "product", "", "start browsing a product's releases [$KEYGEN_PRODUCT_ID=<id>]")
+	cmd.Flags().IntVar(&opts.limit, "limit", 100, "number of products and releases to list")
+
+	bindEnv(cmd.Flags(), "product", "KEYGEN_PRODUCT_ID")
+
+	return cmd
+}
+
+// browser holds the state of the interactive browser.
+type browser struct {
+	opts     *CommandOptions
+	view     browseView
+	products keygenext.Products
+	releases keygenext.Releases
+	product  *keygenext.ProductObject
+	channel  int
+	cursor   int
+	offset   int
+	status   string
+	confirm  string
+}
+
+func browseRun(opts *CommandOptions) error {
+	if !isatty.IsTerminal(os.Stdout.Fd()) && !isatty.IsCygwinTerminal(os.Stdout.Fd()) {
+		return errors.New("browse requires an interactive terminal")
+	}
+
+	if err := opts.checkArtifactHost(); err != nil {
+		return err
+	}
+
+	b := &browser{opts: opts, view: browseViewProducts}
+	if opts.productID != "" {
+		b.product = &keygenext.ProductObject{ID: opts.productID, Name: opts.productID}
+		b.view = browseViewReleases
+	}
+
+	if err := b.load(); err != nil {
+		return formatAPIError(err)
+	}
+
+	if err := keyboard.Open(); err != nil {
+		return err
+	}
+	defer keyboard.Close()
+
+	// Hide the cursor while browsing, and restore the screen afterwards
+	fmt.Print("\033[?25l")
+	defer fmt.Print("\033[?25h\033[H\033[2J")
+
+	for {
+		b.render()
+
+		ch, key, err := keyboard.GetKey()
+		if err != nil {
+			return err
+		}
+
+		if quit := b.handle(ch, key); quit {
+			return nil
+		}
+	}
+}
+
+// load fetches the products or releases for the current view.
+func (b *browser) load() error {
+	switch b.view {
+	case browseViewProducts:
+		products, err := b.opts.client.ListProducts(b.opts.ctx, &keygenext.ListParams{Limit: b.opts.limit})
+		if err != nil {
+			return err
+		}
+
+		b.products = products
+	case browseViewReleases:
+		filter := &keygenext.ReleaseFilter{Product: b.product.ID, Channel: browseChannels[b.channel], Limit: b.opts.limit}
+
+		releases, err := b.opts.client.ListReleases(b.opts.ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		b.releases = releases
+	}
+
+	if n := b.len(); b.cursor >= n {
+		b.cursor = n - 1
+	}
+
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+
+	return nil
+}
+
+// len returns the number of selectable rows in the current view.
+func (b *browser) len() int {
+	switch b.view {
+	case browseViewProducts:
+		return len(b.products)
+	case browseViewReleases:
+		return len(b.releases)
+	default:
+		return 0
+	}
+}
+
+// selected returns the release under the cursor, if any.
+func (b *browser) selected() *keygenext.Release {
+	if b.view == browseViewProducts || b.cursor >= len(b.releases) {
+		return nil
+	}
+
+	return &b.releases[b.cursor]
+}
+
+// handle applies a key press, and reports whether the browser should quit.
+func (b *browser) handle(ch rune, key keyboard.Key) bool {
+	if b.confirm != "" {
+		action := b.confirm
+		b.confirm = ""
+
+		if ch == 'y' || ch == 'Y' {
+			b.perform(action)
+		} else {
+			b.status = action + " aborted"
+		}
+
+		return false
+	}
+
+	b.status = ""
+
+	switch {
+	case ch == 'q' || key == keyboard.KeyCtrlC:
+		return true
+	case ch == 'k' || key == keyboard.KeyArrowUp:
+		if b.cursor > 0 {
+			b.cursor--
+		}
+	case ch == 'j' || key == keyboard.KeyArrowDown:
+		if b.cursor < b.len()-1 {
+			b.cursor++
+		}
+	case key == keyboard.KeyEnter || ch == 'l' || key == keyboard.KeyArrowRight:
+		b.open()
+	case key == keyboard.KeyEsc || ch == 'h' || key == keyboard.KeyArrowLeft || key == keyboard.KeyBackspace || key == keyboard.KeyBackspace2:
+		b.back()
+	case key == keyboard.KeyTab && b.view == browseViewReleases:
+		b.channel = (b.channel + 1) % len(browseChannels)
+		b.cursor = 0
+		b.reload()
+	case ch == 'r':
+		b.reload()
+	case ch == 'c' && b.selected() != nil:
+		b.perform("copy")
+	case ch == 'y' && b.selected() != nil:
+		b.confirm = "yank"
+	case ch == 'd' && b.selected() != nil:
+		b.confirm = "delete"
+	}
+
+	return false
+}
+
+func (b *browser) open() {
+	switch b.view {
+	case browseViewProducts:
+		if len(b.products) == 0 {
+			return
+		}
+
+		b.product = &b.products[b.cursor]
+		b.view = browseViewReleases
+		b.cursor = 0
+		b.reload()
+	case browseViewReleases:
+		if len(b.releases) == 0 {
+			return
+		}
+
+		b.view = browseViewRelease
+	}
+}
+
+func (b *browser) back() {
+	switch b.view {
+	case browseViewRelease:
+		b.view = browseViewReleases
+	case browseViewReleases:
+		b.view = browseViewProducts
+		b.cursor = 0
+		b.reload()
+	}
+}
+
+func (b *browser) reload() {
+	if err := b.load(); err != nil {
+		b.status = formatAPIError(err).Error()
+	}
+}
+
+// perform runs an action against the selected release.
+func (b *browser) perform(action string) {
+	release := b.selected()
+	if release == nil {
+		return
+	}
+
+	switch action {
+	case "copy":
+		artifact, err := b.opts.client.GetReleaseArtifact(b.opts.ctx, release)
+		if err != nil {
+			b.status = formatAPIError(err).Error()
+
+			return
+		}
+
+		if artifact.Location == "" {
+			b.status = keygenext.ErrArtifactLocationMissing.Error()
+
+			return
+		}
+
+		url := b.opts.client.ArtifactDownloadURL(artifact)
+
+		// Copy using an OSC 52 escape sequence, which most terminal emulators
+		// support (including over SSH) without needing a clipboard utility.
+		fmt.Print("\033]52;c;" + base64.StdEncoding.EncodeToString([]byte(url)) + "\a")
+
+		b.status = "copied " + url
+	case "yank":
+		if err := b.opts.client.YankRelease(b.opts.ctx, release); err != nil {
+			b.status = formatAPIError(err).Error()
+
+			return
+		}
+
+		b.status = "yanked release " + release.ID
+	case "delete":
+		id := release.ID
+		if err := b.opts.client.DeleteRelease(b.opts.ctx, release); err != nil {
+			b.status = formatAPIError(err).Error()
+
+			return
+		}
+
+		b.view = browseViewReleases
+		b.reload()
+		b.status = "deleted release " + id
+	}
+}
+
+func (b *browser) render() {
+	bold := color.New(color.Bold).SprintFunc()
+	faint := color.New(color.Faint).SprintFunc()
+	selected := color.New(color.ReverseVideo).SprintFunc()
+
+	_, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || height < 8 {
+		height = 24
+	}
+
+	// Leave room for the title, header, status and help lines
+	rows := height - 6
+
+	var out strings.Builder
+
+	out.WriteString("\033[H\033[2J")
+
+	switch b.view {
+	case browseViewProducts:
+		out.WriteString(bold("products") + "\r\n\r\n")
+
+		lines := []string{}
+		for _, p := range b.products {
+			lines = append(lines, fmt.Sprintf("%-36s  %s", p.ID, p.Name))
+		}
+
+		b.writeList(&out, fmt.Sprintf("%-36s  %s", "ID", "NAME"), lines, rows, selected, faint)
+	case browseViewReleases:
+		channel := browseChannels[b.channel]
+		if channel == "" {
+			channel = "all"
+		}
+
+		out.WriteString(bold(b.product.Name) + " " + glyph("›") + " releases " + faint("(channel: "+channel+")") + "\r\n\r\n")
+
+		lines := []string{}
+		for _, r := range b.releases {
+			lines = append(lines, fmt.Sprintf("%-14s  %-7s  %-14s  %-28s  %10s  %9d  %s", r.Version, r.Channel, r.Platform, r.Filename, formatBytes(r.Filesize), r.Downloads, releaseStatus(&r)))
+		}
+
+		b.writeList(&out, fmt.Sprintf("%-14s  %-7s  %-14s  %-28s  %10s  %9s  %s", "VERSION", "CHANNEL", "PLATFORM", "FILENAME", "SIZE", "DOWNLOADS", "STATUS"), lines, rows, selected, faint)
+	case browseViewRelease:
+		r := b.selected()
+
+		out.WriteString(bold(b.product.Name) + " " + glyph("›") + " " + bold(r.Version) + " " + glyph("›") + " " + r.Platform + "\r\n\r\n")
+
+		fields := [][2]string{
+			{"id", r.ID},
+			{"version", r.Version},
+			{"channel", r.Channel},
+			{"platform", r.Platform},
+			{"filename", r.Filename},
+			{"filetype", r.Filetype},
+			{"filesize", formatBytes(r.Filesize) + " (" + strconv.FormatInt(r.Filesize, 10) + " bytes)"},
+			{"downloads", strconv.FormatInt(r.Downloads, 10)},
+			{"status", releaseStatus(r)},
+			{"checksum", r.Checksum},
+			{"signature", r.Signature},
+			{"artifact", b.opts.client.ReleaseArtifactURL(r)},
+		}
+
+		if r.Created != nil {
+			fields = append(fields, [2]string{"created", r.Created.Format(time.RFC3339)})
+		}
+
+		for _, f := range fields {
+			out.WriteString(fmt.Sprintf("  %-10s %s\r\n", faint(f[0]), f[1]))
+		}
+	}
+
+	out.WriteString("\r\n")
+
+	switch {
+	case b.confirm != "":
+		yellow := color.New(color.FgYellow).SprintFunc()
+
+		out.WriteString(yellow(b.confirm+" release "+b.selected().Version+" ("+b.selected().Platform+")? y/N") + "\r\n")
+	case b.status != "":
+		out.WriteString(b.status + "\r\n")
+	default:
+		out.WriteString("\r\n")
+	}
+
+	switch b.view {
+	case browseViewProducts:
+		out.WriteString(faint(asciiText("↑/↓ move · enter open · r refresh · q quit")))
+	case browseViewReleases:
+		out.WriteString(faint(asciiText("↑/↓ move · enter open · tab channel · y yank · d delete · c copy URL · esc back · q quit")))
+	case browseViewRelease:
+		out.WriteString(faint(asciiText("y yank · d delete · c copy URL · esc back · q quit")))
+	}
+
+	fmt.Print(out.String())
+}
+
+// writeList writes a scrolling window of lines, highlighting the cursor.
+func (b *browser) writeList(out *strings.Builder, header string, lines []string, rows int, selected func(a ...interface{}) string, faint func(a ...interface{}) string) {
+	out.WriteString(faint(header) + "\r\n")
+
+	if len(lines) == 0 {
+		out.WriteString("  nothing to show\r\n")
+
+		return
+	}
+
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+
+	if b.cursor >= b.offset+rows {
+		b.offset = b.cursor - rows + 1
+	}
+
+	for i := b.offset; i < len(lines) && i < b.offset+rows; i++ {
+		if i == b.cursor {
+			out.WriteString(selected(lines[i]) + "\r\n")
+		} else {
+			out.WriteString(lines[i] + "\r\n")
+		}
+	}
+}
+
+func releaseStatus(r *keygenext.Release) string {
+	if r.Yanked != nil {
+		return "yanked"
+	}
+
+	return "published"
+}
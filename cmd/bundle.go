@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/mitchellh/go-homedir"
+)
+
+const bundleVersion = 1
+
+// distBundle is a set of releases prepared by --prepare-only, e.g. inside an
+// air-gapped network, to be published later by --from-bundle on a connected
+// machine. Artifact paths are relative to the bundle, and tokens are never
+// bundled.
+type distBundle struct {
+	Version  int           `json:"version"`
+	Prepared time.Time     `json:"prepared"`
+	Releases []*queueEntry `json:"releases"`
+}
+
+// bundleRelease adds a prepared release of the file at path to the bundle.
+func (s *session) bundleRelease(path string, release *keygenext.Release) error {
+	entry, err := s.newQueueEntry(path, release)
+	if err != nil {
+		return err
+	}
+
+	s.bundled = append(s.bundled, entry)
+
+	return nil
+}
+
+// writeBundle writes the prepared releases to a bundle, making their artifact
+// paths relative to it so that they can be copied alongside it.
+func writeBundle(opts *CommandOptions, bundlePath string) error {
+	p, err := homedir.Expand(bundlePath)
+	if err != nil {
+		return fmt.Errorf(`bundle path "%s" is not expandable (%s)`, bundlePath, err)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(p))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range opts.bundled {
+		if rel, err := filepath.Rel(dir, entry.Path); err == nil {
+			entry.Path = rel
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf(`bundle path "%s" is not writable (%s)`, bundlePath, err)
+	}
+
+	bundle := &distBundle{Version: bundleVersion, Prepared: time.Now().UTC(), Releases: opts.bundled}
+
+	b, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(p, b, 0644); err != nil {
+		return fmt.Errorf(`bundle path "%s" is not writable (%s)`, bundlePath, err)
+	}
+
+	if opts.output == "json" {
+		return printJSON(bundle)
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	for _, entry := range opts.bundled {
+		fmt.Println("prepared release " + italic("v"+entry.Release.Version) + " (" + entry.Path + ")")
+	}
+
+	fmt.Println("wrote bundle " + italic(bundlePath) + " (run `keygen dist --from-bundle` to publish)")
+
+	return nil
+}
+
+// readBundle reads a bundle written by writeBundle.
+func readBundle(bundlePath string) (*distBundle, error) {
+	p, err := homedir.Expand(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf(`bundle path "%s" is not expandable (%s)`, bundlePath, err)
+	}
+
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf(`bundle path "%s" is not readable (%s)`, bundlePath, err.(*os.PathError).Err)
+	}
+
+	bundle := &distBundle{}
+	if err := json.Unmarshal(b, bundle); err != nil {
+		return nil, fmt.Errorf(`bundle "%s" is not readable (%s)`, bundlePath, err)
+	}
+
+	if bundle.Version != bundleVersion {
+		return nil, fmt.Errorf(`bundle "%s" is not supported (version %d)`, bundlePath, bundle.Version)
+	}
+
+	for _, entry := range bundle.Releases {
+		if entry.Release == nil {
+			return nil, fmt.Errorf(`bundle "%s" is missing a release payload`, bundlePath)
+		}
+
+		if !filepath.IsAbs(entry.Path) {
+			entry.Path = filepath.Join(filepath.Dir(p), entry.Path)
+		}
+	}
+
+	return bundle, nil
+}
+
+// distFromBundle publishes the releases in a bundle, which only requires API
+// calls since every artifact was already checksummed and signed.
+func distFromBundle(opts *CommandOptions, bundlePath string) error {
+	bundle, err := readBundle(bundlePath)
+	if err != nil {
+		return err
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+	published := []map[string]interface{}{}
+
+	for _, entry := range bundle.Releases {
+		opts.client.Account = entry.Account
+		opts.productID = entry.Product
+
+		// Checked before entitlements are resolved, which may create them
+		if err := checkFreeze(opts, entry.Release.Channel); err != nil {
+			return err
+		}
+
+		// Entitlement codes can't be resolved offline, so they're resolved now
+		if len(entry.Entitlements) != 0 {
+			entitlements, err := opts.preflightConstraints(entry.Entitlements, opts.createEntitlements)
+			if err != nil {
+				return err
+			}
+
+			entry.Entitlements = entitlements
+		}
+
+		entry.Release.ProductID = entry.Product
+
+		// Package keys can't be resolved offline either
+		if entry.Package != "" {
+			opts.engine, opts.pkg = "", entry.Package
+
+			if err := validateEngine(opts); err != nil {
+				return err
+			}
+
+			entry.Package = opts.pkg
+		}
+
+		if err := checkSizeGate(opts, entry.Release); err != nil {
+			return err
+		}
+
+		if err := publishQueueEntry(opts, entry, entry.Path); err != nil {
+			return fmt.Errorf(`bundled release "%s" could not be published (%w)`, entry.Release.Version, err)
+		}
+
+		if opts.output == "json" {
+			published = append(published, map[string]interface{}{
+				"id":       entry.Release.ID,
+				"version":  entry.Release.Version,
+				"platform": entry.Release.Platform,
+				"filename": entry.Release.Filename,
+			})
+
+			continue
+		}
+
+		fmt.Println("published release " + italic(entry.Release.ID) + " (" + entry.Release.Filename + ")")
+	}
+
+	if opts.output == "json" {
+		return printJSON(published)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Masterminds/semver"
+)
+
+// defaultCIChannels maps branches to release channels when --ci is given,
+// where the first matching pattern wins.
+var defaultCIChannels = []string{"main=stable", "master=stable", "release/*=rc", "*=dev"}
+
+// ciEnvironment describes the CI build the CLI is running in.
+type ciEnvironment struct {
+	Provider string
+	Tag      string
+	Branch   string
+	Commit   string
+	RunURL   string
+	Actor    string
+}
+
+// detectCIEnvironment detects a supported CI provider from its environment
+// variables, returning nil when none is detected.
+func detectCIEnvironment() *ciEnvironment {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		env := &ciEnvironment{
+			Provider: "github-actions",
+			Commit:   os.Getenv("GITHUB_SHA"),
+			Actor:    os.Getenv("GITHUB_ACTOR"),
+		}
+
+		ref := os.Getenv("GITHUB_REF")
+		switch {
+		case strings.HasPrefix(ref, "refs/tags/"):
+			env.Tag = strings.TrimPrefix(ref, "refs/tags/")
+		case os.Getenv("GITHUB_HEAD_REF") != "":
+			env.Branch = os.Getenv("GITHUB_HEAD_REF")
+		default:
+			env.Branch = strings.TrimPrefix(ref, "refs/heads/")
+		}
+
+		if id := os.Getenv("GITHUB_RUN_ID"); id != "" {
+			server := os.Getenv("GITHUB_SERVER_URL")
+			if server == "" {
+				server = "https://github.com"
+			}
+
+			env.RunURL = server + "/" + os.Getenv("GITHUB_REPOSITORY") + "/actions/runs/" + id
+		}
+
+		return env
+	case os.Getenv("GITLAB_CI") == "true":
+		return &ciEnvironment{
+			Provider: "gitlab-ci",
+			Tag:      os.Getenv("CI_COMMIT_TAG"),
+			Branch:   os.Getenv("CI_COMMIT_BRANCH"),
+			Commit:   os.Getenv("CI_COMMIT_SHA"),
+			RunURL:   os.Getenv("CI_PIPELINE_URL"),
+			Actor:    os.Getenv("GITLAB_USER_LOGIN"),
+		}
+	case os.Getenv("CIRCLECI") == "true":
+		return &ciEnvironment{
+			Provider: "circleci",
+			Tag:      os.Getenv("CIRCLE_TAG"),
+			Branch:   os.Getenv("CIRCLE_BRANCH"),
+			Commit:   os.Getenv("CIRCLE_SHA1"),
+			RunURL:   os.Getenv("CIRCLE_BUILD_URL"),
+			Actor:    os.Getenv("CIRCLE_USERNAME"),
+		}
+	case os.Getenv("BUILDKITE") == "true":
+		return &ciEnvironment{
+			Provider: "buildkite",
+			Tag:      os.Getenv("BUILDKITE_TAG"),
+			Branch:   os.Getenv("BUILDKITE_BRANCH"),
+			Commit:   os.Getenv("BUILDKITE_COMMIT"),
+			RunURL:   os.Getenv("BUILDKITE_BUILD_URL"),
+			Actor:    os.Getenv("BUILDKITE_BUILD_CREATOR"),
+		}
+	default:
+		return nil
+	}
+}
+
+// version returns the version from the build's tag, e.g. "1.2.3" for a "v1.2.3"
+// tag, or an empty string when the build isn't for a semver tag.
+func (e *ciEnvironment) version() string {
+	if e.Tag == "" {
+		return ""
+	}
+
+	v, err := semver.NewVersion(e.Tag)
+	if err != nil {
+		return ""
+	}
+
+	return v.String()
+}
+
+// channel returns the release channel for the build. Tag builds use the tag's
+// prerelease identifier, e.g. "rc" for "v1.2.3-rc.1", while branch builds use
+// the first matching "<pattern>=<channel>" mapping.
+func (e *ciEnvironment) channel(mappings []string) string {
+	if v := e.version(); v != "" {
+		pre := semver.MustParse(v).Prerelease()
+		if pre == "" {
+			return "stable"
+		}
+
+		id := strings.SplitN(pre, ".", 2)[0]
+		switch id {
+		case "rc", "beta", "alpha", "dev":
+			return id
+		default:
+			return "dev"
+		}
+	}
+
+	if e.Branch == "" {
+		return ""
+	}
+
+	for _, m := range mappings {
+		parts := strings.SplitN(m, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		if ok, _ := path.Match(parts[0], e.Branch); ok {
+			return parts[1]
+		}
+	}
+
+	return ""
+}
+
+// metadata returns the build's details to attach to the release's metadata.
+func (e *ciEnvironment) metadata() map[string]interface{} {
+	metadata := map[string]interface{}{"ciProvider": e.Provider}
+
+	if e.Commit != "" {
+		metadata["commit"] = e.Commit
+	}
+
+	if e.RunURL != "" {
+		metadata["ciRunUrl"] = e.RunURL
+	}
+
+	if e.Actor != "" {
+		metadata["ciActor"] = e.Actor
+	}
+
+	return metadata
+}
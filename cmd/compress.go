@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// compressionAlgorithms maps --compress algorithms to their file extension
+// and supported level range.
+var compressionAlgorithms = map[string]struct {
+	ext      string
+	min, max int
+}{
+	"gzip": {ext: "gz", min: gzip.BestSpeed, max: gzip.BestCompression},
+	"zstd": {ext: "zst", min: 1, max: 19},
+}
+
+// validateCompression ensures the --compress and --compress-level flags are
+// supported, where a level of zero uses the algorithm's default.
+func validateCompression(algorithm string, level int) error {
+	if algorithm == "" {
+		return nil
+	}
+
+	c, ok := compressionAlgorithms[algorithm]
+	if !ok {
+		return fmt.Errorf(`compression algorithm "%s" is not supported`, algorithm)
+	}
+
+	if level != 0 && (level < c.min || level > c.max) {
+		return fmt.Errorf(`compression level "%d" is not acceptable (must be between %d and %d for %s)`, level, c.min, c.max, algorithm)
+	}
+
+	return nil
+}
+
+// compressArtifact streams the file into a compressed temporary file in dir
+// (or the default temp directory when empty), which the caller must remove.
+// The extension for the compressed file is returned alongside it.
+func compressArtifact(file *os.File, algorithm string, level int, dir string) (*os.File, string, error) {
+	defer file.Seek(0, io.SeekStart) // reset reader
+
+	tmp, err := ioutil.TempFile(dir, "keygen-*."+compressionAlgorithms[algorithm].ext)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch algorithm {
+	case "gzip":
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+
+		var w *gzip.Writer
+
+		w, err = gzip.NewWriterLevel(tmp, level)
+		if err != nil {
+			break
+		}
+
+		if _, err = io.Copy(w, file); err != nil {
+			break
+		}
+
+		err = w.Close()
+	case "zstd":
+		if level == 0 {
+			level = 3
+		}
+
+		// There's no zstd implementation in the standard library, so we'll
+		// shell out to the reference implementation.
+		cmd := exec.Command("zstd", "-q", "-c", "-"+strconv.Itoa(level))
+		cmd.Stdin = file
+		cmd.Stdout = tmp
+		cmd.Stderr = os.Stderr
+
+		if e := cmd.Run(); e != nil {
+			err = fmt.Errorf("zstd failed (%s)", e)
+		}
+	}
+
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return nil, "", fmt.Errorf(`compression using "%s" failed (%s)`, algorithm, err)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return nil, "", err
+	}
+
+	return tmp, compressionAlgorithms[algorithm].ext, nil
+}
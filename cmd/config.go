@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultConfigPath = "keygen.yml"
+
+// configOverrides are environment variables which take precedence over a
+// config key, even though they don't set the key's flag, e.g. a signing key
+// given by value in CI takes precedence over a configured path.
+var configOverrides = map[string][]string{
+	"signing-key": {"KEYGEN_SIGNING_KEY"},
+}
+
+// channelsConfigKey is the config key of per-channel blocks, whose keys
+// override the top-level keys for dist when publishing to the channel, e.g.
+//
+//	channels:
+//	  dev:
+//	    metadata: [retention=7d]
+//	  stable:
+//	    notarize: true
+//	    require: [signing-key, notarize]
+//
+// where require lists the flags which must be set for the channel.
+const channelsConfigKey = "channels"
+
+// loadConfig reads the project's config file, whose keys are flag names, e.g.
+// account, product or signing-key. A missing config file is only an error
+// when it was explicitly given.
+func loadConfig(path string) (map[string]interface{}, error) {
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return nil, fmt.Errorf(`config path "%s" is not expandable (%s)`, path, err)
+	}
+
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		if os.IsNotExist(err) && path == defaultConfigPath {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf(`config file "%s" is not readable (%s)`, path, err)
+	}
+
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return nil, fmt.Errorf(`config file "%s" is not valid (%s)`, path, err)
+	}
+
+	return config, nil
+}
+
+// applyConfig uses the config file as defaults for the command's flags. Flags
+// and environment variables take precedence over the config file, so only
+// flags which still have their default value are set.
+func (s *session) applyConfig(cmd *cobra.Command) error {
+	config, err := loadConfig(s.root.config)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range config {
+		if key == channelsConfigKey {
+			continue
+		}
+
+		f := cmd.Flags().Lookup(key)
+		if f == nil || f.Changed || f.Value.String() != f.DefValue || isConfigOverridden(f) {
+			continue
+		}
+
+		v, ok := configValue(value)
+		if !ok {
+			continue
+		}
+
+		if err := setFlagDefault(f, v); err != nil {
+			return fmt.Errorf(`config key "%s" is not acceptable (%s)`, key, err)
+		}
+	}
+
+	return nil
+}
+
+// channelConfig returns the config file's block for a channel, along with the
+// flags it requires. A missing block is empty.
+func channelConfig(config map[string]interface{}, channel string) (map[string]interface{}, []string, error) {
+	block := map[string]interface{}{}
+	required := []string{}
+
+	channels, ok := config[channelsConfigKey]
+	if !ok || channels == nil {
+		return block, required, nil
+	}
+
+	blocks, ok := channels.(map[interface{}]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf(`config key "%s" is not acceptable (must be a block per channel)`, channelsConfigKey)
+	}
+
+	b, ok := blocks[channel]
+	if !ok || b == nil {
+		return block, required, nil
+	}
+
+	m, ok := b.(map[interface{}]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf(`config key "%s.%s" is not acceptable (must be a block of flag names)`, channelsConfigKey, channel)
+	}
+
+	for k, v := range m {
+		key := fmt.Sprint(k)
+
+		switch key {
+		case "channel":
+			return nil, nil, fmt.Errorf(`config key "%s.%s.channel" is not acceptable (the channel can't be overridden)`, channelsConfigKey, channel)
+		case "require":
+			names, ok := v.([]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf(`config key "%s.%s.require" is not acceptable (must be a list of flag names)`, channelsConfigKey, channel)
+			}
+
+			for _, name := range names {
+				required = append(required, fmt.Sprint(name))
+			}
+		default:
+			block[key] = v
+		}
+	}
+
+	return block, required, nil
+}
+
+// applyChannelConfig uses the config file's block for the channel as defaults
+// for dist's flags, taking precedence over the top-level keys but not over
+// flags and environment variables, then checks the flags the channel requires.
+func (s *session) applyChannelConfig(cmd *cobra.Command, channel string) error {
+	config, err := loadConfig(s.root.config)
+	if err != nil {
+		return err
+	}
+
+	block, required, err := channelConfig(config, channel)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range block {
+		f := cmd.Flags().Lookup(key)
+		if f == nil || f.Changed || isConfigOverridden(f) {
+			continue
+		}
+
+		v, ok := configValue(value)
+		if !ok {
+			continue
+		}
+
+		if err := setFlagDefault(f, v); err != nil {
+			return fmt.Errorf(`config key "%s.%s.%s" is not acceptable (%s)`, channelsConfigKey, channel, key, err)
+		}
+	}
+
+	for _, name := range required {
+		f := cmd.Flags().Lookup(name)
+		if f == nil {
+			return fmt.Errorf(`config key "%s.%s.require" is not acceptable (flag "--%s" is not supported)`, channelsConfigKey, channel, name)
+		}
+
+		if f.Value.String() == f.DefValue && !isConfigOverridden(f) {
+			return fmt.Errorf(`flag "--%s" is required for channel "%s" (by the config file)`, name, channel)
+		}
+	}
+
+	return nil
+}
+
+// isConfigOverridden reports whether an environment variable takes precedence
+// over a flag's config key.
+func isConfigOverridden(f *pflag.Flag) bool {
+	for _, env := range append(configOverrides[f.Name], f.Annotations[envAnnotation]...) {
+		if os.Getenv(env) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// configValue formats a config value as a flag value, where lists become
+// comma seperated values. Nested blocks aren't flag values.
+func configValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case nil, map[interface{}]interface{}:
+		return "", false
+	case []interface{}:
+		values := []string{}
+		for _, e := range v {
+			values = append(values, fmt.Sprint(e))
+		}
+
+		return strings.Join(values, ","), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// setFlagDefault sets a flag's value without marking it as changed, so that
+// e.g. --ci can still override it, while satisfying a required flag. A list
+// flag's value is replaced rather than appended to, since a list flag only
+// replaces its default on its first Set, and a channel's list would otherwise
+// be added to the top-level key's.
+func setFlagDefault(f *pflag.Flag, value string) error {
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		values := []string{}
+		if value != "" {
+			r, err := csv.NewReader(strings.NewReader(value)).Read()
+			if err != nil {
+				return err
+			}
+
+			values = r
+		}
+
+		if err := sv.Replace(values); err != nil {
+			return err
+		}
+	} else if err := f.Value.Set(value); err != nil {
+		return err
+	}
+
+	delete(f.Annotations, cobra.BashCompOneRequiredFlag)
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestSetFlagDefault(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "replaces the default", value: "sha256", want: []string{"sha256"}},
+		{name: "splits values", value: "sha256,blake2b", want: []string{"sha256", "blake2b"}},
+		{name: "keeps quoted commas", value: `a,"b,c"`, want: []string{"a", "b,c"}},
+		{name: "empties the list", value: "", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			cmd.Flags().StringSlice("extra-checksums", []string{"sha1"}, "")
+			cmd.MarkFlagRequired("extra-checksums")
+
+			f := cmd.Flags().Lookup("extra-checksums")
+
+			if err := setFlagDefault(f, tt.value); err != nil {
+				t.Fatalf("setFlagDefault() error = %v", err)
+			}
+
+			// Setting it again must replace, rather than append to, the list
+			if err := setFlagDefault(f, tt.value); err != nil {
+				t.Fatalf("setFlagDefault() error = %v", err)
+			}
+
+			got, _ := cmd.Flags().GetStringSlice("extra-checksums")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flag = %v, want %v", got, tt.want)
+			}
+
+			if f.Changed {
+				t.Error("flag is marked as changed")
+			}
+
+			if _, ok := f.Annotations[cobra.BashCompOneRequiredFlag]; ok {
+				t.Error("flag is still required")
+			}
+		})
+	}
+}
+
+func TestSetFlagDefaultScalar(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().Bool("notarize", false, "")
+
+	f := cmd.Flags().Lookup("notarize")
+
+	if err := setFlagDefault(f, "true"); err != nil {
+		t.Fatalf("setFlagDefault() error = %v", err)
+	}
+
+	if got := f.Value.String(); got != "true" {
+		t.Errorf("flag = %s, want true", got)
+	}
+
+	if err := setFlagDefault(f, "maybe"); err == nil {
+		t.Error("setFlagDefault() accepted an invalid bool")
+	}
+}
+
+func TestApplyChannelConfig(t *testing.T) {
+	config := `
+platform: linux/amd64
+extra-checksums: [sha1]
+metadata: [team=core]
+channels:
+  stable:
+    extra-checksums: [sha256, blake2b]
+    platform: darwin/arm64
+    require: [signing-key]
+  dev:
+    metadata: [retention=7d]
+  beta:
+    channel: stable
+`
+
+	tests := []struct {
+		name     string
+		channel  string
+		args     []string
+		checksum []string
+		metadata []string
+		platform string
+		err      bool
+	}{
+		{name: "channel replaces top-level lists", channel: "stable", args: []string{"--signing-key", "k"}, checksum: []string{"sha256", "blake2b"}, metadata: []string{"team=core"}, platform: "darwin/arm64"},
+		{name: "channel without a key keeps the top-level key", channel: "dev", checksum: []string{"sha1"}, metadata: []string{"retention=7d"}, platform: "linux/amd64"},
+		{name: "missing block", channel: "alpha", checksum: []string{"sha1"}, metadata: []string{"team=core"}, platform: "linux/amd64"},
+		{name: "flags take precedence", channel: "stable", args: []string{"--signing-key", "k", "--platform", "windows/amd64"}, checksum: []string{"sha256", "blake2b"}, metadata: []string{"team=core"}, platform: "windows/amd64"},
+		{name: "required flag", channel: "stable", err: true},
+		{name: "channel override", channel: "beta", err: true},
+	}
+
+	p := filepath.Join(t.TempDir(), "keygen.yml")
+	if err := ioutil.WriteFile(p, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSession(context.Background())
+			s.root.config = p
+
+			cmd := &cobra.Command{Use: "dist"}
+			cmd.Flags().String("platform", "", "")
+			cmd.Flags().String("signing-key", "", "")
+			cmd.Flags().StringSlice("extra-checksums", []string{}, "")
+			cmd.Flags().StringArray("metadata", []string{}, "")
+
+			if err := cmd.Flags().Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := s.applyConfig(cmd); err != nil {
+				t.Fatalf("applyConfig() error = %v", err)
+			}
+
+			err := s.applyChannelConfig(cmd, tt.channel)
+			if (err != nil) != tt.err {
+				t.Fatalf("applyChannelConfig() error = %v, want error %v", err, tt.err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got, _ := cmd.Flags().GetStringSlice("extra-checksums"); !reflect.DeepEqual(got, tt.checksum) {
+				t.Errorf("extra-checksums = %v, want %v", got, tt.checksum)
+			}
+
+			if got, _ := cmd.Flags().GetStringArray("metadata"); !reflect.DeepEqual(got, tt.metadata) {
+				t.Errorf("metadata = %v, want %v", got, tt.metadata)
+			}
+
+			if got, _ := cmd.Flags().GetString("platform"); got != tt.platform {
+				t.Errorf("platform = %s, want %s", got, tt.platform)
+			}
+		})
+	}
+}
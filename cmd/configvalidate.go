@@ -0,0 +1,465 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// redacted replaces secret values in config view --redact.
+const redacted = "[redacted]"
+
+// secretPatterns match the secrets within flags' values, e.g. the PIN of a
+// pkcs11: URI given as --signing-key, and an Authorization header given to
+// keygen api as --header, which are redacted rather than the whole value.
+var secretPatterns = map[string]*regexp.Regexp{
+	"signing-key": regexp.MustCompile(`(?i)(^pkcs11:.*pin-value=)[^;?&]*`),
+	"header":      regexp.MustCompile(`(?i)((?:^|[\[,"])\s*(?:authorization|proxy-authorization)\s*:\s*)[^,\]"]*`),
+}
+
+// inspectsConfigAnnotation marks commands which inspect the config file, and
+// so don't have it applied to their own flags, since an unacceptable value
+// would otherwise prevent them from reporting it.
+const inspectsConfigAnnotation = "keygen_inspects_config"
+
+// configSetting is a flag's effective value for a command, along with where
+// the value came from, i.e. a flag, an environment variable, the config file
+// or the flag's default.
+type configSetting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Secret bool   `json:"secret"`
+}
+
+func newConfigCmd(s *session) *cobra.Command {
+	viewOpts := s.newOptions()
+	viewCmd := &cobra.Command{
+		Use:   "view [command] [-- flags]",
+		Short: "show a command's effective configuration and where each value came from",
+		Example: `  keygen config view dist
+  keygen config view dist --redact -- --channel beta
+  keygen config view releases ls -o json
+
+Sources are the first of: a flag, an environment variable, the config file and
+the flag's default.
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return configViewRun(viewOpts, cmd, args)
+		},
+		Annotations: map[string]string{inspectsConfigAnnotation: "true"},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	viewCmd.Flags().BoolVar(&viewOpts.redact, "redact", false, "hide secret values, e.g. tokens and webhook URLs")
+	viewCmd.Flags().StringVarP(&viewOpts.output, "output", "o", "table", renderOutputUsage)
+
+	validateOpts := s.newOptions()
+	validateCmd := &cobra.Command{
+		Use:   "validate [command] [-- flags]",
+		Short: "check the config file for unknown keys, unacceptable values and a command's missing required flags",
+		Example: `  keygen config validate
+  keygen config validate dist -- --version 1.2.0
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return configValidateRun(validateOpts, cmd, args)
+		},
+		Annotations: map[string]string{inspectsConfigAnnotation: "true"},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "inspect the effective configuration",
+	}
+
+	cmd.AddCommand(viewCmd, validateCmd)
+
+	return cmd
+}
+
+func configViewRun(opts *CommandOptions, cmd *cobra.Command, args []string) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	target, err := findConfigTarget(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	config, err := loadConfig(opts.root.config)
+	if err != nil {
+		return err
+	}
+
+	settings, _, err := resolveTargetConfig(target, config, opts.root.config)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	for _, setting := range settings {
+		if opts.redact {
+			setting.Value = redactSetting(setting)
+		}
+
+		rows = append(rows, []string{setting.Key, setting.Value, setting.Source})
+	}
+
+	return render(opts.output, rendering{
+		value:   settings,
+		headers: []string{"KEY", "VALUE", "SOURCE"},
+		rows:    rows,
+	})
+}
+
+// redactSetting returns a setting's value with its secrets redacted.
+func redactSetting(setting *configSetting) string {
+	if setting.Secret && setting.Value != "" {
+		return redacted
+	}
+
+	if p, ok := secretPatterns[setting.Key]; ok {
+		return p.ReplaceAllString(setting.Value, "${1}"+redacted)
+	}
+
+	return setting.Value
+}
+
+func configValidateRun(opts *CommandOptions, cmd *cobra.Command, args []string) error {
+	target, err := findConfigTarget(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	config, err := loadConfig(opts.root.config)
+	if err != nil {
+		return err
+	}
+
+	problems := []string{}
+
+	// Keys are flag names, so a key which isn't a flag of any command is
+	// most likely a typo, which would otherwise be silently ignored
+	known := configKeys(cmd.Root())
+
+	keys := []string{}
+	for key := range config {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key == channelsConfigKey {
+			problems = append(problems, channelConfigProblems(config, known)...)
+
+			continue
+		}
+
+		if !known[key] {
+			problems = append(problems, fmt.Sprintf(`config key "%s" is not supported (not a flag of any command)`, key))
+
+			continue
+		}
+
+		if _, ok := configValue(config[key]); !ok {
+			problems = append(problems, fmt.Sprintf(`config key "%s" is not acceptable (must be a value or a list of values)`, key))
+		}
+	}
+
+	settings, channel, err := resolveTargetConfig(target, config, opts.root.config)
+	if err != nil {
+		return err
+	}
+
+	for _, setting := range settings {
+		f := target.Flags().Lookup(setting.Key)
+		if required := f.Annotations[cobra.BashCompOneRequiredFlag]; len(required) == 0 || required[0] != "true" {
+			continue
+		}
+
+		if setting.Source == "default" && setting.Value == "" {
+			problems = append(problems, fmt.Sprintf(`flag "--%s" is required by "%s" (%s)`, f.Name, target.CommandPath(), formatConfigSources(f)))
+		}
+	}
+
+	// Values are checked by applying them the way the command would, which
+	// is harmless since the command isn't run
+	if err := applyEnv(target); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if err := opts.applyConfig(target); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if channel != "" {
+		if err := opts.applyChannelConfig(target, channel); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	red := color.New(color.FgRed).SprintFunc()
+	italic := color.New(color.Italic).SprintFunc()
+
+	if len(problems) != 0 {
+		for _, p := range problems {
+			fmt.Fprintln(os.Stderr, red("error:")+" "+p)
+		}
+
+		return fmt.Errorf("config is not valid (%d problems found)", len(problems))
+	}
+
+	if config == nil {
+		fmt.Println("no config file found at " + italic(opts.root.config) + " (flags and environment variables are valid for " + italic(target.CommandPath()) + ")")
+
+		return nil
+	}
+
+	fmt.Println("config " + italic(opts.root.config) + " is valid for " + italic(target.CommandPath()))
+
+	return nil
+}
+
+// findConfigTarget returns the command whose configuration is inspected, given
+// by args along with any of its flags, e.g. dist --channel beta. Without a
+// command, only the global flags are inspected.
+func findConfigTarget(cmd *cobra.Command, args []string) (*cobra.Command, error) {
+	root := cmd.Root()
+	if len(args) == 0 {
+		return root, nil
+	}
+
+	target, rest, err := root.Find(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if target == root {
+		return nil, fmt.Errorf(`command "%s" is not supported (see keygen --help)`, args[0])
+	}
+
+	if err := target.ParseFlags(rest); err != nil {
+		return nil, fmt.Errorf(`flags for "%s" are not acceptable (%s)`, target.CommandPath(), err)
+	}
+
+	return target, nil
+}
+
+// resolveConfig returns the effective value of each of a command's flags
+// which has one, following the same precedence as the command itself: flags,
+// then environment variables, then the config file at path (its block for the
+// channel, then its top-level keys), then defaults.
+func resolveConfig(cmd *cobra.Command, config map[string]interface{}, block map[string]interface{}, channel string, path string) []*configSetting {
+	settings := []*configSetting{}
+
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		// Skip --help and the root's --version, but not e.g. dist's
+		if f.Hidden || f.Name == "help" || (f.Name == "version" && f.Value.Type() == "bool") {
+			return
+		}
+
+		setting := &configSetting{Key: f.Name, Secret: secretFlags[f.Name]}
+
+		switch {
+		case f.Changed:
+			setting.Value, setting.Source = f.Value.String(), "flag"
+		case lookupFlagEnv(f) != "":
+			env := lookupFlagEnv(f)
+
+			// Overrides, e.g. a signing key given by value, are secrets
+			// which don't set the flag itself
+			setting.Value, setting.Source = os.Getenv(env), "env $"+env
+			if !isFlagEnv(f, env) {
+				setting.Secret = true
+			}
+		default:
+			if v, ok := configValue(block[f.Name]); ok {
+				setting.Value, setting.Source = v, "config "+path+" (channel "+channel+")"
+			} else if v, ok := configValue(config[f.Name]); ok {
+				setting.Value, setting.Source = v, "config "+path
+			} else {
+				setting.Value, setting.Source = f.DefValue, "default"
+			}
+		}
+
+		if setting.Value == "" || setting.Value == "[]" {
+			return
+		}
+
+		settings = append(settings, setting)
+	})
+
+	// Required flags without a value are listed too, so that they stand out
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if required := f.Annotations[cobra.BashCompOneRequiredFlag]; len(required) == 0 || required[0] != "true" {
+			return
+		}
+
+		for _, setting := range settings {
+			if setting.Key == f.Name {
+				return
+			}
+		}
+
+		settings = append(settings, &configSetting{Key: f.Name, Source: "default", Secret: secretFlags[f.Name]})
+	})
+
+	sort.SliceStable(settings, func(i, j int) bool {
+		return settings[i].Key < settings[j].Key
+	})
+
+	return settings
+}
+
+// resolveTargetConfig resolves the configuration of the target command. For
+// dist, the config file's block for its channel is included, and the channel
+// is returned.
+func resolveTargetConfig(target *cobra.Command, config map[string]interface{}, path string) ([]*configSetting, string, error) {
+	settings := resolveConfig(target, config, nil, "", path)
+	if target.CommandPath() != "keygen dist" {
+		return settings, "", nil
+	}
+
+	channel := ""
+	for _, setting := range settings {
+		if setting.Key == "channel" {
+			channel = setting.Value
+		}
+	}
+
+	block, _, err := channelConfig(config, channel)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return resolveConfig(target, config, block, channel, path), channel, nil
+}
+
+// channelConfigProblems reports the unknown keys and required flags of every
+// channel block in the config file.
+func channelConfigProblems(config map[string]interface{}, known map[string]bool) []string {
+	problems := []string{}
+
+	blocks, ok := config[channelsConfigKey].(map[interface{}]interface{})
+	if !ok {
+		return []string{fmt.Sprintf(`config key "%s" is not acceptable (must be a block per channel)`, channelsConfigKey)}
+	}
+
+	channels := []string{}
+	for c := range blocks {
+		channels = append(channels, fmt.Sprint(c))
+	}
+
+	sort.Strings(channels)
+
+	for _, channel := range channels {
+		block, required, err := channelConfig(config, channel)
+		if err != nil {
+			problems = append(problems, err.Error())
+
+			continue
+		}
+
+		keys := []string{}
+		for key := range block {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if !known[key] {
+				problems = append(problems, fmt.Sprintf(`config key "%s.%s.%s" is not supported (not a flag of any command)`, channelsConfigKey, channel, key))
+			}
+		}
+
+		for _, name := range required {
+			if !known[name] {
+				problems = append(problems, fmt.Sprintf(`config key "%s.%s.require" is not acceptable (flag "--%s" is not supported)`, channelsConfigKey, channel, name))
+			}
+		}
+	}
+
+	return problems
+}
+
+// lookupFlagEnv returns the set environment variable which takes precedence
+// over a flag's config key, if any.
+func lookupFlagEnv(f *pflag.Flag) string {
+	for _, env := range append(f.Annotations[envAnnotation], configOverrides[f.Name]...) {
+		if os.Getenv(env) != "" {
+			return env
+		}
+	}
+
+	return ""
+}
+
+// isFlagEnv reports whether env is bound to the flag, rather than being an
+// override of its config key.
+func isFlagEnv(f *pflag.Flag, env string) bool {
+	for _, e := range f.Annotations[envAnnotation] {
+		if e == env {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatConfigSources describes the ways a flag can be set, for when a
+// required flag is missing.
+func formatConfigSources(f *pflag.Flag) string {
+	sources := []string{"set it by flag"}
+	for _, env := range f.Annotations[envAnnotation] {
+		sources = append(sources, "$"+env)
+	}
+
+	sources = append(sources, `the "`+f.Name+`" config key`)
+
+	if len(sources) == 2 {
+		return strings.Join(sources, " or ")
+	}
+
+	return strings.Join(sources[:len(sources)-1], ", ") + " or " + sources[len(sources)-1]
+}
+
+// configKeys returns the config keys supported by any command, i.e. the names
+// of every flag in the command tree.
+func configKeys(root *cobra.Command) map[string]bool {
+	keys := map[string]bool{}
+
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		for _, flags := range []*pflag.FlagSet{c.LocalFlags(), c.PersistentFlags()} {
+			flags.VisitAll(func(f *pflag.Flag) {
+				keys[f.Name] = true
+			})
+		}
+
+		for _, sub := range c.Commands() {
+			walk(sub)
+		}
+	}
+
+	walk(root)
+
+	return keys
+}
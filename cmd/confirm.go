@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+// stdin is shared by prompts, so that piped answers aren't lost to buffering.
+var stdin = bufio.NewReader(os.Stdin)
+
+// secretFlags are the flags whose values aren't echoed when prompted for, nor
+// shown by config view --redact. Flags with secrets within their values are
+// redacted using secretPatterns.
+var secretFlags = map[string]bool{
+	"token":           true,
+	"license-key":     true,
+	"slack-webhook":   true,
+	"discord-webhook": true,
+	"teams-webhook":   true,
+	"webhook":         true,
+}
+
+// confirmAction asks the user to confirm a destructive action, after listing
+// what will be affected. When name is given, the action is considered very
+// destructive and the user must type name to confirm rather than "y". Without
+// a terminal to prompt on, --yes is required.
+func (s *session) confirmAction(action string, affected []string, name string) error {
+	if s.root.yes {
+		return nil
+	}
+
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		return fmt.Errorf("refusing to %s without confirmation (use --yes in non-interactive environments)", action)
+	}
+
+	yellow := color.New(color.FgYellow).SprintFunc()
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Fprintln(os.Stderr, yellow("warning:")+" this will "+action+":")
+
+	for _, a := range affected {
+		fmt.Fprintln(os.Stderr, "  "+a)
+	}
+
+	if name != "" {
+		fmt.Fprint(os.Stderr, "type "+italic(name)+" to confirm: ")
+	} else {
+		fmt.Fprint(os.Stderr, "continue? y/N ")
+	}
+
+	input, err := stdin.ReadString('\n')
+	if err != nil && input == "" {
+		return fmt.Errorf("%s aborted", action)
+	}
+
+	input = strings.TrimSpace(input)
+
+	switch {
+	case name != "" && input == name:
+		return nil
+	case name == "" && (input == "y" || input == "Y" || input == "yes"):
+		return nil
+	}
+
+	return fmt.Errorf("%s aborted", action)
+}
+
+// promptValue asks the user for a value, defaulting to def when nothing is
+// entered, or when there's no terminal to prompt on or --yes is given.
+func (s *session) promptValue(label string, def string) (string, error) {
+	if s.root.yes || (!isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd())) {
+		return def, nil
+	}
+
+	if def != "" {
+		fmt.Fprint(os.Stderr, label+" ["+def+"]: ")
+	} else {
+		fmt.Fprint(os.Stderr, label+": ")
+	}
+
+	input, err := stdin.ReadString('\n')
+	if err != nil && input == "" {
+		return "", err
+	}
+
+	if v := strings.TrimSpace(input); v != "" {
+		return v, nil
+	}
+
+	return def, nil
+}
+
+// canPrompt reports whether there's a user to prompt, i.e. stdin is a terminal
+// and --yes wasn't given.
+func (s *session) canPrompt() bool {
+	return !s.root.yes && (isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd()))
+}
+
+// promptValidValue asks the user for a value like promptValue, asking again
+// until the value passes validate. Without a terminal to prompt on, def is
+// validated as-is, so that non-interactive runs fail as usual.
+func (s *session) promptValidValue(label string, def string, validate func(string) error) (string, error) {
+	if !s.canPrompt() {
+		return def, validate(def)
+	}
+
+	red := color.New(color.FgRed).SprintFunc()
+
+	for {
+		v, err := s.promptValue(label, def)
+		if err != nil {
+			return "", err
+		}
+
+		err = validate(v)
+		if err == nil {
+			return v, nil
+		}
+
+		fmt.Fprintln(os.Stderr, red("error:")+" "+err.Error())
+	}
+}
+
+// promptBeforeRun prompts for the required flags of every command in the tree
+// once the command's own PreRunE has run, since e.g. dist relaxes which flags
+// are required there.
+func (s *session) promptBeforeRun(cmd *cobra.Command) {
+	for _, c := range cmd.Commands() {
+		s.promptBeforeRun(c)
+	}
+
+	if cmd.RunE == nil {
+		return
+	}
+
+	pre := cmd.PreRunE
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if pre != nil {
+			if err := pre(cmd, args); err != nil {
+				return err
+			}
+		}
+
+		return s.promptRequiredFlags(cmd)
+	}
+}
+
+// promptRequiredFlags asks for the command's required flags which weren't set
+// by a flag, the environment or the config file, so that ad-hoc runs in a
+// terminal don't fail over a forgotten flag. Without a terminal to prompt on,
+// the missing flags are left for cobra to report.
+func (s *session) promptRequiredFlags(cmd *cobra.Command) error {
+	if !s.canPrompt() {
+		return nil
+	}
+
+	var err error
+
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed {
+			return
+		}
+
+		if required := f.Annotations[cobra.BashCompOneRequiredFlag]; len(required) == 0 || required[0] != "true" {
+			return
+		}
+
+		if secretFlags[f.Name] {
+			err = promptSecretFlag(f)
+
+			return
+		}
+
+		_, err = s.promptValidValue(f.Name, "", func(v string) error {
+			if v == "" {
+				return fmt.Errorf("%s is required", f.Name)
+			}
+
+			if e := setFlagDefault(f, v); e != nil {
+				return fmt.Errorf(`%s "%s" is not acceptable (%s)`, f.Name, v, e)
+			}
+
+			return nil
+		})
+	})
+
+	return err
+}
+
+// promptSecretFlag asks for a flag's value without echoing it, e.g. a token.
+func promptSecretFlag(f *pflag.Flag) error {
+	red := color.New(color.FgRed).SprintFunc()
+
+	for {
+		fmt.Fprint(os.Stderr, f.Name+": ")
+
+		b, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return err
+		}
+
+		if v := strings.TrimSpace(string(b)); v != "" {
+			return setFlagDefault(f, v)
+		}
+
+		fmt.Fprintln(os.Stderr, red("error:")+" "+f.Name+" is required")
+	}
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"gopkg.in/yaml.v2"
+)
+
+// constraintsFile is a --constraints-file, which names groups of entitlements,
+// e.g. per release tier, and the releases each group constrains, matched by
+// filename and platform, e.g.
+//
+//	groups:
+//	  pro: [FEATURE_A, FEATURE_B]
+//	  enterprise: [FEATURE_A, FEATURE_B, SSO]
+//	releases:
+//	  - filename: "*-enterprise.*"
+//	    groups: [enterprise]
+//	  - filename: "*"
+//	    groups: [pro]
+//
+// where the first matching rule wins, and every release must match a rule.
+type constraintsFile struct {
+	Groups   map[string][]string `yaml:"groups"`
+	Releases []*constraintsRule  `yaml:"releases"`
+}
+
+// constraintsRule applies entitlement groups to the releases matching its
+// filename and platform globs, where an empty glob matches every release.
+type constraintsRule struct {
+	Filename string   `yaml:"filename"`
+	Platform string   `yaml:"platform"`
+	Groups   []string `yaml:"groups"`
+}
+
+// loadConstraintsFile reads and validates a --constraints-file, reporting all
+// problems at once.
+func loadConstraintsFile(p string) (*constraintsFile, error) {
+	expanded, err := homedir.Expand(p)
+	if err != nil {
+		return nil, fmt.Errorf(`constraints path "%s" is not expandable (%s)`, p, err)
+	}
+
+	b, err := ioutil.ReadFile(expanded)
+	if err != nil {
+		return nil, fmt.Errorf(`constraints path "%s" is not readable (%s)`, p, err.(*os.PathError).Err)
+	}
+
+	c := &constraintsFile{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf(`constraints file "%s" is not valid (%s)`, p, err)
+	}
+
+	problems := []string{}
+
+	if len(c.Groups) == 0 {
+		problems = append(problems, "no groups are defined")
+	}
+
+	for _, name := range c.groupNames() {
+		if len(c.Groups[name]) == 0 {
+			problems = append(problems, fmt.Sprintf(`group "%s" has no entitlements`, name))
+		}
+	}
+
+	for i, r := range c.Releases {
+		if len(r.Groups) == 0 {
+			problems = append(problems, fmt.Sprintf("release rule %d has no groups", i+1))
+		}
+
+		for _, g := range r.Groups {
+			if _, ok := c.Groups[g]; !ok {
+				problems = append(problems, fmt.Sprintf(`release rule %d uses group "%s", which is not defined`, i+1, g))
+			}
+		}
+
+		for _, glob := range []string{r.Filename, r.Platform} {
+			if _, err := path.Match(glob, ""); err != nil {
+				problems = append(problems, fmt.Sprintf(`release rule %d has an invalid glob "%s"`, i+1, glob))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("constraints file \"%s\" is not valid:\n  - %s", p, strings.Join(problems, "\n  - "))
+	}
+
+	return c, nil
+}
+
+// groupNames returns the names of the file's groups, sorted.
+func (c *constraintsFile) groupNames() []string {
+	names := []string{}
+	for name := range c.Groups {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// entitlements returns every entitlement used by the file's groups, so that
+// they can all be checked before anything is published.
+func (c *constraintsFile) entitlements() []string {
+	seen := map[string]bool{}
+	entitlements := []string{}
+
+	for _, name := range c.groupNames() {
+		for _, e := range c.Groups[name] {
+			if !seen[e] {
+				seen[e] = true
+				entitlements = append(entitlements, e)
+			}
+		}
+	}
+
+	return entitlements
+}
+
+// resolve replaces the file's entitlements, e.g. codes, with their IDs, as
+// returned by preflightConstraints for entitlements().
+func (c *constraintsFile) resolve(ids map[string]string) {
+	for name, entitlements := range c.Groups {
+		for i, e := range entitlements {
+			if id, ok := ids[e]; ok {
+				entitlements[i] = id
+			}
+		}
+
+		c.Groups[name] = entitlements
+	}
+}
+
+// match returns the entitlements of the first rule matching a release's
+// filename and platform. A release matching no rule is an error, since it
+// would otherwise be published without the constraints of any tier.
+func (c *constraintsFile) match(filename string, platform string) ([]string, error) {
+	for _, r := range c.Releases {
+		if ok, _ := path.Match(r.Filename, filename); r.Filename != "" && !ok {
+			continue
+		}
+
+		if ok, _ := path.Match(r.Platform, platform); r.Platform != "" && !ok {
+			continue
+		}
+
+		entitlements := []string{}
+		for _, g := range r.Groups {
+			entitlements = append(entitlements, c.Groups[g]...)
+		}
+
+		return entitlements, nil
+	}
+
+	return nil, fmt.Errorf(`artifact "%s" is not acceptable (it matches no release rule of the constraints file, so add a catch-all rule, e.g. filename: "*")`, filename)
+}
+
+// mergeEntitlements returns the unique entitlements of both lists, in order.
+func mergeEntitlements(a []string, b []string) []string {
+	seen := map[string]bool{}
+	merged := []string{}
+
+	for _, e := range append(append([]string{}, a...), b...) {
+		if !seen[e] {
+			seen[e] = true
+			merged = append(merged, e)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConstraintsFileMatch(t *testing.T) {
+	c := &constraintsFile{
+		Groups: map[string][]string{
+			"pro":        {"PRO"},
+			"enterprise": {"PRO", "SSO"},
+			"mac":        {"MAC"},
+		},
+		Releases: []*constraintsRule{
+			{Filename: "*-enterprise.*", Groups: []string{"enterprise"}},
+			{Filename: "*.dmg", Platform: "darwin/*", Groups: []string{"pro", "mac"}},
+			{Platform: "linux/*", Groups: []string{"pro"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		platform string
+		want     []string
+		err      bool
+	}{
+		{name: "first matching rule", filename: "App-enterprise.dmg", platform: "darwin/arm64", want: []string{"PRO", "SSO"}},
+		{name: "filename and platform", filename: "App.dmg", platform: "darwin/amd64", want: []string{"PRO", "MAC"}},
+		{name: "platform only", filename: "app.tar.gz", platform: "linux/amd64", want: []string{"PRO"}},
+		{name: "filename without platform", filename: "App.dmg", platform: "windows/amd64", err: true},
+		{name: "no rule", filename: "app.exe", platform: "windows/amd64", err: true},
+		{name: "platformless", filename: "src.tar.gz", platform: "", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.match(tt.filename, tt.platform)
+			if (err != nil) != tt.err {
+				t.Fatalf("match(%q, %q) error = %v, want error %v", tt.filename, tt.platform, err, tt.err)
+			}
+
+			if err != nil {
+				if !strings.Contains(err.Error(), tt.filename) {
+					t.Errorf("match(%q, %q) error = %q, want it to name the artifact", tt.filename, tt.platform, err)
+				}
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("match(%q, %q) = %v, want %v", tt.filename, tt.platform, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConstraintsFileMatchCatchAll(t *testing.T) {
+	c := &constraintsFile{
+		Groups:   map[string][]string{"base": {"BASE"}},
+		Releases: []*constraintsRule{{Filename: "*", Groups: []string{"base"}}},
+	}
+
+	got, err := c.match("anything.zip", "")
+	if err != nil {
+		t.Fatalf("match() error = %v", err)
+	}
+
+	if want := []string{"BASE"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("match() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// configureDeadline binds the session's context to the --deadline flag.
+func (s *session) configureDeadline() error {
+	if s.root.deadline < 0 {
+		return fmt.Errorf(`deadline "%s" is not acceptable (must be positive)`, s.root.deadline)
+	}
+
+	if s.root.deadline > 0 {
+		s.ctx, s.cancel = context.WithTimeout(s.ctx, s.root.deadline)
+	} else {
+		s.ctx, s.cancel = context.WithCancel(s.ctx)
+	}
+
+	return nil
+}
+
+// formatDeadlineError explains errors caused by the command's deadline passing,
+// leaving other errors as-is.
+func (s *session) formatDeadlineError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) && errors.Is(s.ctx.Err(), context.DeadlineExceeded) && s.root.deadline > 0 {
+		return fmt.Errorf("command did not finish within its deadline of %s (use --deadline to extend it)", s.root.deadline)
+	}
+
+	return err
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// fileDigest is an artifact's SHA-512 digest, which is both its checksum and
+// its ed25519ph prehash, along with any extra hex-encoded checksums.
+type fileDigest struct {
+	sum       []byte
+	checksums map[string]string
+	size      int64
+	modified  time.Time
+}
+
+// checksum returns the digest's base64 encoded checksum.
+func (d *fileDigest) checksum() string {
+	return base64.RawStdEncoding.EncodeToString(d.sum)
+}
+
+// hashFile calculates a file's SHA-512 digest and any extra checksums from a
+// single read, with each hash running concurrently.
+func hashFile(file *os.File, extra []string) (*fileDigest, error) {
+	defer file.Seek(0, io.SeekStart) // reset reader
+
+	h := sha512.New()
+	writers := []io.Writer{h}
+	hashes := map[string]hash.Hash{}
+
+	for _, algorithm := range extra {
+		newHash, ok := checksumAlgorithms[algorithm]
+		if !ok {
+			return nil, fmt.Errorf(`checksum algorithm "%s" is not supported`, algorithm)
+		}
+
+		hashes[algorithm] = newHash()
+		writers = append(writers, hashes[algorithm])
+	}
+
+	if _, err := io.CopyBuffer(&concurrentWriter{writers}, file, make([]byte, 4*1024*1024)); err != nil {
+		return nil, err
+	}
+
+	digest := &fileDigest{sum: h.Sum(nil)}
+
+	if len(hashes) != 0 {
+		digest.checksums = map[string]string{}
+		for algorithm, h := range hashes {
+			digest.checksums[algorithm] = hex.EncodeToString(h.Sum(nil))
+		}
+	}
+
+	if info, err := file.Stat(); err == nil {
+		digest.size = info.Size()
+		digest.modified = info.ModTime()
+	}
+
+	return digest, nil
+}
+
+// concurrentWriter writes each chunk to all of its writers concurrently,
+// waiting for every writer before accepting the next chunk.
+type concurrentWriter struct {
+	writers []io.Writer
+}
+
+func (w *concurrentWriter) Write(p []byte) (int, error) {
+	if len(w.writers) == 1 {
+		return w.writers[0].Write(p)
+	}
+
+	errs := make([]error, len(w.writers))
+	wg := sync.WaitGroup{}
+
+	for i, writer := range w.writers {
+		wg.Add(1)
+
+		go func(i int, writer io.Writer) {
+			defer wg.Done()
+
+			_, errs[i] = writer.Write(p)
+		}(i, writer)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// artifactDigests caches digests calculated ahead of time by prehashArtifacts,
+// keyed by path.
+var (
+	artifactDigests   = map[string]*fileDigest{}
+	artifactDigestsMu sync.Mutex
+)
+
+// prehashArtifacts hashes multiple artifacts using a worker pool, so that
+// large artifacts aren't hashed one after another. Failures are ignored, and
+// the artifact is hashed again when it's published.
+func prehashArtifacts(artifacts []*distArtifact, extra []string, noCache bool) {
+	paths := make(chan string)
+	wg := sync.WaitGroup{}
+
+	workers := runtime.NumCPU()
+	if workers > len(artifacts) {
+		workers = len(artifacts)
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for path := range paths {
+				file, err := os.Open(path)
+				if err != nil {
+					continue
+				}
+
+				digest, err := hashFileCached(path, file, extra, noCache)
+				file.Close()
+				if err != nil {
+					continue
+				}
+
+				artifactDigestsMu.Lock()
+				artifactDigests[path] = digest
+				artifactDigestsMu.Unlock()
+			}
+		}()
+	}
+
+	for _, a := range artifacts {
+		if path, err := homedir.Expand(a.path); err == nil {
+			paths <- path
+		}
+	}
+
+	close(paths)
+	wg.Wait()
+}
+
+// hashArtifact returns the digest for the artifact at path, using the digest
+// calculated by prehashArtifacts, or cached by a previous run, unless the file
+// has changed since.
+func hashArtifact(path string, file *os.File, extra []string, noCache bool) (*fileDigest, error) {
+	artifactDigestsMu.Lock()
+	digest, ok := artifactDigests[path]
+	artifactDigestsMu.Unlock()
+
+	if ok {
+		info, err := file.Stat()
+		if err == nil && info.Size() == digest.size && info.ModTime().Equal(digest.modified) {
+			return digest, nil
+		}
+	}
+
+	return hashFileCached(path, file, extra, noCache)
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+const (
+	// defaultDigestCache is where digests are cached between runs of dist, so
+	// that retrying a failed publish doesn't hash large artifacts again.
+	defaultDigestCache = "~/.keygen/digests.json"
+
+	// maxDigestCacheEntries bounds the cache, evicting the least recently used.
+	maxDigestCacheEntries = 256
+)
+
+// digestCacheEntry is a cached digest, which is only used while the file's
+// size, modification time and inode are unchanged.
+type digestCacheEntry struct {
+	Size      int64             `json:"size"`
+	Modified  int64             `json:"modified"`
+	Inode     uint64            `json:"inode"`
+	SHA512    string            `json:"sha512"`
+	Checksums map[string]string `json:"checksums,omitempty"`
+	Used      time.Time         `json:"used"`
+}
+
+// digestCacheMu serializes reads and writes of the cache file by concurrent
+// hashing, e.g. by prehashArtifacts.
+var digestCacheMu sync.Mutex
+
+func readDigestCache() (string, map[string]*digestCacheEntry) {
+	entries := map[string]*digestCacheEntry{}
+
+	p, err := homedir.Expand(defaultDigestCache)
+	if err != nil {
+		return "", entries
+	}
+
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return p, entries
+	}
+
+	// A corrupt cache is treated as empty and overwritten
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return p, map[string]*digestCacheEntry{}
+	}
+
+	return p, entries
+}
+
+// cachedDigest returns the cached digest of the file at path, or nil when
+// there's none for the file as it is now, or it lacks an extra checksum. A hit
+// marks the entry as used, so that digests reused on every run aren't evicted.
+func cachedDigest(path string, info os.FileInfo, extra []string) *fileDigest {
+	digestCacheMu.Lock()
+	defer digestCacheMu.Unlock()
+
+	p, entries := readDigestCache()
+
+	e, ok := entries[path]
+	if !ok || e.Size != info.Size() || e.Modified != info.ModTime().UnixNano() || e.Inode != fileInode(info) {
+		return nil
+	}
+
+	sum, err := hex.DecodeString(e.SHA512)
+	if err != nil || len(sum) != 64 {
+		return nil
+	}
+
+	digest := &fileDigest{sum: sum, size: info.Size(), modified: info.ModTime()}
+
+	if len(extra) != 0 {
+		digest.checksums = map[string]string{}
+
+		for _, algorithm := range extra {
+			c, ok := e.Checksums[algorithm]
+			if !ok {
+				return nil
+			}
+
+			digest.checksums[algorithm] = c
+		}
+	}
+
+	if p != "" {
+		e.Used = time.Now().UTC()
+
+		writeDigestCache(p, entries)
+	}
+
+	return digest
+}
+
+// cacheDigest records the digest of the file at path. Failing to write the
+// cache is ignored, since it only saves time.
+func cacheDigest(path string, info os.FileInfo, digest *fileDigest) {
+	digestCacheMu.Lock()
+	defer digestCacheMu.Unlock()
+
+	p, entries := readDigestCache()
+	if p == "" {
+		return
+	}
+
+	entries[path] = &digestCacheEntry{
+		Size:      info.Size(),
+		Modified:  info.ModTime().UnixNano(),
+		Inode:     fileInode(info),
+		SHA512:    hex.EncodeToString(digest.sum),
+		Checksums: digest.checksums,
+		Used:      time.Now().UTC(),
+	}
+
+	writeDigestCache(p, entries)
+}
+
+// writeDigestCache writes the cache to p, evicting the least recently used
+// entries beyond maxDigestCacheEntries. Failing to write it is ignored.
+func writeDigestCache(p string, entries map[string]*digestCacheEntry) {
+	if len(entries) > maxDigestCacheEntries {
+		paths := []string{}
+		for k := range entries {
+			paths = append(paths, k)
+		}
+
+		sort.Slice(paths, func(i, j int) bool {
+			return entries[paths[i]].Used.Before(entries[paths[j]].Used)
+		})
+
+		for _, k := range paths[:len(paths)-maxDigestCacheEntries] {
+			delete(entries, k)
+		}
+	}
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return
+	}
+
+	// Write atomically, so that concurrent runs never read a partial cache
+	tmp, err := ioutil.TempFile(filepath.Dir(p), ".digests-*.json")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return
+	}
+
+	os.Rename(tmp.Name(), p)
+}
+
+// hashFileCached hashes a file using the digest cache, and updates the cache.
+// When noCache is set, the file is always hashed, replacing its cached digest.
+func hashFileCached(path string, file *os.File, extra []string, noCache bool) (*fileDigest, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return hashFile(file, extra)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return hashFile(file, extra)
+	}
+
+	if !noCache {
+		if digest := cachedDigest(abs, info, extra); digest != nil {
+			return digest, nil
+		}
+	}
+
+	digest, err := hashFile(file, extra)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheDigest(abs, info, digest)
+
+	return digest, nil
+}
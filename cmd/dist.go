@@ -2,35 +2,41 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
 	"crypto"
+	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/base64"
-	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strconv"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/Masterminds/semver"
 	"github.com/fatih/color"
-	"github.com/keygen-sh/keygen-cli/internal/keygenext"
+	"github.com/keygen-sh/keygen-cli/keygenext"
 	"github.com/mattn/go-isatty"
 	"github.com/mitchellh/go-homedir"
 	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
 	"github.com/spf13/cobra"
 	"github.com/vbauerster/mpb/v7"
 	"github.com/vbauerster/mpb/v7/decor"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
 )
 
-var (
-	distOpts = &CommandOptions{}
-	distCmd  = &cobra.Command{
-		Use:   "dist <path>",
+func newDistCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "dist <path>...",
 		Short: "publish a new release for a product",
 		Example: `  keygen dist build/my-program-1-0-0 \
       --signing-key ~/.keys/keygen.key \
@@ -42,109 +48,592 @@ var (
 
 Docs:
   https://keygen.sh/docs/cli/`,
-		Args: distArgs,
-		RunE: distRun,
+		Args: func(cmd *cobra.Command, args []string) error {
+			return distArgs(opts, args)
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return distPreRun(opts, cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return distRun(opts, cmd, args)
+		},
 
 		// Encountering an error should not display usage
 		SilenceUsage: true,
 	}
-)
 
-func init() {
-	distCmd.Flags().StringVar(&keygenext.Account, "account", "", "your keygen.sh account identifier [$KEYGEN_ACCOUNT_ID=<id>] (required)")
-	distCmd.Flags().StringVar(&keygenext.Product, "product", "", "your keygen.sh product identifier [$KEYGEN_PRODUCT_ID=<id>] (required)")
-	distCmd.Flags().StringVar(&keygenext.Token, "token", "", "your keygen.sh product token [$KEYGEN_PRODUCT_TOKEN] (required)")
-	distCmd.Flags().StringVar(&distOpts.filename, "filename", "", "filename for the release (default grabs basename from <path>)")
-	distCmd.Flags().StringVar(&distOpts.filetype, "filetype", "auto", "filetype for the release (default grabs extname from <path>)")
-	distCmd.Flags().StringVar(&distOpts.version, "version", "", "version for the release (required)")
-	distCmd.Flags().StringVar(&distOpts.name, "name", "", "human-readable name for the release")
-	distCmd.Flags().StringVar(&distOpts.description, "description", "", "description for the release (e.g. release notes)")
-	distCmd.Flags().StringVar(&distOpts.platform, "platform", "", "platform for the release")
-	distCmd.Flags().StringVar(&distOpts.channel, "channel", "stable", "channel for the release, one of: stable, rc, beta, alpha, dev")
-	distCmd.Flags().StringVar(&distOpts.signature, "signature", "", "pre-calculated signature for the release (defaults using ed25519ph)")
-	distCmd.Flags().StringVar(&distOpts.checksum, "checksum", "", "pre-calculated checksum for the release (defaults using sha-512)")
-	distCmd.Flags().StringVar(&distOpts.signingAlgorithm, "signing-algorithm", "ed25519ph", "the signing algorithm to use, one of: ed25519ph, ed25519")
-	distCmd.Flags().StringVar(&distOpts.signingKeyPath, "signing-key", "", "path to ed25519 private key for signing the release [$KEYGEN_SIGNING_KEY_PATH=<path>, $KEYGEN_SIGNING_KEY=<key>]")
-	distCmd.Flags().BoolVar(&distOpts.noAutoUpgrade, "no-auto-upgrade", false, "disable automatic upgrade checks [$KEYGEN_NO_AUTO_UPGRADE=1]")
-
-	// TODO(ezekg) Accept entitlement codes and entitlement IDs?
-	distCmd.Flags().StringSliceVar(&distOpts.entitlements, "entitlements", []string{}, "comma seperated list of entitlement constraints (e.g. --entitlements <id>,<id>,...)")
+	addAccountFlags(cmd, s)
+	addProductFlag(cmd, s)
+
+	cmd.Flags().StringVar(&opts.filename, "filename", "", "filename for the release (default grabs basename from <path>)")
+	cmd.Flags().StringVar(&opts.filetype, "filetype", "auto", "filetype for the release (default detects from the content of <path>, falling back to its extname)")
+	cmd.Flags().StringVar(&opts.version, "version", "", "version for the release (required unless --ci detects a tag)")
+	cmd.Flags().BoolVar(&opts.semverStrict, "semver-strict", false, "reject versions which aren't strict semantic versions, e.g. v1.2 or 1.2")
+	cmd.Flags().BoolVar(&opts.semverCoerce, "semver-coerce", false, "coerce loose versions into semantic versions, e.g. 1.2.3.4 into 1.2.3")
+	cmd.Flags().StringVar(&opts.contentType, "content-type", "", "content type the artifact is served with (default detects from the filetype)")
+	cmd.Flags().StringVar(&opts.name, "name", "", "human-readable name for the release")
+	cmd.Flags().StringVar(&opts.description, "description", "", "description for the release (e.g. release notes)")
+	cmd.Flags().StringVar(&opts.platform, "platform", "", "platform for the release")
+	cmd.Flags().StringVar(&opts.channel, "channel", "stable", "channel for the release, one of: stable, rc, beta, alpha, dev")
+	cmd.Flags().StringSliceVar(&opts.products, "products", []string{}, "comma seperated list of products to publish the release to instead of --product, signing it for each, e.g. --products <id>,<id>")
+	cmd.Flags().StringVar(&opts.engine, "engine", "", "distribution engine the release is for, validating its artifacts before publishing, one of: raw, pypi, tauri, electron, oci (default the package's engine)")
+	cmd.Flags().StringVar(&opts.pkg, "package", "", "package for the release, by ID or key (required by the pypi, tauri and oci engines)")
+	cmd.Flags().StringSliceVar(&opts.extraChecksums, "extra-checksums", []string{}, "comma seperated list of extra checksums to record in the release's metadata, any of: sha1, sha256, sha384, sha512, blake2b, blake2s")
+	cmd.Flags().StringVar(&opts.signature, "signature", "", "pre-calculated signature for the release (defaults using ed25519ph)")
+	cmd.Flags().StringVar(&opts.checksum, "checksum", "", "pre-calculated checksum for the release (defaults using sha-512)")
+	cmd.Flags().BoolVar(&opts.validateProvided, "validate-provided", false, "verify a pre-calculated --checksum and --signature against the file, and the signature against the product's public key, before publishing")
+	cmd.Flags().StringVar(&opts.signingAlgorithm, "signing-algorithm", "ed25519ph", "the signing algorithm to use, one of: ed25519ph, ed25519")
+	cmd.Flags().StringVar(&opts.signingKeyPath, "signing-key", "", "path to ed25519 private key for signing the release, in hex, PKCS#8 or OpenSSH format, agent://[<fingerprint>] to sign using ssh-agent, or a pkcs11: URI to sign using a hardware token, or vault://<mount>/keys/<name> to sign using vault [$KEYGEN_SIGNING_KEY_PATH=<path>, $KEYGEN_SIGNING_KEY=<key>]")
+	cmd.Flags().StringVar(&opts.signingCtx, "signing-context", "", "context to sign releases with using ed25519ph, which may be empty, e.g. --signing-context '' (default the product ID) [$KEYGEN_SIGNING_CONTEXT]")
+	cmd.Flags().StringVar(&opts.nextSigningKeyPath, "signing-key-next", "", "path to the next ed25519 private key during a key rotation, adding a second signature to the release's metadata [$KEYGEN_NEXT_SIGNING_KEY_PATH=<path>]")
+	cmd.Flags().IntVar(&opts.uploadConcurrency, "upload-concurrency", 1, "upload parts of the file in parallel when a self-hosted instance offers a multipart upload, falling back to a single upload when it doesn't (unsupported by keygen.sh)")
+	cmd.Flags().BoolVar(&opts.verifyUpload, "verify-upload", false, "verify the size and checksum of the uploaded file after publishing")
+	cmd.Flags().Int64Var(&opts.verifyBytes, "verify-upload-bytes", 0, "only verify the first and last n bytes of the uploaded file (default verifies the full file)")
+	cmd.Flags().StringVar(&opts.output, "output", "text", "output format, one of: text, json")
+	cmd.Flags().BoolVar(&opts.queue, "queue", false, "queue the release to be published later when the API is unreachable")
+	cmd.Flags().StringVar(&opts.queueDir, "queue-dir", defaultQueueDir, "directory to store queued releases in [$KEYGEN_QUEUE_DIR=<path>]")
+	cmd.Flags().BoolVar(&opts.prepareOnly, "prepare-only", false, "checksum and sign the release without publishing it, writing it to --bundle to be published using --from-bundle (e.g. for builds inside an air-gapped network)")
+	cmd.Flags().StringVar(&opts.bundle, "bundle", "", "path to write the release bundle prepared by --prepare-only to")
+	cmd.Flags().StringVar(&opts.fromBundle, "from-bundle", "", "publish the releases in a bundle prepared by --prepare-only, which only makes API calls")
+	cmd.Flags().StringVar(&opts.fromSums, "from-sums", "", "publish every file listed in a SHA-512 SUMS manifest, e.g. SHA512SUMS, using the listed digests as checksums once they're verified")
+	cmd.Flags().StringVar(&opts.sumsDir, "dir", "", "directory the files listed by --from-sums are relative to (default the manifest's directory)")
+	cmd.Flags().StringArrayVar(&opts.artifacts, "artifact", []string{}, "publish an additional artifact as a release of the same version, overriding flags per artifact (e.g. --artifact 'build/App.dmg,platform=darwin/amd64,signing-key=~/.keys/macos.key'); may be repeated")
+	cmd.Flags().StringVar(&opts.compress, "compress", "", "compress the file before it's checksummed, signed and uploaded, one of: gzip, zstd (zstd requires the zstd command)")
+	cmd.Flags().IntVar(&opts.compressLevel, "compress-level", 0, "compression level, 1-9 for gzip or 1-19 for zstd (default uses the algorithm's default)")
+	cmd.Flags().StringVar(&opts.maxSize, "max-size", "", "fail when the file is larger than the given size (e.g. 150MB)")
+	cmd.Flags().StringVar(&opts.maxSizeIncrease, "max-size-increase", "", "fail when the file grew by more than a percentage or size since the previous release for the same platform and channel (e.g. 10% or 5MB)")
+	cmd.Flags().BoolVar(&opts.notarize, "notarize", false, "notarize the dmg, pkg or zip using Apple's notarytool, and staple the ticket, before publishing (requires Xcode) [$APPLE_API_KEY_PATH, $APPLE_API_KEY_ID, $APPLE_API_ISSUER or $APPLE_ID, $APPLE_TEAM_ID, $APPLE_APP_PASSWORD]")
+	cmd.Flags().StringVar(&opts.notarizeProfile, "notarize-profile", "", "keychain profile created by `xcrun notarytool store-credentials` for --notarize [$KEYGEN_NOTARIZE_PROFILE=<name>]")
+	cmd.Flags().StringVar(&opts.authenticode, "authenticode", "", "code-sign the exe or msi before publishing, one of: signtool, osslsigncode, azure [$KEYGEN_AUTHENTICODE_CERT, $KEYGEN_AUTHENTICODE_PASSWORD, $KEYGEN_AUTHENTICODE_THUMBPRINT, $KEYGEN_AZURE_CODESIGNING_DLIB, $KEYGEN_AZURE_CODESIGNING_METADATA]")
+	cmd.Flags().StringVar(&opts.timestampURL, "authenticode-timestamp", defaultAuthenticodeTimestampURL, "RFC 3161 timestamp server used by --authenticode")
+	cmd.Flags().StringVar(&opts.publishAt, "publish-at", "", "schedule the release, creating it as a draft which `keygen scheduler run` publishes at an RFC3339 time, e.g. 2024-06-01T09:00Z (requires draft release support)")
+	cmd.Flags().StringVar(&opts.waitEvent, "wait-for-event", "", "wait until an event is logged for each published release, e.g. release.published, so that follow-up steps only run once it has propagated")
+	cmd.Flags().DurationVar(&opts.waitTimeout, "timeout", 10*time.Minute, "how long --wait-for-event waits for the event before failing")
+	cmd.Flags().StringVar(&opts.signatureTSA, "signature-timestamp", "", "RFC 3161 timestamp authority URL used to timestamp the release's signature, recorded in its metadata [$KEYGEN_SIGNATURE_TIMESTAMP_URL]")
+	cmd.Flags().BoolVar(&opts.manifest, "manifest", false, "also publish a SHA512SUMS manifest of every artifact, signed using --signing-key, as a release of the same version")
+	cmd.Flags().StringVar(&opts.summaryFile, "summary-file", "", "write a JSON summary of the published releases, including their URLs, checksums, signatures and upload timings, to a path for later pipeline stages, e.g. keygen-release.json")
+	cmd.Flags().BoolVar(&opts.strictAuth, "strict-auth", false, "fail instead of warning when the token is over-privileged, e.g. an admin token where a product token suffices, or is missing a permission")
+	cmd.Flags().StringVar(&opts.distribution, "require-distribution", "", "fail unless the product's distribution strategy is one of: licensed, open, closed, e.g. to catch a product made OPEN by mistake")
+	cmd.Flags().BoolVar(&opts.overrideFreeze, "override-freeze", false, "publish to the stable channel even while publishing is frozen by keygen freeze, e.g. for a hotfix")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "publish over an existing release of the version without showing what will change and asking to confirm")
+	cmd.Flags().BoolVar(&opts.reproducible, "reproducible", false, "archive deterministically, e.g. symbols directories, using $SOURCE_DATE_EPOCH (or the current commit's time) for every mtime, and record it in the metadata so that builds of the same commit have identical checksums")
+	cmd.Flags().StringVar(&opts.onConflict, "on-conflict", "replace", "what to do when the version's artifact already exists, one of: replace (asking to confirm unless --force), skip, update (merging --metadata into its metadata), fail")
+	cmd.Flags().StringVar(&opts.symbols, "symbols", "", "path to debug symbols, e.g. a .dSYM bundle, .pdb or DWARF file, to publish as a non-distributable companion release")
+	cmd.Flags().StringVar(&opts.gpgKey, "gpg-key", "", "gpg key ID used to publish an armored detached signature as a companion .asc release [$KEYGEN_GPG_PASSPHRASE]")
+	cmd.Flags().StringVar(&opts.watch, "watch", "", "watch a file or directory, publishing a new dev prerelease of --version whenever a file changes")
+	cmd.Flags().DurationVar(&opts.debounce, "watch-debounce", 2*time.Second, "how long a changed file must remain unchanged before it is published")
+	cmd.Flags().BoolVar(&opts.ci, "ci", false, "detect a GitHub Actions, GitLab CI, CircleCI or Buildkite build, defaulting the version to its tag, the channel to its branch, and adding its commit, run URL and actor to the metadata")
+	cmd.Flags().StringSliceVar(&opts.ciChannels, "ci-channels", defaultCIChannels, "comma seperated list of branch to channel mappings used by --ci, where the first match wins (e.g. --ci-channels 'main=stable,release/*=rc,*=dev')")
+	cmd.Flags().BoolVar(&opts.noCache, "no-cache", false, "recompute checksums instead of using ones cached by a previous run for an unchanged file [$KEYGEN_NO_CACHE=1]")
+	cmd.Flags().BoolVar(&opts.noAutoUpgrade, "no-auto-upgrade", false, "disable automatic upgrade checks [$KEYGEN_NO_AUTO_UPGRADE=1]")
+	cmd.Flags().DurationVar(&opts.upgradeInterval, "upgrade-check-interval", 24*time.Hour, "how often to check for an upgrade in the background, where 0 checks every run [$KEYGEN_UPGRADE_CHECK_INTERVAL]")
+
+	cmd.Flags().BoolVar(&opts.createEntitlements, "create-missing-entitlements", false, "create entitlements given by code which don't exist yet, before attaching them as constraints")
+	cmd.Flags().StringVar(&opts.constraintsFile, "constraints-file", "", "path to a YAML file of named entitlement groups, e.g. per release tier, and the releases each group constrains by filename and platform")
+	cmd.Flags().StringSliceVar(&opts.entitlements, "entitlements", []string{}, "comma seperated list of entitlement constraints, by ID or code (e.g. --entitlements <id>,<code>,...)")
+
+	cmd.Flags().StringArrayVar(&opts.metadataPairs, "metadata", []string{}, "key=value to add to the release's metadata, e.g. buildId=1234 or toolchain=go1.17; may be repeated, and set per artifact using metadata.<key>=<value>")
 
 	// TODO(ezekg) Prompt multi-line description input from stdin if "--"?
-	// TODO(ezekg) Add metadata flag
 
-	if v := os.Getenv("KEYGEN_ACCOUNT_ID"); v != "" {
-		if keygenext.Account == "" {
-			keygenext.Account = v
+	bindEnv(cmd.Flags(), "signing-key", "KEYGEN_SIGNING_KEY_PATH")
+	bindEnv(cmd.Flags(), "signing-key-next", "KEYGEN_NEXT_SIGNING_KEY_PATH")
+	bindEnv(cmd.Flags(), "signing-context", "KEYGEN_SIGNING_CONTEXT")
+	bindEnv(cmd.Flags(), "notarize-profile", "KEYGEN_NOTARIZE_PROFILE")
+	bindEnv(cmd.Flags(), "queue-dir", "KEYGEN_QUEUE_DIR")
+	bindEnv(cmd.Flags(), "no-auto-upgrade", "KEYGEN_NO_AUTO_UPGRADE")
+	bindEnv(cmd.Flags(), "signature-timestamp", "KEYGEN_SIGNATURE_TIMESTAMP_URL")
+	bindEnv(cmd.Flags(), "upgrade-check-interval", "KEYGEN_UPGRADE_CHECK_INTERVAL")
+	bindEnv(cmd.Flags(), "no-cache", "KEYGEN_NO_CACHE")
+
+	return cmd
+}
+
+// checksumAlgorithms are the supported --extra-checksums algorithms.
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"sha1":    sha1.New,
+	"sha256":  sha256.New,
+	"sha384":  sha512.New384,
+	"sha512":  sha512.New,
+	"blake2b": func() hash.Hash { h, _ := blake2b.New512(nil); return h },
+	"blake2s": func() hash.Hash { h, _ := blake2s.New256(nil); return h },
+}
+
+func distArgs(opts *CommandOptions, args []string) error {
+	if len(args) == 0 && opts.watch == "" && len(opts.artifacts) == 0 && opts.fromBundle == "" && opts.fromSums == "" {
+		return errors.New("path to file is required")
+	}
+
+	return nil
+}
+
+// distPreRun merges the config file's block for the channel, and relaxes
+// required flags for bundles, which are prepared offline without a token
+// (unless --require-distribution inspects the product), and published using
+// the account and product they were prepared for, and for --products.
+func distPreRun(opts *CommandOptions, cmd *cobra.Command) error {
+	// Channel blocks must be merged before required flags are checked, since
+	// they may set them
+	if err := opts.applyChannelConfig(cmd, distChannel(opts, cmd)); err != nil {
+		return err
+	}
+
+	if len(opts.products) != 0 {
+		delete(cmd.Flags().Lookup("product").Annotations, cobra.BashCompOneRequiredFlag)
+	}
+
+	switch {
+	case opts.prepareOnly && opts.distribution == "":
+		delete(cmd.Flags().Lookup("token").Annotations, cobra.BashCompOneRequiredFlag)
+	case opts.fromBundle != "":
+		delete(cmd.Flags().Lookup("account").Annotations, cobra.BashCompOneRequiredFlag)
+		delete(cmd.Flags().Lookup("product").Annotations, cobra.BashCompOneRequiredFlag)
+	}
+
+	return nil
+}
+
+// distChannel returns the channel dist publishes to, i.e. --channel, or the
+// channel of the CI build when --ci is given.
+func distChannel(opts *CommandOptions, cmd *cobra.Command) string {
+	if opts.ci && !cmd.Flags().Changed("channel") {
+		if env := detectCIEnvironment(); env != nil {
+			if c := env.channel(opts.ciChannels); c != "" {
+				return c
+			}
 		}
 	}
 
-	if v := os.Getenv("KEYGEN_PRODUCT_ID"); v != "" {
-		if keygenext.Product == "" {
-			keygenext.Product = v
+	return opts.channel
+}
+
+func distRun(opts *CommandOptions, cmd *cobra.Command, args []string) error {
+	if err := validateOutput(opts.output); err != nil {
+		return err
+	}
+
+	// Signing keys given by value, e.g. as a CI secret, have no flag
+	if v := os.Getenv("KEYGEN_SIGNING_KEY"); v != "" {
+		opts.signingKey = v
+	}
+
+	switch {
+	case opts.prepareOnly && opts.bundle == "":
+		return errors.New(`flag "--prepare-only" requires "--bundle"`)
+	case opts.prepareOnly && opts.fromBundle != "":
+		return errors.New(`flags "--prepare-only" and "--from-bundle" cannot be used together`)
+	case opts.prepareOnly && (opts.queue || opts.watch != "" || opts.gpgKey != "" || opts.verifyUpload || opts.symbols != ""):
+		return errors.New(`flag "--prepare-only" cannot be used together with "--queue", "--watch", "--gpg-key", "--verify-upload" or "--symbols"`)
+	case opts.prepareOnly && opts.signatureTSA != "":
+		return errors.New(`flag "--prepare-only" cannot be used together with "--signature-timestamp"`)
+	case opts.prepareOnly && opts.validateProvided:
+		return errors.New(`flag "--prepare-only" cannot be used together with "--validate-provided"`)
+	case opts.prepareOnly && opts.waitEvent != "":
+		return errors.New(`flag "--prepare-only" cannot be used together with "--wait-for-event"`)
+	case opts.prepareOnly && opts.publishAt != "":
+		return errors.New(`flag "--prepare-only" cannot be used together with "--publish-at" (pass it when publishing the bundle)`)
+	case opts.manifest && (opts.prepareOnly || opts.queue || opts.watch != ""):
+		return errors.New(`flag "--manifest" cannot be used together with "--prepare-only", "--queue" or "--watch"`)
+	case opts.manifest && opts.signingKeyPath == "" && opts.signingKey == "":
+		return errors.New(`flag "--manifest" requires "--signing-key"`)
+	case opts.fromBundle != "" && (len(args) != 0 || len(opts.artifacts) != 0 || opts.watch != ""):
+		return errors.New(`flag "--from-bundle" cannot be used together with paths, "--artifact" or "--watch"`)
+	case opts.fromSums != "" && (len(args) != 0 || len(opts.artifacts) != 0 || opts.watch != "" || opts.fromBundle != ""):
+		return errors.New(`flag "--from-sums" cannot be used together with paths, "--artifact", "--watch" or "--from-bundle"`)
+	case opts.fromSums != "" && (opts.checksum != "" || opts.compress != "" || opts.authenticode != "" || opts.notarize):
+		return errors.New(`flag "--from-sums" cannot be used together with "--checksum", "--compress", "--authenticode" or "--notarize" (they would change the listed files)`)
+	case opts.sumsDir != "" && opts.fromSums == "":
+		return errors.New(`flag "--dir" requires "--from-sums"`)
+	case opts.summaryFile != "" && (opts.prepareOnly || opts.fromBundle != "" || opts.watch != "" || opts.queue):
+		return errors.New(`flag "--summary-file" cannot be used together with "--prepare-only", "--from-bundle", "--watch" or "--queue"`)
+	case opts.reproducible && (opts.authenticode != "" || opts.notarize):
+		return errors.New(`flag "--reproducible" cannot be used together with "--authenticode" or "--notarize" (they embed signing times in the file)`)
+	case len(opts.products) != 0 && cmd.Flags().Changed("product"):
+		return errors.New(`flags "--product" and "--products" cannot be used together`)
+	case len(opts.products) != 0 && (opts.prepareOnly || opts.fromBundle != "" || opts.watch != "" || opts.queue || opts.pkg != ""):
+		return errors.New(`flag "--products" cannot be used together with "--prepare-only", "--from-bundle", "--watch", "--queue" or "--package"`)
+	case len(opts.products) != 0 && (opts.signature != "" || opts.signingCtx != "" || cmd.Flags().Changed("signing-context")):
+		// Both are bound to a single product, rather than signed for each
+		return errors.New(`flag "--products" cannot be used together with "--signature" or "--signing-context"`)
+	}
+
+	if s := opts.distribution; s != "" {
+		switch strings.ToUpper(s) {
+		case "LICENSED", "OPEN", "CLOSED":
+		default:
+			return fmt.Errorf(`distribution strategy "%s" is not supported (must be one of: licensed, open, closed)`, s)
 		}
 	}
 
-	if v := os.Getenv("KEYGEN_PRODUCT_TOKEN"); v != "" {
-		if keygenext.Token == "" {
-			keygenext.Token = v
+	supported := false
+	for _, strategy := range conflictStrategies {
+		if opts.onConflict == strategy {
+			supported = true
 		}
 	}
 
-	if v := os.Getenv("KEYGEN_SIGNING_KEY_PATH"); v != "" {
-		if distOpts.signingKeyPath == "" {
-			distOpts.signingKeyPath = v
+	if !supported {
+		return fmt.Errorf(`conflict strategy "%s" is not supported (must be one of: %s)`, opts.onConflict, strings.Join(conflictStrategies, ", "))
+	}
+
+	if opts.force && opts.onConflict != "replace" {
+		return fmt.Errorf(`flag "--force" cannot be used together with "--on-conflict %s"`, opts.onConflict)
+	}
+
+	if e := opts.waitEvent; e != "" {
+		if err := validateWaitEvent(e); err != nil {
+			return err
+		}
+
+		if opts.waitTimeout <= 0 {
+			return fmt.Errorf(`timeout "%s" is not acceptable (must be positive)`, opts.waitTimeout)
 		}
 	}
 
-	if v := os.Getenv("KEYGEN_SIGNING_KEY"); v != "" {
-		if distOpts.signingKey == "" {
-			distOpts.signingKey = v
+	if v := opts.publishAt; v != "" {
+		t, err := parsePublishAt(v)
+		if err != nil {
+			return err
 		}
+
+		opts.publishAt = t.Format(time.RFC3339)
 	}
 
-	if v := os.Getenv("KEYGEN_NO_AUTO_UPGRADE"); v != "" {
-		if !distOpts.noAutoUpgrade {
-			distOpts.noAutoUpgrade = v == "1" || v == "true"
+	// An empty context can only be given explicitly, since it's also the
+	// flag's default
+	if cmd.Flags().Changed("signing-context") || opts.signingCtx != "" {
+		if len(opts.signingCtx) > 255 {
+			return fmt.Errorf(`signing context "%s" is not acceptable (must be at most 255 bytes)`, opts.signingCtx)
 		}
+
+		ctx := opts.signingCtx
+		opts.signingContext = &ctx
 	}
 
-	if keygenext.Account == "" {
-		distCmd.MarkFlagRequired("account")
+	if opts.reproducible {
+		epoch, err := sourceDateEpoch()
+		if err != nil {
+			return err
+		}
+
+		opts.sourceDate = epoch
+	}
+
+	if opts.upgradeInterval < 0 {
+		return fmt.Errorf(`upgrade check interval "%s" is not acceptable (must not be negative)`, opts.upgradeInterval)
 	}
 
-	if keygenext.Product == "" {
-		distCmd.MarkFlagRequired("product")
+	// Bundles are prepared offline
+	if !opts.noAutoUpgrade && !opts.prepareOnly {
+		check := startUpgradeCheck(opts.upgradeInterval)
+		defer check.notify()
 	}
 
-	if keygenext.Token == "" {
-		distCmd.MarkFlagRequired("token")
+	if opts.fromBundle != "" {
+		return distFromBundle(opts, opts.fromBundle)
 	}
 
-	distCmd.MarkFlagRequired("version")
+	if opts.ci {
+		env := detectCIEnvironment()
+		if env == nil {
+			return errors.New("no supported CI environment was detected (expected GitHub Actions, GitLab CI, CircleCI or Buildkite)")
+		}
+
+		for _, m := range opts.ciChannels {
+			if !strings.Contains(m, "=") {
+				return fmt.Errorf(`channel mapping "%s" is not acceptable (must be <branch>=<channel>)`, m)
+			}
+		}
+
+		if v := env.version(); v != "" && !cmd.Flags().Changed("version") {
+			opts.version = v
+		}
+
+		if c := env.channel(opts.ciChannels); c != "" && !cmd.Flags().Changed("channel") {
+			opts.channel = c
+		}
+
+		opts.metadata = env.metadata()
+	}
+
+	if opts.version == "" {
+		if !opts.canPrompt() {
+			return errors.New(`required flag(s) "version" not set`)
+		}
 
-	rootCmd.AddCommand(distCmd)
+		v, err := opts.promptValidValue("version", "", func(v string) error {
+			if v == "" {
+				return errors.New("version is required")
+			}
+
+			_, err := checkVersion(opts, v)
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		opts.version = v
+	}
+
+	// Ad-hoc publishes of a single file are asked for its platform, defaulting
+	// to the platform we're running on
+	if len(args) == 1 && len(opts.artifacts) == 0 && opts.watch == "" && !cmd.Flags().Changed("platform") && opts.platform == "" && opts.canPrompt() {
+		p, err := opts.promptValidValue("platform (or "+noPlatform+")", runtime.GOOS+"/"+runtime.GOARCH, func(v string) error {
+			_, err := normalizePlatform(v)
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		opts.platform = p
+	}
+
+	// Explicit metadata takes precedence over metadata detected by --ci
+	if len(opts.metadataPairs) != 0 {
+		metadata, err := parseMetadata(opts.metadataPairs)
+		if err != nil {
+			return err
+		}
+
+		if opts.metadata == nil {
+			opts.metadata = map[string]interface{}{}
+		}
+
+		for k, v := range metadata {
+			opts.metadata[k] = v
+		}
+	}
+
+	for _, algorithm := range opts.extraChecksums {
+		if _, ok := checksumAlgorithms[algorithm]; !ok {
+			return fmt.Errorf(`checksum algorithm "%s" is not supported`, algorithm)
+		}
+	}
+
+	switch opts.authenticode {
+	case "", "signtool", "osslsigncode", "azure":
+	default:
+		return fmt.Errorf(`authenticode tool "%s" is not supported`, opts.authenticode)
+	}
+
+	if opts.uploadConcurrency < 1 || opts.uploadConcurrency > 32 {
+		return fmt.Errorf(`upload concurrency "%d" is not acceptable (must be between 1 and 32)`, opts.uploadConcurrency)
+	}
+
+	// Multipart uploads aren't part of the API, so they're only requested from
+	// self-hosted instances which may have been extended to offer them
+	if opts.uploadConcurrency > 1 && !isSelfHosted() {
+		return errors.New(`flag "--upload-concurrency" is not supported by keygen.sh (only by self-hosted instances which offer multipart uploads)`)
+	}
+
+	if s := opts.maxSize; s != "" {
+		if _, err := parseSize(s); err != nil {
+			return err
+		}
+	}
+
+	if err := validateCompression(opts.compress, opts.compressLevel); err != nil {
+		return err
+	}
+
+	if opts.semverStrict && opts.semverCoerce {
+		return errors.New(`flags "--semver-strict" and "--semver-coerce" cannot be used together`)
+	}
+
+	if err := validateEngine(opts); err != nil {
+		return err
+	}
+
+	if p := opts.constraintsFile; p != "" {
+		if opts.fromBundle != "" {
+			return errors.New(`flag "--constraints-file" cannot be used together with "--from-bundle"`)
+		}
+
+		c, err := loadConstraintsFile(p)
+		if err != nil {
+			return err
+		}
+
+		opts.constraints = c
+	}
+
+	if !opts.prepareOnly {
+		if err := checkTokenScope(opts, len(opts.entitlements) != 0 || opts.constraints != nil); err != nil {
+			return err
+		}
+	}
+
+	// Watched builds are published as dev prereleases, so they're never
+	// frozen. This fails fast, before anything is signed, but every publish is
+	// checked by publishRelease.
+	if !opts.prepareOnly && opts.watch == "" && opts.publishAt == "" {
+		if err := checkFreeze(opts, opts.channel); err != nil {
+			return err
+		}
+	}
+
+	// Catch missing or inaccessible entitlements before anything is published,
+	// or when bundled, once the bundle is published. Every group's entitlements
+	// are checked at once, so that none is applied unless all of them can be.
+	if (len(opts.entitlements) != 0 || opts.constraints != nil) && !opts.prepareOnly {
+		all := opts.entitlements
+		if c := opts.constraints; c != nil {
+			all = mergeEntitlements(all, c.entitlements())
+		}
+
+		ids, err := opts.preflightConstraints(all, opts.createEntitlements)
+		if err != nil {
+			return err
+		}
+
+		resolved := map[string]string{}
+		for i, e := range all {
+			resolved[e] = ids[i]
+		}
+
+		for i, e := range opts.entitlements {
+			opts.entitlements[i] = resolved[e]
+		}
+
+		if c := opts.constraints; c != nil {
+			c.resolve(resolved)
+		}
+	}
+
+	if opts.watch != "" {
+		// Watched builds are published as dev prereleases
+		if !cmd.Flags().Changed("channel") {
+			opts.channel = "dev"
+		}
+
+		return distWatch(opts, opts.watch)
+	}
+
+	artifacts := []*distArtifact{}
+	for _, path := range args {
+		artifacts = append(artifacts, newDistArtifact(opts, path))
+	}
+
+	for _, spec := range opts.artifacts {
+		a, err := parseDistArtifact(opts, spec)
+		if err != nil {
+			return err
+		}
+
+		artifacts = append(artifacts, a)
+	}
+
+	if opts.fromSums != "" {
+		a, err := sumsArtifacts(opts, opts.fromSums, opts.sumsDir)
+		if err != nil {
+			return err
+		}
+
+		artifacts = append(artifacts, a...)
+	}
+
+	// Fail before anything is uploaded, rather than when the server rejects
+	// (or overwrites) a later artifact
+	if err := checkArtifactFlags(opts, artifacts); err != nil {
+		return err
+	}
+
+	if err := checkArtifactCollisions(artifacts, opts.compress); err != nil {
+		return err
+	}
+
+	// Bundles are prepared offline, so their product is only inspected when a
+	// strategy is required
+	if !opts.prepareOnly || opts.distribution != "" {
+		if err := checkDistribution(opts, artifacts); err != nil {
+			return err
+		}
+	}
+
+	// Hash large artifacts concurrently up front, unless they're modified by
+	// compression or code signing before they're hashed
+	if opts.fromSums != "" {
+		if err := verifySums(opts, artifacts); err != nil {
+			return err
+		}
+	} else if len(artifacts) > 1 && opts.compress == "" && opts.authenticode == "" && !opts.notarize {
+		prehashArtifacts(artifacts, opts.extraChecksums, opts.noCache)
+	}
+
+	// The summary lists artifact URLs
+	if opts.summaryFile != "" {
+		if err := opts.checkArtifactHost(); err != nil {
+			return err
+		}
+	}
+
+	started := time.Now()
+
+	var err error
+	if len(opts.products) != 0 {
+		err = distFanOut(opts, artifacts)
+	} else {
+		err = distPublishAll(opts, artifacts)
+	}
+
+	if p := opts.summaryFile; p != "" {
+		if serr := writeSummary(opts, p, started, err); serr != nil && err == nil {
+			err = serr
+		}
+	}
+
+	return err
 }
 
-func distArgs(cmd *cobra.Command, args []string) error {
-	if len(args) == 0 {
-		return errors.New("path to file is required")
+// distPublishAll publishes every artifact to the product, followed by the
+// bundle or manifest, when asked for.
+func distPublishAll(opts *CommandOptions, artifacts []*distArtifact) error {
+	for _, a := range artifacts {
+		if err := distPublish(opts, a, opts.version); err != nil {
+			return err
+		}
+	}
+
+	if opts.prepareOnly {
+		return writeBundle(opts, opts.bundle)
+	}
+
+	if opts.manifest {
+		return distManifest(opts)
 	}
 
 	return nil
 }
 
-func distRun(cmd *cobra.Command, args []string) error {
-	if !distOpts.noAutoUpgrade {
-		err := upgradeRun(nil, nil)
-		if err != nil {
+// distPublish publishes an artifact as a release of version.
+func distPublish(opts *CommandOptions, a *distArtifact, v string) (err error) {
+	defer func() {
+		if err != nil && isGitHubActions() {
+			printGitHubAnnotation("error", "keygen dist", err.Error())
+		}
+	}()
+
+	path, err := homedir.Expand(a.path)
+	if err != nil {
+		return fmt.Errorf(`path "%s" is not expandable (%s)`, a.path, err)
+	}
+
+	// Code signing and notarization modify the file, so they must happen before
+	// the file is checksummed, signed and uploaded
+	if opts.authenticode != "" {
+		if err := authenticodeSign(opts, path); err != nil {
 			return err
 		}
 	}
 
-	path, err := homedir.Expand(args[0])
-	if err != nil {
-		return fmt.Errorf(`path "%s" is not expandable (%s)`, args[0], err)
+	if opts.notarize {
+		if err := notarizeArtifact(opts, path); err != nil {
+			return err
+		}
 	}
 
 	file, err := os.Open(path)
@@ -166,78 +655,332 @@ func distRun(cmd *cobra.Command, args []string) error {
 	filesize := info.Size()
 
 	// Allow filename to be overridden
-	if n := distOpts.filename; n != "" {
+	if n := a.filename; n != "" {
 		filename = n
 	}
 
+	// Compress the artifact before it's checksummed, signed and uploaded. When
+	// queueing, it's compressed into the queue so that it can be flushed later.
+	var compressed string
+	defer func() {
+		if compressed != "" {
+			os.Remove(compressed)
+		}
+	}()
+
+	if opts.compress != "" {
+		var dir string
+		switch {
+		case opts.prepareOnly:
+			// Keep compressed artifacts alongside the bundle
+			p, err := homedir.Expand(opts.bundle)
+			if err != nil {
+				return fmt.Errorf(`bundle path "%s" is not expandable (%s)`, opts.bundle, err)
+			}
+
+			dir = filepath.Dir(p)
+		case opts.queue:
+			dir, err = homedir.Expand(opts.queueDir)
+			if err != nil {
+				return fmt.Errorf(`queue path "%s" is not expandable (%s)`, opts.queueDir, err)
+			}
+
+			if err := os.MkdirAll(dir, 0700); err != nil {
+				return fmt.Errorf(`queue path "%s" is not writable (%s)`, dir, err)
+			}
+		}
+
+		tmp, ext, err := compressArtifact(file, opts.compress, opts.compressLevel, dir)
+		if err != nil {
+			return err
+		}
+		defer tmp.Close()
+
+		info, err := tmp.Stat()
+		if err != nil {
+			return err
+		}
+
+		if !strings.HasSuffix(filename, "."+ext) {
+			filename += "." + ext
+		}
+
+		compressed = tmp.Name()
+		path = compressed
+		file = tmp
+		filesize = info.Size()
+	}
+
 	// Allow filetype to be overridden
 	var filetype string
 
-	if distOpts.filetype == "auto" {
-		filetype = filepath.Ext(filename)
-		if _, e := strconv.Atoi(filetype); e == nil || filetype == "" {
-			filetype = "bin"
+	if a.filetype == "auto" {
+		detected, ext, err := detectFiletype(file, filename)
+		if err != nil {
+			return fmt.Errorf(`path "%s" is not readable (%s)`, path, err)
+		}
+
+		if ext != "" {
+			yellow := color.New(color.FgYellow).SprintFunc()
+
+			fmt.Fprintln(os.Stderr, yellow("warning:")+fmt.Sprintf(` extension "%s" does not match the file's content (using "%s")`, ext, detected))
 		}
+
+		filetype = detected
 	} else {
-		filetype = distOpts.filetype
+		filetype = a.filetype
+	}
+
+	contentType := a.contentType
+	if contentType == "" {
+		contentType = contentTypeForFiletype(filetype)
 	}
 
-	channel := distOpts.channel
-	platform := distOpts.platform
+	channel := opts.channel
+
+	platform, err := normalizePlatform(a.platform)
+	if err != nil {
+		return err
+	}
+
+	if err := checkPlatformFiletype(platform, filetype); err != nil {
+		return err
+	}
+
+	if err := checkEngineArtifact(opts.engine, platform, filetype, a.signature); err != nil {
+		return err
+	}
+
+	entitlements := opts.entitlements
+	if c := opts.constraints; c != nil {
+		matched, err := c.match(filename, platform)
+		if err != nil {
+			return err
+		}
+
+		entitlements = mergeEntitlements(entitlements, matched)
+	}
 
 	constraints := keygenext.Constraints{}
-	if e := distOpts.entitlements; len(e) != 0 {
-		constraints = constraints.From(e)
+	if len(entitlements) != 0 {
+		constraints = constraints.From(entitlements)
 	}
 
 	var name *string
-	if n := distOpts.name; n != "" {
+	if n := opts.name; n != "" {
 		name = &n
 	}
 
 	var desc *string
-	if d := distOpts.description; d != "" {
+	if d := opts.description; d != "" {
 		desc = &d
 	}
 
-	version, err := semver.NewVersion(distOpts.version)
+	version, err := parseVersion(opts, v)
 	if err != nil {
-		return fmt.Errorf(`version "%s" is not acceptable (%s)`, distOpts.version, strings.ToLower(err.Error()))
+		return err
 	}
 
-	checksum := distOpts.checksum
-	if checksum == "" {
-		checksum, err = calculateChecksum(file)
+	// Resolve a conflict with an existing artifact before anything is signed
+	// or timestamped, since a skipped or updated release doesn't need either
+	var live int
+	var existing *keygenext.Release
+
+	if !opts.prepareOnly {
+		live, existing, err = existingRelease(opts, &keygenext.Release{Version: version.String(), Filename: filename, ProductID: opts.productID})
 		if err != nil {
 			return err
 		}
-	}
 
-	signature := distOpts.signature
-	if signature == "" && (distOpts.signingKeyPath != "" || distOpts.signingKey != "") {
-		var key string
+		if existing != nil && opts.onConflict != "replace" {
+			metadata := map[string]interface{}{}
+			for k, v := range opts.metadata {
+				metadata[k] = v
+			}
 
-		switch {
-		case distOpts.signingKeyPath != "":
-			path, err := homedir.Expand(distOpts.signingKeyPath)
-			if err != nil {
-				return fmt.Errorf(`signing-key path is not expandable (%s)`, err)
+			for k, v := range a.metadata {
+				metadata[k] = v
 			}
 
-			b, err := os.ReadFile(path)
-			if err != nil {
-				return fmt.Errorf(`signing-key path is not readable (%s)`, err)
+			return resolveConflict(opts, existing, metadata)
+		}
+	}
+
+	signing := a.signature == "" && (a.signingKeyPath != "" || a.signingKey != "")
+
+	// The checksum and the ed25519ph prehash are the same SHA-512 digest, so
+	// the file is only hashed once
+	var digest *fileDigest
+	var checksums map[string]string
+
+	if a.checksum == "" || len(opts.extraChecksums) != 0 || signing || opts.nextSigningKeyPath != "" || opts.validateProvided {
+		// Compressed artifacts are temporary files, so they're never cached
+		if compressed != "" {
+			digest, err = hashFile(file, opts.extraChecksums)
+		} else {
+			digest, err = hashArtifact(path, file, opts.extraChecksums, opts.noCache)
+		}
+		if err != nil {
+			return err
+		}
+
+		checksums = digest.checksums
+	}
+
+	checksum := a.checksum
+	if checksum == "" {
+		checksum = digest.checksum()
+	}
+
+	signature := a.signature
+	if signing {
+		signer, err := opts.loadSigner(a.signingKeyPath, a.signingKey)
+		if err != nil {
+			return err
+		}
+
+		if !opts.prepareOnly {
+			if err := opts.preflightSigningKey(signer); err != nil {
+				return err
 			}
+		}
 
-			key = string(b)
-		case distOpts.signingKey != "":
-			key = distOpts.signingKey
+		signature, err = opts.calculateSignature(signer, opts.signingAlgorithm, file, digest)
+		if err != nil {
+			return err
 		}
+	}
 
-		signature, err = calculateSignature(key, file)
+	if opts.validateProvided {
+		if err := validateProvided(opts, a, file, digest); err != nil {
+			return err
+		}
+	}
+
+	var metadata map[string]interface{}
+	if len(opts.metadata) != 0 || len(a.metadata) != 0 {
+		metadata = map[string]interface{}{}
+		for k, v := range opts.metadata {
+			metadata[k] = v
+		}
+
+		// Per-artifact metadata, e.g. a build ID per platform
+		for k, v := range a.metadata {
+			metadata[k] = v
+		}
+	}
+
+	// Record extra checksums for ecosystems which don't support SHA-512
+	if len(checksums) != 0 {
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+
+		metadata["checksums"] = checksums
+	}
+
+	// Record the Ed25519ph context, so that clients know how to verify
+	if signing && opts.signingAlgorithm == "ed25519ph" {
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+
+		metadata["signingContext"] = opts.ed25519phContext()
+	}
+
+	// Record what archives were normalized to, so that the build can be
+	// reproduced and its checksums attested
+	if opts.reproducible {
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+
+		metadata["reproducible"] = true
+		metadata["sourceDateEpoch"] = opts.sourceDate.Unix()
+	}
+
+	// Timestamp the signature, so that it's provably valid after the signing
+	// key is rotated or expires
+	if u := opts.signatureTSA; u != "" {
+		if signature == "" {
+			return errors.New(`flag "--signature-timestamp" requires a signature (use --signing-key or --signature)`)
+		}
+
+		token, timestamped, err := timestampSignature(u, signature)
+		if err != nil {
+			return fmt.Errorf("signature could not be timestamped (%s)", err)
+		}
+
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+
+		metadata["signatureTimestamp"] = base64.StdEncoding.EncodeToString(token)
+		metadata["signatureTimestampAuthority"] = u
+		metadata["signatureTimestamped"] = timestamped.UTC().Format(time.RFC3339)
+	}
+
+	// Sign the final file using gpg, to be published as a companion .asc
+	var gpgSignature []byte
+	var gpgKeyFingerprint string
+
+	if k := opts.gpgKey; k != "" {
+		gpgKeyFingerprint, err = gpgFingerprint(k)
 		if err != nil {
 			return err
 		}
+
+		gpgSignature, err = gpgSign(k, path)
+		if err != nil {
+			return err
+		}
+
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+
+		metadata["gpgFingerprint"] = gpgKeyFingerprint
+	}
+
+	// Link to the release's debug symbols, which are published after it
+	var symbolsType string
+
+	if p := a.symbols; p != "" {
+		if opts.prepareOnly {
+			return errors.New(`option "symbols" cannot be used together with "--prepare-only"`)
+		}
+
+		symbolsType, err = symbolsFiletype(p)
+		if err != nil {
+			return err
+		}
+
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+
+		metadata["symbols"] = symbolsFilename(filename, symbolsType)
+	}
+
+	// Attach a second signature during a key rotation window
+	if opts.nextSigningKeyPath != "" {
+		signer, err := opts.loadSigner(opts.nextSigningKeyPath, "")
+		if err != nil {
+			return err
+		}
+
+		next, err := opts.calculateNextSignature(signer, opts.signingAlgorithm, file, digest)
+		if err != nil {
+			return err
+		}
+
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+
+		for k, v := range next {
+			metadata[k] = v
+		}
 	}
 
 	release := &keygenext.Release{
@@ -251,129 +994,456 @@ func distRun(cmd *cobra.Command, args []string) error {
 		Signature:   signature,
 		Checksum:    checksum,
 		Channel:     channel,
-		ProductID:   keygenext.Product,
+		Metadata:    metadata,
+		ProductID:   opts.productID,
+		PackageID:   opts.pkg,
 		Constraints: constraints,
+		ContentType: contentType,
 	}
 
-	// TODO(ezekg) Should we do a Create() unless a --upsert flag is given?
-	if err := release.Upsert(); err != nil {
-		e, ok := err.(*keygenext.APIError)
-		if ok {
-			italic := color.New(color.Italic).SprintFunc()
-			code := e.Code
-			if code == "" {
-				code = "API_ERROR"
+	if opts.prepareOnly {
+		if err := opts.bundleRelease(path, release); err != nil {
+			return err
+		}
+
+		// Keep the compressed artifact alongside the bundle
+		compressed = ""
+
+		return nil
+	}
+
+	if err := checkSizeGate(opts, release); err != nil {
+		return err
+	}
+
+	if err := confirmUpsert(opts, release, live, existing); err != nil {
+		return err
+	}
+
+	// Allow for the API's clock being behind ours when matching events
+	started := time.Now().Add(-30 * time.Second)
+
+	telemetry, err := publishRelease(opts, release, file)
+	if err != nil {
+		// Queue the release to be published later when the API is unreachable
+		if opts.queue && isNetworkError(err) {
+			entry, err := opts.enqueueRelease(opts.queueDir, path, release)
+			if err != nil {
+				return err
 			}
 
-			return fmt.Errorf("%s - %s: %s", italic(code), e.Title, e.Detail)
+			// Keep the compressed artifact until the queue is flushed
+			compressed = ""
+
+			if isGitHubActions() {
+				if err := writeGitHubOutput(map[string]string{"queued": "true", "queue-id": entry.ID}); err != nil {
+					return err
+				}
+
+				printGitHubAnnotation("warning", "keygen dist", "queued release "+release.Version+" ("+entry.ID+") because the API is unreachable")
+			}
+
+			if opts.output == "json" {
+				return printJSON(map[string]interface{}{"queued": true, "queue_id": entry.ID})
+			}
+
+			italic := color.New(color.Italic).SprintFunc()
+
+			fmt.Println("queued release " + italic(entry.ID) + " (run `keygen queue flush` to publish)")
+
+			return nil
 		}
 
 		return err
 	}
 
-	// Create a buffered reader to limit memory footprint
-	var reader io.Reader = bufio.NewReaderSize(file, 1024*1024*50 /* 50 mb */)
+	opts.published = append(opts.published, release)
+	opts.summarized = append(opts.summarized, opts.summarizeRelease(release, telemetry))
+
+	var companion *keygenext.Release
+	if gpgSignature != nil {
+		companion, err = publishGPGSignature(opts, release, gpgSignature, gpgKeyFingerprint)
+		if err != nil {
+			return fmt.Errorf("gpg signature could not be published (%w)", err)
+		}
+	}
+
+	var symbols *keygenext.Release
+	if a.symbols != "" {
+		symbols, err = publishSymbols(opts, release, a.symbols)
+		if err != nil {
+			return fmt.Errorf("symbols could not be published (%w)", err)
+		}
+	}
+
+	if e := opts.waitEvent; e != "" {
+		if err := waitForEvent(opts, release, e, started); err != nil {
+			return err
+		}
+	}
+
+	exportTelemetry("keygen.dist", map[string]string{
+		"keygen.release.id":       release.ID,
+		"keygen.release.version":  release.Version,
+		"keygen.release.platform": release.Platform,
+		"keygen.release.channel":  release.Channel,
+	}, telemetry)
+
+	if isGitHubActions() {
+		err := writeGitHubOutput(map[string]string{
+			"release-id":  release.ID,
+			"artifact-id": release.ArtifactID,
+			"version":     release.Version,
+			"channel":     release.Channel,
+			"platform":    release.Platform,
+			"checksum":    release.Checksum,
+			"signature":   release.Signature,
+		})
+		if err != nil {
+			return err
+		}
+
+		printGitHubAnnotation("notice", "keygen dist", "published release "+release.Version+" ("+release.ID+")")
+	}
+
+	if opts.output == "json" {
+		var gpg map[string]interface{}
+		if companion != nil {
+			gpg = map[string]interface{}{"id": companion.ID, "filename": companion.Filename, "fingerprint": gpgKeyFingerprint}
+		}
+
+		var syms map[string]interface{}
+		if symbols != nil {
+			syms = map[string]interface{}{"id": symbols.ID, "filename": symbols.Filename, "filesize": symbols.Filesize}
+		}
+
+		return opts.printDistJSON(map[string]interface{}{
+			"id":           release.ID,
+			"artifact_id":  release.ArtifactID,
+			"version":      release.Version,
+			"channel":      release.Channel,
+			"platform":     release.Platform,
+			"filename":     release.Filename,
+			"filesize":     release.Filesize,
+			"filetype":     release.Filetype,
+			"content_type": release.ContentType,
+			"checksum":     release.Checksum,
+			"checksums":    checksums,
+			"signature":    release.Signature,
+			"metadata":     release.Metadata,
+			"status":       release.Status,
+			"skipped":      false,
+			"gpg":          gpg,
+			"symbols":      syms,
+			"telemetry":    telemetry,
+			"warnings":     opts.warnings,
+		})
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	if opts.publishAt != "" {
+		fmt.Println("scheduled release " + italic(release.ID) + " for " + opts.publishAt + " (run `keygen scheduler run` to publish it)")
+	} else {
+		fmt.Println("published release " + italic(release.ID))
+	}
+
+	if telemetry != nil {
+		faint := color.New(color.Faint).SprintFunc()
+
+		fmt.Println(faint(telemetry.summary()))
+	}
+
+	if companion != nil {
+		fmt.Println("published gpg signature " + italic(companion.ID) + " (" + companion.Filename + ")")
+	}
+
+	if symbols != nil {
+		fmt.Println("published symbols " + italic(symbols.ID) + " (" + symbols.Filename + ")")
+	}
+
+	return nil
+}
+
+// printDistJSON prints dist's JSON output for a release, unless it's collected
+// by distFanOut to be printed along with the other products' releases.
+func (s *session) printDistJSON(v interface{}) error {
+	if s.collected != nil {
+		s.collected = append(s.collected, v)
+
+		return nil
+	}
+
+	return printJSON(v)
+}
+
+// distWarning prints a warning identified by code, which is also annotated in
+// GitHub Actions and included in JSON output.
+func (s *session) distWarning(code string, message string) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	fmt.Fprintln(os.Stderr, yellow("warning:")+" "+message+" ("+code+")")
+
+	if isGitHubActions() {
+		printGitHubAnnotation("warning", "keygen dist", message+" ("+code+")")
+	}
+
+	s.warnings = append(s.warnings, map[string]string{"code": code, "detail": message})
+}
+
+// publishRelease upserts the release and uploads the file to its artifact,
+// returning telemetry for the upload.
+func publishRelease(opts *CommandOptions, release *keygenext.Release, file *os.File) (*uploadTelemetry, error) {
+	scheduleRelease(opts, release)
+
+	// Scheduled drafts are checked once the scheduler publishes them
+	if release.Status != "DRAFT" {
+		if err := checkFreeze(opts, release.Channel); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkReleaseLocks(opts, release); err != nil {
+		return nil, err
+	}
+
+	// TODO(ezekg) Should we do a Create() unless a --upsert flag is given?
+	if err := opts.client.UpsertRelease(opts.ctx, release); err != nil {
+		return nil, formatAPIError(err)
+	}
+
+	if err := checkScheduledRelease(opts, release); err != nil {
+		return nil, err
+	}
+
 	var progress *mpb.Progress
+	var bar *mpb.Bar
+
+	// The parts of a concurrent upload uploaded and retried so far, which are
+	// shown alongside its progress once the server offers a multipart upload
+	var parts atomic.Value
 
-	// Create a progress bar for file upload if TTY
-	if isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd()) {
+	// Create a progress bar for file upload if TTY (but not when the output is
+	// meant to be machine-readable)
+	if opts.output != "json" && (isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())) {
 		progress = mpb.New(mpb.WithWidth(60), mpb.WithRefreshRate(180*time.Millisecond))
-		bar := progress.Add(
+		bar = progress.Add(
 			release.Filesize,
 			mpb.NewBarFiller(mpb.BarStyle().Rbound("|")),
 			mpb.BarRemoveOnComplete(),
 			mpb.PrependDecorators(
-				decor.CountersKibiByte("% .2f / % .2f"),
+				byteCountersDecorator(),
 			),
 			mpb.AppendDecorators(
 				decor.EwmaETA(decor.ET_STYLE_GO, 90),
 				decor.Name(" ] "),
-				decor.EwmaSpeed(decor.UnitKiB, "% .2f", 60),
+				byteSpeedDecorator(),
+				decor.Any(func(decor.Statistics) string {
+					result, ok := parts.Load().(keygenext.UploadResult)
+					if !ok {
+						return ""
+					}
+
+					return fmt.Sprintf(" [ %d / %d parts, %d retries ]", result.Uploaded, result.Parts, result.Retries)
+				}),
 			),
 		)
+	}
+
+	// Count and report the progress of every reader uploaded, i.e. the file,
+	// or each of its parts when it's uploaded concurrently
+	counters := []*countingReader{}
+	countersMu := sync.Mutex{}
+
+	wrap := func(r io.Reader) io.Reader {
+		counter := &countingReader{reader: r}
+
+		countersMu.Lock()
+		counters = append(counters, counter)
+		countersMu.Unlock()
 
-		// Create proxy reader for the progress bar
-		reader = bar.ProxyReader(reader)
-		closer, ok := reader.(io.ReadCloser)
-		if ok {
-			defer closer.Close()
+		if bar != nil {
+			return bar.ProxyReader(counter)
 		}
+
+		return counter
 	}
 
-	if err := release.Upload(reader); err != nil {
-		return err
+	telemetry := &uploadTelemetry{Started: time.Now()}
+
+	if opts.uploadConcurrency > 1 {
+		result, err := opts.client.UploadReleaseConcurrently(opts.ctx, release, file, opts.uploadConcurrency, wrap, func(result keygenext.UploadResult) {
+			parts.Store(result)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		telemetry.Parts = result.Parts
+		telemetry.Retries += result.Retries
+	} else {
+		// Create a buffered reader to limit memory footprint
+		reader := wrap(bufio.NewReaderSize(file, 1024*1024*50 /* 50 mb */))
+
+		if err := opts.client.UploadRelease(opts.ctx, release, reader); err != nil {
+			return nil, err
+		}
+
+		telemetry.Parts = 1
+	}
+
+	for _, counter := range counters {
+		telemetry.BytesSent += atomic.LoadInt64(&counter.count)
 	}
 
+	telemetry.finish()
+
 	if progress != nil {
+		// Parts which were retried may leave the bar short of, or past, the total
+		bar.Abort(true)
 		progress.Wait()
 	}
 
-	italic := color.New(color.Italic).SprintFunc()
-
-	fmt.Println("published release " + italic(release.ID))
+	if opts.verifyUpload {
+		if err := verifyUpload(opts, release, file); err != nil {
+			return nil, err
+		}
+	}
 
-	return nil
+	return telemetry, nil
 }
 
-func calculateChecksum(file *os.File) (string, error) {
-	defer file.Seek(0, io.SeekStart) // reset reader
+func verifyUpload(opts *CommandOptions, release *keygenext.Release, file *os.File) error {
+	var artifact *keygenext.Artifact
+	var err error
 
-	h := sha512.New()
+	// The artifact may not be immediately available while it's being processed,
+	// so we'll retry a few times before giving up.
+	for attempt := 1; attempt <= 5; attempt++ {
+		artifact, err = opts.client.GetReleaseArtifact(opts.ctx, release)
+		if err == nil && artifact.Location != "" {
+			break
+		}
 
-	if _, err := io.Copy(h, file); err != nil {
-		return "", err
+		time.Sleep(time.Duration(attempt) * time.Second)
 	}
 
-	digest := h.Sum(nil)
+	if err != nil {
+		return fmt.Errorf("upload verification failed (%s)", err)
+	}
 
-	return base64.RawStdEncoding.EncodeToString(digest), nil
-}
+	n := opts.verifyBytes
 
-func calculateSignature(encSigningKey string, file *os.File) (string, error) {
-	defer file.Seek(0, io.SeekStart) // reset reader
+	// Verify the entire file when no byte count is given, or when the ranges
+	// would overlap anyways.
+	if n <= 0 || 2*n >= release.Filesize {
+		body, _, err := artifact.Download(opts.ctx, 0, 0)
+		if err != nil {
+			return fmt.Errorf("upload verification failed (%s)", err)
+		}
+		defer body.Close()
 
-	decSigningKey, err := hex.DecodeString(encSigningKey)
-	if err != nil {
-		return "", fmt.Errorf("bad signing key (%s)", err)
+		h := sha512.New()
+
+		size, err := io.Copy(h, body)
+		if err != nil {
+			return fmt.Errorf("upload verification failed (%s)", err)
+		}
+
+		if size != release.Filesize {
+			return fmt.Errorf("upload verification failed (expected size %d got %d)", release.Filesize, size)
+		}
+
+		if checksum := base64.RawStdEncoding.EncodeToString(h.Sum(nil)); checksum != release.Checksum {
+			return fmt.Errorf("upload verification failed (expected checksum %s got %s)", release.Checksum, checksum)
+		}
+
+		return nil
 	}
 
-	if l := len(decSigningKey); l != ed25519.PrivateKeySize {
-		return "", fmt.Errorf("bad signing key length (got %d expected %d)", l, ed25519.PrivateKeySize)
+	for _, offset := range []int64{0, release.Filesize - n} {
+		body, size, err := artifact.Download(opts.ctx, offset, n)
+		if err != nil {
+			return fmt.Errorf("upload verification failed (%s)", err)
+		}
+
+		if size != release.Filesize {
+			body.Close()
+
+			return fmt.Errorf("upload verification failed (expected size %d got %d)", release.Filesize, size)
+		}
+
+		remote := sha512.New()
+		_, err = io.Copy(remote, io.LimitReader(body, n))
+		body.Close()
+		if err != nil {
+			return fmt.Errorf("upload verification failed (%s)", err)
+		}
+
+		local := sha512.New()
+		if _, err := io.Copy(local, io.NewSectionReader(file, offset, n)); err != nil {
+			return err
+		}
+
+		if !bytes.Equal(remote.Sum(nil), local.Sum(nil)) {
+			return fmt.Errorf("upload verification failed (bytes %d-%d do not match)", offset, offset+n-1)
+		}
 	}
 
-	signingKey := ed25519.PrivateKey(decSigningKey)
+	return nil
+}
+
+// calculateChecksum calculates the release's SHA-512 checksum, along with any
+// extra hex-encoded checksums, reading the file only once.
+func calculateChecksum(file *os.File, extra []string) (string, map[string]string, error) {
+	digest, err := hashFile(file, extra)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return digest.checksum(), digest.checksums, nil
+}
+
+// calculateSignature signs the file. For ed25519ph, the file's SHA-512 digest
+// is signed, which is calculated unless it's given.
+func (s *session) calculateSignature(signingKey crypto.Signer, algorithm string, file *os.File, digest *fileDigest) (string, error) {
+	defer file.Seek(0, io.SeekStart) // reset reader
+
 	var sig []byte
+	var err error
 
-	switch distOpts.signingAlgorithm {
+	switch algorithm {
 	case "ed25519ph":
 		// We're using Ed25519ph which expects a pre-hashed message using SHA-512
-		h := sha512.New()
-
-		if _, err := io.Copy(h, file); err != nil {
-			return "", err
+		if digest == nil {
+			digest, err = hashFile(file, nil)
+			if err != nil {
+				return "", err
+			}
 		}
 
-		opts := &ed25519.Options{Hash: crypto.SHA512, Context: keygenext.Product}
-		digest := h.Sum(nil)
+		opts := &ed25519.Options{Hash: crypto.SHA512, Context: s.ed25519phContext()}
 
-		sig, err = signingKey.Sign(nil, digest, opts)
+		sig, err = signingKey.Sign(nil, digest.sum, opts)
 		if err != nil {
 			return "", err
 		}
 	case "ed25519":
-		fmt.Println("warning: using ed25519 to sign large files is not recommended (use ed25519ph instead)")
+		s.distWarning("ED25519_UNHASHED", "using ed25519 to sign large files is not recommended (use ed25519ph instead)")
 
-		b, err := ioutil.ReadAll(file)
+		// Ed25519 reads the message twice, so it can't be streamed
+		b, unmap, err := mapFile(file)
 		if err != nil {
-			return "", err
+			return "", fmt.Errorf("file could not be signed (%s)", err)
 		}
+		defer unmap()
 
 		sig, err = signingKey.Sign(nil, b, &ed25519.Options{})
 		if err != nil {
 			return "", err
 		}
 	default:
-		return "", fmt.Errorf(`signing algorithm "%s" is not supported`, distOpts.signingAlgorithm)
+		return "", fmt.Errorf(`signing algorithm "%s" is not supported`, algorithm)
 	}
 
 	return base64.RawStdEncoding.EncodeToString(sig), nil
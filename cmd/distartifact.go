@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// distArtifact is a file to publish as a release, along with the options which
+// may differ between the artifacts of a multi-artifact release.
+type distArtifact struct {
+	path           string
+	filename       string
+	filetype       string
+	contentType    string
+	platform       string
+	signature      string
+	checksum       string
+	signingKeyPath string
+	signingKey     string
+	symbols        string
+	metadata       map[string]string
+}
+
+// newDistArtifact returns an artifact for the path using the command's options.
+func newDistArtifact(opts *CommandOptions, path string) *distArtifact {
+	return &distArtifact{
+		path:           path,
+		filename:       opts.filename,
+		filetype:       opts.filetype,
+		contentType:    opts.contentType,
+		platform:       opts.platform,
+		signature:      opts.signature,
+		checksum:       opts.checksum,
+		signingKeyPath: opts.signingKeyPath,
+		signingKey:     opts.signingKey,
+		symbols:        opts.symbols,
+	}
+}
+
+// parseDistArtifact parses an --artifact spec, formatted as a path followed by
+// comma seperated overrides, e.g. "build/App.exe,platform=windows/amd64".
+func parseDistArtifact(opts *CommandOptions, spec string) (*distArtifact, error) {
+	parts := strings.Split(spec, ",")
+	if parts[0] == "" {
+		return nil, fmt.Errorf(`artifact "%s" is not acceptable (path is required)`, spec)
+	}
+
+	a := newDistArtifact(opts, parts[0])
+
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf(`artifact "%s" is not acceptable (option "%s" must be <key>=<value>)`, spec, part)
+		}
+
+		if k := kv[0]; strings.HasPrefix(k, "metadata.") && len(k) > len("metadata.") {
+			if a.metadata == nil {
+				a.metadata = map[string]string{}
+			}
+
+			a.metadata[strings.TrimPrefix(k, "metadata.")] = kv[1]
+
+			continue
+		}
+
+		switch k, v := kv[0], kv[1]; k {
+		case "filename":
+			a.filename = v
+		case "filetype":
+			a.filetype = v
+		case "content-type":
+			a.contentType = v
+		case "platform":
+			a.platform = v
+		case "signature":
+			a.signature = v
+		case "checksum":
+			a.checksum = v
+		case "signing-key":
+			// A per-artifact key replaces the global key entirely, including one
+			// given by $KEYGEN_SIGNING_KEY
+			a.signingKeyPath = v
+			a.signingKey = ""
+		case "symbols":
+			a.symbols = v
+		default:
+			return nil, fmt.Errorf(`artifact "%s" is not acceptable (unknown option "%s")`, spec, k)
+		}
+	}
+
+	return a, nil
+}
+
+// uploadFilename returns the filename the artifact is published as, including
+// the extension added by --compress.
+func (a *distArtifact) uploadFilename(compress string) string {
+	filename := a.filename
+	if filename == "" {
+		filename = filepath.Base(a.path)
+	}
+
+	if c, ok := compressionAlgorithms[compress]; ok && !strings.HasSuffix(filename, "."+c.ext) {
+		filename += "." + c.ext
+	}
+
+	return filename
+}
+
+// checkArtifactFlags ensures flags which only make sense for a single artifact
+// aren't applied to every artifact of a multi-artifact release, e.g. one
+// artifact's signature published for every platform. Each --artifact can be
+// given its own instead.
+func checkArtifactFlags(opts *CommandOptions, artifacts []*distArtifact) error {
+	if len(artifacts) < 2 {
+		return nil
+	}
+
+	flags := map[string]string{"signature": opts.signature, "checksum": opts.checksum, "symbols": opts.symbols}
+
+	for _, name := range []string{"signature", "checksum", "symbols"} {
+		if flags[name] != "" {
+			return fmt.Errorf(`flag "--%s" cannot be used with more than one artifact (use %s=<value> with each --artifact)`, name, name)
+		}
+	}
+
+	return nil
+}
+
+// checkArtifactCollisions ensures the artifacts of a multi-artifact release
+// have unique filenames, since releases are upserted by filename and one
+// artifact would otherwise replace another.
+func checkArtifactCollisions(artifacts []*distArtifact, compress string) error {
+	if len(artifacts) < 2 {
+		return nil
+	}
+
+	filenames := []string{}
+	paths := map[string][]string{}
+
+	for _, a := range artifacts {
+		filename := a.uploadFilename(compress)
+		if _, ok := paths[filename]; !ok {
+			filenames = append(filenames, filename)
+		}
+
+		paths[filename] = append(paths[filename], a.path)
+	}
+
+	conflicts := []string{}
+	for _, filename := range filenames {
+		if p := paths[filename]; len(p) > 1 {
+			conflicts = append(conflicts, fmt.Sprintf(`artifact filename "%s" is not unique (used by %s, rename with filename=<name>)`, filename, strings.Join(p, ", ")))
+		}
+	}
+
+	if len(conflicts) != 0 {
+		return errors.New(strings.Join(conflicts, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/keygen-sh/keygen-cli/keygenext"
+)
+
+// checkDistribution inspects the distribution strategy of every product being
+// published to, since a misconfigured strategy doesn't fail the publish but
+// breaks the rollout: releases of an OPEN product can be downloaded by anyone,
+// so they should be signed, while no client can download releases of a CLOSED
+// product at all. With --require-distribution, any other strategy is refused,
+// as is a product whose strategy can't be inspected.
+func checkDistribution(opts *CommandOptions, artifacts []*distArtifact) error {
+	products := opts.products
+	if len(products) == 0 {
+		products = []string{opts.productID}
+	}
+
+	required := strings.ToUpper(opts.distribution)
+
+	for _, id := range products {
+		product, err := opts.client.GetProduct(opts.ctx, id)
+		if err != nil {
+			if _, ok := err.(*keygenext.APIError); ok {
+				return fmt.Errorf(`product "%s" could not be inspected (%w)`, id, formatAPIError(err))
+			}
+
+			// A required strategy can't be left unchecked, but otherwise leave it
+			// to the publish to fail (or queue) when unreachable
+			if required != "" {
+				return fmt.Errorf(`product "%s" could not be inspected (%w)`, id, err)
+			}
+
+			return nil
+		}
+
+		strategy := product.DistributionStrategy
+
+		if required != "" && strategy != required {
+			return fmt.Errorf(`product "%s" is not acceptable (its distribution strategy is %s, but --require-distribution is %s)`, id, strategy, required)
+		}
+
+		switch strategy {
+		case "OPEN":
+			if unsigned := unsignedArtifacts(artifacts); len(unsigned) != 0 {
+				opts.distWarning("OPEN_UNSIGNED", fmt.Sprintf(`product "%s" is OPEN, so anyone can download its releases, but these are unsigned: %s`, id, strings.Join(unsigned, ", ")))
+			}
+		case "CLOSED":
+			opts.distWarning("CLOSED_PRODUCT", fmt.Sprintf(`product "%s" is CLOSED, so no client can download the releases published to it`, id))
+		}
+	}
+
+	return nil
+}
+
+// unsignedArtifacts returns the paths of the artifacts which have neither a
+// signature nor a signing key.
+func unsignedArtifacts(artifacts []*distArtifact) []string {
+	unsigned := []string{}
+	for _, a := range artifacts {
+		if a.signature == "" && a.signingKeyPath == "" && a.signingKey == "" {
+			unsigned = append(unsigned, a.path)
+		}
+	}
+
+	return unsigned
+}
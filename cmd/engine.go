@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// distEngine is a distribution engine, i.e. how clients install and upgrade a
+// release's artifacts, along with what it requires of them.
+type distEngine struct {
+	// packaged engines require the release to belong to a package.
+	packaged bool
+
+	// platformed engines require a platform which includes its arch.
+	platformed bool
+
+	// signed engines require a pre-calculated signature, since their clients
+	// verify artifacts using their own signing scheme.
+	signed bool
+
+	// filetypes are the acceptable filetypes per OS, where "" applies to every
+	// OS. Engines without filetypes accept any filetype.
+	filetypes map[string][]string
+}
+
+// distEngines are the supported --engine values.
+var distEngines = map[string]*distEngine{
+	"raw": {},
+	"pypi": {
+		packaged:  true,
+		filetypes: map[string][]string{"": {"whl", "tar.gz"}},
+	},
+	"tauri": {
+		packaged:   true,
+		platformed: true,
+		signed:     true,
+		filetypes: map[string][]string{
+			"darwin":  {"tar.gz"},
+			"linux":   {"tar.gz", "appimage"},
+			"windows": {"zip", "msi", "exe"},
+		},
+	},
+	"electron": {
+		platformed: true,
+		filetypes: map[string][]string{
+			"darwin":  {"zip", "dmg"},
+			"linux":   {"appimage", "deb", "rpm"},
+			"windows": {"exe", "nupkg"},
+		},
+	},
+	"oci": {
+		packaged:  true,
+		filetypes: map[string][]string{"": {"tar"}},
+	},
+}
+
+// distEngineNames returns the supported engines, sorted.
+func distEngineNames() []string {
+	names := []string{}
+	for name := range distEngines {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// validateEngine checks the --engine and --package flags before anything is
+// published, resolving the engine from the package when it isn't given.
+func validateEngine(opts *CommandOptions) error {
+	if opts.engine != "" {
+		if _, ok := distEngines[opts.engine]; !ok {
+			return fmt.Errorf(`engine "%s" is not supported (must be one of: %s)`, opts.engine, strings.Join(distEngineNames(), ", "))
+		}
+	}
+
+	if opts.pkg == "" {
+		if e := distEngines[opts.engine]; e != nil && e.packaged {
+			return fmt.Errorf(`engine "%s" requires a package (use --package <id>)`, opts.engine)
+		}
+
+		return nil
+	}
+
+	// Packages can't be looked up offline, so they're checked when the bundle
+	// is published
+	if opts.prepareOnly {
+		return nil
+	}
+
+	pkg, err := opts.client.GetPackage(opts.ctx, opts.pkg)
+	if err != nil {
+		return fmt.Errorf(`package "%s" is not found (%w)`, opts.pkg, formatAPIError(err))
+	}
+
+	if pkg.ProductID != "" && pkg.ProductID != opts.productID {
+		return fmt.Errorf(`package "%s" is not acceptable (it belongs to product %s)`, opts.pkg, pkg.ProductID)
+	}
+
+	engine := "raw"
+	if pkg.Engine != nil && *pkg.Engine != "" {
+		engine = strings.ToLower(*pkg.Engine)
+	}
+
+	switch {
+	case opts.engine == "":
+		if _, ok := distEngines[engine]; ok {
+			opts.engine = engine
+		}
+	case opts.engine != engine:
+		return fmt.Errorf(`package "%s" is not acceptable (its engine is %s, not %s)`, opts.pkg, engine, opts.engine)
+	}
+
+	opts.pkg = pkg.ID
+
+	return nil
+}
+
+// checkEngineArtifact validates an artifact against what its engine requires,
+// rather than leaving it to the server to reject.
+func checkEngineArtifact(engine string, platform string, filetype string, signature string) error {
+	e, ok := distEngines[engine]
+	if !ok {
+		return nil
+	}
+
+	goos := ""
+	if platform != "" {
+		parts := strings.SplitN(platform, "/", 2)
+		goos = strings.ToLower(parts[0])
+		if alias, ok := platformOSAliases[goos]; ok {
+			goos = alias
+		}
+
+		if e.platformed && (len(parts) != 2 || parts[1] == "") {
+			return fmt.Errorf(`platform "%s" is not acceptable (the %s engine requires an arch, e.g. %s/amd64)`, platform, engine, goos)
+		}
+	}
+
+	if e.platformed && platform == "" {
+		return fmt.Errorf(`platform is required by the %s engine (e.g. --platform darwin/arm64)`, engine)
+	}
+
+	if e.signed && signature == "" {
+		return fmt.Errorf(`signature is required by the %s engine (use --signature with the signature from its own signing tool)`, engine)
+	}
+
+	if len(e.filetypes) == 0 {
+		return nil
+	}
+
+	filetypes, ok := e.filetypes[""]
+	if !ok {
+		filetypes, ok = e.filetypes[goos]
+		if !ok {
+			return fmt.Errorf(`platform "%s" is not acceptable (the %s engine supports darwin, linux and windows)`, platform, engine)
+		}
+	}
+
+	for _, t := range filetypes {
+		if strings.EqualFold(t, filetype) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(`filetype "%s" is not acceptable (the %s engine requires one of: %s)`, filetype, engine, strings.Join(filetypes, ", "))
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envAnnotation annotates a flag with the environment variables it falls back
+// to, in order of precedence.
+const envAnnotation = "keygen_env"
+
+// bindEnv makes a flag fall back to the environment variables envs.
+func bindEnv(flags *pflag.FlagSet, name string, envs ...string) {
+	flags.SetAnnotation(name, envAnnotation, envs)
+}
+
+// applyEnv uses the environment as defaults for the command's flags. It's
+// applied once the command's flags are parsed, rather than when the command
+// is built, so that flags take precedence over environment variables, which
+// take precedence over the config file.
+func applyEnv(cmd *cobra.Command) error {
+	var err error
+
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed {
+			return
+		}
+
+		for _, env := range f.Annotations[envAnnotation] {
+			v := os.Getenv(env)
+			if v == "" {
+				continue
+			}
+
+			if e := setFlagDefault(f, v); e != nil {
+				err = fmt.Errorf(`environment variable "%s" is not acceptable (%s)`, env, e)
+			}
+
+			return
+		}
+	})
+
+	return err
+}
+
+// loadEnvFile sets environment variables from a dotenv file, i.e. KEY=VALUE
+// lines, optionally quoted or prefixed with export, and # comments. Variables
+// which are already set take precedence over the file. Like the rest of the
+// environment, loaded variables apply to the whole process, so that they're
+// also seen by the tools we run, e.g. codesign or gh.
+func loadEnvFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf(`env file "%s" is not readable (%s)`, path, err.(*os.PathError).Err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		i := strings.Index(line, "=")
+		if i < 1 {
+			return fmt.Errorf(`env file "%s" is not valid (line %d is not KEY=VALUE)`, path, n)
+		}
+
+		key := strings.TrimSpace(line[:i])
+		value, err := parseEnvValue(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return fmt.Errorf(`env file "%s" is not valid (line %d %s)`, path, n, err)
+		}
+
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+
+		os.Setenv(key, value)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf(`env file "%s" is not readable (%s)`, path, err)
+	}
+
+	return nil
+}
+
+// parseEnvValue unquotes a dotenv value, e.g. "a b" or 'a b', and strips
+// trailing comments from unquoted values.
+func parseEnvValue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	switch q := value[0]; q {
+	case '"':
+		end := strings.LastIndexByte(value, '"')
+		if end == 0 {
+			return "", errors.New("has an unterminated quote")
+		}
+
+		v, err := strconv.Unquote(value[:end+1])
+		if err != nil {
+			return "", errors.New("has an invalid escape sequence")
+		}
+
+		return v, nil
+	case '\'':
+		end := strings.LastIndexByte(value, '\'')
+		if end == 0 {
+			return "", errors.New("has an unterminated quote")
+		}
+
+		return value[1:end], nil
+	}
+
+	if i := strings.Index(value, " #"); i != -1 {
+		value = strings.TrimSpace(value[:i])
+	}
+
+	return value, nil
+}
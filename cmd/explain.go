@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// displayedAPIError is an API error formatted for display by formatAPIError,
+// which keeps the original error so that it can be explained by
+// explainAPIError once the command fails.
+type displayedAPIError struct {
+	message string
+	err     *keygenext.APIError
+}
+
+func (e *displayedAPIError) Error() string {
+	return e.message
+}
+
+func (e *displayedAPIError) Unwrap() error {
+	return e.err
+}
+
+// adminTokenAnnotation marks commands which need an admin token rather than a
+// product token, e.g. to manage the account, so that a rejected token is
+// explained accordingly.
+const adminTokenAnnotation = "keygen_admin_token"
+
+// apiErrorExplanation is what an API error most likely means, since the API
+// describes what was refused rather than what was misconfigured.
+type apiErrorExplanation struct {
+	summary string
+	causes  []string
+	fixes   []string
+}
+
+// explainAPIError explains common API errors, i.e. rejected tokens, missing
+// accounts and products, and missing permissions, along with their likely
+// causes and fixes for the command which failed. Other errors aren't
+// explained.
+func explainAPIError(e *keygenext.APIError, cmd *cobra.Command) string {
+	var x *apiErrorExplanation
+
+	admin := cmd.Annotations[adminTokenAnnotation] != ""
+
+	detail := strings.ToLower(e.Title + " " + e.Detail + " " + e.Source)
+
+	switch {
+	case (strings.HasPrefix(e.Code, "TOKEN_") || e.Status == http.StatusUnauthorized) && admin:
+		x = &apiErrorExplanation{
+			summary: "the API did not accept the token",
+			causes: []string{
+				"the token was revoked, has expired, or was copied incompletely, e.g. with surrounding whitespace",
+				"the token belongs to another account than " + envHint("--account", "KEYGEN_ACCOUNT_ID"),
+				"the token belongs to another Keygen instance than " + envHint("--host", "KEYGEN_HOST"),
+			},
+			fixes: []string{
+				"check " + envHint("--token", "KEYGEN_PRODUCT_TOKEN") + ", or generate a new admin token in the dashboard",
+			},
+		}
+	case strings.HasPrefix(e.Code, "TOKEN_") || e.Status == http.StatusUnauthorized:
+		x = &apiErrorExplanation{
+			summary: "the API did not accept the token",
+			causes: []string{
+				"the token was revoked, has expired, or was copied incompletely, e.g. with surrounding whitespace",
+				"the token belongs to another account than " + envHint("--account", "KEYGEN_ACCOUNT_ID"),
+				"the token belongs to another Keygen instance than " + envHint("--host", "KEYGEN_HOST"),
+			},
+			fixes: []string{
+				"check " + envHint("--token", "KEYGEN_PRODUCT_TOKEN") + ", or generate a new product token in the dashboard",
+			},
+		}
+	case e.Status == http.StatusNotFound && strings.Contains(detail, "account"):
+		x = &apiErrorExplanation{
+			summary: "the account does not exist",
+			causes: []string{
+				envHint("--account", "KEYGEN_ACCOUNT_ID") + " is mistyped, or is the ID of another resource",
+				"the account is on another Keygen instance than " + envHint("--host", "KEYGEN_HOST"),
+			},
+			fixes: []string{
+				"copy the account ID from the dashboard's settings, or use the account's slug",
+			},
+		}
+	case e.Code == "NOT_FOUND" || e.Status == http.StatusNotFound:
+		x = &apiErrorExplanation{
+			summary: "the resource does not exist, or the token can't see it",
+			causes: []string{
+				envHint("--product", "KEYGEN_PRODUCT_ID") + " is mistyped, or belongs to another account",
+				"a product token only sees its own product's releases, licenses and machines",
+				"the resource was deleted, e.g. by a concurrent pipeline",
+			},
+			fixes: []string{
+				"check that " + envHint("--account", "KEYGEN_ACCOUNT_ID") + " and " + envHint("--product", "KEYGEN_PRODUCT_ID") + " match the token's product",
+			},
+		}
+	case e.Status == http.StatusForbidden && strings.Contains(detail, "constraint"):
+		x = &apiErrorExplanation{
+			summary: "the token is not permitted to attach entitlement constraints",
+			causes: []string{
+				"the token is missing the permissions: " + strings.Join(constraintPermissions, ", "),
+			},
+			fixes: []string{
+				"add the permissions to the token, or publish without --entitlements and --constraints-file",
+			},
+		}
+	case (e.Code == "PERMISSION_DENIED" || e.Code == "FORBIDDEN" || e.Status == http.StatusForbidden) && admin:
+		x = &apiErrorExplanation{
+			summary: "the token is not permitted to do this",
+			causes: []string{
+				"\"" + cmd.CommandPath() + "\" manages the whole account, so it needs an admin token",
+				"the token is a product, license or user token, rather than an admin token",
+			},
+			fixes: []string{
+				"use an admin token, e.g. with " + envHint("--token", "KEYGEN_PRODUCT_TOKEN"),
+			},
+		}
+	case e.Code == "PERMISSION_DENIED" || e.Code == "FORBIDDEN" || e.Status == http.StatusForbidden:
+		x = &apiErrorExplanation{
+			summary: "the token is not permitted to do this",
+			causes: []string{
+				"the token is for another product than " + envHint("--product", "KEYGEN_PRODUCT_ID"),
+				"the token is a license or user token, rather than a product token",
+				"the token is missing permissions, e.g. to publish it needs: " + strings.Join(publishPermissions, ", "),
+			},
+			fixes: []string{
+				"use a product token for the product, e.g. with " + envHint("--token", "KEYGEN_PRODUCT_TOKEN"),
+			},
+		}
+	default:
+		return ""
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	lines := []string{italic("hint:") + " " + x.summary, "  likely causes:"}
+	for _, c := range x.causes {
+		lines = append(lines, "    - "+c)
+	}
+
+	lines = append(lines, "  try:")
+	for _, f := range x.fixes {
+		lines = append(lines, "    - "+f)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// envHint names a flag along with the environment variable it falls back to,
+// pointing out when the variable is set, since a stale value exported in a
+// shell or CI secret is the usual culprit.
+func envHint(flag string, env string) string {
+	if os.Getenv(env) != "" {
+		return flag + " ($" + env + " is set)"
+	}
+
+	return flag + " ($" + env + ")"
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+func TestExplainAPIError(t *testing.T) {
+	product := &cobra.Command{Use: "dist"}
+	admin := &cobra.Command{Use: "gc", Annotations: map[string]string{adminTokenAnnotation: "true"}}
+
+	tests := []struct {
+		name string
+		err  *keygenext.APIError
+		cmd  *cobra.Command
+		want []string
+	}{
+		{
+			name: "rejected product token",
+			err:  &keygenext.APIError{Status: http.StatusUnauthorized, Code: "TOKEN_INVALID"},
+			cmd:  product,
+			want: []string{"did not accept the token", "new product token"},
+		},
+		{
+			name: "rejected admin token",
+			err:  &keygenext.APIError{Status: http.StatusUnauthorized},
+			cmd:  admin,
+			want: []string{"did not accept the token", "new admin token"},
+		},
+		{
+			name: "missing account",
+			err:  &keygenext.APIError{Status: http.StatusNotFound, Detail: "The requested account was not found"},
+			cmd:  product,
+			want: []string{"the account does not exist"},
+		},
+		{
+			name: "missing resource",
+			err:  &keygenext.APIError{Status: http.StatusNotFound, Code: "NOT_FOUND", Detail: "The requested resource was not found"},
+			cmd:  product,
+			want: []string{"the resource does not exist", "--product"},
+		},
+		{
+			name: "missing constraint permissions",
+			err:  &keygenext.APIError{Status: http.StatusForbidden, Detail: "You do not have permission to create constraints"},
+			cmd:  product,
+			want: []string{"entitlement constraints", constraintPermissions[0]},
+		},
+		{
+			name: "forbidden admin command",
+			err:  &keygenext.APIError{Status: http.StatusForbidden, Code: "FORBIDDEN"},
+			cmd:  admin,
+			want: []string{"not permitted", `"gc" manages the whole account`},
+		},
+		{
+			name: "forbidden product command",
+			err:  &keygenext.APIError{Status: http.StatusForbidden, Code: "FORBIDDEN"},
+			cmd:  product,
+			want: []string{"not permitted", publishPermissions[0]},
+		},
+		{
+			name: "unexplained",
+			err:  &keygenext.APIError{Status: http.StatusUnprocessableEntity, Code: "VERSION_INVALID"},
+			cmd:  product,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := explainAPIError(tt.err, tt.cmd)
+
+			if len(tt.want) == 0 {
+				if got != "" {
+					t.Errorf("explainAPIError() = %q, want no explanation", got)
+				}
+
+				return
+			}
+
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("explainAPIError() = %q, want it to contain %q", got, w)
+				}
+			}
+		})
+	}
+}
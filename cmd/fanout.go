@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+)
+
+// distFanOut publishes the artifacts to each of --products in turn. Since
+// ed25519ph signatures are bound to the product, every artifact is signed
+// again for each product. Products which fail don't stop the rest, and a
+// summary of every product's result is printed once they're all done. Under
+// -o json, that's the only document printed, and it includes the output for
+// every product's releases.
+func distFanOut(opts *CommandOptions, artifacts []*distArtifact) error {
+	rows := [][]string{}
+	values := []map[string]interface{}{}
+	failed := 0
+
+	for _, product := range opts.products {
+		product = strings.TrimSpace(product)
+		if product == "" {
+			continue
+		}
+
+		// Every product has its own public keys and releases
+		opts.productID = product
+		opts.publicKeys = nil
+		opts.published = []*keygenext.Release{}
+		opts.collected = []interface{}{}
+
+		if opts.output != "json" {
+			faint := color.New(color.Faint).SprintFunc()
+
+			fmt.Println(faint("publishing to product " + product))
+		}
+
+		err := distPublishAll(opts, artifacts)
+
+		ids := []string{}
+		for _, r := range opts.published {
+			ids = append(ids, r.ID)
+		}
+
+		status, message := "published", ""
+		if err != nil {
+			failed++
+
+			status, message = "failed", err.Error()
+		}
+
+		rows = append(rows, []string{product, status, strings.Join(ids, ","), message})
+		values = append(values, map[string]interface{}{
+			"product":  product,
+			"status":   status,
+			"releases": opts.collected,
+			"error":    message,
+		})
+	}
+
+	opts.collected = nil
+
+	if opts.output == "json" {
+		if err := printJSON(map[string]interface{}{"products": values}); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println()
+
+		if err := render("table", rendering{headers: []string{"PRODUCT", "STATUS", "RELEASES", "ERROR"}, rows: rows}); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("release could not be published to %d of %d products", failed, len(rows))
+	}
+
+	return nil
+}
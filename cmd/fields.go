@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// listFilter is a --filter expression, matching results whose field is (or,
+// when negated, isn't) equal to value.
+type listFilter struct {
+	field  string
+	value  string
+	negate bool
+}
+
+// parseListFilters parses field=value and field!=value expressions.
+func parseListFilters(exprs []string) ([]listFilter, error) {
+	filters := []listFilter{}
+
+	for _, expr := range exprs {
+		i := strings.Index(expr, "=")
+		if i < 1 {
+			return nil, fmt.Errorf(`filter "%s" is not acceptable (must be field=value or field!=value)`, expr)
+		}
+
+		f := listFilter{field: expr[:i], value: expr[i+1:]}
+		if strings.HasSuffix(f.field, "!") {
+			f.field, f.negate = strings.TrimSuffix(f.field, "!"), true
+		}
+
+		if f.field == "" {
+			return nil, fmt.Errorf(`filter "%s" is not acceptable (must be field=value or field!=value)`, expr)
+		}
+
+		filters = append(filters, f)
+	}
+
+	return filters, nil
+}
+
+// serverFilter returns the value of an equality filter on field, so that it
+// can be sent to the API when the API supports filtering by field. Results
+// are still filtered client-side.
+func serverFilter(opts *CommandOptions, field string) string {
+	filters, _ := parseListFilters(opts.filters)
+
+	for _, f := range filters {
+		if f.field == field && !f.negate {
+			return f.value
+		}
+	}
+
+	return ""
+}
+
+// selectList applies the --filter and --fields flags to a list rendering,
+// whose value is a slice of results and whose rows correspond to them. When
+// fields are selected, the table is rebuilt from the selected fields.
+func selectList(opts *CommandOptions, r rendering) (rendering, error) {
+	if len(opts.filters) == 0 && len(opts.fields) == 0 {
+		return r, nil
+	}
+
+	v, err := plainValue(r.value)
+	if err != nil {
+		return r, err
+	}
+
+	items, _ := v.([]interface{})
+	filters, err := parseListFilters(opts.filters)
+	if err != nil {
+		return r, err
+	}
+
+	selected := []interface{}{}
+	rows := [][]string{}
+
+	for i, item := range items {
+		match := true
+		for _, f := range filters {
+			if (formatField(lookupField(item, f.field)) == f.value) == f.negate {
+				match = false
+
+				break
+			}
+		}
+
+		if !match {
+			continue
+		}
+
+		selected = append(selected, item)
+		if i < len(r.rows) {
+			rows = append(rows, r.rows[i])
+		}
+	}
+
+	if len(opts.fields) == 0 {
+		return rendering{value: selected, headers: r.headers, rows: rows, wide: r.wide}, nil
+	}
+
+	// Catch typos, but allow nested fields such as metadata keys to be absent
+	for _, field := range opts.fields {
+		if len(items) == 0 || strings.Contains(field, ".") {
+			continue
+		}
+
+		if _, ok := items[0].(map[string]interface{})[field]; !ok {
+			return r, fmt.Errorf(`field "%s" is not supported`, field)
+		}
+	}
+
+	headers := []string{}
+	for _, field := range opts.fields {
+		headers = append(headers, strings.ToUpper(strings.ReplaceAll(field, "_", " ")))
+	}
+
+	value := []interface{}{}
+	rows = [][]string{}
+
+	for _, item := range selected {
+		obj := map[string]interface{}{}
+		row := []string{}
+
+		for _, field := range opts.fields {
+			v := lookupField(item, field)
+
+			obj[field] = v
+			row = append(row, formatField(v))
+		}
+
+		value = append(value, obj)
+		rows = append(rows, row)
+	}
+
+	return rendering{value: value, headers: headers, rows: rows}, nil
+}
+
+// lookupField returns a result's field, where nested fields are separated by
+// dots, e.g. metadata.commit.
+func lookupField(item interface{}, field string) interface{} {
+	for _, key := range strings.Split(field, ".") {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		item = obj[key]
+	}
+
+	return item
+}
+
+// formatField formats a plain field value for display and comparison.
+func formatField(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		b, _ := json.Marshal(v)
+
+		return string(b)
+	}
+}
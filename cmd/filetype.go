@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// filetypeAliases are extensions which are equivalent to a detected filetype.
+var filetypeAliases = map[string][]string{
+	"tar.gz": {"tgz", "gz"},
+	"zip":    {"jar", "war", "ear", "apk", "aab", "ipa", "nupkg", "whl", "vsix", "xpi", "crx", "appx", "msix", "epub"},
+	"msi":    {"msp", "msm"},
+	"exe":    {"dll", "sys", "scr", "efi"},
+}
+
+// filetypeContentTypes are MIME types for filetypes which are missing from, or
+// commonly wrong in, the system's MIME database.
+var filetypeContentTypes = map[string]string{
+	"appimage": "application/vnd.appimage",
+	"deb":      "application/vnd.debian.binary-package",
+	"dmg":      "application/x-apple-diskimage",
+	"exe":      "application/vnd.microsoft.portable-executable",
+	"gz":       "application/gzip",
+	"msi":      "application/x-msi",
+	"pkg":      "application/vnd.apple.installer+xml",
+	"rpm":      "application/x-rpm",
+	"tar":      "application/x-tar",
+	"tar.gz":   "application/gzip",
+	"tgz":      "application/gzip",
+	"zip":      "application/zip",
+	"zst":      "application/zstd",
+}
+
+// contentTypeForFiletype returns the MIME type an artifact should be served
+// with, falling back to application/octet-stream.
+func contentTypeForFiletype(filetype string) string {
+	filetype = strings.ToLower(filetype)
+
+	if t, ok := filetypeContentTypes[filetype]; ok {
+		return t
+	}
+
+	if t := mime.TypeByExtension("." + filetype); t != "" {
+		return t
+	}
+
+	return "application/octet-stream"
+}
+
+// detectFiletype detects a file's type from its content, falling back to its
+// extension. When both are known but disagree, the type implied by the
+// extension is also returned so that it can be reported.
+func detectFiletype(file *os.File, filename string) (string, string, error) {
+	ext := filetypeFromExtension(filename)
+
+	sniffed, err := sniffFiletype(file)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch {
+	case sniffed == "":
+		return ext, "", nil
+	case ext == sniffed:
+		return ext, "", nil
+	}
+
+	for _, alias := range filetypeAliases[sniffed] {
+		if ext == alias {
+			return ext, "", nil
+		}
+	}
+
+	if ext == "bin" {
+		return sniffed, "", nil
+	}
+
+	return sniffed, ext, nil
+}
+
+// filetypeFromExtension returns the filetype for a filename's extension, e.g.
+// "zip" for "App-1-0-0.zip", or "bin" when it has none.
+func filetypeFromExtension(filename string) string {
+	name := strings.ToLower(filename)
+	if strings.HasSuffix(name, ".tar.gz") {
+		return "tar.gz"
+	}
+
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	if _, e := strconv.Atoi(ext); e == nil || ext == "" {
+		return "bin"
+	}
+
+	return ext
+}
+
+// sniffFiletype detects a file's type using its magic bytes, or returns an
+// empty string if the type is unknown.
+func sniffFiletype(file *os.File) (string, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	size := info.Size()
+
+	head := make([]byte, 512)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, []byte("!<arch>\ndebian-binary")):
+		return "deb", nil
+	case bytes.HasPrefix(head, []byte{0xed, 0xab, 0xee, 0xdb}):
+		return "rpm", nil
+	case bytes.HasPrefix(head, []byte{0xd0, 0xcf, 0x11, 0xe0, 0xa1, 0xb1, 0x1a, 0xe1}):
+		return "msi", nil
+	case bytes.HasPrefix(head, []byte("MZ")):
+		return "exe", nil
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return "zip", nil
+	case bytes.HasPrefix(head, []byte("\x7fELF")) && len(head) > 10 && bytes.Equal(head[8:11], []byte("AI\x02")):
+		return "appimage", nil
+	case bytes.HasPrefix(head, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return "zst", nil
+	case isTar(head):
+		return "tar", nil
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		// Peek into the stream to tell tarballs apart from other gzipped files
+		r, err := gzip.NewReader(io.NewSectionReader(file, 0, size))
+		if err != nil {
+			return "gz", nil
+		}
+		defer r.Close()
+
+		inner := make([]byte, 512)
+		n, _ := io.ReadFull(r, inner)
+		if isTar(inner[:n]) {
+			return "tar.gz", nil
+		}
+
+		return "gz", nil
+	}
+
+	// Disk images have a "koly" trailer in their last 512 bytes
+	if size >= 512 {
+		tail := make([]byte, 4)
+		if _, err := file.ReadAt(tail, size-512); err != nil && err != io.EOF {
+			return "", err
+		}
+
+		if bytes.Equal(tail, []byte("koly")) {
+			return "dmg", nil
+		}
+	}
+
+	return "", nil
+}
+
+// isTar reports whether a header block belongs to a tar archive.
+func isTar(head []byte) bool {
+	return len(head) >= 262 && bytes.Equal(head[257:262], []byte("ustar"))
+}
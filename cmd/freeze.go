@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// Publish freezes are recorded in the account's metadata, so that every
+// pipeline publishing to the account honors them. dist refuses to publish to
+// the stable channel until the freeze ends, unless given --override-freeze.
+const (
+	freezeUntilMetadataKey  = "freezeUntil"
+	freezeReasonMetadataKey = "freezeReason"
+)
+
+func newFreezeCmd(s *session) *cobra.Command {
+	enableOpts := s.newOptions()
+	enableCmd := &cobra.Command{
+		Use:   "enable",
+		Short: "freeze publishing to the stable channel until a date",
+		Example: `  keygen freeze enable --until 2024-12-26 --reason 'holiday change freeze' \
+      --account '1fddcec8-8dd3-4d8d-9b16-215cac0f9b52' \
+      --token 'admin-xxx'
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return freezeEnableRun(enableOpts)
+		},
+		Annotations: map[string]string{adminTokenAnnotation: "true"},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(enableCmd, s)
+
+	enableCmd.Flags().StringVar(&enableOpts.until, "until", "", "when the freeze ends, as a date, e.g. 2024-12-26 (the start of the day in UTC), or an RFC3339 timestamp (required)")
+	enableCmd.Flags().StringVar(&enableOpts.reason, "reason", "", "why publishing is frozen, shown when dist refuses to publish")
+
+	enableCmd.MarkFlagRequired("until")
+
+	disableOpts := s.newOptions()
+	disableCmd := &cobra.Command{
+		Use:   "disable",
+		Short: "end a publish freeze early",
+		Example: `  keygen freeze disable \
+      --account '1fddcec8-8dd3-4d8d-9b16-215cac0f9b52' \
+      --token 'admin-xxx'
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return freezeDisableRun(disableOpts)
+		},
+		Annotations: map[string]string{adminTokenAnnotation: "true"},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(disableCmd, s)
+
+	statusOpts := s.newOptions()
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "show whether publishing is frozen",
+		Example: `  keygen freeze status --output json
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return freezeStatusRun(statusOpts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(statusCmd, s)
+
+	statusCmd.Flags().StringVarP(&statusOpts.output, "output", "o", "text", "output format, one of: text, json, yaml, go-template=<template>")
+
+	cmd := &cobra.Command{
+		Use:   "freeze",
+		Short: "freeze publishing to the stable channel, e.g. for a holiday change freeze",
+	}
+
+	cmd.AddCommand(enableCmd, disableCmd, statusCmd)
+
+	return cmd
+}
+
+// parseFreezeUntil parses when a freeze ends, given as a date or an RFC3339
+// timestamp.
+func parseFreezeUntil(v string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			if !t.After(time.Now()) {
+				return time.Time{}, fmt.Errorf(`freeze end "%s" is not acceptable (must be in the future)`, v)
+			}
+
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf(`freeze end "%s" is not acceptable (must be a date, e.g. 2024-12-26, or an RFC3339 timestamp)`, v)
+}
+
+// activeFreeze returns when the account's publish freeze ends and why it's
+// frozen, if it's frozen.
+func activeFreeze(account *keygenext.Account) (time.Time, string, bool) {
+	v, _ := account.Metadata[freezeUntilMetadataKey].(string)
+
+	until, err := time.Parse(time.RFC3339, v)
+	if err != nil || !until.After(time.Now()) {
+		return time.Time{}, "", false
+	}
+
+	reason, _ := account.Metadata[freezeReasonMetadataKey].(string)
+
+	return until, reason, true
+}
+
+func freezeEnableRun(opts *CommandOptions) error {
+	until, err := parseFreezeUntil(opts.until)
+	if err != nil {
+		return err
+	}
+
+	account, err := opts.client.GetAccount(opts.ctx)
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	metadata := map[string]interface{}{}
+	for k, v := range account.Metadata {
+		metadata[k] = v
+	}
+
+	metadata[freezeUntilMetadataKey] = until.Format(time.RFC3339)
+	if opts.reason != "" {
+		metadata[freezeReasonMetadataKey] = opts.reason
+	} else {
+		delete(metadata, freezeReasonMetadataKey)
+	}
+
+	if err := opts.client.UpdateAccountMetadata(opts.ctx, account, metadata); err != nil {
+		return formatAPIError(err)
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("froze publishing to stable until " + italic(until.Format(time.RFC3339)))
+
+	return nil
+}
+
+func freezeDisableRun(opts *CommandOptions) error {
+	account, err := opts.client.GetAccount(opts.ctx)
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	until, _, ok := activeFreeze(account)
+	if !ok {
+		fmt.Println("publishing is not frozen")
+
+		return nil
+	}
+
+	// Ending a freeze early removes the guardrail for every pipeline
+	if err := opts.confirmAction("end the publish freeze", []string{"frozen until " + until.Format(time.RFC3339)}, ""); err != nil {
+		return err
+	}
+
+	metadata := map[string]interface{}{}
+	for k, v := range account.Metadata {
+		metadata[k] = v
+	}
+
+	delete(metadata, freezeUntilMetadataKey)
+	delete(metadata, freezeReasonMetadataKey)
+
+	if err := opts.client.UpdateAccountMetadata(opts.ctx, account, metadata); err != nil {
+		return formatAPIError(err)
+	}
+
+	fmt.Println("ended the publish freeze")
+
+	return nil
+}
+
+func freezeStatusRun(opts *CommandOptions) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	account, err := opts.client.GetAccount(opts.ctx)
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	until, reason, ok := activeFreeze(account)
+
+	if isStructuredOutput(opts.output) {
+		value := map[string]interface{}{"frozen": ok}
+		if ok {
+			value["until"] = until.Format(time.RFC3339)
+			value["reason"] = reason
+		}
+
+		return render(opts.output, rendering{value: value})
+	}
+
+	if !ok {
+		fmt.Println("publishing is not frozen")
+
+		return nil
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	line := "publishing to stable is frozen until " + italic(until.Format(time.RFC3339))
+	if reason != "" {
+		line += " (" + reason + ")"
+	}
+
+	fmt.Println(line)
+
+	return nil
+}
+
+// checkFreeze refuses to publish to the stable channel while the account's
+// publish freeze is active, unless given --override-freeze, in which case the
+// override is warned about so that it shows up in the pipeline's annotations.
+// An account which can't be read, e.g. using a token without access to it,
+// also refuses to publish unless overridden, since the freeze can't be ruled
+// out, other than by dist --queue while the API is unreachable. Each account is
+// only checked once per run.
+func checkFreeze(opts *CommandOptions, channel string) error {
+	if channel != "stable" || opts.unfrozen[opts.client.Account] {
+		return nil
+	}
+
+	account, err := opts.client.GetAccount(opts.ctx)
+	if err != nil {
+		// Queued releases are checked once they're flushed
+		if opts.queue && isNetworkError(err) {
+			return nil
+		}
+
+		if !opts.overrideFreeze {
+			return fmt.Errorf("publish freeze could not be checked (%w), use --override-freeze to publish anyway", formatAPIError(err))
+		}
+
+		opts.distWarning("FREEZE_UNCHECKED", fmt.Sprintf("publish freeze could not be checked (%s), but it was overridden by --override-freeze", formatAPIError(err)))
+		opts.markUnfrozen()
+
+		return nil
+	}
+
+	until, reason, ok := activeFreeze(account)
+	if !ok {
+		opts.markUnfrozen()
+
+		return nil
+	}
+
+	detail := "publishing to stable is frozen until " + until.Format(time.RFC3339)
+	if reason != "" {
+		detail += " (" + reason + ")"
+	}
+
+	if opts.overrideFreeze {
+		opts.distWarning("FREEZE_OVERRIDDEN", detail+", but it was overridden by --override-freeze")
+		opts.markUnfrozen()
+
+		return nil
+	}
+
+	return fmt.Errorf("%s (use --override-freeze to publish anyway)", detail)
+}
+
+// markUnfrozen records that the account may be published to, so that its
+// freeze isn't checked again.
+func (s *session) markUnfrozen() {
+	if s.unfrozen == nil {
+		s.unfrozen = map[string]bool{}
+	}
+
+	s.unfrozen[s.client.Account] = true
+}
@@ -12,35 +12,36 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var (
-	genkeyOpts = &CommandOptions{}
-	genkeyCmd  = &cobra.Command{
+func newGenkeyCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
 		Use:   "genkey",
 		Short: "generate an ed25519 key pair for code signing",
 		Args:  cobra.NoArgs,
-		RunE:  genkeyRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return genkeyRun(opts)
+		},
 
 		// Encountering an error should not display usage
 		SilenceUsage: true,
 	}
-)
 
-func init() {
-	genkeyCmd.Flags().StringVar(&genkeyOpts.signingKeyPath, "out", "keygen.key", "output the private publishing key to specified file")
-	genkeyCmd.Flags().StringVar(&genkeyOpts.verifyKeyPath, "pubout", "keygen.pub", "output the public upgrade key to specified file")
+	cmd.Flags().StringVar(&opts.signingKeyPath, "out", "keygen.key", "output the private publishing key to specified file")
+	cmd.Flags().StringVar(&opts.verifyKeyPath, "pubout", "keygen.pub", "output the public upgrade key to specified file")
 
-	rootCmd.AddCommand(genkeyCmd)
+	return cmd
 }
 
-func genkeyRun(cmd *cobra.Command, args []string) error {
-	signingKeyPath, err := homedir.Expand(genkeyOpts.signingKeyPath)
+func genkeyRun(opts *CommandOptions) error {
+	signingKeyPath, err := homedir.Expand(opts.signingKeyPath)
 	if err != nil {
-		return fmt.Errorf(`path "%s" is not expandable (%s)`, genkeyOpts.signingKeyPath, err)
+		return fmt.Errorf(`path "%s" is not expandable (%s)`, opts.signingKeyPath, err)
 	}
 
-	verifyKeyPath, err := homedir.Expand(genkeyOpts.verifyKeyPath)
+	verifyKeyPath, err := homedir.Expand(opts.verifyKeyPath)
 	if err != nil {
-		return fmt.Errorf(`path "%s" is not expandable (%s)`, genkeyOpts.verifyKeyPath, err)
+		return fmt.Errorf(`path "%s" is not expandable (%s)`, opts.verifyKeyPath, err)
 	}
 
 	if _, err := os.Stat(signingKeyPath); err == nil {
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// isGitHubActions reports whether the CLI is running in a GitHub Actions
+// workflow.
+func isGitHubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// writeGitHubOutput writes step outputs to $GITHUB_OUTPUT, so that later
+// steps can consume them, e.g. ${{ steps.<id>.outputs.release-id }}.
+func writeGitHubOutput(outputs map[string]string) error {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf(`github output "%s" is not writable (%s)`, path, err.(*os.PathError).Err)
+	}
+	defer f.Close()
+
+	keys := make([]string, 0, len(outputs))
+	for k := range outputs {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var b strings.Builder
+
+	for _, k := range keys {
+		v := outputs[k]
+		if !strings.ContainsAny(v, "\r\n") {
+			fmt.Fprintf(&b, "%s=%s\n", k, v)
+
+			continue
+		}
+
+		// Multiline values use a random heredoc delimiter which can't collide
+		// with the value itself
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+
+		delim := "ghadelimiter_" + hex.EncodeToString(buf)
+
+		fmt.Fprintf(&b, "%s<<%s\n%s\n%s\n", k, delim, v, delim)
+	}
+
+	if _, err := f.WriteString(b.String()); err != nil {
+		return fmt.Errorf(`github output "%s" is not writable (%s)`, path, err)
+	}
+
+	return nil
+}
+
+// printGitHubAnnotation prints a workflow command which annotates the run
+// with a message, where level is one of: notice, warning, error. It's printed
+// to stderr so that it doesn't interfere with --output json.
+func printGitHubAnnotation(level string, title string, message string) {
+	escape := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	escapeProperty := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+
+	fmt.Fprintf(os.Stderr, "::%s title=%s::%s\n", level, escapeProperty.Replace(title), escape.Replace(message))
+}
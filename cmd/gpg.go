@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/keygen-sh/keygen-cli/keygenext"
+)
+
+// gpgSign creates an armored detached signature for the file at path using
+// gpg, reading the key's passphrase from $KEYGEN_GPG_PASSPHRASE when set.
+func gpgSign(keyID string, path string) ([]byte, error) {
+	args := []string{"--batch", "--yes", "--local-user", keyID, "--armor", "--detach-sign", "--output", "-"}
+
+	var stdin io.Reader
+	if passphrase := os.Getenv("KEYGEN_GPG_PASSPHRASE"); passphrase != "" {
+		args = append(args, "--pinentry-mode", "loopback", "--passphrase-fd", "0")
+		stdin = strings.NewReader(passphrase + "\n")
+	}
+
+	args = append(args, path)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("gpg", args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gpg signing failed (%s)", strings.TrimSpace(stderr.String()+" "+err.Error()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// gpgFingerprint returns the fingerprint of the gpg key used for signing.
+func gpgFingerprint(keyID string) (string, error) {
+	out, err := exec.Command("gpg", "--batch", "--with-colons", "--fingerprint", keyID).Output()
+	if err != nil {
+		return "", fmt.Errorf(`gpg key "%s" is not available (%s)`, keyID, err)
+	}
+
+	// The first fpr record belongs to the primary key
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) > 9 && fields[0] == "fpr" {
+			return fields[9], nil
+		}
+	}
+
+	return "", fmt.Errorf(`gpg key "%s" has no fingerprint`, keyID)
+}
+
+// publishGPGSignature publishes an armored signature as a companion release
+// of the signed release, with an .asc filename and filetype. Like symbols, the
+// signature is created as a draft and yanked once uploaded, so it's never
+// offered to upgrading clients, but remains downloadable.
+func publishGPGSignature(opts *CommandOptions, release *keygenext.Release, signature []byte, fingerprint string) (*keygenext.Release, error) {
+	tmp, err := ioutil.TempFile("", "keygen-*.asc")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := tmp.Write(signature); err != nil {
+		return nil, err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	checksum, _, err := calculateChecksum(tmp, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	companion := &keygenext.Release{
+		Name:        release.Name,
+		Version:     release.Version,
+		Filename:    release.Filename + ".asc",
+		Filesize:    int64(len(signature)),
+		Filetype:    "asc",
+		Platform:    release.Platform,
+		Channel:     release.Channel,
+		Checksum:    checksum,
+		Status:      "DRAFT",
+		ContentType: "application/pgp-signature",
+		ProductID:   release.ProductID,
+		Constraints: release.Constraints,
+		Metadata: map[string]interface{}{
+			"gpgFingerprint": fingerprint,
+			"signatureFor":   release.Filename,
+		},
+	}
+
+	if _, err := publishRelease(opts, companion, tmp); err != nil {
+		return nil, err
+	}
+
+	if err := opts.client.YankRelease(opts.ctx, companion); err != nil {
+		return nil, formatAPIError(err)
+	}
+
+	return companion, nil
+}
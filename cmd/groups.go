@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// groupsSortFields are the fields groups can be sorted by.
+var groupsSortFields = []string{"name", "created", "updated"}
+
+func newGroupsCmd(s *session) *cobra.Command {
+	listOpts := s.newOptions()
+	listCmd := &cobra.Command{
+		Use:   "ls",
+		Short: "list groups",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return groupsListRun(listOpts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addListFlags(listCmd, listOpts, 10, groupsSortFields...)
+	listCmd.Flags().StringVarP(&listOpts.output, "output", "o", "table", renderOutputUsage)
+
+	createOpts := s.newOptions()
+	createCmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "create a group",
+		Example: `  keygen groups create 'Acme Corp' --max-users 25 --max-licenses 25
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: groupsNameArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return groupsCreateRun(createOpts, cmd, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	createCmd.Flags().IntVar(&createOpts.maxLicenses, "max-licenses", 0, "maximum number of licenses in the group (default unlimited)")
+	createCmd.Flags().IntVar(&createOpts.maxMachines, "max-machines", 0, "maximum number of machines in the group (default unlimited)")
+	createCmd.Flags().IntVar(&createOpts.maxUsers, "max-users", 0, "maximum number of users in the group (default unlimited)")
+	createCmd.Flags().StringVarP(&createOpts.output, "output", "o", "text", "output format, one of: text, json, yaml, go-template=<template>")
+
+	deleteOpts := s.newOptions()
+	deleteCmd := &cobra.Command{
+		Use:   "delete <id>",
+		Short: "delete a group",
+		Args:  groupsIDArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return groupsDeleteRun(deleteOpts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	attachOpts := s.newOptions()
+	attachCmd := &cobra.Command{
+		Use:   "attach <id>",
+		Short: "attach licenses and users to a group",
+		Example: `  keygen groups attach 8c2f3c8a-0b6b-4e8c-9b2a-6a3d0f8e1b2c \
+      --licenses <id>,<id> \
+      --users <id>,<id>
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: groupsIDArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return groupsAttachRun(attachOpts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	attachCmd.Flags().StringSliceVar(&attachOpts.licenses, "licenses", []string{}, "comma seperated list of license IDs to attach")
+	attachCmd.Flags().StringSliceVar(&attachOpts.users, "users", []string{}, "comma seperated list of user IDs to attach")
+
+	cmd := &cobra.Command{
+		Use:   "groups",
+		Short: "manage groups",
+	}
+
+	for _, c := range []*cobra.Command{listCmd, createCmd, deleteCmd, attachCmd} {
+		addAccountFlags(c, s)
+
+		cmd.AddCommand(c)
+	}
+
+	return cmd
+}
+
+func groupsNameArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("group name is required")
+	}
+
+	return nil
+}
+
+func groupsIDArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("group ID is required")
+	}
+
+	return nil
+}
+
+func groupsListRun(opts *CommandOptions) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	if err := validateListFlags(opts, groupsSortFields...); err != nil {
+		return err
+	}
+
+	limit, paging := opts.limit, listPaging(opts)
+
+	// Results are sorted locally, so every page is listed
+	if sortsLocally(opts) {
+		limit, paging = localPaging(opts)
+	}
+
+	groups, err := opts.client.ListGroups(opts.ctx, &keygenext.ListParams{Limit: limit, Paging: paging})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	sortList(opts, groups, func(i, j int) bool {
+		switch opts.sort {
+		case "name":
+			return groups[i].Name < groups[j].Name
+		case "updated":
+			return groups[i].Updated.Before(groups[j].Updated)
+		default:
+			return groups[i].Created.Before(groups[j].Created)
+		}
+	})
+
+	if sortsLocally(opts) {
+		groups = groups[:truncateList(opts, len(groups))]
+	}
+
+	rows := [][]string{}
+	for _, g := range groups {
+		rows = append(rows, []string{g.ID, g.Name, formatLimit(g.MaxLicenses), formatLimit(g.MaxMachines), formatLimit(g.MaxUsers), g.Created.Format(time.RFC3339), g.Updated.Format(time.RFC3339)})
+	}
+
+	r, err := selectList(opts, rendering{
+		value:   groupsJSON(groups...),
+		headers: []string{"ID", "NAME", "MAX LICENSES", "MAX MACHINES", "MAX USERS", "CREATED", "UPDATED"},
+		rows:    rows,
+		wide:    1,
+	})
+	if err != nil {
+		return err
+	}
+
+	return render(opts.output, r)
+}
+
+func groupsCreateRun(opts *CommandOptions, cmd *cobra.Command, args []string) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	group := &keygenext.Group{Name: args[0]}
+
+	if cmd.Flags().Changed("max-licenses") {
+		group.MaxLicenses = &opts.maxLicenses
+	}
+
+	if cmd.Flags().Changed("max-machines") {
+		group.MaxMachines = &opts.maxMachines
+	}
+
+	if cmd.Flags().Changed("max-users") {
+		group.MaxUsers = &opts.maxUsers
+	}
+
+	if err := opts.client.CreateGroup(opts.ctx, group); err != nil {
+		return formatAPIError(err)
+	}
+
+	if isStructuredOutput(opts.output) {
+		return render(opts.output, rendering{value: groupsJSON(*group)[0]})
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("created group " + italic(group.ID))
+
+	return nil
+}
+
+func groupsDeleteRun(opts *CommandOptions, args []string) error {
+	group, err := opts.client.GetGroup(opts.ctx, args[0])
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	// Users and licenses in the group are removed from it, so make sure
+	// the right group is being deleted.
+	if err := opts.confirmAction("delete a group", []string{group.Name + " (" + group.ID + ")"}, group.Name); err != nil {
+		return err
+	}
+
+	if err := opts.client.DeleteGroup(opts.ctx, group); err != nil {
+		return formatAPIError(err)
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("deleted group " + italic(group.ID))
+
+	return nil
+}
+
+func groupsAttachRun(opts *CommandOptions, args []string) error {
+	if len(opts.licenses) == 0 && len(opts.users) == 0 {
+		return errors.New("at least one of --licenses or --users is required")
+	}
+
+	group := &keygenext.Group{ID: args[0]}
+	italic := color.New(color.Italic).SprintFunc()
+
+	for _, id := range opts.licenses {
+		if err := opts.client.AttachGroupLicense(opts.ctx, group, id); err != nil {
+			return formatAPIError(err)
+		}
+
+		fmt.Println("attached license " + italic(id) + " to group " + italic(group.ID))
+	}
+
+	for _, id := range opts.users {
+		if err := opts.client.AttachGroupUser(opts.ctx, group, id); err != nil {
+			return formatAPIError(err)
+		}
+
+		fmt.Println("attached user " + italic(id) + " to group " + italic(group.ID))
+	}
+
+	return nil
+}
+
+func groupsJSON(groups ...keygenext.Group) []map[string]interface{} {
+	out := []map[string]interface{}{}
+
+	for _, g := range groups {
+		out = append(out, map[string]interface{}{
+			"id":           g.ID,
+			"name":         g.Name,
+			"max_licenses": g.MaxLicenses,
+			"max_machines": g.MaxMachines,
+			"max_users":    g.MaxUsers,
+			"metadata":     g.Metadata,
+			"created":      g.Created,
+			"updated":      g.Updated,
+		})
+	}
+
+	return out
+}
+
+// formatLimit formats an optional limit, where nil means unlimited.
+func formatLimit(limit *int) string {
+	if limit == nil {
+		return "-"
+	}
+
+	return strconv.Itoa(*limit)
+}
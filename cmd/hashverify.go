@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mitchellh/go-homedir"
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+	"github.com/spf13/cobra"
+)
+
+func newHashVerifyCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "hash-verify [dir]",
+		Short: "verify downloaded artifacts against a signed SUMS manifest published by dist",
+		Example: `  keygen hash-verify --sums SHA512SUMS-1.0.0 --signature "$SIGNATURE" \
+      --public-key keygen.pub --product "$KEYGEN_PRODUCT_ID"
+  keygen hash-verify ./downloads --sums SHA512SUMS-1.0.0 --signature SHA512SUMS-1.0.0.sig \
+      --public-key "$PUBLIC_KEY" --signing-algorithm ed25519 --ignore-missing
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				opts.sumsDir = args[0]
+			}
+
+			if cmd.Flags().Changed("signing-context") {
+				ctx := opts.signingCtx
+				opts.signingContext = &ctx
+			}
+
+			return hashVerifyRun(opts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	// Install scripts run offline, so the product is only the default context
+	cmd.Flags().StringVar(&s.productID, "product", "", "product the manifest was published for, whose ID is the default --signing-context [$KEYGEN_PRODUCT_ID=<id>]")
+	cmd.Flags().StringVar(&opts.fromSums, "sums", "", "path to the SUMS manifest, e.g. SHA512SUMS-1.0.0 (required)")
+	cmd.Flags().StringVar(&opts.signature, "signature", "", "the manifest's base64 encoded signature, i.e. its release's signature, or a path to one (required)")
+	cmd.Flags().StringVar(&opts.publicKey, "public-key", "", "hex-encoded ed25519 public key the manifest was signed for, or a path to one, e.g. from genkey (required)")
+	cmd.Flags().StringVar(&opts.signingAlgorithm, "signing-algorithm", "ed25519ph", "the signing algorithm the manifest was signed using, one of: ed25519ph, ed25519")
+	cmd.Flags().StringVar(&opts.signingCtx, "signing-context", "", "context the manifest was signed with using ed25519ph, which may be empty (default the product ID)")
+	cmd.Flags().BoolVar(&opts.ignoreMissing, "ignore-missing", false, "don't fail for listed files which weren't downloaded, e.g. other platforms' artifacts")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "text", "output format, one of: text, json, yaml, go-template=<template>")
+
+	bindEnv(cmd.Flags(), "product", "KEYGEN_PRODUCT_ID")
+
+	cmd.MarkFlagRequired("sums")
+	cmd.MarkFlagRequired("signature")
+	cmd.MarkFlagRequired("public-key")
+
+	return cmd
+}
+
+// hashVerifyFile is the result of verifying a file listed in a SUMS manifest.
+type hashVerifyFile struct {
+	Filename string `json:"filename"`
+	Status   string `json:"status"`
+}
+
+func hashVerifyRun(opts *CommandOptions) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	path, err := homedir.Expand(opts.fromSums)
+	if err != nil {
+		return fmt.Errorf(`sums path "%s" is not expandable (%s)`, opts.fromSums, err)
+	}
+
+	manifest, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf(`sums path "%s" is not readable (%s)`, opts.fromSums, err.(*os.PathError).Err)
+	}
+
+	// The digests can't be trusted until the manifest itself is verified
+	if err := verifyManifestSignature(opts, manifest); err != nil {
+		return err
+	}
+
+	entries, err := parseSums(manifest)
+	if err != nil {
+		return fmt.Errorf(`sums file "%s" is not acceptable (%s)`, opts.fromSums, err)
+	}
+
+	dir := opts.sumsDir
+	if dir == "" {
+		dir = filepath.Dir(path)
+	}
+
+	files := []*hashVerifyFile{}
+	failed := 0
+
+	for _, e := range entries {
+		status, err := verifySumsEntry(dir, e)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case status == "missing" && opts.ignoreMissing:
+			status = "skipped"
+		case status != "ok":
+			failed++
+		}
+
+		files = append(files, &hashVerifyFile{Filename: e.filename, Status: status})
+	}
+
+	if isStructuredOutput(opts.output) {
+		if err := render(opts.output, rendering{value: map[string]interface{}{
+			"manifest":  opts.fromSums,
+			"signature": "verified",
+			"verified":  failed == 0,
+			"files":     files,
+		}}); err != nil {
+			return err
+		}
+	} else {
+		printHashVerifyFiles(opts, files)
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("%d of %d files listed in the manifest could not be verified", failed, len(files))
+	}
+
+	return nil
+}
+
+// verifyManifestSignature checks the manifest's signature using the public
+// key, in the same way that dist signed it.
+func verifyManifestSignature(opts *CommandOptions, manifest []byte) error {
+	key, err := readPublicKey(opts.publicKey)
+	if err != nil {
+		return err
+	}
+
+	verifyKey, _ := hex.DecodeString(key)
+
+	value := opts.signature
+	if p, err := homedir.Expand(value); err == nil {
+		if b, err := ioutil.ReadFile(p); err == nil {
+			value = strings.TrimSpace(string(b))
+		}
+	}
+
+	sig, err := decodeBase64(value)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf(`signature "%s" is not acceptable (must be a base64 encoded ed25519 signature, or a path to one)`, abbreviate(opts.signature))
+	}
+
+	var message []byte
+	var verifyOpts *ed25519.Options
+
+	switch opts.signingAlgorithm {
+	case "ed25519ph":
+		if opts.signingContext == nil && opts.productID == "" {
+			return errors.New(`flag "--signing-algorithm ed25519ph" requires "--product" or "--signing-context"`)
+		}
+
+		digest := sha512.Sum512(manifest)
+
+		message = digest[:]
+		verifyOpts = &ed25519.Options{Hash: crypto.SHA512, Context: opts.ed25519phContext()}
+	case "ed25519":
+		message = manifest
+		verifyOpts = &ed25519.Options{}
+	default:
+		return fmt.Errorf(`signing algorithm "%s" is not supported`, opts.signingAlgorithm)
+	}
+
+	if !ed25519.VerifyWithOptions(ed25519.PublicKey(verifyKey), message, sig, verifyOpts) {
+		return fmt.Errorf(`signature "%s" does not verify for the manifest using the public key (check the signature, --signing-algorithm and --signing-context)`, abbreviate(value))
+	}
+
+	return nil
+}
+
+// verifySumsEntry checks a listed file's size, when listed, and digest,
+// returning "ok", "mismatch" or "missing".
+func verifySumsEntry(dir string, e *sumsEntry) (string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(e.filename))
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "missing", nil
+		}
+
+		return "", fmt.Errorf(`path "%s" is not readable (%s)`, path, err.(*os.PathError).Err)
+	}
+	defer file.Close()
+
+	if e.size >= 0 {
+		info, err := file.Stat()
+		if err != nil {
+			return "", err
+		}
+
+		if info.Size() != e.size {
+			return "mismatch", nil
+		}
+	}
+
+	digest, err := hashFile(file, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if !bytes.Equal(digest.sum, e.sum) {
+		return "mismatch", nil
+	}
+
+	return "ok", nil
+}
+
+// printHashVerifyFiles prints a line per verified file, like sha512sum -c.
+func printHashVerifyFiles(opts *CommandOptions, files []*hashVerifyFile) {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("verified manifest signature for " + italic(opts.fromSums))
+
+	for _, f := range files {
+		switch f.Status {
+		case "ok":
+			fmt.Println(f.Filename + ": " + green("ok"))
+		case "skipped":
+			fmt.Println(f.Filename + ": " + italic("skipped (missing)"))
+		default:
+			fmt.Println(f.Filename + ": " + red(strings.ToUpper(f.Status)))
+		}
+	}
+}
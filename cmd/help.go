@@ -6,8 +6,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var (
-	helpCmd = &cobra.Command{
+func newHelpCmd() *cobra.Command {
+	return &cobra.Command{
 		Use:          "help [command]",
 		Short:        "help for a command",
 		SilenceUsage: true,
@@ -27,4 +27,4 @@ var (
 			return nil
 		},
 	}
-)
+}
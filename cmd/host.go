@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keygen-sh/keygen-go"
+	"github.com/mitchellh/go-homedir"
+)
+
+const defaultHost = "https://api.keygen.sh"
+
+// isSelfHosted reports whether the CLI is pointed at a self-hosted Keygen CE
+// or EE instance rather than keygen.sh.
+func isSelfHosted() bool {
+	return strings.TrimSuffix(keygen.APIURL, "/") != defaultHost
+}
+
+// configureHost points API requests at the --host instance, and configures
+// response signature verification using its --public-key. For self-hosted
+// instances, CLI upgrades are checked against the instance's mirror of the
+// CLI's releases, given by $KEYGEN_UPGRADE_ACCOUNT and $KEYGEN_UPGRADE_PRODUCT.
+// Since keygen-go's host is process-wide, so is the --host.
+func (s *session) configureHost() error {
+	host := strings.TrimSuffix(s.root.host, "/")
+
+	u, err := url.Parse(host)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf(`host "%s" is not acceptable (must be a URL, e.g. https://keygen.example.com)`, s.root.host)
+	}
+
+	keygen.APIURL = host
+
+	if s.root.publicKey != "" {
+		key, err := readPublicKey(s.root.publicKey)
+		if err != nil {
+			return err
+		}
+
+		s.client.PublicKey = key
+	}
+
+	if !isSelfHosted() {
+		return nil
+	}
+
+	// keygen.sh's public key can't verify a self-hosted instance's responses
+	keygen.PublicKey = s.client.PublicKey
+	keygen.Account = os.Getenv("KEYGEN_UPGRADE_ACCOUNT")
+	keygen.Product = upgradeProduct()
+
+	return nil
+}
+
+// configureArtifactHost rewrites the artifact URLs printed by commands, e.g.
+// by latest, brew and dist's summary, to the --artifact-host, such as a
+// custom domain fronting artifact downloads. The host is checked by
+// checkArtifactHost once a command prints an artifact URL.
+func (s *session) configureArtifactHost() error {
+	if s.root.artifactHost == "" {
+		return nil
+	}
+
+	host := strings.TrimSuffix(s.root.artifactHost, "/")
+
+	u, err := url.Parse(host)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf(`artifact host "%s" is not acceptable (must be a URL, e.g. https://downloads.example.com)`, s.root.artifactHost)
+	}
+
+	s.client.ArtifactHost = host
+
+	return nil
+}
+
+// checkArtifactHost ensures the --artifact-host serves the account's API, i.e.
+// that it's a custom domain configured for the account, so that customers
+// aren't pointed at a broken domain. Any response from the API will do, even
+// one requiring authentication. It's only checked once per session.
+func (s *session) checkArtifactHost() error {
+	host := s.client.ArtifactHost
+	if host == "" || s.client.Account == "" || s.artifactHostChecked {
+		return nil
+	}
+
+	s.artifactHostChecked = true
+
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodGet, host+"/"+keygen.APIVersion+"/accounts/"+s.client.Account+"/releases?limit=1", nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.api+json")
+
+	res, err := newExternalClient(30 * time.Second).Do(req)
+	if err != nil {
+		// Leave it to the command to fail (or queue) when unreachable
+		return nil
+	}
+	defer res.Body.Close()
+
+	switch {
+	case !strings.HasPrefix(res.Header.Get("Content-Type"), "application/vnd.api+json"):
+		return fmt.Errorf(`artifact host "%s" is not acceptable (it doesn't serve the API, so check its DNS and proxy configuration)`, host)
+	case res.StatusCode == http.StatusNotFound:
+		return fmt.Errorf(`artifact host "%s" is not acceptable (it isn't configured as a custom domain for account "%s")`, host, s.client.Account)
+	}
+
+	return nil
+}
+
+// readPublicKey reads a hex-encoded Ed25519 public key, given either directly
+// or as the path to a file containing it, e.g. one downloaded from an
+// instance's account settings.
+func readPublicKey(value string) (string, error) {
+	key := value
+
+	if path, err := homedir.Expand(value); err == nil {
+		if b, err := ioutil.ReadFile(path); err == nil {
+			key = strings.TrimSpace(string(b))
+		}
+	}
+
+	if b, err := hex.DecodeString(key); err != nil || len(b) != 32 {
+		return "", fmt.Errorf(`public key "%s" is not acceptable (must be a hex-encoded Ed25519 public key, or a path to one)`, value)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mitchellh/go-homedir"
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+	"github.com/spf13/cobra"
+)
+
+const initWorkflowPath = ".github/workflows/keygen.yml"
+
+func newInitCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "set up a project for publishing releases",
+		Example: `  keygen init
+
+  keygen init --yes \
+    --account '1fddcec8-8dd3-4d8d-9b16-215cac0f9b52' \
+    --product '2313b7e7-1ea6-4a01-901e-2931de6bb1e2'
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return initRun(opts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringVar(&opts.account, "account", "", "your keygen.sh account identifier [$KEYGEN_ACCOUNT_ID=<id>]")
+	cmd.Flags().StringVar(&opts.product, "product", "", "your keygen.sh product identifier [$KEYGEN_PRODUCT_ID=<id>]")
+	cmd.Flags().StringVar(&opts.signingKeyPath, "signing-key", "", "path to an existing ed25519 private key (default generates a new key pair)")
+	cmd.Flags().BoolVar(&opts.githubActions, "github-actions", true, "write a sample GitHub Actions workflow to "+initWorkflowPath)
+	cmd.Flags().BoolVar(&opts.force, "force", false, "overwrite an existing config file and workflow")
+
+	bindEnv(cmd.Flags(), "account", "KEYGEN_ACCOUNT_ID")
+	bindEnv(cmd.Flags(), "product", "KEYGEN_PRODUCT_ID")
+
+	return cmd
+}
+
+func initRun(opts *CommandOptions) error {
+	configPath := opts.root.config
+
+	if _, err := os.Stat(configPath); err == nil && !opts.force {
+		return fmt.Errorf(`config file "%s" already exists (use --force to overwrite)`, configPath)
+	}
+
+	account, err := opts.promptValue("account ID", opts.account)
+	if err != nil {
+		return err
+	}
+
+	product, err := opts.promptValue("product ID", opts.product)
+	if err != nil {
+		return err
+	}
+
+	if account == "" || product == "" {
+		return errors.New("an account and product are required (use --account and --product)")
+	}
+
+	signingKeyPath := opts.signingKeyPath
+	if signingKeyPath == "" {
+		signingKeyPath, err = opts.promptValue("signing key path (leave as-is to generate a new key pair)", "~/.keygen/"+product+".key")
+		if err != nil {
+			return err
+		}
+	}
+
+	publicKey, err := opts.initSigningKey(signingKeyPath)
+	if err != nil {
+		return err
+	}
+
+	config := `# Keygen CLI config, see https://keygen.sh/docs/cli/
+#
+# Keys are flag names, used as defaults by every command. Flags and environment
+# variables take precedence, e.g. $KEYGEN_SIGNING_KEY in CI.
+account: ` + account + `
+product: ` + product + `
+signing-key: ` + signingKeyPath + `
+signing-algorithm: ed25519ph
+`
+
+	if err := ioutil.WriteFile(configPath, []byte(config), 0644); err != nil {
+		return fmt.Errorf(`config file "%s" is not writable (%s)`, configPath, err)
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	fmt.Println("wrote config " + italic(configPath))
+
+	if opts.githubActions {
+		if _, err := os.Stat(initWorkflowPath); err == nil && !opts.force {
+			fmt.Fprintln(os.Stderr, yellow("warning:")+" workflow "+initWorkflowPath+" already exists (use --force to overwrite)")
+		} else {
+			if err := os.MkdirAll(filepath.Dir(initWorkflowPath), 0755); err != nil {
+				return fmt.Errorf(`workflow path "%s" is not writable (%s)`, initWorkflowPath, err)
+			}
+
+			if err := ioutil.WriteFile(initWorkflowPath, []byte(initWorkflow), 0644); err != nil {
+				return fmt.Errorf(`workflow path "%s" is not writable (%s)`, initWorkflowPath, err)
+			}
+
+			fmt.Println("wrote workflow " + italic(initWorkflowPath))
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("public key: " + publicKey)
+	fmt.Println()
+	fmt.Println("Add the public key to your product's settings in the dashboard, and use it")
+	fmt.Println("to verify upgrades within your app. In CI, store the signing key's contents")
+	fmt.Println("in a KEYGEN_SIGNING_KEY secret and a product token in KEYGEN_PRODUCT_TOKEN.")
+
+	fmt.Fprintf(os.Stderr, yellow("warning:")+" never share your signing key -- "+italic("it's a secret!")+"\n")
+
+	return nil
+}
+
+// initSigningKey returns the hex-encoded public key for the signing key at
+// path, generating a new key pair there when it doesn't exist yet.
+func (s *session) initSigningKey(path string) (string, error) {
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return "", fmt.Errorf(`path "%s" is not expandable (%s)`, path, err)
+	}
+
+	if _, err := os.Stat(p); err == nil {
+		signer, err := s.loadSigner(path, "")
+		if err != nil {
+			return "", err
+		}
+
+		key, ok := signer.Public().(ed25519.PublicKey)
+		if !ok {
+			return "", fmt.Errorf(`signing key "%s" is not an ed25519 key`, path)
+		}
+
+		return hex.EncodeToString(key), nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return "", fmt.Errorf(`path "%s" is not writable (%s)`, path, err)
+	}
+
+	verifyKey, signingKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return "", err
+	}
+
+	if err := writeSigningKeyFile(p, signingKey); err != nil {
+		return "", err
+	}
+
+	if err := writeVerifyKeyFile(strings.TrimSuffix(p, filepath.Ext(p))+".pub", verifyKey); err != nil {
+		return "", err
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("generated signing key " + italic(p))
+
+	return hex.EncodeToString(verifyKey), nil
+}
+
+// initWorkflow is a sample GitHub Actions workflow which publishes a release
+// for each tag.
+const initWorkflow = `name: Publish
+
+on:
+  push:
+    tags:
+      - 'v*'
+
+jobs:
+  publish:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+
+      # Build your app here, e.g. into dist/
+
+      - name: Install Keygen CLI
+        run: curl -sSL https://get.keygen.sh/keygen/cli/install.sh | sh
+
+      - name: Publish release
+        env:
+          KEYGEN_PRODUCT_TOKEN: ${{ secrets.KEYGEN_PRODUCT_TOKEN }}
+          KEYGEN_SIGNING_KEY: ${{ secrets.KEYGEN_SIGNING_KEY }}
+        run: keygen dist dist/app --ci --platform linux/amd64
+`
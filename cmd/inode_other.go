@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package cmd
+
+import "os"
+
+// fileInode returns 0, since inode numbers aren't available on this platform.
+// Cached digests are still keyed by path, size and modification time.
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+// +build linux darwin freebsd netbsd openbsd
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileInode returns a file's inode number, or 0 when it's unknown.
+func fileInode(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+
+	return 0
+}
@@ -0,0 +1,434 @@
+package cmd
+
+import (
+	"crypto"
+	stded25519 "crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+	"github.com/spf13/cobra"
+)
+
+func newKeysCmd(s *session) *cobra.Command {
+	checkOpts := s.newOptions()
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "check that a signing key matches the product's public key",
+		Example: `  keygen keys check \
+      --signing-key ~/.keys/keygen.key \
+      --account '1fddcec8-8dd3-4d8d-9b16-215cac0f9b52' \
+      --product '2313b7e7-1ea6-4a01-901e-2931de6bb1e2' \
+      --token 'prod-xxx'
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return keysCheckRun(checkOpts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(checkCmd, s)
+	addProductFlag(checkCmd, s)
+
+	checkCmd.Flags().StringVar(&checkOpts.signingKeyPath, "signing-key", "", "path to ed25519 private key, or any other key supported by dist [$KEYGEN_SIGNING_KEY_PATH=<path>, $KEYGEN_SIGNING_KEY=<key>]")
+	checkCmd.Flags().BoolVar(&checkOpts.publish, "publish", false, "publish the signing key's public key to the product's metadata when it has none")
+
+	bindEnv(checkCmd.Flags(), "signing-key", "KEYGEN_SIGNING_KEY_PATH")
+
+	showOpts := s.newOptions()
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "print the product's public key for embedding into an app",
+		Example: `  keygen keys show \
+      --account '1fddcec8-8dd3-4d8d-9b16-215cac0f9b52' \
+      --product '2313b7e7-1ea6-4a01-901e-2931de6bb1e2' \
+      --token 'prod-xxx' \
+      --verify-against ~/.keys/keygen.key
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return keysShowRun(showOpts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(showCmd, s)
+	addProductFlag(showCmd, s)
+
+	showCmd.Flags().StringVar(&showOpts.signingKeyPath, "verify-against", "", "path to a signing key, or any other key supported by dist, to check is paired with the public key")
+	showCmd.Flags().StringVarP(&showOpts.output, "output", "o", "text", "output format, one of: text, json, yaml, go-template=<template>")
+
+	rotateOpts := s.newOptions()
+	rotateCmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "sign recent releases with the next signing key during a key rotation",
+		Example: `  keygen keys rotate \
+      --signing-key-next ~/.keys/keygen-next.key \
+      --account '1fddcec8-8dd3-4d8d-9b16-215cac0f9b52' \
+      --product '2313b7e7-1ea6-4a01-901e-2931de6bb1e2' \
+      --token 'prod-xxx' \
+      --limit 10
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return keysRotateRun(rotateOpts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(rotateCmd, s)
+	addProductFlag(rotateCmd, s)
+
+	rotateCmd.Flags().StringVar(&rotateOpts.nextSigningKeyPath, "signing-key-next", "", "path to the next ed25519 private key [$KEYGEN_NEXT_SIGNING_KEY_PATH=<path>] (required)")
+	rotateCmd.Flags().StringVar(&rotateOpts.signingAlgorithm, "signing-algorithm", "ed25519ph", "the signing algorithm to use, one of: ed25519ph, ed25519")
+	rotateCmd.Flags().StringVar(&rotateOpts.channel, "channel", "", "only sign releases for the given channel")
+	rotateCmd.Flags().IntVar(&rotateOpts.limit, "limit", 10, "number of recent releases to sign")
+
+	bindEnv(rotateCmd.Flags(), "signing-key-next", "KEYGEN_NEXT_SIGNING_KEY_PATH")
+
+	rotateCmd.MarkFlagRequired("signing-key-next")
+
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "manage signing keys",
+	}
+
+	cmd.AddCommand(checkCmd)
+	cmd.AddCommand(showCmd)
+	cmd.AddCommand(rotateCmd)
+
+	return cmd
+}
+
+func keysCheckRun(opts *CommandOptions) error {
+	if v := os.Getenv("KEYGEN_SIGNING_KEY"); v != "" {
+		opts.signingKey = v
+	}
+
+	if opts.signingKeyPath == "" && opts.signingKey == "" {
+		return errors.New(`required flag(s) "signing-key" not set`)
+	}
+
+	signer, err := opts.loadSigner(opts.signingKeyPath, opts.signingKey)
+	if err != nil {
+		return err
+	}
+
+	key, err := signerPublicKey(signer)
+	if err != nil {
+		return err
+	}
+
+	keys, err := opts.publishedPublicKeys()
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	if len(keys) == 0 {
+		if !opts.publish {
+			return errors.New("product has no public key in its metadata to check against (use --publish to publish the signing key's public key)")
+		}
+
+		product, err := opts.client.GetProduct(opts.ctx, opts.productID)
+		if err != nil {
+			return formatAPIError(err)
+		}
+
+		metadata := map[string]interface{}{}
+		for k, v := range product.Metadata {
+			metadata[k] = v
+		}
+
+		metadata["publicKey"] = key
+
+		if err := opts.client.UpdateProductMetadata(opts.ctx, product, metadata); err != nil {
+			return formatAPIError(err)
+		}
+
+		fmt.Println("published public key " + italic(key) + " to product " + italic(product.ID))
+
+		return nil
+	}
+
+	if err := opts.preflightSigningKey(signer); err != nil {
+		return err
+	}
+
+	for k, v := range keys {
+		if v == key {
+			fmt.Println("signing key matches the product's " + k + " " + italic(key))
+		}
+	}
+
+	return nil
+}
+
+// keysShowRun prints the public keys published in the product's metadata in
+// the forms clients commonly embed: hex, as used by Keygen's SDKs, base64 and
+// PEM-encoded PKIX.
+func keysShowRun(opts *CommandOptions) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	keys, err := opts.publishedPublicKeys()
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	if len(keys) == 0 {
+		return errors.New("product has no public key in its metadata (use keygen keys check --publish to publish one)")
+	}
+
+	forms := map[string]map[string]string{}
+	for _, k := range []string{"publicKey", "nextPublicKey"} {
+		v, ok := keys[k]
+		if !ok {
+			continue
+		}
+
+		f, err := publicKeyForms(v)
+		if err != nil {
+			return fmt.Errorf(`product's %s "%s" is not acceptable (%s)`, k, abbreviate(v), err)
+		}
+
+		forms[k] = f
+	}
+
+	var matched string
+	if opts.signingKeyPath != "" {
+		signer, err := opts.loadSigner(opts.signingKeyPath, "")
+		if err != nil {
+			return err
+		}
+
+		key, err := signerPublicKey(signer)
+		if err != nil {
+			return err
+		}
+
+		for _, k := range []string{"publicKey", "nextPublicKey"} {
+			if keys[k] == key {
+				matched = k
+
+				break
+			}
+		}
+
+		if matched == "" {
+			return fmt.Errorf(`signing key's public key "%s" does not match the product's public key "%s"`, abbreviate(key), abbreviate(keys["publicKey"]))
+		}
+	}
+
+	if isStructuredOutput(opts.output) {
+		value := map[string]interface{}{}
+		for k, f := range forms {
+			value[k] = f
+		}
+
+		if opts.signingKeyPath != "" {
+			value["verified"] = matched
+		}
+
+		return render(opts.output, rendering{value: value})
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	for _, k := range []string{"publicKey", "nextPublicKey"} {
+		f, ok := forms[k]
+		if !ok {
+			continue
+		}
+
+		if k == "nextPublicKey" && len(forms) > 1 {
+			fmt.Println()
+		}
+
+		fmt.Println(k + ":")
+		fmt.Println("  hex:    " + f["hex"])
+		fmt.Println("  base64: " + f["base64"])
+		fmt.Println()
+		fmt.Print(f["pem"])
+	}
+
+	if matched != "" {
+		fmt.Println()
+		fmt.Println("signing key matches the product's " + matched + " " + italic(keys[matched]))
+	}
+
+	return nil
+}
+
+// publicKeyForms encodes a hex-encoded ed25519 public key as hex, base64 and
+// PEM-encoded PKIX.
+func publicKeyForms(key string) (map[string]string, error) {
+	b, err := hex.DecodeString(key)
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, errors.New("must be a hex-encoded ed25519 public key")
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(stded25519.PublicKey(b))
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"hex":    hex.EncodeToString(b),
+		"base64": base64.StdEncoding.EncodeToString(b),
+		"pem":    string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	}, nil
+}
+
+func keysRotateRun(opts *CommandOptions) error {
+	signer, err := opts.loadSigner(opts.nextSigningKeyPath, "")
+	if err != nil {
+		return err
+	}
+
+	releases, err := opts.client.ListReleases(opts.ctx, &keygenext.ReleaseFilter{
+		Product: opts.productID,
+		Channel: opts.channel,
+		Limit:   opts.limit,
+	})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	affected := []string{}
+	for _, release := range releases {
+		affected = append(affected, release.ID+" (v"+release.Version+", "+release.Platform+")")
+	}
+
+	if len(affected) > 0 {
+		if err := opts.confirmAction("replace the next signature of these releases", affected, ""); err != nil {
+			return err
+		}
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	for i := range releases {
+		release := &releases[i]
+
+		metadata, err := opts.calculateRemoteNextSignature(signer, opts.signingAlgorithm, release)
+		if err != nil {
+			return fmt.Errorf(`release "%s" could not be signed (%s)`, release.ID, err)
+		}
+
+		merged := map[string]interface{}{}
+		for k, v := range release.Metadata {
+			merged[k] = v
+		}
+
+		for k, v := range metadata {
+			merged[k] = v
+		}
+
+		if err := opts.client.UpdateReleaseMetadata(opts.ctx, release, merged); err != nil {
+			return formatAPIError(err)
+		}
+
+		fmt.Println("signed release " + italic(release.ID) + " (v" + release.Version + ")")
+	}
+
+	return nil
+}
+
+// calculateNextSignature signs the file using the next signing key, returning
+// release metadata containing the signature and the next public key, so that
+// clients which have already switched keys are able to verify the release.
+func (s *session) calculateNextSignature(signer crypto.Signer, algorithm string, file *os.File, digest *fileDigest) (map[string]interface{}, error) {
+	signature, err := s.calculateSignature(signer, algorithm, file, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{"nextSignature": signature}
+
+	if key, ok := signer.Public().(ed25519.PublicKey); ok {
+		metadata["nextPublicKey"] = hex.EncodeToString(key)
+	}
+
+	return metadata, nil
+}
+
+// calculateRemoteNextSignature downloads a release's artifact to a temporary
+// file and signs it using the next signing key, once the download is verified
+// against the release's checksum, so that a corrupted or tampered download is
+// never signed.
+func (s *session) calculateRemoteNextSignature(signer crypto.Signer, algorithm string, release *keygenext.Release) (map[string]interface{}, error) {
+	artifact, err := s.client.GetReleaseArtifact(s.ctx, release)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := artifact.Download(s.ctx, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	file, err := ioutil.TempFile("", "keygen-rotate-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err := io.Copy(file, body); err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	digest, err := hashFile(file, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch checksum := strings.TrimRight(release.Checksum, "="); {
+	case checksum == "":
+		return nil, errors.New("release has no checksum to verify its artifact against")
+	case checksum != digest.checksum():
+		return nil, fmt.Errorf(`downloaded artifact does not match the release's checksum "%s" (got "%s")`, abbreviate(release.Checksum), abbreviate(digest.checksum()))
+	}
+
+	// Sign using the context the release was signed with, rather than the
+	// current one, so that clients verify both of its signatures alike
+	if v, ok := release.Metadata["signingContext"].(string); ok {
+		current := s.signingContext
+		defer func() { s.signingContext = current }()
+
+		s.signingContext = &v
+	}
+
+	return s.calculateNextSignature(signer, algorithm, file, digest)
+}
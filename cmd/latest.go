@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// upgradeChannels are the release channels an upgrading client on a channel
+// receives, where each prerelease channel includes the more stable ones.
+var upgradeChannels = map[string][]string{
+	"stable": {"stable"},
+	"rc":     {"stable", "rc"},
+	"beta":   {"stable", "rc", "beta"},
+	"alpha":  {"stable", "rc", "beta", "alpha"},
+	"dev":    {"stable", "rc", "beta", "alpha", "dev"},
+}
+
+func newReleasesLatestCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "latest",
+		Short: "show the release an upgrading client would receive",
+		Example: `  keygen releases latest --channel stable --constraint '^1.x'
+  keygen releases latest --channel beta --platform linux/amd64 -o json
+  keygen releases latest --arch arm64
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return releasesLatestRun(opts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+	addProductFlag(cmd, s)
+
+	cmd.Flags().StringVar(&opts.channel, "channel", "stable", "channel of the upgrading client, one of: stable, rc, beta, alpha, dev")
+	cmd.Flags().StringVar(&opts.constraint, "constraint", "", "only consider versions matching a semver constraint, e.g. ^1.x or ~1.2")
+	cmd.Flags().StringVar(&opts.platform, "platform", "", "only consider releases for a platform, or \"none\" for platformless releases")
+	cmd.Flags().StringVar(&opts.arch, "arch", "", "only consider releases for an arch across all OSes, e.g. arm64")
+	cmd.Flags().StringVar(&opts.filetype, "filetype", "", "only consider releases for a filetype")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "table", renderOutputUsage)
+
+	return cmd
+}
+
+// isCompanionRelease reports whether a release was published alongside
+// another release, i.e. its GPG signature or debug symbols, rather than being
+// one clients upgrade to.
+func isCompanionRelease(r keygenext.Release) bool {
+	_, signature := r.Metadata["signatureFor"]
+	_, symbols := r.Metadata["symbolsFor"]
+
+	return signature || symbols
+}
+
+func releasesLatestRun(opts *CommandOptions) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	if err := opts.checkArtifactHost(); err != nil {
+		return err
+	}
+
+	channels, ok := upgradeChannels[opts.channel]
+	if !ok {
+		return fmt.Errorf(`channel "%s" is not supported (must be one of: stable, rc, beta, alpha, dev)`, opts.channel)
+	}
+
+	included := map[string]bool{}
+	for _, c := range channels {
+		included[c] = true
+	}
+
+	var constraint *semver.Constraints
+	if c := opts.constraint; c != "" {
+		var err error
+
+		constraint, err = semver.NewConstraint(c)
+		if err != nil {
+			return fmt.Errorf(`constraint "%s" is not acceptable (%s)`, c, strings.ToLower(err.Error()))
+		}
+	}
+
+	if opts.arch != "" && opts.platform != "" {
+		return errors.New(`flag "--arch" cannot be used together with "--platform"`)
+	}
+
+	platform := opts.platform
+	if platform == noPlatform {
+		platform = ""
+	}
+
+	releases, err := opts.client.ListReleases(opts.ctx, &keygenext.ReleaseFilter{
+		Product:  opts.productID,
+		Platform: platform,
+		Filetype: opts.filetype,
+		Paging:   keygenext.Paging{All: true},
+	})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	// Resolve the latest version the way the upgrade endpoint does, skipping
+	// unpublished and yanked releases, companions such as GPG signatures and
+	// debug symbols, and versions outside of the channel or constraint
+	var latest *semver.Version
+	candidates := keygenext.Releases{}
+
+	for _, r := range releases {
+		if r.Status != "PUBLISHED" || r.Yanked != nil || isCompanionRelease(r) {
+			continue
+		}
+
+		if !matchPlatform(r, opts.platform) || !matchArch(r, opts.arch) || !included[r.Channel] {
+			continue
+		}
+
+		v, err := semver.NewVersion(r.Version)
+		if err != nil {
+			continue
+		}
+
+		// Prereleases are matched by their version core, since constraints
+		// would otherwise never match them
+		if constraint != nil {
+			core, _ := v.SetPrerelease("")
+			core, _ = core.SetMetadata("")
+			if !constraint.Check(&core) {
+				continue
+			}
+		}
+
+		candidates = append(candidates, r)
+
+		if latest == nil || v.GreaterThan(latest) {
+			latest = v
+		}
+	}
+
+	if latest == nil {
+		return fmt.Errorf(`no release is available for channel "%s"%s`, opts.channel, formatLatestConstraint(opts))
+	}
+
+	value := []map[string]interface{}{}
+	rows := [][]string{}
+
+	for _, r := range candidates {
+		if v, _ := semver.NewVersion(r.Version); !v.Equal(latest) {
+			continue
+		}
+
+		url := opts.client.ReleaseArtifactURL(&r)
+
+		value = append(value, map[string]interface{}{
+			"id":        r.ID,
+			"version":   r.Version,
+			"channel":   r.Channel,
+			"platform":  r.Platform,
+			"arch":      platformArch(r.Platform),
+			"filetype":  r.Filetype,
+			"filename":  r.Filename,
+			"filesize":  r.Filesize,
+			"url":       url,
+			"checksum":  r.Checksum,
+			"signature": r.Signature,
+		})
+
+		rows = append(rows, []string{r.Version, r.Channel, formatPlatform(r.Platform), r.Filetype, r.Filename, url, r.Signature, r.Checksum})
+	}
+
+	return render(opts.output, rendering{
+		value:   value,
+		headers: []string{"VERSION", "CHANNEL", "PLATFORM", "FILETYPE", "FILENAME", "URL", "SIGNATURE", "CHECKSUM"},
+		rows:    rows,
+		wide:    1,
+	})
+}
+
+// formatLatestConstraint describes the filters a latest release was resolved
+// with, for when none is available.
+func formatLatestConstraint(opts *CommandOptions) string {
+	filters := []string{}
+	if c := opts.constraint; c != "" {
+		filters = append(filters, "constraint "+c)
+	}
+
+	if p := opts.platform; p != "" {
+		filters = append(filters, "platform "+p)
+	}
+
+	if a := opts.arch; a != "" {
+		filters = append(filters, "arch "+a)
+	}
+
+	if f := opts.filetype; f != "" {
+		filters = append(filters, "filetype "+f)
+	}
+
+	if len(filters) == 0 {
+		return ""
+	}
+
+	return " (" + strings.Join(filters, ", ") + ")"
+}
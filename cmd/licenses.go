@@ -0,0 +1,627 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+// maxLicenseCount limits how many licenses are created at once.
+const maxLicenseCount = 10000
+
+// licenseCSVHeaders are the columns of exported licenses, which are also the
+// columns accepted by licenses import.
+var licenseCSVHeaders = []string{"id", "key", "name", "policy", "user", "group", "expiry", "metadata"}
+
+// licensesSortFields are the fields licenses can be sorted by.
+var licensesSortFields = []string{"name", "status", "expiry", "created", "updated"}
+
+// licenseStatuses are the statuses licenses can be listed by.
+var licenseStatuses = []string{"active", "inactive", "expiring", "expired", "suspended", "banned"}
+
+func newLicensesCmd(s *session) *cobra.Command {
+	listOpts := s.newOptions()
+	listCmd := &cobra.Command{
+		Use:   "ls",
+		Short: "list licenses",
+		Example: `  keygen licenses ls --status active --expiring-within 30d --metadata customer=acme --all -o csv
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return licensesListRun(listOpts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addListFlags(listCmd, listOpts, 10, licensesSortFields...)
+
+	listCmd.Flags().StringVar(&listOpts.status, "status", "", "only list licenses with a status, one of: "+strings.Join(licenseStatuses, ", "))
+	listCmd.Flags().StringVar(&listOpts.expiringWithin, "expiring-within", "", "only list licenses which expire within a duration, e.g. 30d or 12h")
+	listCmd.Flags().StringSliceVar(&listOpts.metadataFilters, "metadata", []string{}, "comma seperated list of key=value pairs the licenses' metadata must match")
+	listCmd.Flags().StringVar(&listOpts.policy, "policy", "", "only list licenses for a policy")
+	listCmd.Flags().StringVar(&listOpts.user, "user", "", "only list licenses for a user")
+	listCmd.Flags().StringVar(&listOpts.group, "group", "", "only list licenses for a group")
+	listCmd.Flags().StringVarP(&listOpts.output, "output", "o", "table", renderOutputUsage+", csv")
+
+	createOpts := s.newOptions()
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "create licenses, generating their keys server-side",
+		Example: `  keygen licenses create --policy <id> --count 500 --out keys.csv
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return licensesCreateRun(createOpts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	createCmd.Flags().StringVar(&createOpts.policy, "policy", "", "policy to create the licenses for (required)")
+	createCmd.Flags().IntVar(&createOpts.count, "count", 1, "number of licenses to create, up to 10000")
+	createCmd.Flags().StringVar(&createOpts.name, "name", "", "name for the licenses")
+	createCmd.Flags().StringVar(&createOpts.user, "user", "", "user to assign the licenses to")
+	createCmd.Flags().StringVar(&createOpts.group, "group", "", "group to add the licenses to")
+	createCmd.Flags().StringVar(&createOpts.expiry, "expiry", "", "expiry for the licenses, as a date (e.g. 2022-12-31) or an RFC3339 timestamp (default the policy's duration)")
+	createCmd.Flags().StringVar(&createOpts.out, "out", "", "write the licenses to a CSV file as they're created, which must not exist (default prints them)")
+	createCmd.Flags().StringVarP(&createOpts.output, "output", "o", "table", renderOutputUsage+", csv")
+
+	createCmd.MarkFlagRequired("policy")
+
+	importOpts := s.newOptions()
+	importCmd := &cobra.Command{
+		Use:   "import <path>",
+		Short: "create licenses from a CSV file, e.g. when migrating existing license keys",
+		Example: `  keygen licenses import keys.csv --policy <id> --out imported.csv
+
+Columns:
+  key, name, policy, user, group, expiry and metadata (a JSON object), in any
+  order. Licenses without a key are generated one, and an id column is ignored.
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: licensesImportArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return licensesImportRun(importOpts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	importCmd.Flags().StringVar(&importOpts.policy, "policy", "", "policy for rows without a policy column")
+	importCmd.Flags().StringVar(&importOpts.out, "out", "", "write the imported licenses, including their IDs, to a CSV file which must not exist")
+
+	cmd := &cobra.Command{
+		Use:   "licenses",
+		Short: "manage licenses",
+	}
+
+	for _, c := range []*cobra.Command{listCmd, createCmd, importCmd} {
+		addAccountFlags(c, s)
+
+		cmd.AddCommand(c)
+	}
+
+	cmd.AddCommand(newLicensesBulkCmd(s))
+	cmd.AddCommand(newLicensesTransferCmd(s))
+	cmd.AddCommand(newLicensesReportCmd(s))
+
+	return cmd
+}
+
+func licensesImportArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("path to a CSV file is required")
+	}
+
+	return nil
+}
+
+func licensesListRun(opts *CommandOptions) error {
+	if o := opts.output; o != "csv" {
+		if err := validateRenderOutput(o); err != nil {
+			return err
+		}
+	}
+
+	if err := validateListFlags(opts, licensesSortFields...); err != nil {
+		return err
+	}
+
+	status := strings.ToLower(opts.status)
+	if status != "" {
+		supported := false
+		for _, s := range licenseStatuses {
+			if s == status {
+				supported = true
+			}
+		}
+
+		if !supported {
+			return fmt.Errorf(`status "%s" is not supported (must be one of: %s)`, opts.status, strings.Join(licenseStatuses, ", "))
+		}
+	}
+
+	var within time.Duration
+	if w := opts.expiringWithin; w != "" {
+		d, err := parseWithin(w)
+		if err != nil {
+			return err
+		}
+
+		within = d
+	}
+
+	metadata := keygenext.MetadataFilter{}
+	for _, kv := range opts.metadataFilters {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf(`metadata "%s" is not acceptable (must be key=value)`, kv)
+		}
+
+		metadata[parts[0]] = parts[1]
+	}
+
+	limit, paging := opts.limit, listPaging(opts)
+
+	// Expiring licenses are filtered locally, and results are sorted locally, so
+	// every page is listed
+	local := within > 0 || sortsLocally(opts)
+	if local {
+		limit, paging = localPaging(opts)
+	}
+
+	licenses, err := opts.client.ListLicenses(opts.ctx, &keygenext.LicenseFilter{
+		Status:   strings.ToUpper(status),
+		Policy:   opts.policy,
+		User:     opts.user,
+		Group:    opts.group,
+		Metadata: metadata,
+		Limit:    limit,
+		Paging:   paging,
+	})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	// The API has no filter for an expiry window, so it's applied locally
+	if within > 0 {
+		now := time.Now()
+		expiring := keygenext.Licenses{}
+
+		for _, l := range licenses {
+			if l.Expiry != nil && l.Expiry.After(now) && l.Expiry.Before(now.Add(within)) {
+				expiring = append(expiring, l)
+			}
+		}
+
+		licenses = expiring
+	}
+
+	sortList(opts, licenses, func(i, j int) bool {
+		a, b := licenses[i], licenses[j]
+
+		switch opts.sort {
+		case "name":
+			return a.Name < b.Name
+		case "status":
+			return a.Status < b.Status
+		case "expiry":
+			// Licenses which never expire sort last
+			return a.Expiry != nil && (b.Expiry == nil || a.Expiry.Before(*b.Expiry))
+		case "updated":
+			return a.Updated.Before(b.Updated)
+		default:
+			return a.Created.Before(b.Created)
+		}
+	})
+
+	if local {
+		licenses = licenses[:truncateList(opts, len(licenses))]
+	}
+
+	if opts.output == "csv" {
+		r, err := selectList(opts, rendering{value: licensesJSON(licenses...), headers: licenseCSVHeaders, rows: licenseCSVRows(licenses)})
+		if err != nil {
+			return err
+		}
+
+		// Keep the columns importable rather than using table headers
+		headers := r.headers
+		if len(opts.fields) != 0 {
+			headers = opts.fields
+		}
+
+		return printCSV(headers, r.rows)
+	}
+
+	rows := [][]string{}
+	for _, l := range licenses {
+		expiry := ""
+		if l.Expiry != nil {
+			expiry = l.Expiry.Format(time.RFC3339)
+		}
+
+		rows = append(rows, []string{l.ID, l.Name, l.Status, l.PolicyID, expiry, l.Key, l.UserID, l.GroupID, l.Created.Format(time.RFC3339)})
+	}
+
+	r, err := selectList(opts, rendering{
+		value:   licensesJSON(licenses...),
+		headers: []string{"ID", "NAME", "STATUS", "POLICY", "EXPIRY", "KEY", "USER", "GROUP", "CREATED"},
+		rows:    rows,
+		wide:    4,
+	})
+	if err != nil {
+		return err
+	}
+
+	return render(opts.output, r)
+}
+
+// parseWithin parses a duration from now, given as a duration or a number of
+// days, e.g. 12h or 30d.
+func parseWithin(within string) (time.Duration, error) {
+	if strings.HasSuffix(within, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(within, "d")); err == nil && days > 0 {
+			return time.Duration(days) * 24 * time.Hour, nil
+		}
+	}
+
+	if d, err := time.ParseDuration(within); err == nil && d > 0 {
+		return d, nil
+	}
+
+	return 0, fmt.Errorf(`duration "%s" is not acceptable (must be a duration, e.g. 30d or 12h)`, within)
+}
+
+func licensesCreateRun(opts *CommandOptions) error {
+	if o := opts.output; o != "csv" {
+		if err := validateRenderOutput(o); err != nil {
+			return err
+		}
+	}
+
+	if opts.count < 1 || opts.count > maxLicenseCount {
+		return fmt.Errorf(`count "%d" is not acceptable (must be between 1 and %d)`, opts.count, maxLicenseCount)
+	}
+
+	expiry, err := parseLicenseExpiry(opts.expiry)
+	if err != nil {
+		return err
+	}
+
+	// Keys are written as they're created, so that they're kept even when a
+	// later license can't be created
+	out, err := newLicenseCSVWriter(opts.out)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	created := keygenext.Licenses{}
+
+	for i := 0; i < opts.count; i++ {
+		license := &keygenext.License{
+			Name:     opts.name,
+			Expiry:   expiry,
+			PolicyID: opts.policy,
+			UserID:   opts.user,
+			GroupID:  opts.group,
+		}
+
+		if err := opts.client.CreateLicense(opts.ctx, license); err != nil {
+			return licensesPartialError(fmt.Sprintf("license %d of %d could not be created", i+1, opts.count), err, len(created), opts.out)
+		}
+
+		if err := out.Write(license); err != nil {
+			return err
+		}
+
+		created = append(created, *license)
+	}
+
+	if opts.out != "" {
+		italic := color.New(color.Italic).SprintFunc()
+
+		fmt.Println("created " + strconv.Itoa(len(created)) + " licenses (wrote " + italic(opts.out) + ")")
+
+		return nil
+	}
+
+	return renderLicenses(opts.output, created)
+}
+
+func licensesImportRun(opts *CommandOptions, args []string) error {
+	licenses, err := readLicenseCSV(args[0], opts.policy)
+	if err != nil {
+		return err
+	}
+
+	out, err := newLicenseCSVWriter(opts.out)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	for i, license := range licenses {
+		if err := opts.client.CreateLicense(opts.ctx, license); err != nil {
+			// Rows are numbered from the header
+			return licensesPartialError(fmt.Sprintf("license on row %d could not be imported", i+2), err, i, opts.out)
+		}
+
+		if err := out.Write(license); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("imported " + strconv.Itoa(len(licenses)) + " licenses from " + italic(args[0]))
+
+	return nil
+}
+
+// licensesPartialError reports a license which couldn't be created, along with
+// how many were created before it, so that e.g. an import can be resumed.
+func licensesPartialError(message string, err error, created int, out string) error {
+	err = formatAPIError(err)
+
+	switch {
+	case created == 0:
+		return fmt.Errorf("%s (%w)", message, err)
+	case out != "":
+		return fmt.Errorf("%s (%w); created %d before it, written to %s", message, err, created, out)
+	default:
+		return fmt.Errorf("%s (%w); created %d before it", message, err, created)
+	}
+}
+
+// parseLicenseExpiry parses a license expiry given as a date or an RFC3339
+// timestamp.
+func parseLicenseExpiry(v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, fmt.Errorf(`expiry "%s" is not acceptable (must be a date, e.g. 2022-12-31, or an RFC3339 timestamp)`, v)
+}
+
+// licenseCSVRow returns a license's columns for licenseCSVHeaders.
+func licenseCSVRow(l *keygenext.License) []string {
+	expiry := ""
+	if l.Expiry != nil {
+		expiry = l.Expiry.Format(time.RFC3339)
+	}
+
+	metadata := ""
+	if len(l.Metadata) != 0 {
+		b, _ := json.Marshal(l.Metadata)
+		metadata = string(b)
+	}
+
+	return []string{l.ID, l.Key, l.Name, l.PolicyID, l.UserID, l.GroupID, expiry, metadata}
+}
+
+// licenseCSVRows returns the CSV rows for licenses.
+func licenseCSVRows(licenses keygenext.Licenses) [][]string {
+	rows := [][]string{}
+	for i := range licenses {
+		rows = append(rows, licenseCSVRow(&licenses[i]))
+	}
+
+	return rows
+}
+
+// licenseCSVWriter writes licenses to a CSV file one at a time, flushing each
+// one. Writes are no-ops when there's no file.
+type licenseCSVWriter struct {
+	file *os.File
+	w    *csv.Writer
+}
+
+// newLicenseCSVWriter creates a CSV file for licenses at path, refusing to
+// overwrite an existing file since it may hold the only copy of their keys.
+func newLicenseCSVWriter(path string) (*licenseCSVWriter, error) {
+	if path == "" {
+		return &licenseCSVWriter{}, nil
+	}
+
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return nil, fmt.Errorf(`out path "%s" is not expandable (%s)`, path, err)
+	}
+
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf(`out path "%s" is not writable (%s)`, path, err.(*os.PathError).Err)
+	}
+
+	lw := &licenseCSVWriter{file: f, w: csv.NewWriter(f)}
+	if err := lw.w.Write(licenseCSVHeaders); err != nil {
+		f.Close()
+
+		return nil, err
+	}
+
+	return lw, nil
+}
+
+func (lw *licenseCSVWriter) Write(l *keygenext.License) error {
+	if lw.w == nil {
+		return nil
+	}
+
+	if err := lw.w.Write(licenseCSVRow(l)); err != nil {
+		return err
+	}
+
+	lw.w.Flush()
+
+	return lw.w.Error()
+}
+
+func (lw *licenseCSVWriter) Close() error {
+	if lw.file == nil {
+		return nil
+	}
+
+	return lw.file.Close()
+}
+
+// readLicenseCSV reads and validates every row of a CSV file of licenses
+// before any are created, where policy is used for rows without one.
+func readLicenseCSV(path string, policy string) ([]*keygenext.License, error) {
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return nil, fmt.Errorf(`path "%s" is not expandable (%s)`, path, err)
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf(`path "%s" is not readable (%s)`, path, err.(*os.PathError).Err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+
+	headers, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf(`CSV file "%s" is not readable (%s)`, path, err)
+	}
+
+	columns := map[string]int{}
+	for i, h := range headers {
+		known := false
+		for _, k := range licenseCSVHeaders {
+			if h == k {
+				known = true
+			}
+		}
+
+		if !known {
+			return nil, fmt.Errorf(`CSV file "%s" is not acceptable (unknown column "%s")`, path, h)
+		}
+
+		columns[h] = i
+	}
+
+	licenses := []*keygenext.License{}
+
+	for row := 2; ; row++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf(`CSV file "%s" is not readable (%s)`, path, err)
+		}
+
+		column := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return record[i]
+			}
+
+			return ""
+		}
+
+		license := &keygenext.License{
+			Key:      column("key"),
+			Name:     column("name"),
+			PolicyID: column("policy"),
+			UserID:   column("user"),
+			GroupID:  column("group"),
+		}
+
+		if license.PolicyID == "" {
+			license.PolicyID = policy
+		}
+
+		if license.PolicyID == "" {
+			return nil, fmt.Errorf(`CSV file "%s" is not acceptable (row %d has no policy, pass --policy for rows without one)`, path, row)
+		}
+
+		license.Expiry, err = parseLicenseExpiry(column("expiry"))
+		if err != nil {
+			return nil, fmt.Errorf(`CSV file "%s" is not acceptable (row %d %s)`, path, row, err)
+		}
+
+		if m := column("metadata"); m != "" {
+			if err := json.Unmarshal([]byte(m), &license.Metadata); err != nil {
+				return nil, fmt.Errorf(`CSV file "%s" is not acceptable (row %d metadata must be a JSON object)`, path, row)
+			}
+		}
+
+		licenses = append(licenses, license)
+	}
+
+	if len(licenses) == 0 {
+		return nil, fmt.Errorf(`CSV file "%s" has no licenses`, path)
+	}
+
+	return licenses, nil
+}
+
+// renderLicenses renders licenses in the given output format, including CSV
+// using the same columns as exported files.
+func renderLicenses(output string, licenses keygenext.Licenses) error {
+	rows := licenseCSVRows(licenses)
+
+	if output == "csv" {
+		return printCSV(licenseCSVHeaders, rows)
+	}
+
+	return render(output, rendering{
+		value:   licensesJSON(licenses...),
+		headers: []string{"ID", "KEY", "NAME", "POLICY", "USER", "GROUP", "EXPIRY", "METADATA"},
+		rows:    rows,
+		wide:    3,
+	})
+}
+
+func licensesJSON(licenses ...keygenext.License) []map[string]interface{} {
+	out := []map[string]interface{}{}
+
+	for _, l := range licenses {
+		out = append(out, map[string]interface{}{
+			"id":       l.ID,
+			"key":      l.Key,
+			"name":     l.Name,
+			"status":   l.Status,
+			"policy":   l.PolicyID,
+			"user":     l.UserID,
+			"group":    l.GroupID,
+			"expiry":   l.Expiry,
+			"metadata": l.Metadata,
+			"created":  l.Created,
+			"updated":  l.Updated,
+		})
+	}
+
+	return out
+}
@@ -0,0 +1,451 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/mattn/go-isatty"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/vbauerster/mpb/v7"
+	"github.com/vbauerster/mpb/v7/decor"
+)
+
+// licenseBulkActions are the actions licenses bulk performs, along with their
+// past tense for reporting.
+var licenseBulkActions = map[string]string{
+	"suspend":   "suspended",
+	"reinstate": "reinstated",
+	"renew":     "renewed",
+	"revoke":    "revoked",
+	"extend":    "extended",
+}
+
+// maxBulkAttempts is how many times a rate limited license is attempted.
+const maxBulkAttempts = 5
+
+func newLicensesBulkCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "bulk <action>",
+		Short: "suspend, reinstate, renew, revoke or extend licenses in bulk",
+		Example: `  keygen licenses bulk suspend --from-file ids.txt --report report.csv
+  keygen licenses bulk extend --by 30d --from-file ids.txt
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: licensesBulkArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return licensesBulkRun(opts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+
+	cmd.Flags().StringVar(&opts.fromFile, "from-file", "", "file of license IDs or keys, one per line, or - for stdin (required)")
+	cmd.Flags().StringVar(&opts.by, "by", "", "duration to extend licenses by, e.g. 30d (required for extend)")
+	cmd.Flags().IntVar(&opts.concurrency, "concurrency", 4, "number of licenses to process at once, up to 16")
+	cmd.Flags().StringVar(&opts.report, "report", "", "write the result for each license to a CSV file")
+
+	cmd.MarkFlagRequired("from-file")
+
+	return cmd
+}
+
+func licensesBulkArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("action is required (one of: suspend, reinstate, renew, revoke, extend)")
+	}
+
+	if _, ok := licenseBulkActions[args[0]]; !ok {
+		return fmt.Errorf(`action "%s" is not supported (must be one of: suspend, reinstate, renew, revoke, extend)`, args[0])
+	}
+
+	return nil
+}
+
+// licenseBulkResult is the result of an action on one license.
+type licenseBulkResult struct {
+	id     string
+	expiry *time.Time
+	err    error
+}
+
+func licensesBulkRun(opts *CommandOptions, args []string) error {
+	action := args[0]
+
+	if opts.concurrency < 1 || opts.concurrency > 16 {
+		return fmt.Errorf(`concurrency "%d" is not acceptable (must be between 1 and 16)`, opts.concurrency)
+	}
+
+	var by time.Duration
+	switch {
+	case action == "extend" && opts.by == "":
+		return errors.New("--by is required to extend licenses")
+	case action == "extend":
+		d, err := parseWithin(opts.by)
+		if err != nil {
+			return err
+		}
+
+		by = d
+	case opts.by != "":
+		return fmt.Errorf("--by is only supported when extending licenses")
+	}
+
+	ids, err := readLicenseIDs(opts.fromFile)
+	if err != nil {
+		return err
+	}
+
+	if action == "revoke" {
+		affected := ids
+		if len(affected) > 10 {
+			affected = append(append([]string{}, ids[:10]...), glyph("…")+" and "+strconv.Itoa(len(ids)-10)+" more")
+		}
+
+		if err := opts.confirmAction("revoke "+strconv.Itoa(len(ids))+" licenses", affected, "revoke"); err != nil {
+			return err
+		}
+	}
+
+	report, err := newBulkReport(opts.report)
+	if err != nil {
+		return err
+	}
+	defer report.Close()
+
+	var progress *mpb.Progress
+	var bar *mpb.Bar
+
+	if isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd()) {
+		progress = mpb.New(mpb.WithOutput(os.Stderr), mpb.WithWidth(60), mpb.WithRefreshRate(180*time.Millisecond))
+		bar = progress.Add(
+			int64(len(ids)),
+			mpb.NewBarFiller(mpb.BarStyle().Rbound("|")),
+			mpb.BarRemoveOnComplete(),
+			mpb.PrependDecorators(
+				decor.Name(action+" "),
+				decor.CountersNoUnit("%d / %d"),
+			),
+			mpb.AppendDecorators(
+				decor.EwmaETA(decor.ET_STYLE_GO, 90),
+			),
+		)
+	}
+
+	limiter := &rateLimiter{}
+	jobs := make(chan string)
+	results := make(chan licenseBulkResult)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < opts.concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for id := range jobs {
+				started := time.Now()
+				expiry, err := bulkLicenseAction(opts, limiter, action, id, by)
+
+				results <- licenseBulkResult{id: id, expiry: expiry, err: err}
+
+				if bar != nil {
+					bar.Increment()
+					bar.DecoratorEwmaUpdate(time.Since(started))
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+
+		for _, id := range ids {
+			select {
+			case jobs <- id:
+			case <-opts.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	failed := []licenseBulkResult{}
+	succeeded := 0
+
+	var reportErr error
+
+	for r := range results {
+		if err := report.Write(r); err != nil && reportErr == nil {
+			reportErr = err
+		}
+
+		if r.err != nil {
+			failed = append(failed, r)
+
+			continue
+		}
+
+		succeeded++
+	}
+
+	if progress != nil {
+		bar.Abort(true)
+		progress.Wait()
+	}
+
+	if err := opts.ctx.Err(); err != nil {
+		return err
+	}
+
+	if reportErr != nil {
+		return fmt.Errorf(`report path "%s" is not writable (%s)`, opts.report, reportErr)
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	fmt.Println(licenseBulkActions[action] + " " + strconv.Itoa(succeeded) + " licenses")
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	// List the first few failures, leaving the rest to the report
+	for i, r := range failed {
+		if i == 10 && opts.report != "" {
+			fmt.Fprintln(os.Stderr, "  "+glyph("…")+" and "+strconv.Itoa(len(failed)-10)+" more in "+italic(opts.report))
+
+			break
+		}
+
+		fmt.Fprintln(os.Stderr, "  "+red(r.id)+" "+formatAPIError(r.err).Error())
+	}
+
+	return fmt.Errorf("%d of %d licenses could not be %s", len(failed), len(ids), licenseBulkActions[action])
+}
+
+// bulkLicenseAction performs an action on a license, retrying when rate
+// limited. The license's expiry is returned after it's renewed or extended.
+func bulkLicenseAction(opts *CommandOptions, limiter *rateLimiter, action string, id string, by time.Duration) (*time.Time, error) {
+	license := &keygenext.License{ID: id}
+
+	for attempt := 1; ; attempt++ {
+		if err := limiter.wait(opts.ctx); err != nil {
+			return nil, err
+		}
+
+		var err error
+
+		switch action {
+		case "suspend":
+			err = opts.client.SuspendLicense(opts.ctx, license)
+		case "reinstate":
+			err = opts.client.ReinstateLicense(opts.ctx, license)
+		case "renew":
+			err = opts.client.RenewLicense(opts.ctx, license)
+		case "revoke":
+			err = opts.client.RevokeLicense(opts.ctx, license)
+		case "extend":
+			err = extendLicense(opts, license, by)
+		}
+
+		if err == nil {
+			return license.Expiry, nil
+		}
+
+		if !keygenext.IsRateLimited(err) || attempt == maxBulkAttempts {
+			return nil, err
+		}
+
+		// Back off every worker, rather than having each of them hit the
+		// limit in turn
+		wait := err.(*keygenext.APIError).RetryAfter
+		if wait == 0 {
+			wait = time.Duration(attempt) * time.Second
+		}
+
+		limiter.pause(wait)
+	}
+}
+
+// extendLicense moves a license's expiry forward by a duration.
+func extendLicense(opts *CommandOptions, license *keygenext.License, by time.Duration) error {
+	l, err := opts.client.GetLicense(opts.ctx, license.ID)
+	if err != nil {
+		return err
+	}
+
+	*license = *l
+
+	if license.Expiry == nil {
+		return errors.New("license does not expire")
+	}
+
+	expiry := license.Expiry.Add(by)
+
+	return opts.client.UpdateLicenseExpiry(opts.ctx, license, &expiry)
+}
+
+// rateLimiter pauses workers once any of them is rate limited.
+type rateLimiter struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+func (r *rateLimiter) pause(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(r.until) {
+		r.until = until
+	}
+}
+
+func (r *rateLimiter) wait(ctx context.Context) error {
+	r.mu.Lock()
+	d := time.Until(r.until)
+	r.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// readLicenseIDs reads license IDs or keys, one per line, ignoring blank lines
+// and # comments, and duplicates.
+func readLicenseIDs(path string) ([]string, error) {
+	var r io.Reader
+
+	if path == "-" {
+		r = os.Stdin
+	} else {
+		p, err := homedir.Expand(path)
+		if err != nil {
+			return nil, fmt.Errorf(`path "%s" is not expandable (%s)`, path, err)
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, fmt.Errorf(`path "%s" is not readable (%s)`, path, err.(*os.PathError).Err)
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	ids := []string{}
+	seen := map[string]bool{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		id := strings.TrimSpace(scanner.Text())
+		if id == "" || strings.HasPrefix(id, "#") || seen[id] {
+			continue
+		}
+
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf(`path "%s" is not readable (%s)`, path, err)
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf(`path "%s" has no license IDs`, path)
+	}
+
+	return ids, nil
+}
+
+// bulkReport writes the result for each license to a CSV file as it's
+// processed. Writes are no-ops when there's no file.
+type bulkReport struct {
+	file *os.File
+	w    *csv.Writer
+}
+
+func newBulkReport(path string) (*bulkReport, error) {
+	if path == "" {
+		return &bulkReport{}, nil
+	}
+
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return nil, fmt.Errorf(`report path "%s" is not expandable (%s)`, path, err)
+	}
+
+	f, err := os.Create(p)
+	if err != nil {
+		return nil, fmt.Errorf(`report path "%s" is not writable (%s)`, path, err.(*os.PathError).Err)
+	}
+
+	r := &bulkReport{file: f, w: csv.NewWriter(f)}
+	if err := r.w.Write([]string{"id", "status", "expiry", "error"}); err != nil {
+		f.Close()
+
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *bulkReport) Write(result licenseBulkResult) error {
+	if r.w == nil {
+		return nil
+	}
+
+	status, expiry, message := "ok", "", ""
+	if result.expiry != nil {
+		expiry = result.expiry.Format(time.RFC3339)
+	}
+
+	if result.err != nil {
+		status, message = "failed", result.err.Error()
+	}
+
+	if err := r.w.Write([]string{result.id, status, expiry, message}); err != nil {
+		return err
+	}
+
+	r.w.Flush()
+
+	return r.w.Error()
+}
+
+func (r *bulkReport) Close() error {
+	if r.file == nil {
+		return nil
+	}
+
+	return r.file.Close()
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// licenseReportGroupings are the fields a license report can be grouped by.
+var licenseReportGroupings = []string{"policy", "user", "group"}
+
+// licenseReportHeaders are the leading columns of a license report, followed
+// by a metadata.<key> column for every metadata key.
+var licenseReportHeaders = []string{"id", "key", "name", "status", "policy", "policy_name", "user", "email", "group", "expiry", "days_left"}
+
+func newLicensesReportCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "report licenses which are due for renewal, e.g. for a CRM",
+		Example: `  keygen licenses report --expiring 60d --group-by policy --output csv > renewals.csv
+
+Columns:
+  id, key, name, status, policy, policy_name, user, email (the owner's), group,
+  expiry and days_left, followed by a metadata.<key> column for every metadata
+  key of the reported licenses.
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return licensesReportRun(opts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+
+	cmd.Flags().StringVar(&opts.expiringWithin, "expiring", "", "report licenses which expire within a duration, e.g. 60d or 12h (required)")
+	cmd.Flags().StringVar(&opts.groupBy, "group-by", "", "group licenses by a field, ordered by expiry within each group, one of: "+strings.Join(licenseReportGroupings, ", "))
+	cmd.Flags().StringVar(&opts.policy, "policy", "", "only report licenses for a policy")
+	cmd.Flags().StringSliceVar(&opts.metadataFilters, "metadata", []string{}, "comma seperated list of key=value pairs the licenses' metadata must match")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "table", renderOutputUsage+", csv")
+
+	cmd.MarkFlagRequired("expiring")
+
+	return cmd
+}
+
+func licensesReportRun(opts *CommandOptions) error {
+	if o := opts.output; o != "csv" {
+		if err := validateRenderOutput(o); err != nil {
+			return err
+		}
+	}
+
+	within, err := parseWithin(opts.expiringWithin)
+	if err != nil {
+		return err
+	}
+
+	if g := opts.groupBy; g != "" {
+		supported := false
+		for _, grouping := range licenseReportGroupings {
+			if grouping == g {
+				supported = true
+			}
+		}
+
+		if !supported {
+			return fmt.Errorf(`grouping "%s" is not supported (must be one of: %s)`, g, strings.Join(licenseReportGroupings, ", "))
+		}
+	}
+
+	metadata := keygenext.MetadataFilter{}
+	for _, kv := range opts.metadataFilters {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf(`metadata "%s" is not acceptable (must be key=value)`, kv)
+		}
+
+		metadata[parts[0]] = parts[1]
+	}
+
+	licenses, err := opts.client.ListLicenses(opts.ctx, &keygenext.LicenseFilter{
+		Policy:   opts.policy,
+		Metadata: metadata,
+		Paging:   keygenext.Paging{All: true},
+	})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	// The API has no filter for an expiry window, so it's applied locally
+	now := time.Now()
+	expiring := keygenext.Licenses{}
+
+	for _, l := range licenses {
+		if l.Expiry != nil && l.Expiry.After(now) && l.Expiry.Before(now.Add(within)) {
+			expiring = append(expiring, l)
+		}
+	}
+
+	// Owners and policies are listed once, rather than fetched per license
+	emails := map[string]string{}
+	policies := map[string]string{}
+
+	if len(expiring) != 0 {
+		users, err := opts.client.ListUsers(opts.ctx, &keygenext.ListParams{Paging: keygenext.Paging{All: true}})
+		if err != nil {
+			return formatAPIError(err)
+		}
+
+		for _, u := range users {
+			emails[u.ID] = u.Email
+		}
+
+		ps, err := opts.client.ListPolicies(opts.ctx, &keygenext.PolicyFilter{Paging: keygenext.Paging{All: true}})
+		if err != nil {
+			return formatAPIError(err)
+		}
+
+		for _, p := range ps {
+			policies[p.ID] = p.Name
+		}
+	}
+
+	groupKey := func(l *keygenext.License) string {
+		switch opts.groupBy {
+		case "policy":
+			return l.PolicyID
+		case "user":
+			return l.UserID
+		case "group":
+			return l.GroupID
+		default:
+			return ""
+		}
+	}
+
+	sort.SliceStable(expiring, func(i, j int) bool {
+		a, b := &expiring[i], &expiring[j]
+
+		if ka, kb := groupKey(a), groupKey(b); ka != kb {
+			return ka < kb
+		}
+
+		return a.Expiry.Before(*b.Expiry)
+	})
+
+	keys := licenseReportMetadataKeys(expiring)
+
+	headers := append([]string{}, licenseReportHeaders...)
+	for _, k := range keys {
+		headers = append(headers, "metadata."+k)
+	}
+
+	rows := [][]string{}
+	values := []map[string]interface{}{}
+
+	for _, l := range expiring {
+		days := int(l.Expiry.Sub(now).Hours() / 24)
+
+		row := []string{l.ID, l.Key, l.Name, l.Status, l.PolicyID, policies[l.PolicyID], l.UserID, emails[l.UserID], l.GroupID, l.Expiry.Format(time.RFC3339), strconv.Itoa(days)}
+		for _, k := range keys {
+			row = append(row, licenseReportMetadataValue(l.Metadata[k]))
+		}
+
+		rows = append(rows, row)
+		values = append(values, map[string]interface{}{
+			"id":          l.ID,
+			"key":         l.Key,
+			"name":        l.Name,
+			"status":      l.Status,
+			"policy":      l.PolicyID,
+			"policy_name": policies[l.PolicyID],
+			"user":        l.UserID,
+			"email":       emails[l.UserID],
+			"group":       l.GroupID,
+			"expiry":      l.Expiry,
+			"days_left":   days,
+			"metadata":    l.Metadata,
+		})
+	}
+
+	if opts.output == "csv" {
+		return printCSV(headers, rows)
+	}
+
+	// Metadata columns only fit wide tables
+	tableHeaders := []string{}
+	for _, h := range headers {
+		tableHeaders = append(tableHeaders, strings.ToUpper(h))
+	}
+
+	return render(opts.output, rendering{
+		value:   values,
+		headers: tableHeaders,
+		rows:    rows,
+		wide:    len(keys),
+	})
+}
+
+// licenseReportMetadataKeys returns the sorted metadata keys of licenses.
+func licenseReportMetadataKeys(licenses keygenext.Licenses) []string {
+	seen := map[string]bool{}
+	keys := []string{}
+
+	for _, l := range licenses {
+		for k := range l.Metadata {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// licenseReportMetadataValue formats a metadata value for a report column,
+// where anything but a string is JSON encoded.
+func licenseReportMetadataValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		b, _ := json.Marshal(v)
+
+		return string(b)
+	}
+}
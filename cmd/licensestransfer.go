@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/keygen-sh/keygen-go"
+	"github.com/spf13/cobra"
+)
+
+func newLicensesTransferCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "transfer <id>",
+		Short: "transfer a license to another user or group",
+		Example: `  keygen licenses transfer <id> --to-user jane@example.com
+  keygen licenses transfer <id> --to-user jane@example.com --to-group <group-id>
+
+Users which don't exist yet are created and emailed an invite, unless
+--send-invite=false is given.
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: licensesIDArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return licensesTransferRun(opts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+
+	cmd.Flags().StringVar(&opts.user, "to-user", "", "user ID or email to transfer the license to, creating the user when an email doesn't exist")
+	cmd.Flags().StringVar(&opts.group, "to-group", "", "group ID to move the license into")
+	cmd.Flags().BoolVar(&opts.invite, "send-invite", true, "email users created for the transfer an invite to set their password")
+
+	return cmd
+}
+
+func licensesIDArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("license ID or key is required")
+	}
+
+	return nil
+}
+
+func licensesTransferRun(opts *CommandOptions, args []string) error {
+	if opts.user == "" && opts.group == "" {
+		return errors.New("--to-user or --to-group is required")
+	}
+
+	license, err := opts.client.GetLicense(opts.ctx, args[0])
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	var user *keygenext.User
+	var create bool
+
+	if u := opts.user; u != "" {
+		user, err = opts.client.GetUser(opts.ctx, u)
+		switch {
+		case err == nil:
+		case errors.Is(err, keygen.ErrNotFound) && strings.Contains(u, "@"):
+			user, create = &keygenext.User{Email: u}, true
+		case errors.Is(err, keygen.ErrNotFound):
+			return fmt.Errorf(`user "%s" does not exist (give an email to create them)`, u)
+		default:
+			return formatAPIError(err)
+		}
+	}
+
+	var group *keygenext.Group
+	if g := opts.group; g != "" {
+		group, err = opts.client.GetGroup(opts.ctx, g)
+		if err != nil {
+			return formatAPIError(err)
+		}
+	}
+
+	affected := []string{}
+	if user != nil {
+		from := license.UserID
+		if from == "" {
+			from = "no user"
+		}
+
+		to := user.Email + " (" + user.ID + ")"
+		if create {
+			to = user.Email + " (new user)"
+		}
+
+		affected = append(affected, "user: "+from+" "+glyph("→")+" "+to)
+	}
+
+	if group != nil {
+		from := license.GroupID
+		if from == "" {
+			from = "no group"
+		}
+
+		affected = append(affected, "group: "+from+" "+glyph("→")+" "+group.Name+" ("+group.ID+")")
+	}
+
+	if err := opts.confirmAction("transfer license "+license.ID, affected, ""); err != nil {
+		return err
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	if create {
+		if err := opts.client.CreateUser(opts.ctx, user); err != nil {
+			return formatAPIError(err)
+		}
+
+		if opts.invite {
+			if err := opts.client.InviteUser(opts.ctx, user); err != nil {
+				return formatAPIError(err)
+			}
+
+			fmt.Fprintln(os.Stderr, "invited user "+italic(user.ID)+" ("+user.Email+")")
+		} else {
+			fmt.Fprintln(os.Stderr, "created user "+italic(user.ID)+" ("+user.Email+")")
+		}
+	}
+
+	if user != nil && user.ID != license.UserID {
+		if err := opts.client.AttachUserLicense(opts.ctx, user, license.ID); err != nil {
+			return formatAPIError(err)
+		}
+	}
+
+	if group != nil && group.ID != license.GroupID {
+		if err := opts.client.AttachGroupLicense(opts.ctx, group, license.ID); err != nil {
+			return formatAPIError(err)
+		}
+	}
+
+	switch {
+	case user != nil && group != nil:
+		fmt.Println("transferred license " + italic(license.ID) + " to user " + italic(user.ID) + " in group " + italic(group.ID))
+	case user != nil:
+		fmt.Println("transferred license " + italic(license.ID) + " to user " + italic(user.ID))
+	default:
+		fmt.Println("transferred license " + italic(license.ID) + " to group " + italic(group.ID))
+	}
+
+	return nil
+}
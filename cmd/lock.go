@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// Releases are locked using their metadata, since the API has no notion of an
+// immutable release. dist, queue flush and bundles refuse to publish over a
+// locked release.
+const (
+	lockedMetadataKey   = "locked"
+	lockedAtMetadataKey = "lockedAt"
+)
+
+func newReleasesLockCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "lock <id>...",
+		Short: "lock releases so that dist refuses to publish over them",
+		Example: `  keygen releases lock 1fddcec8-8dd3-4d8d-9b16-215cac0f9b52 \
+      --account '1fddcec8-8dd3-4d8d-9b16-215cac0f9b52' \
+      --token 'prod-xxx'
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: releasesIDArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return releasesLockRun(opts, args, true)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+
+	return cmd
+}
+
+func newReleasesUnlockCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "unlock <id>...",
+		Short: "unlock releases so that dist can publish over them again",
+		Example: `  keygen releases unlock 1fddcec8-8dd3-4d8d-9b16-215cac0f9b52 \
+      --account '1fddcec8-8dd3-4d8d-9b16-215cac0f9b52' \
+      --token 'prod-xxx'
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: releasesIDArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return releasesLockRun(opts, args, false)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+
+	return cmd
+}
+
+func releasesIDArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("release ID is required")
+	}
+
+	return nil
+}
+
+func releasesLockRun(opts *CommandOptions, args []string, lock bool) error {
+	releases := []*keygenext.Release{}
+	for _, id := range args {
+		release, err := opts.client.GetRelease(opts.ctx, id)
+		if err != nil {
+			return formatAPIError(err)
+		}
+
+		releases = append(releases, release)
+	}
+
+	// Unlocking removes the guardrail for GA versions, so make sure it's meant
+	if !lock {
+		affected := []string{}
+		for _, r := range releases {
+			affected = append(affected, r.ID+" (v"+r.Version+", "+r.Filename+")")
+		}
+
+		if err := opts.confirmAction("unlock these releases", affected, ""); err != nil {
+			return err
+		}
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	for _, release := range releases {
+		if isReleaseLocked(release) == lock {
+			if lock {
+				fmt.Println("release " + italic(release.ID) + " is already locked")
+			} else {
+				fmt.Println("release " + italic(release.ID) + " is not locked")
+			}
+
+			continue
+		}
+
+		metadata := map[string]interface{}{}
+		for k, v := range release.Metadata {
+			metadata[k] = v
+		}
+
+		if lock {
+			metadata[lockedMetadataKey] = true
+			metadata[lockedAtMetadataKey] = time.Now().UTC().Format(time.RFC3339)
+		} else {
+			delete(metadata, lockedMetadataKey)
+			delete(metadata, lockedAtMetadataKey)
+		}
+
+		if err := opts.client.UpdateReleaseMetadata(opts.ctx, release, metadata); err != nil {
+			return formatAPIError(err)
+		}
+
+		if lock {
+			fmt.Println("locked release " + italic(release.ID) + " (v" + release.Version + ")")
+		} else {
+			fmt.Println("unlocked release " + italic(release.ID) + " (v" + release.Version + ")")
+		}
+	}
+
+	return nil
+}
+
+// isReleaseLocked reports whether a release is locked.
+func isReleaseLocked(release *keygenext.Release) bool {
+	locked, _ := release.Metadata[lockedMetadataKey].(bool)
+
+	return locked
+}
+
+// checkReleaseLocks refuses to publish a release when a release of its version
+// is locked, other than ones published by this run, e.g. the other artifacts
+// of a multi-artifact release. Since a locked release can't be ruled out when
+// the releases can't be listed, that fails too.
+func checkReleaseLocks(opts *CommandOptions, release *keygenext.Release) error {
+	existing, err := opts.client.ListReleases(opts.ctx, &keygenext.ReleaseFilter{Product: release.ProductID, Version: release.Version, Limit: 100})
+	if err != nil {
+		return fmt.Errorf("locked releases could not be checked (%w)", formatAPIError(err))
+	}
+
+	published := map[string]bool{}
+	for _, r := range opts.published {
+		published[r.ID] = true
+	}
+
+	for i := range existing {
+		r := &existing[i]
+		if published[r.ID] || !isReleaseLocked(r) {
+			continue
+		}
+
+		return fmt.Errorf(`release "%s" is locked (v%s, %s), run keygen releases unlock %s to publish over it`, r.ID, r.Version, r.Filename, r.ID)
+	}
+
+	return nil
+}
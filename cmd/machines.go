@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+func newMachinesCmd(s *session) *cobra.Command {
+	heartbeatsOpts := s.newOptions()
+	heartbeatsCmd := &cobra.Command{
+		Use:   "heartbeats",
+		Short: "show the heartbeat status of a license's machines, optionally deleting dead ones",
+		Example: `  keygen machines heartbeats --license <id> --watch
+  keygen machines heartbeats --license <id> --reap
+
+Machines whose heartbeat is DEAD still hold a seat until they're deleted, e.g.
+after a crash, which --reap does once confirmed.
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return machinesHeartbeatsRun(heartbeatsOpts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	heartbeatsCmd.Flags().StringVar(&heartbeatsOpts.license, "license", "", "license to show the machines of, by ID (required)")
+	heartbeatsCmd.Flags().BoolVar(&heartbeatsOpts.live, "watch", false, "keep refreshing the heartbeat status until interrupted")
+	heartbeatsCmd.Flags().DurationVar(&heartbeatsOpts.interval, "interval", 5*time.Second, "how often --watch refreshes the heartbeat status")
+	heartbeatsCmd.Flags().BoolVar(&heartbeatsOpts.reap, "reap", false, "delete machines whose heartbeat is dead, freeing their seats")
+	heartbeatsCmd.Flags().StringVarP(&heartbeatsOpts.output, "output", "o", "table", renderOutputUsage)
+
+	heartbeatsCmd.MarkFlagRequired("license")
+
+	cmd := &cobra.Command{
+		Use:   "machines",
+		Short: "manage machines",
+	}
+
+	for _, c := range []*cobra.Command{heartbeatsCmd} {
+		addAccountFlags(c, s)
+
+		cmd.AddCommand(c)
+	}
+
+	return cmd
+}
+
+func machinesHeartbeatsRun(opts *CommandOptions) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	if opts.live {
+		if isStructuredOutput(opts.output) {
+			return fmt.Errorf(`flag "--watch" cannot be used together with output format "%s"`, opts.output)
+		}
+
+		if opts.interval <= 0 {
+			return fmt.Errorf(`interval "%s" is not acceptable (must be positive)`, opts.interval)
+		}
+	}
+
+	for {
+		machines, err := opts.client.ListMachines(opts.ctx, &keygenext.MachineFilter{
+			License: opts.license,
+			Paging:  keygenext.Paging{All: true},
+		})
+		if err != nil {
+			return formatAPIError(err)
+		}
+
+		if opts.live {
+			fmt.Print("\033[H\033[2J")
+		}
+
+		if err := renderMachineHeartbeats(opts.output, machines); err != nil {
+			return err
+		}
+
+		if opts.reap {
+			if err := reapDeadMachines(opts, machines); err != nil {
+				return err
+			}
+		}
+
+		if !opts.live {
+			return nil
+		}
+
+		select {
+		case <-opts.ctx.Done():
+			return nil
+		case <-time.After(opts.interval):
+		}
+	}
+}
+
+// reapDeadMachines deletes the machines whose heartbeat is dead, once
+// confirmed, since they otherwise hold on to their license's seats.
+func reapDeadMachines(opts *CommandOptions, machines keygenext.Machines) error {
+	dead := keygenext.Machines{}
+	affected := []string{}
+
+	for _, m := range machines {
+		if m.HeartbeatStatus == "DEAD" {
+			dead = append(dead, m)
+			affected = append(affected, machineLabel(&m)+" (last seen "+formatLastSeen(m.LastHeartbeat, time.Now())+")")
+		}
+	}
+
+	if len(dead) == 0 {
+		return nil
+	}
+
+	if err := opts.confirmAction("delete "+strconv.Itoa(len(dead))+" dead machines", affected, ""); err != nil {
+		return err
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	for i := range dead {
+		m := &dead[i]
+
+		if err := opts.client.DeleteMachine(opts.ctx, m); err != nil {
+			return fmt.Errorf("machine %s could not be deleted (%w)", m.ID, formatAPIError(err))
+		}
+
+		// Status lines would corrupt structured output
+		if isStructuredOutput(opts.output) {
+			fmt.Fprintln(os.Stderr, "deleted dead machine "+m.ID)
+		} else {
+			fmt.Println("deleted dead machine " + italic(m.ID) + " (" + machineLabel(m) + ")")
+		}
+	}
+
+	return nil
+}
+
+// renderMachineHeartbeats renders machines along with their heartbeat status
+// and when they were last seen.
+func renderMachineHeartbeats(output string, machines keygenext.Machines) error {
+	now := time.Now()
+
+	rows := [][]string{}
+	values := []map[string]interface{}{}
+
+	for _, m := range machines {
+		status := m.HeartbeatStatus
+		if !m.RequireHeartbeat && status == "NOT_STARTED" {
+			status = "NOT_REQUIRED"
+		}
+
+		next := ""
+		if m.NextHeartbeat != nil {
+			next = m.NextHeartbeat.Format(time.RFC3339)
+		}
+
+		rows = append(rows, []string{m.ID, m.Name, status, formatLastSeen(m.LastHeartbeat, now), next, m.Fingerprint, m.Hostname, m.Platform, m.Created.Format(time.RFC3339)})
+		values = append(values, map[string]interface{}{
+			"id":               m.ID,
+			"name":             m.Name,
+			"fingerprint":      m.Fingerprint,
+			"hostname":         m.Hostname,
+			"platform":         m.Platform,
+			"heartbeat_status": m.HeartbeatStatus,
+			"last_heartbeat":   m.LastHeartbeat,
+			"next_heartbeat":   m.NextHeartbeat,
+			"license":          m.LicenseID,
+			"created":          m.Created,
+		})
+	}
+
+	return render(output, rendering{
+		value:   values,
+		headers: []string{"ID", "NAME", "HEARTBEAT", "LAST SEEN", "NEXT DUE", "FINGERPRINT", "HOSTNAME", "PLATFORM", "CREATED"},
+		rows:    rows,
+		wide:    4,
+	})
+}
+
+// machineLabel returns a machine's name, falling back to its hostname or
+// fingerprint.
+func machineLabel(m *keygenext.Machine) string {
+	for _, v := range []string{m.Name, m.Hostname, m.Fingerprint} {
+		if v != "" {
+			return v
+		}
+	}
+
+	return m.ID
+}
+
+// formatLastSeen formats when a machine's last heartbeat was relative to now,
+// e.g. 3m ago.
+func formatLastSeen(t *time.Time, now time.Time) string {
+	if t == nil {
+		return "never"
+	}
+
+	d := now.Sub(*t)
+
+	switch {
+	case d < time.Minute:
+		return strconv.Itoa(int(d.Seconds())) + "s ago"
+	case d < time.Hour:
+		return strconv.Itoa(int(d.Minutes())) + "m ago"
+	case d < 48*time.Hour:
+		return strconv.Itoa(int(d.Hours())) + "h ago"
+	default:
+		return strconv.Itoa(int(d.Hours()/24)) + "d ago"
+	}
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+)
+
+// manifestSizePrefix starts the comment lines of a manifest which list the
+// filesizes.
+const manifestSizePrefix = "# size "
+
+// manifestFilename returns the filename of a version's SUMS manifest. Release
+// filenames are unique per product, so it includes the version.
+func manifestFilename(version string) string {
+	return "SHA512SUMS-" + version
+}
+
+// releaseSHA512 returns a release's hex-encoded SHA-512 digest, decoded from
+// its base64 checksum.
+func releaseSHA512(release *keygenext.Release) (string, error) {
+	for _, enc := range []*base64.Encoding{base64.RawStdEncoding, base64.StdEncoding} {
+		if sum, err := enc.DecodeString(release.Checksum); err == nil && len(sum) == 64 {
+			return hex.EncodeToString(sum), nil
+		}
+	}
+
+	return "", fmt.Errorf(`checksum of "%s" is not a SHA-512 digest (the manifest requires one)`, release.Filename)
+}
+
+// buildManifest returns a SUMS manifest for the releases, in the format used
+// by sha512sum, i.e. one "<hex digest>  <filename>" line per release sorted by
+// filename, so that it can be checked using `sha512sum -c`. Each line is
+// preceded by a "# size <bytes>  <filename>" comment, which sha512sum skips,
+// so that the signature covers the filesizes too.
+func buildManifest(releases []*keygenext.Release) ([]byte, error) {
+	sorted := append([]*keygenext.Release{}, releases...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Filename < sorted[j].Filename
+	})
+
+	var b strings.Builder
+	for _, r := range sorted {
+		sum, err := releaseSHA512(r)
+		if err != nil {
+			return nil, err
+		}
+
+		b.WriteString(manifestSizePrefix + strconv.FormatInt(r.Filesize, 10) + "  " + r.Filename + "\n")
+		b.WriteString(sum + "  " + r.Filename + "\n")
+	}
+
+	return []byte(b.String()), nil
+}
+
+// publishManifest publishes a signed SUMS manifest of the version's published
+// releases as a platformless release of the same version, so that clients can
+// verify every artifact using a single signature check. Filesizes are listed
+// in the manifest, and recorded in its metadata too.
+func publishManifest(opts *CommandOptions, releases []*keygenext.Release) (*keygenext.Release, error) {
+	if len(releases) == 0 {
+		return nil, errors.New("manifest has no releases")
+	}
+
+	first := releases[0]
+	filename := manifestFilename(first.Version)
+
+	filenames := []string{}
+	filesizes := map[string]interface{}{}
+
+	for _, r := range releases {
+		if r.Filename == filename {
+			return nil, fmt.Errorf(`filename "%s" is not acceptable (it's reserved for the manifest)`, r.Filename)
+		}
+
+		filenames = append(filenames, r.Filename)
+		filesizes[r.Filename] = r.Filesize
+	}
+
+	sort.Strings(filenames)
+
+	manifest, err := buildManifest(releases)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := ioutil.TempFile("", "keygen-*.sums")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := tmp.Write(manifest); err != nil {
+		return nil, err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	digest, err := hashFile(tmp, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := opts.loadSigner(opts.signingKeyPath, opts.signingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := opts.calculateSignature(signer, opts.signingAlgorithm, tmp, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	release := &keygenext.Release{
+		Name:        first.Name,
+		Version:     first.Version,
+		Filename:    filename,
+		Filesize:    int64(len(manifest)),
+		Filetype:    "txt",
+		Channel:     first.Channel,
+		Checksum:    digest.checksum(),
+		Signature:   signature,
+		ContentType: "text/plain; charset=utf-8",
+		ProductID:   first.ProductID,
+		PackageID:   first.PackageID,
+		Constraints: first.Constraints,
+		Metadata: map[string]interface{}{
+			"manifestFor": filenames,
+			"filesizes":   filesizes,
+		},
+	}
+
+	if opts.signingAlgorithm == "ed25519ph" {
+		release.Metadata["signingContext"] = opts.ed25519phContext()
+	}
+
+	if _, err := publishRelease(opts, release, tmp); err != nil {
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// distManifest publishes the manifest for the releases published by dist.
+func distManifest(opts *CommandOptions) error {
+	release, err := publishManifest(opts, opts.published)
+	if err != nil {
+		return fmt.Errorf("manifest could not be published (%s)", err)
+	}
+
+	opts.summarizedManifests = append(opts.summarizedManifests, opts.summarizeRelease(release, nil))
+
+	if opts.output == "json" {
+		return opts.printDistJSON(map[string]interface{}{
+			"manifest": map[string]interface{}{
+				"id":        release.ID,
+				"version":   release.Version,
+				"filename":  release.Filename,
+				"checksum":  release.Checksum,
+				"signature": release.Signature,
+				"releases":  release.Metadata["manifestFor"],
+			},
+		})
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("published manifest " + italic(release.ID) + " (" + release.Filename + ")")
+
+	return nil
+}
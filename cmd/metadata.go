@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseMetadata parses --metadata key=value pairs. An empty value is kept, so
+// that updates can remove a key by setting it to nothing.
+func parseMetadata(pairs []string) (map[string]string, error) {
+	metadata := map[string]string{}
+
+	for _, kv := range pairs {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf(`metadata "%s" is not acceptable (must be key=value)`, kv)
+		}
+
+		metadata[parts[0]] = parts[1]
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,34 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// maxUnmappedSize is the largest file read into memory where files can't be
+// mapped into memory, e.g. on Windows.
+const maxUnmappedSize = 512 * 1024 * 1024
+
+// mapFile reads a file into memory, refusing files larger than
+// maxUnmappedSize rather than running out of memory.
+func mapFile(file *os.File) (b []byte, unmap func(), err error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if size := info.Size(); size > maxUnmappedSize {
+		return nil, nil, fmt.Errorf("file is too large to sign using ed25519 on this platform (%s, max %s)", formatBytes(size), formatBytes(maxUnmappedSize))
+	}
+
+	b, err = ioutil.ReadAll(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return b, func() {}, nil
+}
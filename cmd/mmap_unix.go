@@ -0,0 +1,35 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+// +build linux darwin freebsd netbsd openbsd
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// mapFile maps a file into memory read-only, so that large files are paged in
+// by the kernel rather than copied onto the heap. Call unmap when done.
+func mapFile(file *os.File) (b []byte, unmap func(), err error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	size := info.Size()
+	if size == 0 {
+		return []byte{}, func() {}, nil
+	}
+
+	if int64(int(size)) != size {
+		return nil, nil, fmt.Errorf("file is too large to map into memory (%s)", formatBytes(size))
+	}
+
+	b, err = syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return b, func() { syscall.Munmap(b) }, nil
+}
@@ -0,0 +1,552 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+// mockReleasesFixture is the fixture file a mock server is seeded from, in the
+// format output by `keygen releases ls --all -o json`.
+const mockReleasesFixture = "releases.json"
+
+// mockRelease is a release served by the mock server.
+type mockRelease struct {
+	ID        string                 `json:"id"`
+	Version   string                 `json:"version"`
+	Channel   string                 `json:"channel"`
+	Platform  string                 `json:"platform"`
+	Filetype  string                 `json:"filetype"`
+	Filename  string                 `json:"filename"`
+	Filesize  int64                  `json:"filesize"`
+	Checksum  string                 `json:"checksum"`
+	Signature string                 `json:"signature"`
+	Metadata  map[string]interface{} `json:"metadata"`
+	Yanked    *time.Time             `json:"yanked"`
+	Created   *time.Time             `json:"created"`
+}
+
+func newMockCmd(s *session) *cobra.Command {
+	exportOpts := s.newOptions()
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "export a product's releases as fixtures for mock serve",
+		Example: `  keygen mock export --fixtures fixtures/ --download
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return mockExportRun(exportOpts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(exportCmd, s)
+	addProductFlag(exportCmd, s)
+
+	exportCmd.Flags().StringVar(&exportOpts.fixtures, "fixtures", "", "directory to write the fixtures to (required)")
+	exportCmd.Flags().BoolVar(&exportOpts.download, "download", false, "also download each release's artifact into the directory, so that mock serve can serve it")
+
+	exportCmd.MarkFlagRequired("fixtures")
+
+	serveOpts := s.newOptions()
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "serve a local emulation of the releases, artifacts and upgrade API, e.g. to test an app's updater",
+		Example: `  keygen mock serve --fixtures fixtures/ --listen localhost:8080
+
+Fixtures:
+  releases.json, as written by mock export or keygen releases ls --all -o json,
+  along with any artifacts, named by their release's filename. Requests for any
+  account are served, without authentication, at the /v1/accounts/<account>
+  prefix, so apps can be pointed at the server using their API host.
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return mockServeRun(serveOpts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	serveCmd.Flags().StringVar(&serveOpts.fixtures, "fixtures", "", "directory containing releases.json and artifacts to serve (required)")
+	serveCmd.Flags().StringVar(&serveOpts.listen, "listen", "localhost:8080", "address to serve the API on")
+
+	serveCmd.MarkFlagRequired("fixtures")
+
+	cmd := &cobra.Command{
+		Use:   "mock",
+		Short: "emulate the Keygen API locally for integration tests",
+	}
+
+	cmd.AddCommand(exportCmd, serveCmd)
+
+	return cmd
+}
+
+func mockExportRun(opts *CommandOptions) error {
+	dir, err := homedir.Expand(opts.fixtures)
+	if err != nil {
+		return fmt.Errorf(`fixtures path "%s" is not expandable (%s)`, opts.fixtures, err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf(`fixtures path "%s" is not writable (%s)`, opts.fixtures, err)
+	}
+
+	releases, err := opts.client.ListReleases(opts.ctx, &keygenext.ReleaseFilter{
+		Product: opts.productID,
+		Paging:  keygenext.Paging{All: true},
+	})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	fixtures := []*mockRelease{}
+	for _, r := range releases {
+		fixtures = append(fixtures, &mockRelease{
+			ID:        r.ID,
+			Version:   r.Version,
+			Channel:   r.Channel,
+			Platform:  r.Platform,
+			Filetype:  r.Filetype,
+			Filename:  r.Filename,
+			Filesize:  r.Filesize,
+			Checksum:  r.Checksum,
+			Signature: r.Signature,
+			Metadata:  r.Metadata,
+			Yanked:    r.Yanked,
+			Created:   r.Created,
+		})
+	}
+
+	b, err := json.MarshalIndent(fixtures, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, mockReleasesFixture), b, 0644); err != nil {
+		return fmt.Errorf(`fixtures path "%s" is not writable (%s)`, opts.fixtures, err)
+	}
+
+	if opts.download {
+		for i := range releases {
+			if err := downloadMockArtifact(opts, &releases[i], dir); err != nil {
+				return err
+			}
+		}
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("exported " + strconv.Itoa(len(releases)) + " releases to " + italic(opts.fixtures))
+
+	return nil
+}
+
+// downloadMockArtifact downloads a release's artifact into dir, named by the
+// release's filename.
+func downloadMockArtifact(opts *CommandOptions, release *keygenext.Release, dir string) error {
+	artifact, err := opts.client.GetReleaseArtifact(opts.ctx, release)
+	if err != nil {
+		return fmt.Errorf("artifact for %s could not be downloaded (%w)", release.Filename, formatAPIError(err))
+	}
+
+	body, _, err := artifact.Download(opts.ctx, 0, 0)
+	if err != nil {
+		return fmt.Errorf("artifact for %s could not be downloaded (%s)", release.Filename, err)
+	}
+	defer body.Close()
+
+	f, err := os.Create(filepath.Join(dir, filepath.Base(release.Filename)))
+	if err != nil {
+		return fmt.Errorf(`fixtures path "%s" is not writable (%s)`, opts.fixtures, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, body); err != nil {
+		return fmt.Errorf("artifact for %s could not be downloaded (%s)", release.Filename, err)
+	}
+
+	return nil
+}
+
+func mockServeRun(opts *CommandOptions) error {
+	dir, err := homedir.Expand(opts.fixtures)
+	if err != nil {
+		return fmt.Errorf(`fixtures path "%s" is not expandable (%s)`, opts.fixtures, err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, mockReleasesFixture))
+	if err != nil {
+		return fmt.Errorf(`fixtures path "%s" is not readable (%s)`, opts.fixtures, err.(*os.PathError).Err)
+	}
+
+	releases := []*mockRelease{}
+	if err := json.Unmarshal(b, &releases); err != nil {
+		return fmt.Errorf(`fixture "%s" is not acceptable (%s)`, mockReleasesFixture, err)
+	}
+
+	for i, r := range releases {
+		if r.ID == "" || r.Version == "" || r.Filename == "" {
+			return fmt.Errorf(`fixture "%s" is not acceptable (release %d must have an id, version and filename)`, mockReleasesFixture, i+1)
+		}
+	}
+
+	listener, err := net.Listen("tcp", opts.listen)
+	if err != nil {
+		return fmt.Errorf(`address "%s" is not listenable (%s)`, opts.listen, err)
+	}
+
+	host := "http://" + listener.Addr().String()
+	server := &http.Server{Handler: &mockServer{releases: releases, dir: dir, host: host}}
+
+	go func() {
+		<-opts.ctx.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		server.Shutdown(ctx)
+	}()
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("serving " + strconv.Itoa(len(releases)) + " releases at " + italic(host) + " (e.g. --host " + host + ")")
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// mockServer emulates the releases, artifacts and upgrade endpoints used by
+// the CLI and by apps' updaters, along with a storage provider serving the
+// artifacts' files.
+type mockServer struct {
+	releases []*mockRelease
+	dir      string
+	host     string
+}
+
+func (m *mockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Artifacts are redirected to /files/<release>/<filename>
+	if strings.HasPrefix(r.URL.Path, "/files/") {
+		m.serveFile(w, r)
+
+		return
+	}
+
+	// Paths are /v1/accounts/<account>/<resource>...
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 4 || parts[0] != "v1" || parts[1] != "accounts" {
+		m.writeError(w, http.StatusNotFound, "NOT_FOUND", "the requested endpoint was not found")
+
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		m.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "the mock server is read-only")
+
+		return
+	}
+
+	account, path := parts[2], parts[3:]
+
+	switch {
+	case len(path) == 1 && path[0] == "releases":
+		m.listReleases(w, r, account)
+	case len(path) == 3 && path[0] == "releases" && path[1] == "actions" && path[2] == "upgrade":
+		m.upgrade(w, r, account)
+	case len(path) == 2 && path[0] == "releases":
+		if release := m.findRelease(path[1]); release != nil {
+			m.writeJSON(w, http.StatusOK, map[string]interface{}{"data": m.releaseData(account, release)})
+
+			return
+		}
+
+		m.writeError(w, http.StatusNotFound, "NOT_FOUND", "the requested release was not found")
+	case len(path) == 3 && path[0] == "releases" && path[2] == "artifact", len(path) == 2 && path[0] == "artifacts":
+		id := path[1]
+		if release := m.findRelease(id); release != nil {
+			m.redirectArtifact(w, account, release)
+
+			return
+		}
+
+		m.writeError(w, http.StatusNotFound, "NOT_FOUND", "the requested artifact was not found")
+	default:
+		m.writeError(w, http.StatusNotFound, "NOT_FOUND", "the requested endpoint was not found")
+	}
+}
+
+// findRelease finds a release by its ID, or by its version or filename like
+// the API's release lookups.
+func (m *mockServer) findRelease(id string) *mockRelease {
+	for _, r := range m.releases {
+		if r.ID == id || r.Filename == id {
+			return r
+		}
+	}
+
+	for _, r := range m.releases {
+		if r.Version == id {
+			return r
+		}
+	}
+
+	return nil
+}
+
+func (m *mockServer) listReleases(w http.ResponseWriter, r *http.Request, account string) {
+	q := r.URL.Query()
+
+	matches := []*mockRelease{}
+	for _, release := range m.releases {
+		switch {
+		case q.Get("version") != "" && release.Version != q.Get("version"):
+		case q.Get("channel") != "" && release.Channel != q.Get("channel"):
+		case q.Get("platform") != "" && release.Platform != q.Get("platform"):
+		case q.Get("filetype") != "" && release.Filetype != q.Get("filetype"):
+		default:
+			matches = append(matches, release)
+		}
+	}
+
+	// Like the API, releases are listed newest first
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+
+		return a.Created != nil && b.Created != nil && a.Created.After(*b.Created)
+	})
+
+	size, _ := strconv.Atoi(q.Get("page[size]"))
+	page, _ := strconv.Atoi(q.Get("page[number]"))
+	if size <= 0 {
+		size, _ = strconv.Atoi(q.Get("limit"))
+	}
+	if size <= 0 || size > 100 {
+		size = 10
+	}
+	if page <= 0 {
+		page = 1
+	}
+
+	start, end := (page-1)*size, page*size
+	if start > len(matches) {
+		start = len(matches)
+	}
+	if end > len(matches) {
+		end = len(matches)
+	}
+
+	data := []interface{}{}
+	for _, release := range matches[start:end] {
+		data = append(data, m.releaseData(account, release))
+	}
+
+	links := map[string]interface{}{"next": nil}
+	if end < len(matches) {
+		next := *r.URL
+		nq := next.Query()
+		nq.Set("page[number]", strconv.Itoa(page+1))
+		nq.Set("page[size]", strconv.Itoa(size))
+		next.RawQuery = nq.Encode()
+
+		links["next"] = next.String()
+	}
+
+	m.writeJSON(w, http.StatusOK, map[string]interface{}{"data": data, "links": links})
+}
+
+// upgrade resolves an upgrade the way the API does, i.e. the greatest version
+// above the app's version on its channel (including more stable channels),
+// platform and filetype, skipping yanked releases.
+func (m *mockServer) upgrade(w http.ResponseWriter, r *http.Request, account string) {
+	q := r.URL.Query()
+
+	current, err := semver.NewVersion(q.Get("version"))
+	if err != nil {
+		m.writeError(w, http.StatusBadRequest, "VERSION_INVALID", "version must be a valid semver version")
+
+		return
+	}
+
+	channel := q.Get("channel")
+	if channel == "" {
+		channel = "stable"
+	}
+
+	channels, ok := upgradeChannels[channel]
+	if !ok {
+		m.writeError(w, http.StatusBadRequest, "CHANNEL_INVALID", "channel must be one of: stable, rc, beta, alpha, dev")
+
+		return
+	}
+
+	included := map[string]bool{}
+	for _, c := range channels {
+		included[c] = true
+	}
+
+	var constraint *semver.Constraints
+	if c := q.Get("constraint"); c != "" {
+		constraint, err = semver.NewConstraint(c)
+		if err != nil {
+			m.writeError(w, http.StatusBadRequest, "CONSTRAINT_INVALID", "constraint must be a valid semver constraint")
+
+			return
+		}
+	}
+
+	var latest *mockRelease
+	var latestVersion *semver.Version
+
+	for _, release := range m.releases {
+		if release.Yanked != nil || !included[release.Channel] {
+			continue
+		}
+
+		if p := q.Get("platform"); p != "" && release.Platform != p {
+			continue
+		}
+
+		if f := q.Get("filetype"); f != "" && release.Filetype != f {
+			continue
+		}
+
+		v, err := semver.NewVersion(release.Version)
+		if err != nil || !v.GreaterThan(current) {
+			continue
+		}
+
+		if constraint != nil {
+			core, _ := v.SetPrerelease("")
+			core, _ = core.SetMetadata("")
+			if !constraint.Check(&core) {
+				continue
+			}
+		}
+
+		if latestVersion == nil || v.GreaterThan(latestVersion) {
+			latest, latestVersion = release, v
+		}
+	}
+
+	if latest == nil {
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+
+	m.redirectArtifact(w, account, latest)
+}
+
+// redirectArtifact responds with a release's artifact, redirecting to its file.
+func (m *mockServer) redirectArtifact(w http.ResponseWriter, account string, release *mockRelease) {
+	w.Header().Set("Location", m.host+"/files/"+release.ID+"/"+release.Filename)
+
+	m.writeJSON(w, http.StatusSeeOther, map[string]interface{}{
+		"data": map[string]interface{}{
+			"id":   release.ID,
+			"type": "artifacts",
+			"attributes": map[string]interface{}{
+				"key":     release.Filename,
+				"created": release.Created,
+				"updated": release.Created,
+			},
+			"relationships": map[string]interface{}{
+				"release": map[string]interface{}{
+					"data": map[string]interface{}{"type": "releases", "id": release.ID},
+				},
+			},
+			"links": map[string]interface{}{"self": "/v1/accounts/" + account + "/artifacts/" + release.ID},
+		},
+	})
+}
+
+// serveFile serves an artifact's file from the fixtures directory, supporting
+// range requests like a storage provider.
+func (m *mockServer) serveFile(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/files/"), "/", 2)
+
+	release := m.findRelease(parts[0])
+	if release == nil || len(parts) != 2 || parts[1] != release.Filename {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	http.ServeFile(w, r, filepath.Join(m.dir, filepath.Base(release.Filename)))
+}
+
+func (m *mockServer) releaseData(account string, release *mockRelease) map[string]interface{} {
+	status := "PUBLISHED"
+	if release.Yanked != nil {
+		status = "YANKED"
+	}
+
+	return map[string]interface{}{
+		"id":   release.ID,
+		"type": "releases",
+		"attributes": map[string]interface{}{
+			"version":   release.Version,
+			"channel":   release.Channel,
+			"platform":  release.Platform,
+			"filetype":  release.Filetype,
+			"filename":  release.Filename,
+			"filesize":  release.Filesize,
+			"checksum":  release.Checksum,
+			"signature": release.Signature,
+			"metadata":  release.Metadata,
+			"status":    status,
+			"yanked":    release.Yanked,
+			"created":   release.Created,
+		},
+		"relationships": map[string]interface{}{
+			"artifact": map[string]interface{}{
+				"data": map[string]interface{}{"type": "artifacts", "id": release.ID},
+			},
+		},
+		"links": map[string]interface{}{"self": "/v1/accounts/" + account + "/releases/" + release.ID},
+	}
+}
+
+func (m *mockServer) writeError(w http.ResponseWriter, status int, code string, detail string) {
+	m.writeJSON(w, status, map[string]interface{}{
+		"errors": []interface{}{
+			map[string]interface{}{"title": http.StatusText(status), "detail": detail, "code": code},
+		},
+	})
+}
+
+func (m *mockServer) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(v)
+}
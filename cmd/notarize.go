@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// notaryResult is the JSON output of `xcrun notarytool submit`.
+type notaryResult struct {
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// notaryCredentials returns the notarytool arguments used to authenticate,
+// from a keychain profile, an App Store Connect API key or an Apple ID.
+func notaryCredentials(opts *CommandOptions) ([]string, error) {
+	if p := opts.notarizeProfile; p != "" {
+		return []string{"--keychain-profile", p}, nil
+	}
+
+	if key := os.Getenv("APPLE_API_KEY_PATH"); key != "" {
+		args := []string{"--key", key, "--key-id", os.Getenv("APPLE_API_KEY_ID")}
+		if issuer := os.Getenv("APPLE_API_ISSUER"); issuer != "" {
+			args = append(args, "--issuer", issuer)
+		}
+
+		return args, nil
+	}
+
+	// The password is read from the environment by notarytool, since
+	// arguments are visible to every user, e.g. using ps
+	if id := os.Getenv("APPLE_ID"); id != "" {
+		return []string{"--apple-id", id, "--team-id", os.Getenv("APPLE_TEAM_ID"), "--password", "@env:APPLE_APP_PASSWORD"}, nil
+	}
+
+	return nil, errors.New("notarization credentials are missing (use --notarize-profile, $APPLE_API_KEY_PATH or $APPLE_ID)")
+}
+
+// notarizeArtifact submits a dmg, pkg or zip to Apple's notary service, waits
+// for it to be accepted, and staples the ticket to it when supported. Other
+// files are skipped, e.g. the Windows and Linux artifacts of a multi-artifact
+// release, other than app bundles, which must be archived first.
+func notarizeArtifact(opts *CommandOptions, path string) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".dmg", ".pkg", ".zip":
+	case ".app":
+		return fmt.Errorf(`path "%s" cannot be notarized (app bundles must be archived first, e.g. as a zip or dmg)`, path)
+	default:
+		if opts.output != "json" {
+			fmt.Fprintln(os.Stderr, "skipped notarizing "+filepath.Base(path)+" (not a dmg, pkg or zip)")
+		}
+
+		return nil
+	}
+
+	creds, err := notaryCredentials(opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.output != "json" {
+		fmt.Fprintln(os.Stderr, "notarizing "+filepath.Base(path)+" (this may take a while)...")
+	}
+
+	args := append([]string{"notarytool", "submit", path, "--wait", "--output-format", "json"}, creds...)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("xcrun", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+
+	result := &notaryResult{}
+	if err := json.Unmarshal(stdout.Bytes(), result); err != nil {
+		if runErr != nil {
+			return fmt.Errorf("notarization failed (%s)", strings.TrimSpace(stderr.String()+" "+runErr.Error()))
+		}
+
+		return fmt.Errorf("notarization failed (unexpected notarytool output: %s)", err)
+	}
+
+	if result.Status != "Accepted" {
+		return fmt.Errorf(`notarization failed (status "%s": %s, run "xcrun notarytool log %s" for details)`, result.Status, result.Message, result.ID)
+	}
+
+	// Tickets can't be stapled to zips, so Gatekeeper fetches them online
+	if ext == ".zip" {
+		return nil
+	}
+
+	out, err := exec.Command("xcrun", "stapler", "staple", path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("stapling failed (%s)", strings.TrimSpace(string(out)))
+	}
+
+	if opts.output != "json" {
+		italic := color.New(color.Italic).SprintFunc()
+
+		fmt.Fprintln(os.Stderr, "notarized and stapled "+filepath.Base(path)+" (submission "+italic(result.ID)+")")
+	}
+
+	return nil
+}
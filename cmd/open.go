@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+// defaultDashboardURL is keygen.sh's dashboard, whose pages are scoped to an
+// account, e.g. /<account>/releases/<id>.
+const defaultDashboardURL = "https://app.keygen.sh"
+
+// dashboardCollections maps the kinds of resources which have a dashboard
+// page to their collection's path.
+var dashboardCollections = map[string]string{
+	"artifact": "artifacts",
+	"license":  "licenses",
+	"product":  "products",
+	"release":  "releases",
+}
+
+func newOpenCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "open <kind> <id>",
+		Short: "open the dashboard page of a release, artifact, license or product in a browser",
+		Example: `  keygen open release 3f3a1b62-8a4b-4a3c-bf84-0f1b8e1a3e4d
+  keygen open license 8d8a2e8c-6a1f-4e6d-9f8e-2f1c0a6b7d5e --print
+
+Kinds:
+  ` + strings.Join(dashboardKinds(), ", ") + `
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: openArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return openRun(opts, strings.TrimSuffix(args[0], "s"), args[1])
+		},
+		Annotations: map[string]string{offlineAnnotation: "true"},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addOpenFlags(cmd, s, opts)
+
+	return cmd
+}
+
+func newReleasesOpenCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "open <id>",
+		Short: "open a release's dashboard page in a browser",
+		Example: `  keygen releases open 3f3a1b62-8a4b-4a3c-bf84-0f1b8e1a3e4d
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return openRun(opts, "release", args[0])
+		},
+		Annotations: map[string]string{offlineAnnotation: "true"},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addOpenFlags(cmd, s, opts)
+
+	return cmd
+}
+
+// addOpenFlags adds the flags of commands which open dashboard pages. Only the
+// account is needed, since the URL is built without making any requests.
+func addOpenFlags(cmd *cobra.Command, s *session, opts *CommandOptions) {
+	cmd.Flags().StringVar(&s.client.Account, "account", "", "your keygen.sh account identifier [$KEYGEN_ACCOUNT_ID=<id>] (required)")
+	cmd.Flags().StringVar(&opts.dashboardURL, "dashboard-url", "", "dashboard of a self-hosted instance, e.g. https://portal.example.com (default https://app.keygen.sh, and required when self-hosted) [$KEYGEN_DASHBOARD_URL=<url>]")
+	cmd.Flags().BoolVar(&opts.printURL, "print", false, "print the URL rather than opening it, e.g. over SSH")
+
+	bindEnv(cmd.Flags(), "account", "KEYGEN_ACCOUNT_ID")
+	bindEnv(cmd.Flags(), "dashboard-url", "KEYGEN_DASHBOARD_URL")
+
+	cmd.MarkFlagRequired("account")
+}
+
+func openArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("kind and id are required (kind is one of: %s)", strings.Join(dashboardKinds(), ", "))
+	}
+
+	if _, ok := dashboardCollections[strings.TrimSuffix(args[0], "s")]; !ok {
+		return fmt.Errorf(`kind "%s" is not supported (must be one of: %s)`, args[0], strings.Join(dashboardKinds(), ", "))
+	}
+
+	return nil
+}
+
+// dashboardKinds returns the sorted kinds of resources with a dashboard page.
+func dashboardKinds() []string {
+	kinds := []string{}
+	for k := range dashboardCollections {
+		kinds = append(kinds, k)
+	}
+
+	sort.Strings(kinds)
+
+	return kinds
+}
+
+// dashboardPageURL returns the dashboard page of a resource. Self-hosted
+// instances serve their portal from wherever they're deployed, so it must be
+// given using --dashboard-url. The --host is read as given, since the command
+// skips configuring it.
+func (opts *CommandOptions) dashboardPageURL(kind string, id string) (string, error) {
+	base := opts.dashboardURL
+	if base == "" {
+		if strings.TrimSuffix(opts.root.host, "/") != defaultHost {
+			return "", errors.New(`flag "--dashboard-url" is required for self-hosted instances (their dashboard isn't served from the --host)`)
+		}
+
+		base = defaultDashboardURL
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(base, "/"))
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf(`dashboard URL "%s" is not acceptable (must be a URL, e.g. https://portal.example.com)`, base)
+	}
+
+	u.Path += "/" + opts.client.Account + "/" + dashboardCollections[kind] + "/" + id
+
+	return u.String(), nil
+}
+
+func openRun(opts *CommandOptions, kind string, id string) error {
+	u, err := opts.dashboardPageURL(kind, id)
+	if err != nil {
+		return err
+	}
+
+	// Without a terminal there's usually no browser either, e.g. in CI
+	if opts.printURL || (!isatty.IsTerminal(os.Stdout.Fd()) && !isatty.IsCygwinTerminal(os.Stdout.Fd())) {
+		fmt.Println(u)
+
+		return nil
+	}
+
+	if err := openBrowser(u); err != nil {
+		fmt.Println(u)
+
+		return nil
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("opened " + italic(u))
+
+	return nil
+}
+
+// openBrowser opens a URL using the platform's default browser.
+func openBrowser(u string) error {
+	var c *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		c = exec.Command("open", u)
+	case "windows":
+		c = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		c = exec.Command("xdg-open", u)
+	}
+
+	return c.Start()
+}
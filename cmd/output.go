@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+// renderOutputUsage describes the formats supported by render, for use in
+// --output flag usage.
+const renderOutputUsage = "output format, one of: table, wide, json, yaml, go-template=<template>"
+
+// validateOutput ensures the --output flag is a supported format.
+func validateOutput(output string) error {
+	switch output {
+	case "", "text", "json":
+		return nil
+	default:
+		return fmt.Errorf(`output format "%s" is not supported`, output)
+	}
+}
+
+// rendering is a command's result, renderable in any supported output format.
+// Tables are rendered from headers and rows, where the last wide columns are
+// only shown for wide output. Everything else is rendered from value.
+type rendering struct {
+	value   interface{}
+	headers []string
+	rows    [][]string
+	wide    int
+}
+
+// validateRenderOutput ensures the --output flag is a format supported by
+// render, including that a go-template is parseable.
+func validateRenderOutput(output string) error {
+	switch {
+	case output == "" || output == "text" || output == "table" || output == "wide" || output == "json" || output == "yaml":
+		return nil
+	case strings.HasPrefix(output, "go-template="):
+		if _, err := template.New("output").Parse(strings.TrimPrefix(output, "go-template=")); err != nil {
+			return fmt.Errorf(`output template is not valid (%s)`, err)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf(`output format "%s" is not supported`, output)
+	}
+}
+
+// isStructuredOutput reports whether output is rendered from a value rather
+// than printed as text, e.g. so that commands which print a status line
+// instead of a table can tell the difference.
+func isStructuredOutput(output string) bool {
+	return output == "json" || output == "yaml" || strings.HasPrefix(output, "go-template=")
+}
+
+// render writes r to stdout using the given output format.
+func render(output string, r rendering) error {
+	switch {
+	case output == "json":
+		return printJSON(r.value)
+	case output == "yaml":
+		v, err := plainValue(r.value)
+		if err != nil {
+			return err
+		}
+
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		_, err = os.Stdout.Write(b)
+
+		return err
+	case strings.HasPrefix(output, "go-template="):
+		v, err := plainValue(r.value)
+		if err != nil {
+			return err
+		}
+
+		t, err := template.New("output").Parse(strings.TrimPrefix(output, "go-template="))
+		if err != nil {
+			return fmt.Errorf(`output template is not valid (%s)`, err)
+		}
+
+		if err := t.Execute(os.Stdout, v); err != nil {
+			return fmt.Errorf(`output template could not be rendered (%s)`, err)
+		}
+
+		return nil
+	}
+
+	headers, rows := r.headers, r.rows
+	if output != "wide" && r.wide > 0 {
+		headers = headers[:len(headers)-r.wide]
+		rows = [][]string{}
+
+		for _, row := range r.rows {
+			rows = append(rows, row[:len(row)-r.wide])
+		}
+	}
+
+	printTable(headers, rows)
+
+	return nil
+}
+
+// plainValue converts v into plain maps, slices and scalars by way of JSON,
+// so that yaml and go-template output use the same keys as json output.
+func plainValue(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// printJSON writes v to stdout as indented JSON.
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+// printTable writes rows to stdout as aligned columns.
+func printTable(headers []string, rows [][]string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	w.Flush()
+}
+
+// printCSV writes rows to stdout as CSV.
+func printCSV(headers []string, rows [][]string) error {
+	w := csv.NewWriter(os.Stdout)
+
+	if err := w.Write(headers); err != nil {
+		return err
+	}
+
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return w.Error()
+}
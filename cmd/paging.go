@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// addListFlags adds the pagination, ordering and selection flags shared by
+// list commands. Results can be sorted by any of the given sort fields.
+func addListFlags(cmd *cobra.Command, opts *CommandOptions, limit int, sortFields ...string) {
+	cmd.Flags().IntVar(&opts.limit, "limit", limit, "number of results to list, or the page size when paginating (max 100)")
+	cmd.Flags().IntVar(&opts.page, "page", 0, "page of results to list, starting at 1")
+	cmd.Flags().BoolVar(&opts.all, "all", false, "list every page of results")
+	cmd.Flags().StringVar(&opts.sort, "sort", "", "field to sort results by, listing every page first unless given --page, one of: "+strings.Join(sortFields, ", "))
+	cmd.Flags().BoolVar(&opts.desc, "desc", false, "sort results in descending order")
+	cmd.Flags().StringSliceVar(&opts.fields, "fields", []string{}, "comma seperated list of fields to output, e.g. id,version,created")
+	cmd.Flags().StringSliceVar(&opts.filters, "filter", []string{}, "comma seperated list of field=value or field!=value expressions results must match")
+}
+
+// validateListFlags ensures the pagination, ordering and selection flags are
+// acceptable, before anything is listed.
+func validateListFlags(opts *CommandOptions, sortFields ...string) error {
+	if opts.limit < 1 || opts.limit > 100 {
+		return fmt.Errorf(`limit "%d" is not acceptable (must be between 1 and 100)`, opts.limit)
+	}
+
+	if opts.page < 0 {
+		return fmt.Errorf(`page "%d" is not acceptable (must be 1 or more)`, opts.page)
+	}
+
+	if _, err := parseListFilters(opts.filters); err != nil {
+		return err
+	}
+
+	if opts.sort == "" {
+		return nil
+	}
+
+	for _, f := range sortFields {
+		if opts.sort == f {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(`sort field "%s" is not supported (must be one of: %s)`, opts.sort, strings.Join(sortFields, ", "))
+}
+
+// listPaging returns the page of results selected by the list flags.
+func listPaging(opts *CommandOptions) keygenext.Paging {
+	return keygenext.Paging{Page: opts.page, All: opts.all}
+}
+
+// localPaging returns the page size and paging for results which are filtered
+// locally, e.g. platformless releases, since filtering a single page of
+// results would silently miss the rest. Every page is listed, 100 results at
+// a time, unless given --page, and the filtered results are then truncated to
+// --limit by truncateList.
+func localPaging(opts *CommandOptions) (int, keygenext.Paging) {
+	if opts.page != 0 {
+		return opts.limit, listPaging(opts)
+	}
+
+	return 100, keygenext.Paging{All: true}
+}
+
+// truncateList returns how many of n locally filtered results to list, i.e.
+// up to --limit, unless given --all or --page.
+func truncateList(opts *CommandOptions, n int) int {
+	if opts.all || opts.page != 0 || n <= opts.limit {
+		return n
+	}
+
+	return opts.limit
+}
+
+// sortsLocally reports whether results are sorted locally, i.e. given --sort
+// or --desc, since the API lists them newest first. Like locally filtered
+// results, every page is then listed using localPaging, so that the first
+// --limit results are sorted across all of them rather than within a page.
+func sortsLocally(opts *CommandOptions) bool {
+	return opts.sort != "" || opts.desc
+}
+
+// sortList stably sorts slice using less, which compares the elements at i
+// and j by the --sort field. Results are left in API order unless --sort or
+// --desc is given.
+func sortList(opts *CommandOptions, slice interface{}, less func(i, j int) bool) {
+	if !sortsLocally(opts) {
+		return
+	}
+
+	sort.SliceStable(slice, func(i, j int) bool {
+		if opts.desc {
+			return less(j, i)
+		}
+
+		return less(i, j)
+	})
+}
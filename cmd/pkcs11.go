@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto"
+	stded25519 "crypto/ed25519"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+	"golang.org/x/term"
+)
+
+// pkcs11PINEnv is the environment variable pkcs11-tool is given the PIN in.
+const pkcs11PINEnv = "KEYGEN_PKCS11_TOOL_PIN"
+
+// pkcs11Signer signs messages using an ed25519 key stored on a PKCS#11 hardware
+// token, e.g. a YubiKey. Signing is delegated to OpenSC's pkcs11-tool, since
+// the CLI is built without cgo.
+type pkcs11Signer struct {
+	module string
+	token  string
+	object string
+	id     string
+	pin    string
+}
+
+// newPKCS11Signer parses a PKCS#11 URI (RFC 7512), e.g.
+// "pkcs11:token=YubiKey;object=release-key?module-path=/usr/lib/opensc-pkcs11.so".
+// The module may also be given by $KEYGEN_PKCS11_MODULE, and the PIN by the
+// pin-value attribute or $KEYGEN_PKCS11_PIN, otherwise it's prompted for.
+func newPKCS11Signer(uri string) (*pkcs11Signer, error) {
+	s := &pkcs11Signer{}
+
+	path, query := strings.TrimPrefix(uri, "pkcs11:"), ""
+	if i := strings.Index(path, "?"); i != -1 {
+		path, query = path[:i], path[i+1:]
+	}
+
+	for _, attr := range strings.Split(path, ";") {
+		if attr == "" {
+			continue
+		}
+
+		kv := strings.SplitN(attr, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf(`pkcs11 uri attribute "%s" is not acceptable`, attr)
+		}
+
+		v, err := url.PathUnescape(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf(`pkcs11 uri attribute "%s" is not acceptable (%s)`, attr, err)
+		}
+
+		switch kv[0] {
+		case "token":
+			s.token = v
+		case "object":
+			s.object = v
+		case "id":
+			s.id = fmt.Sprintf("%x", v)
+		}
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf(`pkcs11 uri query is not acceptable (%s)`, err)
+	}
+
+	s.module = values.Get("module-path")
+	if s.module == "" {
+		s.module = os.Getenv("KEYGEN_PKCS11_MODULE")
+	}
+
+	s.pin = values.Get("pin-value")
+	if s.pin == "" {
+		s.pin = os.Getenv("KEYGEN_PKCS11_PIN")
+	}
+
+	if s.object == "" && s.id == "" {
+		return nil, errors.New("pkcs11 uri must identify a key using the object or id attribute")
+	}
+
+	if _, err := exec.LookPath("pkcs11-tool"); err != nil {
+		return nil, errors.New("pkcs11 signing requires pkcs11-tool (install OpenSC)")
+	}
+
+	return s, nil
+}
+
+func (s *pkcs11Signer) args(extra ...string) []string {
+	args := []string{}
+
+	if s.module != "" {
+		args = append(args, "--module", s.module)
+	}
+
+	if s.token != "" {
+		args = append(args, "--token-label", s.token)
+	}
+
+	if s.object != "" {
+		args = append(args, "--label", s.object)
+	}
+
+	if s.id != "" {
+		args = append(args, "--id", s.id)
+	}
+
+	return append(args, extra...)
+}
+
+func (s *pkcs11Signer) Public() crypto.PublicKey {
+	out, err := exec.Command("pkcs11-tool", s.args("--read-object", "--type", "pubkey")...).Output()
+	if err != nil {
+		return nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(out)
+	if err != nil {
+		return nil
+	}
+
+	if k, ok := key.(stded25519.PublicKey); ok {
+		return ed25519.PublicKey(k)
+	}
+
+	return nil
+}
+
+// Sign signs the message on the token using the EDDSA mechanism. The token
+// receives the full message, since pkcs11-tool has no way to request an
+// ed25519ph signature.
+func (s *pkcs11Signer) Sign(_ io.Reader, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts != nil && opts.HashFunc() != crypto.Hash(0) {
+		return nil, errors.New("pkcs11 tokens do not support ed25519ph signatures (use --signing-algorithm ed25519)")
+	}
+
+	if o, ok := opts.(*ed25519.Options); ok && o.Context != "" {
+		return nil, errors.New("pkcs11 tokens do not support ed25519 signature contexts")
+	}
+
+	pin, err := s.readPIN()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "keygen-pkcs11-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	in, out := filepath.Join(dir, "message"), filepath.Join(dir, "signature")
+	if err := ioutil.WriteFile(in, message, 0600); err != nil {
+		return nil, err
+	}
+
+	var stderr bytes.Buffer
+
+	// The pin is passed through the environment, since arguments are visible
+	// to every user, e.g. using ps, and pkcs11-tool reads env:<name> pins
+	c := exec.Command("pkcs11-tool", s.args("--sign", "--mechanism", "EDDSA", "--login", "--pin", "env:"+pkcs11PINEnv, "--input-file", in, "--output-file", out)...)
+	c.Env = append(os.Environ(), pkcs11PINEnv+"="+pin)
+	c.Stderr = &stderr
+
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("pkcs11 token failed to sign (%s)", strings.TrimSpace(stderr.String()))
+	}
+
+	sig, err := ioutil.ReadFile(out)
+	if err != nil {
+		return nil, err
+	}
+
+	if l := len(sig); l != ed25519.SignatureSize {
+		return nil, fmt.Errorf("pkcs11 token returned a bad signature length (got %d expected %d)", l, ed25519.SignatureSize)
+	}
+
+	return sig, nil
+}
+
+func (s *pkcs11Signer) readPIN() (string, error) {
+	if s.pin != "" {
+		return s.pin, nil
+	}
+
+	// Never prompt in non-interactive environments, e.g. CI
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		return "", errors.New("pkcs11 pin is required (set $KEYGEN_PKCS11_PIN)")
+	}
+
+	fmt.Fprint(os.Stderr, "enter pin for pkcs11 token: ")
+
+	pin, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	s.pin = string(pin)
+
+	return s.pin, nil
+}
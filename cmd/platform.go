@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+)
+
+// noPlatform explicitly marks a release as platformless, e.g. a source tarball,
+// in which case the platform attribute is omitted.
+const noPlatform = "none"
+
+var platformRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+(/[A-Za-z0-9_.-]+)?$`)
+
+// wildcardPlatforms are platforms commonly used for platformless artifacts,
+// which are published as literal platform strings, but warned about since
+// they're likely meant to be platformless.
+var wildcardPlatforms = map[string]bool{
+	"any":       true,
+	"all":       true,
+	"noarch":    true,
+	"source":    true,
+	"src":       true,
+	"universal": true,
+}
+
+// platformFiletypes maps filetypes which only run on one OS to that OS.
+var platformFiletypes = map[string]string{
+	"exe":      "windows",
+	"msi":      "windows",
+	"msix":     "windows",
+	"appx":     "windows",
+	"dmg":      "darwin",
+	"pkg":      "darwin",
+	"deb":      "linux",
+	"rpm":      "linux",
+	"appimage": "linux",
+}
+
+// platformOSAliases normalizes common names for an OS.
+var platformOSAliases = map[string]string{
+	"win":     "windows",
+	"win32":   "windows",
+	"win64":   "windows",
+	"macos":   "darwin",
+	"mac":     "darwin",
+	"osx":     "darwin",
+	"windows": "windows",
+	"darwin":  "darwin",
+	"linux":   "linux",
+}
+
+// platformArchAliases normalizes common names for an arch, e.g. those used by
+// uname or Rust targets, to their Go names.
+var platformArchAliases = map[string]string{
+	"x86_64":  "amd64",
+	"x64":     "amd64",
+	"aarch64": "arm64",
+	"armv8":   "arm64",
+	"i386":    "386",
+	"i686":    "386",
+	"x86":     "386",
+	"armv7":   "arm",
+	"armv7l":  "arm",
+	"armhf":   "arm",
+}
+
+// normalizePlatform validates a release's platform, e.g. "linux/amd64",
+// returning an empty platform for platformless releases.
+func normalizePlatform(platform string) (string, error) {
+	switch {
+	case platform == "" || platform == noPlatform:
+		return "", nil
+	case wildcardPlatforms[strings.ToLower(platform)]:
+		yellow := color.New(color.FgYellow).SprintFunc()
+
+		fmt.Fprintln(os.Stderr, yellow("warning:")+fmt.Sprintf(` platform "%s" is published as-is (use "%s" or omit the platform for platformless releases)`, platform, noPlatform))
+	case !platformRegex.MatchString(platform):
+		return "", fmt.Errorf(`platform "%s" is not acceptable (must be <os>/<arch>, e.g. linux/amd64)`, platform)
+	}
+
+	return platform, nil
+}
+
+// checkPlatformFiletype validates a release's platform against its filetype,
+// e.g. rejecting an exe for linux/amd64, and warns when a platformless
+// release has a filetype which only runs on one OS.
+func checkPlatformFiletype(platform string, filetype string) error {
+	goos, ok := platformFiletypes[strings.ToLower(filetype)]
+	if !ok {
+		return nil
+	}
+
+	if platform == "" {
+		yellow := color.New(color.FgYellow).SprintFunc()
+
+		fmt.Fprintln(os.Stderr, yellow("warning:")+fmt.Sprintf(` filetype "%s" is for %s but the release has no platform`, filetype, goos))
+
+		return nil
+	}
+
+	name := strings.ToLower(strings.SplitN(platform, "/", 2)[0])
+	if alias, ok := platformOSAliases[name]; ok && alias != goos {
+		return fmt.Errorf(`platform "%s" is not acceptable (filetype "%s" is for %s)`, platform, filetype, goos)
+	}
+
+	return nil
+}
+
+// matchPlatform reports whether a release matches a platform filter, where
+// noPlatform only matches platformless releases. An empty filter matches all.
+func matchPlatform(release keygenext.Release, platform string) bool {
+	switch platform {
+	case "":
+		return true
+	case noPlatform:
+		return release.Platform == ""
+	}
+
+	return release.Platform == platform
+}
+
+// normalizeArch lowercases an arch and resolves its aliases, e.g. aarch64 to
+// arm64.
+func normalizeArch(arch string) string {
+	arch = strings.ToLower(arch)
+	if alias, ok := platformArchAliases[arch]; ok {
+		return alias
+	}
+
+	return arch
+}
+
+// platformArch returns the arch of a platform, e.g. arm64 for linux/arm64, or
+// an empty arch for platforms without one.
+func platformArch(platform string) string {
+	parts := strings.SplitN(platform, "/", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+
+	return normalizeArch(parts[1])
+}
+
+// matchArch reports whether a release is for an arch regardless of its OS.
+// Platformless releases never match. An empty filter matches all.
+func matchArch(release keygenext.Release, arch string) bool {
+	if arch == "" {
+		return true
+	}
+
+	return platformArch(release.Platform) == normalizeArch(arch)
+}
+
+// formatPlatform formats a release's platform for display.
+func formatPlatform(platform string) string {
+	if platform == "" {
+		return noPlatform
+	}
+
+	return platform
+}
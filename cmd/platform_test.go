@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNormalizePlatform(t *testing.T) {
+	tests := []struct {
+		platform string
+		want     string
+		warns    bool
+		err      bool
+	}{
+		{platform: "", want: ""},
+		{platform: "none", want: ""},
+		{platform: "linux/amd64", want: "linux/amd64"},
+		{platform: "darwin/arm64", want: "darwin/arm64"},
+		{platform: "linux", want: "linux"},
+		{platform: "any", want: "any", warns: true},
+		{platform: "Universal", want: "Universal", warns: true},
+		{platform: "noarch", want: "noarch", warns: true},
+		{platform: "*", err: true},
+		{platform: "linux/*", err: true},
+		{platform: "linux/amd64/v3", err: true},
+		{platform: "linux amd64", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			var got string
+			var err error
+
+			stderr := captureOutput(t, &os.Stderr, func() {
+				got, err = normalizePlatform(tt.platform)
+			})
+
+			if (err != nil) != tt.err {
+				t.Fatalf("normalizePlatform(%q) error = %v, want error %v", tt.platform, err, tt.err)
+			}
+
+			if got != tt.want {
+				t.Errorf("normalizePlatform(%q) = %q, want %q", tt.platform, got, tt.want)
+			}
+
+			if warned := strings.Contains(stderr, "warning:"); warned != tt.warns {
+				t.Errorf("normalizePlatform(%q) warned = %v, want %v (stderr %q)", tt.platform, warned, tt.warns, stderr)
+			}
+		})
+	}
+}
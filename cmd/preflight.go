@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/keygen-sh/keygen-go"
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+)
+
+// uuidRegex matches resource IDs, which are never created as entitlement codes.
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// preflightConstraints ensures the token is permitted to attach constraints,
+// and that every entitlement given by ID or code exists and can be read by the
+// token, reporting all problems at once. When createMissing is true, missing
+// entitlements given by code are created. The entitlements are returned as
+// IDs, so that codes can be used as constraints.
+func (s *session) preflightConstraints(entitlements []string, createMissing bool) ([]string, error) {
+	ids := make([]string, len(entitlements))
+	missing := []int{}
+	problems := []string{}
+
+	bearer, err := s.client.GetBearer(s.ctx)
+	switch {
+	case isNetworkError(err):
+		// Leave it to the publish to fail (or queue) when unreachable
+		return entitlements, nil
+	case err != nil:
+		return nil, fmt.Errorf("token could not be inspected (%w)", formatAPIError(err))
+	}
+
+	// Permissions are only given by editions of Keygen which support them
+	if len(bearer.Permissions) != 0 {
+		if m := missingPermissions(bearer.Permissions, constraintPermissions); len(m) != 0 {
+			problems = append(problems, "token is missing permissions: "+strings.Join(m, ", "))
+		}
+	}
+
+	for i, e := range entitlements {
+		entitlement, err := s.client.GetEntitlement(s.ctx, e)
+		switch {
+		case err == nil:
+			ids[i] = entitlement.ID
+		case errors.Is(err, keygen.ErrNotFound) && createMissing && !uuidRegex.MatchString(e):
+			missing = append(missing, i)
+		case errors.Is(err, keygen.ErrNotFound):
+			problems = append(problems, fmt.Sprintf(`entitlement "%s" does not exist`, e))
+		case errors.Is(err, keygen.ErrNotAuthorized):
+			problems = append(problems, fmt.Sprintf(`entitlement "%s" is not accessible by the token`, e))
+		case isNetworkError(err):
+			// Leave it to the publish to fail (or queue) when unreachable
+			return entitlements, nil
+		default:
+			problems = append(problems, fmt.Sprintf(`entitlement "%s" could not be checked (%s)`, e, formatAPIError(err)))
+		}
+	}
+
+	if len(missing) != 0 && len(bearer.Permissions) != 0 {
+		if m := missingPermissions(bearer.Permissions, []string{"entitlement.create"}); len(m) != 0 {
+			problems = append(problems, "token is missing permissions to create entitlements: "+strings.Join(m, ", "))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("entitlement constraints are not acceptable:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+
+	// Only create missing entitlements once everything else checks out
+	italic := color.New(color.Italic).SprintFunc()
+
+	for _, i := range missing {
+		entitlement := &keygenext.Entitlement{Name: entitlements[i], Code: entitlements[i]}
+		if err := s.client.CreateEntitlement(s.ctx, entitlement); err != nil {
+			return nil, fmt.Errorf(`entitlement "%s" could not be created (%w)`, entitlements[i], formatAPIError(err))
+		}
+
+		fmt.Fprintln(os.Stderr, "created entitlement "+italic(entitlement.Code)+" ("+entitlement.ID+")")
+
+		ids[i] = entitlement.ID
+	}
+
+	return ids, nil
+}
+
+// publishedPublicKeys returns the hex-encoded public keys published in the
+// product's publicKey and nextPublicKey metadata, keyed by metadata key.
+func (s *session) publishedPublicKeys() (map[string]string, error) {
+	if s.publicKeys != nil {
+		return s.publicKeys, nil
+	}
+
+	product, err := s.client.GetProduct(s.ctx, s.productID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]string{}
+	for _, k := range []string{"publicKey", "nextPublicKey"} {
+		if v, ok := product.Metadata[k].(string); ok && v != "" {
+			keys[k] = strings.ToLower(v)
+		}
+	}
+
+	s.publicKeys = keys
+
+	return keys, nil
+}
+
+// signerPublicKey returns the signer's hex-encoded ed25519 public key.
+func signerPublicKey(signer crypto.Signer) (string, error) {
+	key, ok := signer.Public().(ed25519.PublicKey)
+	if !ok {
+		return "", errors.New("signing key is not an ed25519 key")
+	}
+
+	return hex.EncodeToString(key), nil
+}
+
+// preflightSigningKey ensures the signing key matches a public key published
+// in the product's metadata, so that a release signed using the wrong key is
+// never published for clients to reject. Products without a published key
+// can't be checked, which is warned about once.
+func (s *session) preflightSigningKey(signer crypto.Signer) error {
+	keys, err := s.publishedPublicKeys()
+	switch {
+	case isNetworkError(err):
+		// Leave it to the publish to fail (or queue) when unreachable
+		return nil
+	case err != nil:
+		return fmt.Errorf("signing key could not be checked against the product's public key (%w)", formatAPIError(err))
+	case len(keys) == 0:
+		if !s.unpublishedKeyWarned {
+			s.unpublishedKeyWarned = true
+			s.distWarning("PUBLIC_KEY_UNPUBLISHED", fmt.Sprintf(`product "%s" has no public key in its metadata, so the signing key can't be checked (publish it using keygen keys check --publish)`, s.productID))
+		}
+
+		return nil
+	}
+
+	key, err := signerPublicKey(signer)
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if k == key {
+			return nil
+		}
+	}
+
+	published := keys["publicKey"]
+	if published == "" {
+		published = keys["nextPublicKey"]
+	}
+
+	return fmt.Errorf(`signing key's public key "%s" does not match the product's public key "%s"`, abbreviate(key), abbreviate(published))
+}
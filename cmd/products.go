@@ -0,0 +1,351 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// productsSortFields are the fields products can be sorted by.
+var productsSortFields = []string{"name", "created", "updated"}
+
+func newProductsCmd(s *session) *cobra.Command {
+	listOpts := s.newOptions()
+	listCmd := &cobra.Command{
+		Use:   "ls",
+		Short: "list products",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return productsListRun(listOpts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addListFlags(listCmd, listOpts, 10, productsSortFields...)
+	listCmd.Flags().StringVarP(&listOpts.output, "output", "o", "table", renderOutputUsage)
+
+	getOpts := s.newOptions()
+	getCmd := &cobra.Command{
+		Use:   "get <id>",
+		Short: "show a product",
+		Args:  productsIDArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return productsGetRun(getOpts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	getCmd.Flags().StringVarP(&getOpts.output, "output", "o", "table", renderOutputUsage)
+
+	createOpts := s.newOptions()
+	createCmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "create a product",
+		Example: `  keygen genkey
+  keygen products create 'Acme App' \
+      --distribution-strategy licensed \
+      --platforms darwin,linux,windows \
+      --public-key keygen.pub
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: productsNameArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return productsCreateRun(createOpts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addProductAttributeFlags(createCmd, createOpts)
+
+	updateOpts := s.newOptions()
+	updateCmd := &cobra.Command{
+		Use:   "update <id>",
+		Short: "update a product",
+		Example: `  keygen products update 2313b7e7-1ea6-4a01-901e-2931de6bb1e2 \
+      --distribution-strategy open \
+      --public-key keygen.pub
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: productsIDArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return productsUpdateRun(updateOpts, cmd, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	updateCmd.Flags().StringVar(&updateOpts.name, "name", "", "name of the product")
+	addProductAttributeFlags(updateCmd, updateOpts)
+
+	cmd := &cobra.Command{
+		Use:   "products",
+		Short: "manage products",
+	}
+
+	for _, c := range []*cobra.Command{listCmd, getCmd, createCmd, updateCmd} {
+		addAccountFlags(c, s)
+
+		cmd.AddCommand(c)
+	}
+
+	return cmd
+}
+
+// addProductAttributeFlags adds the flags shared by products create and
+// update.
+func addProductAttributeFlags(cmd *cobra.Command, opts *CommandOptions) {
+	cmd.Flags().StringVar(&opts.url, "url", "", "URL of the product, e.g. its website, or \"\" to remove it")
+	cmd.Flags().StringSliceVar(&opts.platforms, "platforms", []string{}, "comma seperated list of platforms the product supports (e.g. --platforms darwin,linux), or \"\" to remove them")
+	cmd.Flags().StringVar(&opts.distribution, "distribution-strategy", "", "who can download the product's releases, one of: licensed, open, closed")
+	cmd.Flags().StringVar(&opts.publicKey, "public-key", "", "hex-encoded ed25519 public key to verify releases with, or a path to one, e.g. from genkey")
+	cmd.Flags().StringArrayVar(&opts.metadataPairs, "metadata", []string{}, "key=value to set in the product's metadata, or key= to remove it; may be repeated")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "text", "output format, one of: text, json, yaml, go-template=<template>")
+}
+
+func productsNameArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("product name is required")
+	}
+
+	return nil
+}
+
+func productsIDArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("product ID is required")
+	}
+
+	return nil
+}
+
+func productsListRun(opts *CommandOptions) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	if err := validateListFlags(opts, productsSortFields...); err != nil {
+		return err
+	}
+
+	limit, paging := opts.limit, listPaging(opts)
+
+	// Results are sorted locally, so every page is listed
+	if sortsLocally(opts) {
+		limit, paging = localPaging(opts)
+	}
+
+	products, err := opts.client.ListProducts(opts.ctx, &keygenext.ListParams{Limit: limit, Paging: paging})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	sortList(opts, products, func(i, j int) bool {
+		switch opts.sort {
+		case "name":
+			return products[i].Name < products[j].Name
+		case "updated":
+			return products[i].Updated.Before(products[j].Updated)
+		default:
+			return products[i].Created.Before(products[j].Created)
+		}
+	})
+
+	if sortsLocally(opts) {
+		products = products[:truncateList(opts, len(products))]
+	}
+
+	r, err := selectList(opts, productsRendering(products...))
+	if err != nil {
+		return err
+	}
+
+	return render(opts.output, r)
+}
+
+func productsGetRun(opts *CommandOptions, args []string) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	product, err := opts.client.GetProduct(opts.ctx, args[0])
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	r := productsRendering(*product)
+	r.value = productsJSON(*product)[0]
+
+	return render(opts.output, r)
+}
+
+func productsCreateRun(opts *CommandOptions, args []string) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	product := &keygenext.ProductObject{Name: args[0], URL: opts.url, Platforms: opts.platforms}
+
+	if err := applyProductFlags(opts, product); err != nil {
+		return err
+	}
+
+	if err := opts.client.CreateProduct(opts.ctx, product); err != nil {
+		return formatAPIError(err)
+	}
+
+	if isStructuredOutput(opts.output) {
+		return render(opts.output, rendering{value: productsJSON(*product)[0]})
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("created product " + italic(product.ID))
+
+	return nil
+}
+
+func productsUpdateRun(opts *CommandOptions, cmd *cobra.Command, args []string) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	product, err := opts.client.GetProduct(opts.ctx, args[0])
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	if cmd.Flags().Changed("name") {
+		product.Name = opts.name
+	}
+
+	if cmd.Flags().Changed("url") {
+		product.URL = opts.url
+	}
+
+	if cmd.Flags().Changed("platforms") {
+		product.Platforms = opts.platforms
+	}
+
+	if err := applyProductFlags(opts, product); err != nil {
+		return err
+	}
+
+	if err := opts.client.UpdateProduct(opts.ctx, product); err != nil {
+		return formatAPIError(err)
+	}
+
+	if isStructuredOutput(opts.output) {
+		return render(opts.output, rendering{value: productsJSON(*product)[0]})
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("updated product " + italic(product.ID))
+
+	return nil
+}
+
+// applyProductFlags applies the distribution strategy, public key and metadata
+// flags to a product. The public key is kept in the product's publicKey
+// metadata, where dist checks signing keys against it.
+func applyProductFlags(opts *CommandOptions, product *keygenext.ProductObject) error {
+	if s := opts.distribution; s != "" {
+		switch strategy := strings.ToUpper(s); strategy {
+		case "LICENSED", "OPEN", "CLOSED":
+			product.DistributionStrategy = strategy
+		default:
+			return fmt.Errorf(`distribution strategy "%s" is not supported (must be one of: licensed, open, closed)`, s)
+		}
+	}
+
+	changes, err := parseMetadata(opts.metadataPairs)
+	if err != nil {
+		return err
+	}
+
+	if opts.publicKey != "" {
+		key, err := readPublicKey(opts.publicKey)
+		if err != nil {
+			return err
+		}
+
+		changes["publicKey"] = strings.ToLower(key)
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	metadata := map[string]interface{}{}
+	for k, v := range product.Metadata {
+		metadata[k] = v
+	}
+
+	for k, v := range changes {
+		if v == "" {
+			delete(metadata, k)
+
+			continue
+		}
+
+		metadata[k] = v
+	}
+
+	product.Metadata = metadata
+
+	return nil
+}
+
+// productsRendering renders products as a table, with their platforms and
+// public key only shown for wide output.
+func productsRendering(products ...keygenext.ProductObject) rendering {
+	rows := [][]string{}
+	for _, p := range products {
+		key, _ := p.Metadata["publicKey"].(string)
+		if key == "" {
+			key = "-"
+		}
+
+		rows = append(rows, []string{p.ID, p.Name, strings.ToLower(p.DistributionStrategy), p.Created.Format(time.RFC3339), p.Updated.Format(time.RFC3339), strings.Join(p.Platforms, ","), key})
+	}
+
+	return rendering{
+		value:   productsJSON(products...),
+		headers: []string{"ID", "NAME", "DISTRIBUTION", "CREATED", "UPDATED", "PLATFORMS", "PUBLIC KEY"},
+		rows:    rows,
+		wide:    2,
+	}
+}
+
+func productsJSON(products ...keygenext.ProductObject) []map[string]interface{} {
+	out := []map[string]interface{}{}
+
+	for _, p := range products {
+		out = append(out, map[string]interface{}{
+			"id":                    p.ID,
+			"name":                  p.Name,
+			"url":                   p.URL,
+			"distribution_strategy": p.DistributionStrategy,
+			"platforms":             p.Platforms,
+			"metadata":              p.Metadata,
+			"created":               p.Created,
+			"updated":               p.Updated,
+		})
+	}
+
+	return out
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+)
+
+// validateProvided checks a pre-calculated checksum and signature against the
+// file being uploaded, i.e. that the checksum is the file's SHA-512 digest and
+// that the signature verifies using one of the product's public keys, so that
+// a copy-paste mistake is caught before customers reject the release.
+func validateProvided(opts *CommandOptions, a *distArtifact, file *os.File, digest *fileDigest) error {
+	if c := a.checksum; c != "" {
+		sum, err := decodeBase64(c)
+		if err != nil || !bytes.Equal(sum, digest.sum) {
+			return fmt.Errorf(`checksum "%s" does not match the file (expected "%s")`, abbreviate(c), abbreviate(digest.checksum()))
+		}
+	}
+
+	if a.signature == "" {
+		return nil
+	}
+
+	sig, err := decodeBase64(a.signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf(`signature "%s" is not acceptable (must be a base64 encoded ed25519 signature)`, abbreviate(a.signature))
+	}
+
+	keys, err := opts.publishedPublicKeys()
+	switch {
+	case err != nil:
+		return fmt.Errorf("signature could not be verified against the product's public key (%w)", formatAPIError(err))
+	case len(keys) == 0:
+		return errors.New("signature could not be verified (the product has no publicKey in its metadata)")
+	}
+
+	var message []byte
+	var verifyOpts *ed25519.Options
+
+	switch opts.signingAlgorithm {
+	case "ed25519ph":
+		message = digest.sum
+		verifyOpts = &ed25519.Options{Hash: crypto.SHA512, Context: opts.ed25519phContext()}
+	case "ed25519":
+		b, unmap, err := mapFile(file)
+		if err != nil {
+			return fmt.Errorf("signature could not be verified (%s)", err)
+		}
+		defer unmap()
+		defer file.Seek(0, io.SeekStart)
+
+		message = b
+		verifyOpts = &ed25519.Options{}
+	default:
+		return fmt.Errorf(`signing algorithm "%s" is not supported`, opts.signingAlgorithm)
+	}
+
+	for _, k := range keys {
+		key, err := hex.DecodeString(k)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			continue
+		}
+
+		if ed25519.VerifyWithOptions(ed25519.PublicKey(key), message, sig, verifyOpts) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(`signature "%s" does not verify for the file using the product's public key (check the signature, --signing-algorithm and --signing-context)`, abbreviate(a.signature))
+}
+
+// decodeBase64 decodes padded or unpadded base64, since externally calculated
+// checksums and signatures may be either.
+func decodeBase64(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+}
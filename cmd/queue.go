@@ -0,0 +1,379 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/keygen-sh/keygen-go"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+const defaultQueueDir = "~/.keygen/queue"
+
+// queueEntry is a fully prepared release which could not be published, along
+// with everything needed to publish it later. Tokens are never queued.
+type queueEntry struct {
+	ID           string             `json:"id"`
+	Host         string             `json:"host,omitempty"`
+	Account      string             `json:"account"`
+	Product      string             `json:"product"`
+	Package      string             `json:"package,omitempty"`
+	Path         string             `json:"path"`
+	Entitlements []string           `json:"entitlements"`
+	ContentType  string             `json:"content_type,omitempty"`
+	Digest       string             `json:"digest,omitempty"`
+	Release      *keygenext.Release `json:"release"`
+	Queued       time.Time          `json:"queued"`
+
+	dir string
+}
+
+func newQueueCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	listCmd := &cobra.Command{
+		Use:   "ls",
+		Short: "list queued releases",
+		Example: `  keygen queue ls
+  keygen queue ls -o json
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return queueListRun(opts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	flushCmd := &cobra.Command{
+		Use:   "flush",
+		Short: "publish all queued releases",
+		Example: `  keygen queue flush \
+      --token 'prod-xxx'
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return queueFlushRun(opts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	listCmd.Flags().StringVarP(&opts.output, "output", "o", "table", renderOutputUsage)
+
+	flushCmd.Flags().StringVar(&s.client.Token, "token", "", "your keygen.sh product token [$KEYGEN_PRODUCT_TOKEN] (required)")
+	flushCmd.Flags().BoolVar(&opts.overrideFreeze, "override-freeze", false, "publish queued releases to the stable channel even while publishing is frozen by keygen freeze")
+
+	bindEnv(flushCmd.Flags(), "token", "KEYGEN_PRODUCT_TOKEN")
+
+	flushCmd.MarkFlagRequired("token")
+
+	for _, c := range []*cobra.Command{listCmd, flushCmd} {
+		c.Flags().StringVar(&opts.queueDir, "queue-dir", defaultQueueDir, "directory where releases are queued [$KEYGEN_QUEUE_DIR=<path>]")
+
+		bindEnv(c.Flags(), "queue-dir", "KEYGEN_QUEUE_DIR")
+	}
+
+	cmd := &cobra.Command{
+		Use:   "queue",
+		Short: "manage releases queued while the API was unreachable",
+	}
+
+	cmd.AddCommand(listCmd)
+	cmd.AddCommand(flushCmd)
+
+	return cmd
+}
+
+func queueListRun(opts *CommandOptions) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	entries, err := readQueue(opts.queueDir)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 && !isStructuredOutput(opts.output) {
+		fmt.Println("queue is empty")
+
+		return nil
+	}
+
+	value := []map[string]interface{}{}
+	rows := [][]string{}
+
+	for _, entry := range entries {
+		value = append(value, map[string]interface{}{
+			"id":       entry.ID,
+			"queued":   entry.Queued,
+			"version":  entry.Release.Version,
+			"channel":  entry.Release.Channel,
+			"platform": entry.Release.Platform,
+			"filename": entry.Release.Filename,
+			"path":     entry.Path,
+			"host":     entry.Host,
+			"account":  entry.Account,
+			"product":  entry.Product,
+		})
+
+		rows = append(rows, []string{entry.ID, entry.Queued.Format(time.RFC3339), entry.Release.Version, entry.Release.Channel, formatPlatform(entry.Release.Platform), entry.Path, entry.Account, entry.Product})
+	}
+
+	return render(opts.output, rendering{
+		value:   value,
+		headers: []string{"ID", "QUEUED", "VERSION", "CHANNEL", "PLATFORM", "PATH", "ACCOUNT", "PRODUCT"},
+		rows:    rows,
+		wide:    2,
+	})
+}
+
+func queueFlushRun(opts *CommandOptions) error {
+	entries, err := readQueue(opts.queueDir)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("queue is empty")
+
+		return nil
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	failed := 0
+
+	for _, entry := range entries {
+		if err := flushQueueEntry(opts, entry); err != nil {
+			fmt.Fprintln(os.Stderr, red("error:")+" queued release "+italic(entry.ID)+" could not be published ("+err.Error()+")")
+
+			failed++
+
+			continue
+		}
+
+		fmt.Println("published queued release " + italic(entry.Release.ID))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d queued releases could not be published", failed, len(entries))
+	}
+
+	return nil
+}
+
+func flushQueueEntry(opts *CommandOptions, entry *queueEntry) error {
+	if err := publishQueueEntry(opts, entry, entry.Path); err != nil {
+		return err
+	}
+
+	// Remove artifacts which were compressed into the queue when queued
+	if filepath.Dir(entry.Path) == filepath.Clean(entry.dir) {
+		os.Remove(entry.Path)
+	}
+
+	return os.Remove(filepath.Join(entry.dir, entry.ID+".json"))
+}
+
+// publishQueueEntry publishes a queued or bundled release, uploading the file
+// at path as its artifact.
+func publishQueueEntry(opts *CommandOptions, entry *queueEntry, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf(`path "%s" is not readable (%s)`, path, err.(*os.PathError).Err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	// The checksum and signature were calculated when queued, so make sure the
+	// file hasn't been changed out from under us in the meantime.
+	if info.Size() != entry.Release.Filesize {
+		return fmt.Errorf(`path "%s" has changed since it was prepared`, path)
+	}
+
+	if entry.Digest != "" {
+		digest, _, err := calculateChecksum(file, nil)
+		if err != nil {
+			return fmt.Errorf(`path "%s" is not readable (%s)`, path, err)
+		}
+
+		if digest != entry.Digest {
+			return fmt.Errorf(`path "%s" has changed since it was prepared`, path)
+		}
+	}
+
+	opts.client.Account = entry.Account
+	opts.productID = entry.Product
+
+	// Entries are published to the instance they were queued for
+	if entry.Host != "" {
+		keygen.APIURL = entry.Host
+	}
+
+	release := entry.Release
+	release.ProductID = entry.Product
+	release.PackageID = entry.Package
+	release.Constraints = keygenext.Constraints{}.From(entry.Entitlements)
+	release.ContentType = entry.ContentType
+
+	_, err = publishRelease(opts, release, file)
+
+	return err
+}
+
+// enqueueRelease writes a prepared release to the queue directory.
+func (s *session) enqueueRelease(queueDir string, path string, release *keygenext.Release) (*queueEntry, error) {
+	dir, err := homedir.Expand(queueDir)
+	if err != nil {
+		return nil, fmt.Errorf(`queue path "%s" is not expandable (%s)`, queueDir, err)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf(`queue path "%s" is not writable (%s)`, dir, err)
+	}
+
+	entry, err := s.newQueueEntry(path, release)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, entry.ID+".json"), b, 0600); err != nil {
+		return nil, fmt.Errorf(`queue path "%s" is not writable (%s)`, dir, err)
+	}
+
+	return entry, nil
+}
+
+// newQueueEntry prepares an entry for a release of the file at path, which can
+// be published later using publishQueueEntry.
+func (s *session) newQueueEntry(path string, release *keygenext.Release) (*queueEntry, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(abs)
+	if err != nil {
+		return nil, fmt.Errorf(`path "%s" is not readable (%s)`, path, err.(*os.PathError).Err)
+	}
+	defer file.Close()
+
+	digest, _, err := calculateChecksum(file, nil)
+	if err != nil {
+		return nil, fmt.Errorf(`path "%s" is not readable (%s)`, path, err)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	entitlements := []string{}
+	for _, c := range release.Constraints {
+		entitlements = append(entitlements, c.EntitlementID)
+	}
+
+	entry := &queueEntry{
+		ID:           time.Now().UTC().Format("20060102150405") + "-" + hex.EncodeToString(id),
+		Host:         keygen.APIURL,
+		Account:      s.client.Account,
+		Product:      release.ProductID,
+		Package:      release.PackageID,
+		Path:         abs,
+		Entitlements: entitlements,
+		ContentType:  release.ContentType,
+		Digest:       digest,
+		Release:      release,
+		Queued:       time.Now().UTC(),
+	}
+
+	return entry, nil
+}
+
+// readQueue reads all queued releases, oldest first.
+func readQueue(queueDir string) ([]*queueEntry, error) {
+	dir, err := homedir.Expand(queueDir)
+	if err != nil {
+		return nil, fmt.Errorf(`queue path "%s" is not expandable (%s)`, queueDir, err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf(`queue path "%s" is not readable (%s)`, dir, err)
+	}
+
+	entries := []*queueEntry{}
+
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &queueEntry{dir: dir}
+		if err := json.Unmarshal(b, entry); err != nil {
+			return nil, fmt.Errorf(`queued release "%s" is not readable (%s)`, f.Name(), err)
+		}
+
+		if entry.Release == nil {
+			return nil, fmt.Errorf(`queued release "%s" is missing its payload`, f.Name())
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Queued.Before(entries[j].Queued) })
+
+	return entries, nil
+}
+
+// isNetworkError reports whether err was caused by the API being unreachable,
+// rather than the API rejecting the request or the command being canceled.
+func isNetworkError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var e net.Error
+
+	return errors.As(err, &e)
+}
@@ -0,0 +1,600 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// releasesSortFields are the fields releases can be sorted by.
+var releasesSortFields = []string{"version", "platform", "channel", "size", "created"}
+
+func newReleasesCmd(s *session) *cobra.Command {
+	listOpts := s.newOptions()
+	listCmd := &cobra.Command{
+		Use:   "ls",
+		Short: "list releases",
+		Example: `  keygen releases ls --channel stable --all --sort version --desc
+  keygen releases ls --arch arm64 --all
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return releasesListRun(listOpts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(listCmd, s)
+	addProductFlag(listCmd, s)
+	addListFlags(listCmd, listOpts, 10, releasesSortFields...)
+
+	listCmd.Flags().StringVar(&listOpts.version, "version", "", "only list releases for a version")
+	listCmd.Flags().StringVar(&listOpts.channel, "channel", "", "only list releases for a channel")
+	listCmd.Flags().StringVar(&listOpts.platform, "platform", "", "only list releases for a platform, or \"none\" for platformless releases")
+	listCmd.Flags().StringVar(&listOpts.arch, "arch", "", "only list releases for an arch across all OSes, e.g. arm64")
+	listCmd.Flags().StringVar(&listOpts.filetype, "filetype", "", "only list releases for a filetype")
+	listCmd.Flags().StringVarP(&listOpts.output, "output", "o", "table", renderOutputUsage)
+
+	diffOpts := s.newOptions()
+	diffCmd := &cobra.Command{
+		Use:   "diff <version> <version>",
+		Short: "show what changed between the releases of two versions",
+		Example: `  keygen releases diff 1.0.0 1.1.0
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: releasesDiffArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return releasesDiffRun(diffOpts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(diffCmd, s)
+	addProductFlag(diffCmd, s)
+
+	diffCmd.Flags().StringVarP(&diffOpts.output, "output", "o", "text", "output format, one of: text, json, yaml, go-template=<template>")
+
+	cmd := &cobra.Command{
+		Use:   "releases",
+		Short: "manage releases",
+	}
+
+	cmd.AddCommand(listCmd)
+	cmd.AddCommand(diffCmd)
+	cmd.AddCommand(newReleasesLatestCmd(s))
+	cmd.AddCommand(newReleasesStatsCmd(s))
+	cmd.AddCommand(newReleasesLockCmd(s))
+	cmd.AddCommand(newReleasesUnlockCmd(s))
+	cmd.AddCommand(newReleasesOpenCmd(s))
+
+	return cmd
+}
+
+func releasesListRun(opts *CommandOptions) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	if err := validateListFlags(opts, releasesSortFields...); err != nil {
+		return err
+	}
+
+	if opts.arch != "" && opts.platform != "" {
+		return errors.New(`flag "--arch" cannot be used together with "--platform"`)
+	}
+
+	limit, paging := opts.limit, listPaging(opts)
+
+	// Platformless releases and arches are filtered locally, and results are
+	// sorted locally, so every page is listed
+	local := opts.platform == noPlatform || opts.arch != "" || sortsLocally(opts)
+	if local {
+		limit, paging = localPaging(opts)
+	}
+
+	releases, err := opts.client.ListReleases(opts.ctx, &keygenext.ReleaseFilter{
+		Product:  opts.productID,
+		Version:  releasesListFilter(opts, "version"),
+		Channel:  releasesListFilter(opts, "channel"),
+		Platform: releasesListFilter(opts, "platform"),
+		Filetype: releasesListFilter(opts, "filetype"),
+		Limit:    limit,
+		Paging:   paging,
+	})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	if opts.platform == noPlatform {
+		platformless := keygenext.Releases{}
+		for _, r := range releases {
+			if matchPlatform(r, noPlatform) {
+				platformless = append(platformless, r)
+			}
+		}
+
+		releases = platformless
+	}
+
+	// The API filters by platform only, so arches are filtered locally
+	if opts.arch != "" {
+		matched := keygenext.Releases{}
+		for _, r := range releases {
+			if matchArch(r, opts.arch) {
+				matched = append(matched, r)
+			}
+		}
+
+		releases = matched
+	}
+
+	sortList(opts, releases, func(i, j int) bool {
+		a, b := releases[i], releases[j]
+
+		switch opts.sort {
+		case "version":
+			return compareVersions(a.Version, b.Version) < 0
+		case "platform":
+			return a.Platform < b.Platform
+		case "channel":
+			return a.Channel < b.Channel
+		case "size":
+			return a.Filesize < b.Filesize
+		default:
+			return a.Created != nil && b.Created != nil && a.Created.Before(*b.Created)
+		}
+	})
+
+	if local {
+		releases = releases[:truncateList(opts, len(releases))]
+	}
+
+	value := []map[string]interface{}{}
+	rows := [][]string{}
+
+	for _, r := range releases {
+		created := ""
+		if r.Created != nil {
+			created = r.Created.Format(time.RFC3339)
+		}
+
+		value = append(value, map[string]interface{}{
+			"id":        r.ID,
+			"version":   r.Version,
+			"channel":   r.Channel,
+			"platform":  r.Platform,
+			"arch":      platformArch(r.Platform),
+			"filetype":  r.Filetype,
+			"filename":  r.Filename,
+			"filesize":  r.Filesize,
+			"checksum":  r.Checksum,
+			"signature": r.Signature,
+			"metadata":  r.Metadata,
+			"yanked":    r.Yanked,
+			"created":   r.Created,
+		})
+
+		rows = append(rows, []string{r.ID, r.Version, r.Channel, r.Platform, r.Filetype, formatBytes(r.Filesize), created, r.Filename, strconv.FormatInt(r.Downloads, 10)})
+	}
+
+	r, err := selectList(opts, rendering{
+		value:   value,
+		headers: []string{"ID", "VERSION", "CHANNEL", "PLATFORM", "FILETYPE", "SIZE", "CREATED", "FILENAME", "DOWNLOADS"},
+		rows:    rows,
+		wide:    2,
+	})
+	if err != nil {
+		return err
+	}
+
+	return render(opts.output, r)
+}
+
+// releasesListFilter returns the API filter for a release field, given by
+// its flag or otherwise by an equality --filter on the field.
+func releasesListFilter(opts *CommandOptions, field string) string {
+	flags := map[string]string{
+		"version":  opts.version,
+		"channel":  opts.channel,
+		"platform": opts.platform,
+		"filetype": opts.filetype,
+	}
+
+	switch v := flags[field]; {
+	case field == "platform" && v == noPlatform:
+		// Platformless releases are filtered locally, since the API has no
+		// filter for them
+		return ""
+	case v != "":
+		return v
+	}
+
+	return serverFilter(opts, field)
+}
+
+// compareVersions compares two versions by semver precedence, falling back to
+// comparing them as strings when either isn't a valid semantic version.
+func compareVersions(a, b string) int {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+
+	return va.Compare(vb)
+}
+
+func releasesDiffArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("two versions are required")
+	}
+
+	return nil
+}
+
+// releaseDiff describes what changed for an artifact, i.e. a release's
+// platform and filetype, between two versions.
+type releaseDiff struct {
+	Key         string               `json:"key"`
+	Status      string               `json:"status"`
+	From        *releaseSummary      `json:"from,omitempty"`
+	To          *releaseSummary      `json:"to,omitempty"`
+	SizeDelta   int64                `json:"size_delta"`
+	Changes     []string             `json:"changes"`
+	Metadata    map[string][2]string `json:"metadata,omitempty"`
+	Constraints map[string][]string  `json:"constraints,omitempty"`
+}
+
+// releaseSummary is the subset of a release which is compared.
+type releaseSummary struct {
+	ID          string                 `json:"id"`
+	Filename    string                 `json:"filename"`
+	Filesize    int64                  `json:"filesize"`
+	Checksum    string                 `json:"checksum"`
+	Signature   string                 `json:"signature"`
+	Description string                 `json:"description"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	Constraints []string               `json:"constraints"`
+}
+
+func releasesDiffRun(opts *CommandOptions, args []string) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	from, err := opts.releasesForDiff(args[0])
+	if err != nil {
+		return err
+	}
+
+	to, err := opts.releasesForDiff(args[1])
+	if err != nil {
+		return err
+	}
+
+	keys := []string{}
+	for k := range from {
+		keys = append(keys, k)
+	}
+
+	for k := range to {
+		if _, ok := from[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	diffs := []*releaseDiff{}
+	for _, k := range keys {
+		diffs = append(diffs, diffReleases(k, from[k], to[k]))
+	}
+
+	if isStructuredOutput(opts.output) {
+		return render(opts.output, rendering{value: map[string]interface{}{"from": args[0], "to": args[1], "artifacts": diffs}})
+	}
+
+	bold := color.New(color.Bold).SprintFunc()
+
+	fmt.Println(bold(args[0]) + " " + glyph("→") + " " + bold(args[1]))
+
+	for _, d := range diffs {
+		fmt.Println()
+
+		for _, line := range formatReleaseDiff(d) {
+			fmt.Println(line)
+		}
+	}
+
+	return nil
+}
+
+// formatReleaseDiff formats an artifact's changes as lines for display.
+func formatReleaseDiff(d *releaseDiff) []string {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	faint := color.New(color.Faint).SprintFunc()
+
+	switch d.Status {
+	case "added":
+		return []string{green("+ "+d.Key) + "  " + d.To.Filename + " (" + formatBytes(d.To.Filesize) + ")"}
+	case "removed":
+		return []string{red("- "+d.Key) + "  " + d.From.Filename + " (" + formatBytes(d.From.Filesize) + ")"}
+	case "unchanged":
+		return []string{faint("  " + d.Key + "  unchanged")}
+	}
+
+	lines := []string{yellow("~ " + d.Key)}
+
+	if d.From.Filename != d.To.Filename {
+		lines = append(lines, fmt.Sprintf("    %-12s %s %s %s", "filename", d.From.Filename, glyph("→"), d.To.Filename))
+	}
+
+	lines = append(lines, fmt.Sprintf("    %-12s %s %s %s (%s)", "size", formatBytes(d.From.Filesize), glyph("→"), formatBytes(d.To.Filesize), formatSizeDelta(d.From.Filesize, d.To.Filesize)))
+
+	for _, c := range d.Changes {
+		switch c {
+		case "checksum":
+			lines = append(lines, fmt.Sprintf("    %-12s %s %s %s", c, abbreviate(d.From.Checksum), glyph("→"), abbreviate(d.To.Checksum)))
+		case "signature":
+			lines = append(lines, fmt.Sprintf("    %-12s %s %s %s", c, abbreviate(d.From.Signature), glyph("→"), abbreviate(d.To.Signature)))
+		case "description":
+			lines = append(lines, "    "+c)
+			lines = append(lines, red("      - "+strings.ReplaceAll(d.From.Description, "\n", "\n      - ")))
+			lines = append(lines, green("      + "+strings.ReplaceAll(d.To.Description, "\n", "\n      + ")))
+		}
+	}
+
+	if len(d.Constraints) > 0 {
+		line := fmt.Sprintf("    %-12s", "constraints")
+		for _, id := range d.Constraints["added"] {
+			line += " " + green("+"+id)
+		}
+
+		for _, id := range d.Constraints["removed"] {
+			line += " " + red("-"+id)
+		}
+
+		lines = append(lines, line)
+	}
+
+	if len(d.Metadata) > 0 {
+		metaKeys := []string{}
+		for k := range d.Metadata {
+			metaKeys = append(metaKeys, k)
+		}
+
+		sort.Strings(metaKeys)
+
+		lines = append(lines, "    metadata")
+		for _, k := range metaKeys {
+			v := d.Metadata[k]
+			lines = append(lines, fmt.Sprintf("      %s: %s %s %s", k, v[0], glyph("→"), v[1]))
+		}
+	}
+
+	return lines
+}
+
+// releasesForDiff retrieves a version's releases keyed by platform and filetype,
+// along with their constraints. Releases which can't be told apart by their
+// key, e.g. two zips for the same platform, can't be compared, so they're an
+// error rather than one silently replacing the other.
+func (s *session) releasesForDiff(version string) (map[string]*releaseSummary, error) {
+	releases, err := s.client.ListReleases(s.ctx, &keygenext.ReleaseFilter{Product: s.productID, Version: version, Limit: 100})
+	if err != nil {
+		return nil, formatAPIError(err)
+	}
+
+	if len(releases) == 0 {
+		return nil, fmt.Errorf(`version "%s" has no releases`, version)
+	}
+
+	summaries := map[string]*releaseSummary{}
+
+	for _, r := range releases {
+		constraints, err := s.client.ListReleaseConstraints(s.ctx, &r)
+		if err != nil {
+			return nil, formatAPIError(err)
+		}
+
+		key := releaseDiffKey(&r)
+		if other, ok := summaries[key]; ok {
+			return nil, fmt.Errorf(`version "%s" has more than one release for %s (%s and %s), so its releases can't be compared`, version, key, other.Filename, r.Filename)
+		}
+
+		summaries[key] = summarizeRelease(&r, constraints)
+	}
+
+	return summaries, nil
+}
+
+// releaseDiffKey identifies a release's artifact within its version, where
+// debug symbols are told apart from the artifact they're for.
+func releaseDiffKey(r *keygenext.Release) string {
+	key := formatPlatform(r.Platform) + " (" + r.Filetype + ")"
+	if _, ok := r.Metadata["symbolsFor"]; ok {
+		key += " symbols"
+	}
+
+	return key
+}
+
+// summarizeRelease returns the subset of a release which is compared, where
+// constraints are compared as sorted entitlement IDs.
+func summarizeRelease(r *keygenext.Release, constraints keygenext.Constraints) *releaseSummary {
+	ids := []string{}
+	for _, c := range constraints {
+		ids = append(ids, c.EntitlementID)
+	}
+
+	sort.Strings(ids)
+
+	var desc string
+	if r.Description != nil {
+		desc = *r.Description
+	}
+
+	return &releaseSummary{
+		ID:          r.ID,
+		Filename:    r.Filename,
+		Filesize:    r.Filesize,
+		Checksum:    r.Checksum,
+		Signature:   r.Signature,
+		Description: desc,
+		Metadata:    r.Metadata,
+		Constraints: ids,
+	}
+}
+
+func diffReleases(key string, from *releaseSummary, to *releaseSummary) *releaseDiff {
+	d := &releaseDiff{Key: key, From: from, To: to, Changes: []string{}}
+
+	switch {
+	case from == nil:
+		d.Status = "added"
+
+		return d
+	case to == nil:
+		d.Status = "removed"
+
+		return d
+	}
+
+	d.SizeDelta = to.Filesize - from.Filesize
+
+	if from.Filename != to.Filename {
+		d.Changes = append(d.Changes, "filename")
+	}
+
+	if d.SizeDelta != 0 {
+		d.Changes = append(d.Changes, "filesize")
+	}
+
+	if from.Checksum != to.Checksum {
+		d.Changes = append(d.Changes, "checksum")
+	}
+
+	if from.Signature != to.Signature {
+		d.Changes = append(d.Changes, "signature")
+	}
+
+	if from.Description != to.Description {
+		d.Changes = append(d.Changes, "description")
+	}
+
+	// Constraints are compared as sets of entitlement IDs
+	added, removed := diffStrings(from.Constraints, to.Constraints)
+	if len(added) > 0 || len(removed) > 0 {
+		d.Changes = append(d.Changes, "constraints")
+		d.Constraints = map[string][]string{"added": added, "removed": removed}
+	}
+
+	d.Metadata = map[string][2]string{}
+	for k, v := range from.Metadata {
+		if w, ok := to.Metadata[k]; !ok || !reflect.DeepEqual(v, w) {
+			d.Metadata[k] = [2]string{formatMetadataValue(v, true), formatMetadataValue(w, ok)}
+		}
+	}
+
+	for k, w := range to.Metadata {
+		if _, ok := from.Metadata[k]; !ok {
+			d.Metadata[k] = [2]string{formatMetadataValue(nil, false), formatMetadataValue(w, true)}
+		}
+	}
+
+	if len(d.Metadata) > 0 {
+		d.Changes = append(d.Changes, "metadata")
+	}
+
+	if len(d.Changes) == 0 {
+		d.Status = "unchanged"
+	} else {
+		d.Status = "changed"
+	}
+
+	return d
+}
+
+// diffStrings returns the strings which were added to and removed from a set.
+func diffStrings(from []string, to []string) ([]string, []string) {
+	added, removed := []string{}, []string{}
+
+	seen := map[string]bool{}
+	for _, s := range from {
+		seen[s] = true
+	}
+
+	for _, s := range to {
+		if !seen[s] {
+			added = append(added, s)
+		}
+
+		delete(seen, s)
+	}
+
+	for _, s := range from {
+		if seen[s] {
+			removed = append(removed, s)
+		}
+	}
+
+	return added, removed
+}
+
+func formatMetadataValue(v interface{}, ok bool) string {
+	if !ok {
+		return "(none)"
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
+
+// formatSizeDelta formats the change in size, e.g. +512.0 KiB, +5.0%.
+func formatSizeDelta(from int64, to int64) string {
+	delta := to - from
+
+	sign := "+"
+	if delta < 0 {
+		sign = "-"
+		delta = -delta
+	}
+
+	if from == 0 {
+		return sign + formatBytes(delta)
+	}
+
+	return fmt.Sprintf("%s%s, %s%.1f%%", sign, formatBytes(delta), sign, float64(delta)/float64(from)*100)
+}
+
+// abbreviate shortens a checksum or signature for display.
+func abbreviate(s string) string {
+	if len(s) <= 16 {
+		return s
+	}
+
+	return s[:16] + glyph("…")
+}
@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// licenseUsageHeaders are the columns of a usage report.
+var licenseUsageHeaders = []string{"id", "key", "name", "status", "activations", "max_machines", "machine_overage", "uses", "max_uses", "use_overage", "validations", "failed_validations"}
+
+func newReportCmd(s *session) *cobra.Command {
+	usageOpts := s.newOptions()
+	usageCmd := &cobra.Command{
+		Use:   "usage",
+		Short: "report each license's activations, validations and overages for a policy, e.g. for a true-up",
+		Example: `  keygen report usage --policy 2c3d0a6e-3f3a-4b43-8d7c-3c4d4b4e5e2f --since 30d
+  keygen report usage --policy 2c3d0a6e-3f3a-4b43-8d7c-3c4d4b4e5e2f --since 2021-10-01 --output csv > usage.csv
+
+Columns:
+  id, key, name, status, activations (machines currently activated),
+  max_machines, machine_overage (activations beyond max_machines), uses,
+  max_uses, use_overage, validations (including failed ones) and
+  failed_validations, within the time window. A license's own limits take
+  precedence over its policy's.
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return reportUsageRun(usageOpts)
+		},
+		Annotations: map[string]string{adminTokenAnnotation: "true"},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(usageCmd, s)
+
+	usageCmd.Flags().StringVar(&usageOpts.policy, "policy", "", "policy whose licenses to report (required)")
+	usageCmd.Flags().StringVar(&usageOpts.since, "since", "30d", "start of the time window for validations, as a duration (e.g. 7d, 12h) or a date (e.g. 2021-11-01)")
+	usageCmd.Flags().StringVar(&usageOpts.until, "until", "", "end of the time window, as a date (default today)")
+	usageCmd.Flags().StringVarP(&usageOpts.output, "output", "o", "table", renderOutputUsage+", csv")
+
+	usageCmd.MarkFlagRequired("policy")
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "report on licensing usage, e.g. for account managers",
+	}
+
+	cmd.AddCommand(usageCmd)
+
+	return cmd
+}
+
+// licenseUsage is a license's usage, where validations are counted within the
+// time window.
+type licenseUsage struct {
+	ID                string `json:"id"`
+	Key               string `json:"key"`
+	Name              string `json:"name"`
+	Status            string `json:"status"`
+	Activations       int    `json:"activations"`
+	MaxMachines       *int   `json:"max_machines"`
+	MachineOverage    int    `json:"machine_overage"`
+	Uses              int    `json:"uses"`
+	MaxUses           *int   `json:"max_uses"`
+	UseOverage        int    `json:"use_overage"`
+	Validations       int64  `json:"validations"`
+	FailedValidations int64  `json:"failed_validations"`
+}
+
+func reportUsageRun(opts *CommandOptions) error {
+	if o := opts.output; o != "csv" {
+		if err := validateRenderOutput(o); err != nil {
+			return err
+		}
+	}
+
+	start, err := parseSince(opts.since)
+	if err != nil {
+		return err
+	}
+
+	end := time.Now().UTC()
+	if u := opts.until; u != "" {
+		end, err = time.Parse("2006-01-02", u)
+		if err != nil {
+			return fmt.Errorf(`until "%s" is not acceptable (must be a date, e.g. 2021-11-30)`, u)
+		}
+	}
+
+	policy, err := opts.client.GetPolicy(opts.ctx, opts.policy)
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	licenses, err := opts.client.ListLicenses(opts.ctx, &keygenext.LicenseFilter{Policy: policy.ID, Paging: keygenext.Paging{All: true}})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	// Machines are listed once for the policy, rather than per license
+	machines, err := opts.client.ListMachines(opts.ctx, &keygenext.MachineFilter{Policy: policy.ID, Paging: keygenext.Paging{All: true}})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	activations := map[string]int{}
+	for _, m := range machines {
+		activations[m.LicenseID]++
+	}
+
+	usage := map[string]*licenseUsage{}
+	for _, l := range licenses {
+		u := &licenseUsage{
+			ID:          l.ID,
+			Key:         l.Key,
+			Name:        l.Name,
+			Status:      l.Status,
+			Activations: activations[l.ID],
+			MaxMachines: l.MaxMachines,
+			Uses:        l.Uses,
+			MaxUses:     l.MaxUses,
+		}
+
+		if u.MaxMachines == nil {
+			u.MaxMachines = policy.MaxMachines
+		}
+
+		if u.MaxUses == nil {
+			u.MaxUses = policy.MaxUses
+		}
+
+		if u.MaxMachines != nil && u.Activations > *u.MaxMachines {
+			u.MachineOverage = u.Activations - *u.MaxMachines
+		}
+
+		if u.MaxUses != nil && u.Uses > *u.MaxUses {
+			u.UseOverage = u.Uses - *u.MaxUses
+		}
+
+		usage[l.ID] = u
+	}
+
+	// Event logs can't be filtered by policy, so the account's validations are
+	// read and matched to the policy's licenses, up to maxEventLogPages
+	truncated := false
+
+	for _, event := range []string{"license.validation.succeeded", "license.validation.failed"} {
+		for page := 1; page <= maxEventLogPages; page++ {
+			logs, err := opts.client.ListEventLogs(opts.ctx, &keygenext.EventLogFilter{
+				Event:        event,
+				Start:        start.Format("2006-01-02"),
+				End:          end.Format("2006-01-02"),
+				ResourceType: "licenses",
+				PageSize:     100,
+				PageNumber:   page,
+			})
+			if err != nil {
+				return formatAPIError(err)
+			}
+
+			for _, l := range logs {
+				u, ok := usage[l.ResourceID]
+				if !ok {
+					continue
+				}
+
+				u.Validations++
+				if event == "license.validation.failed" {
+					u.FailedValidations++
+				}
+			}
+
+			if len(logs) < 100 {
+				break
+			}
+
+			if page == maxEventLogPages {
+				truncated = true
+			}
+		}
+	}
+
+	if truncated {
+		yellow := color.New(color.FgYellow).SprintFunc()
+
+		fmt.Fprintln(os.Stderr, yellow("warning:")+fmt.Sprintf(" validations may be undercounted, since at most %d event logs of each outcome are read (use a shorter window with --since and --until)", maxEventLogPages*100))
+	}
+
+	rows := []*licenseUsage{}
+	for _, u := range usage {
+		rows = append(rows, u)
+	}
+
+	// Overages come first, since they're what a true-up is about
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+
+		if oa, ob := a.MachineOverage+a.UseOverage, b.MachineOverage+b.UseOverage; oa != ob {
+			return oa > ob
+		}
+
+		if a.Validations != b.Validations {
+			return a.Validations > b.Validations
+		}
+
+		return a.ID < b.ID
+	})
+
+	table := [][]string{}
+	for _, u := range rows {
+		table = append(table, []string{u.ID, u.Key, u.Name, u.Status, strconv.Itoa(u.Activations), formatUsageLimit(u.MaxMachines), strconv.Itoa(u.MachineOverage), strconv.Itoa(u.Uses), formatUsageLimit(u.MaxUses), strconv.Itoa(u.UseOverage), strconv.FormatInt(u.Validations, 10), strconv.FormatInt(u.FailedValidations, 10)})
+	}
+
+	switch {
+	case isStructuredOutput(opts.output):
+		return render(opts.output, rendering{value: map[string]interface{}{
+			"policy":    policy.ID,
+			"since":     start.Format("2006-01-02"),
+			"until":     end.Format("2006-01-02"),
+			"licenses":  rows,
+			"truncated": truncated,
+		}})
+	case opts.output == "csv":
+		return printCSV(licenseUsageHeaders, table)
+	}
+
+	var activated, machineOverage, useOverage int
+	var validations, failed int64
+
+	for _, u := range rows {
+		activated += u.Activations
+		machineOverage += u.MachineOverage
+		useOverage += u.UseOverage
+		validations += u.Validations
+		failed += u.FailedValidations
+	}
+
+	// The key is the widest column, so it's only shown for wide output
+	headers := []string{}
+	for _, h := range []string{"id", "name", "status", "activations", "max machines", "overage", "uses", "max uses", "use overage", "validations", "failed", "key"} {
+		headers = append(headers, strings.ToUpper(h))
+	}
+
+	for i, row := range table {
+		table[i] = append(append([]string{row[0]}, row[2:]...), row[1])
+	}
+
+	table = append(table, []string{"total", "", "", strconv.Itoa(activated), "", strconv.Itoa(machineOverage), "", "", strconv.Itoa(useOverage), strconv.FormatInt(validations, 10), strconv.FormatInt(failed, 10), ""})
+
+	fmt.Println("policy " + policy.Name + " (" + policy.ID + ") from " + start.Format("2006-01-02") + " to " + end.Format("2006-01-02"))
+	fmt.Println()
+
+	return render(opts.output, rendering{
+		headers: headers,
+		rows:    table,
+		wide:    1,
+	})
+}
+
+// formatUsageLimit formats a machine or use limit, where nil is unlimited.
+func formatUsageLimit(limit *int) string {
+	if limit == nil {
+		return "unlimited"
+	}
+
+	return strconv.Itoa(*limit)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sourceDateEpoch returns the time --reproducible normalizes archives to, given
+// by $SOURCE_DATE_EPOCH (see https://reproducible-builds.org/specs/source-date-epoch/),
+// or otherwise the time of the current git commit, so that two builds of the
+// same commit agree.
+func sourceDateEpoch() (time.Time, error) {
+	if v := os.Getenv("SOURCE_DATE_EPOCH"); v != "" {
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || sec < 0 {
+			return time.Time{}, errors.New(`environment variable "SOURCE_DATE_EPOCH" is not acceptable (must be a Unix timestamp)`)
+		}
+
+		return time.Unix(sec, 0).UTC(), nil
+	}
+
+	out, err := exec.Command("git", "log", "-1", "--format=%ct").Output()
+	if err != nil {
+		return time.Time{}, errors.New(`flag "--reproducible" requires SOURCE_DATE_EPOCH to be set (or a git repository to use the time of its current commit)`)
+	}
+
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(`commit time "%s" is not acceptable (must be a Unix timestamp)`, strings.TrimSpace(string(out)))
+	}
+
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// normalizeTarHeader strips everything from a tar header which differs
+// between two builds of the same files, i.e. times, owners and the umask's
+// effect on permissions. Entries are already written in a fixed order, since
+// filepath.Walk visits them sorted by byte rather than by locale.
+func normalizeTarHeader(header *tar.Header, epoch time.Time) {
+	// Whole seconds, so that no PAX records with sub-second times are written
+	header.ModTime = epoch.Truncate(time.Second)
+	header.AccessTime = time.Time{}
+	header.ChangeTime = time.Time{}
+	header.Uid = 0
+	header.Gid = 0
+	header.Uname = ""
+	header.Gname = ""
+
+	// Keep the executable bit, which is all that matters once extracted
+	switch {
+	case header.Typeflag == tar.TypeDir || header.Mode&0111 != 0:
+		header.Mode = 0755
+	default:
+		header.Mode = 0644
+	}
+}
@@ -1,17 +1,185 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"runtime"
+	"time"
 
 	"github.com/fatih/color"
-	"github.com/keygen-sh/keygen-cli/internal/keygenext"
+	"github.com/keygen-sh/keygen-cli/keygenext"
 	"github.com/spf13/cobra"
 )
 
-var (
-	rootCmd = &cobra.Command{
+// CommandOptions are a command's flags. Every invocation of a command has its
+// own options, which carry the invocation's session.
+type CommandOptions struct {
+	*session
+
+	filename           string
+	filetype           string
+	name               string
+	description        string
+	version            string
+	platform           string
+	channel            string
+	entitlements       []string
+	signature          string
+	checksum           string
+	signingAlgorithm   string
+	signingKeyPath     string
+	verifyKeyPath      string
+	signingKey         string
+	noAutoUpgrade      bool
+	verifyUpload       bool
+	verifyBytes        int64
+	ttl                time.Duration
+	output             string
+	formula            string
+	homepage           string
+	binary             string
+	tap                string
+	out                string
+	openPR             bool
+	queue              bool
+	queueDir           string
+	nextSigningKeyPath string
+	limit              int
+	maxLicenses        int
+	maxMachines        int
+	maxUsers           int
+	licenses           []string
+	users              []string
+	firstName          string
+	lastName           string
+	role               string
+	invite             bool
+	group              string
+	watch              string
+	debounce           time.Duration
+	ci                 bool
+	ciChannels         []string
+	metadata           map[string]interface{}
+	semverStrict       bool
+	semverCoerce       bool
+	extraChecksums     []string
+	artifacts          []string
+	compress           string
+	compressLevel      int
+	contentType        string
+	createEntitlements bool
+	since              string
+	until              string
+	maxSize            string
+	maxSizeIncrease    string
+	notarize           bool
+	notarizeProfile    string
+	authenticode       string
+	timestampURL       string
+	gpgKey             string
+	host               string
+	publicKey          string
+	apiVersion         string
+	config             string
+	account            string
+	product            string
+	githubActions      bool
+	force              bool
+	publish            bool
+	page               int
+	all                bool
+	sort               string
+	desc               bool
+	fields             []string
+	filters            []string
+	verifyOnly         bool
+	prepareOnly        bool
+	bundle             string
+	fromBundle         string
+	deadline           time.Duration
+	proxy              string
+	caCert             string
+	responseTimeout    time.Duration
+	yes                bool
+	ascii              bool
+	noColor            bool
+	envFile            string
+	constraint         string
+	data               string
+	headers            []string
+	include            bool
+	policy             string
+	count              int
+	user               string
+	expiry             string
+	status             string
+	expiringWithin     string
+	metadataFilters    []string
+	fromFile           string
+	by                 string
+	concurrency        int
+	report             string
+	dryRun             bool
+	engine             string
+	pkg                string
+	manifest           bool
+	noCache            bool
+	uploadConcurrency  int
+	metadataPairs      []string
+	symbols            string
+	url                string
+	platforms          []string
+	distribution       string
+	upgradeInterval    time.Duration
+	signatureTSA       string
+	publishAt          string
+	interval           time.Duration
+	waitEvent          string
+	waitTimeout        time.Duration
+	products           []string
+	signingCtx         string
+	validateProvided   bool
+	fromSums           string
+	sumsDir            string
+	groupBy            string
+	license            string
+	live               bool
+	reap               bool
+	fixtures           string
+	download           bool
+	listen             string
+	summaryFile        string
+	release            string
+	template           string
+	slackWebhook       string
+	discordWebhook     string
+	teamsWebhook       string
+	webhook            string
+	arch               string
+	redact             bool
+	onConflict         string
+	reproducible       bool
+	sourceDate         time.Time
+	constraintsFile    string
+	constraints        *constraintsFile
+	strictAuth         bool
+	units              string
+	ignoreMissing      bool
+	lang               string
+	artifactHost       string
+	reason             string
+	overrideFreeze     bool
+	olderThan          time.Duration
+	dashboardURL       string
+	printURL           bool
+}
+
+// newRootCmd returns a command tree whose commands share the session s.
+func newRootCmd(s *session) *cobra.Command {
+	cmd := &cobra.Command{
 		Use:   "keygen",
 		Short: "CLI to interact with keygen.sh",
 		Long: `CLI to interact with keygen.sh
@@ -20,47 +188,244 @@ Version:
   keygen/` + Version + " " + runtime.GOOS + "-" + runtime.GOARCH + " " + runtime.Version(),
 		Version:       Version,
 		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return s.configure(cmd)
+		},
 		CompletionOptions: cobra.CompletionOptions{
 			DisableDefaultCmd: true,
 		},
 	}
-)
 
-type CommandOptions struct {
-	filename         string
-	filetype         string
-	name             string
-	description      string
-	version          string
-	platform         string
-	channel          string
-	entitlements     []string
-	signature        string
-	checksum         string
-	signingAlgorithm string
-	signingKeyPath   string
-	verifyKeyPath    string
-	signingKey       string
-	noAutoUpgrade    bool
+	opts := s.root
+
+	cmd.PersistentFlags().BoolVar(&opts.noColor, "no-color", false, "disable colors in command output [$NO_COLOR=1]")
+	cmd.PersistentFlags().BoolVar(&opts.ascii, "ascii", false, "only use ASCII in command output, e.g. for progress spinners and arrows [$KEYGEN_ASCII=1]")
+	cmd.PersistentFlags().StringVar(&opts.units, "units", "binary", "units of sizes and speeds in command output: binary, e.g. MiB, decimal, e.g. MB, or raw byte counts [$KEYGEN_UNITS=<units>]")
+	cmd.PersistentFlags().BoolVarP(&opts.yes, "yes", "y", false, "skip confirmation prompts for destructive actions [$KEYGEN_YES=1]")
+	cmd.PersistentFlags().BoolVar(&opts.yes, "non-interactive", false, "alias for --yes")
+	cmd.PersistentFlags().StringVar(&opts.host, "host", defaultHost, "API host, e.g. of a self-hosted Keygen CE or EE instance [$KEYGEN_HOST=<url>]")
+	cmd.PersistentFlags().StringVar(&opts.artifactHost, "artifact-host", "", "rewrite printed artifact URLs to a custom domain fronting downloads, e.g. https://downloads.example.com [$KEYGEN_ARTIFACT_HOST=<url>]")
+	cmd.PersistentFlags().StringVar(&opts.publicKey, "public-key", "", "hex-encoded Ed25519 public key, or a path to one, used to verify API response signatures [$KEYGEN_PUBLIC_KEY=<key>]")
+	cmd.PersistentFlags().StringVar(&opts.config, "config", defaultConfigPath, "path to the project's config file, whose keys are used as flag defaults [$KEYGEN_CONFIG=<path>]")
+	cmd.PersistentFlags().StringVar(&opts.envFile, "env-file", "", "load environment variables from a dotenv file, e.g. .env.release, without overriding ones already set")
+	cmd.PersistentFlags().StringVar(&opts.apiVersion, "api-version", "", "pin API requests to a version, e.g. 1.7 (default the account's version) [$KEYGEN_API_VERSION=<version>]")
+	cmd.PersistentFlags().DurationVar(&opts.deadline, "deadline", 0, "abort the command and any in-flight API requests after a duration, e.g. 30m (default no deadline) [$KEYGEN_DEADLINE=<duration>]")
+	cmd.PersistentFlags().StringVar(&opts.proxy, "proxy", "", "proxy for API and storage requests, e.g. http://proxy.example.com:3128 (default from $HTTPS_PROXY, $HTTP_PROXY and $NO_PROXY) [$KEYGEN_PROXY=<url>]")
+	cmd.PersistentFlags().StringVar(&opts.caCert, "ca-cert", "", "path to PEM-encoded CA certificates to trust in addition to the system's, e.g. a self-hosted instance's private CA [$KEYGEN_CA_CERT=<path>]")
+	cmd.PersistentFlags().DurationVar(&opts.responseTimeout, "response-timeout", 0, "fail API and storage requests whose response doesn't start within a duration, e.g. 1m, without limiting uploads and downloads (default no timeout) [$KEYGEN_RESPONSE_TIMEOUT=<duration>]")
+
+	bindEnv(cmd.PersistentFlags(), "ascii", "KEYGEN_ASCII")
+	bindEnv(cmd.PersistentFlags(), "units", "KEYGEN_UNITS")
+	bindEnv(cmd.PersistentFlags(), "yes", "KEYGEN_YES")
+	bindEnv(cmd.PersistentFlags(), "host", "KEYGEN_HOST")
+	bindEnv(cmd.PersistentFlags(), "public-key", "KEYGEN_PUBLIC_KEY")
+	bindEnv(cmd.PersistentFlags(), "artifact-host", "KEYGEN_ARTIFACT_HOST")
+	bindEnv(cmd.PersistentFlags(), "config", "KEYGEN_CONFIG")
+	bindEnv(cmd.PersistentFlags(), "api-version", "KEYGEN_API_VERSION")
+	bindEnv(cmd.PersistentFlags(), "deadline", "KEYGEN_DEADLINE")
+	bindEnv(cmd.PersistentFlags(), "proxy", "KEYGEN_PROXY")
+	bindEnv(cmd.PersistentFlags(), "ca-cert", "KEYGEN_CA_CERT")
+	bindEnv(cmd.PersistentFlags(), "response-timeout", "KEYGEN_RESPONSE_TIMEOUT")
+
+	cmd.InitDefaultVersionFlag()
+	cmd.InitDefaultHelpFlag()
+
+	cmd.SetHelpCommand(newHelpCmd())
+
+	cmd.AddCommand(
+		newAnnounceCmd(s),
+		newAPICmd(s),
+		newApplyCmd(s),
+		newArtifactsCmd(s),
+		newBrewCmd(s),
+		newBrowseCmd(s),
+		newConfigCmd(s),
+		newDistCmd(s),
+		newFreezeCmd(s),
+		newGenkeyCmd(s),
+		newGroupsCmd(s),
+		newHashVerifyCmd(s),
+		newInitCmd(s),
+		newKeysCmd(s),
+		newLicensesCmd(s),
+		newMachinesCmd(s),
+		newMockCmd(s),
+		newOpenCmd(s),
+		newProductsCmd(s),
+		newQueueCmd(s),
+		newReleasesCmd(s),
+		newReportCmd(s),
+		newSchedulerCmd(s),
+		newSnapshotCmd(s),
+		newSnippetsCmd(s),
+		newUpgradeCmd(s),
+		newUpgradeCheckCmd(s),
+		newUsersCmd(s),
+		newVersionCmd(),
+	)
+
+	s.promptBeforeRun(cmd)
+
+	return cmd
 }
 
-func init() {
-	keygenext.UserAgent = "cli/" + Version
+// offlineAnnotation marks commands which make no requests, e.g. to open a
+// dashboard page, so that neither the API host nor the connections used to
+// reach it are configured for them, and no cassette is recorded.
+const offlineAnnotation = "keygen_offline"
+
+// configure applies the environment, config file and global flags before a
+// command is run.
+func (s *session) configure(cmd *cobra.Command) error {
+	if s.root.envFile != "" {
+		if err := loadEnvFile(s.root.envFile); err != nil {
+			return err
+		}
+	}
+
+	if err := applyEnv(cmd); err != nil {
+		return err
+	}
+
+	if cmd.Annotations[inspectsConfigAnnotation] == "" {
+		if err := s.applyConfig(cmd); err != nil {
+			return err
+		}
+	}
+
+	// Respect https://no-color.org, which our version of color predates. Like
+	// colors, ASCII mode applies to the whole process's output.
+	if s.root.noColor || os.Getenv("NO_COLOR") != "" {
+		color.NoColor = true
+	}
+
+	if s.root.ascii || os.Getenv("TERM") == "dumb" {
+		asciiOutput = true
+	}
+
+	if err := s.configureUnits(); err != nil {
+		return err
+	}
+
+	if cmd.Annotations[offlineAnnotation] != "" {
+		return nil
+	}
+
+	if err := s.configureHost(); err != nil {
+		return err
+	}
 
-	rootCmd.PersistentFlags().BoolVar(&color.NoColor, "no-color", false, "disable colors in command output [$NO_COLOR=1]")
+	if err := s.configureDeadline(); err != nil {
+		return err
+	}
 
-	rootCmd.InitDefaultVersionFlag()
-	rootCmd.InitDefaultHelpFlag()
+	if err := s.configureArtifactHost(); err != nil {
+		return err
+	}
 
-	rootCmd.SetHelpCommand(helpCmd)
+	// Sessions share a pool of connections to the API and storage provider,
+	// which a cassette wraps
+	if err := s.configureTransport(); err != nil {
+		return err
+	}
+
+	// Record or replay API interactions, e.g. for testing pipelines
+	if err := keygenext.UseCassetteFromEnv(); err != nil {
+		return err
+	}
+
+	return s.configureAPIVersion()
 }
 
+// Execute runs the CLI using the process's arguments.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	if cmd, err := run(context.Background(), os.Args[1:]); err != nil {
 		red := color.New(color.FgRed).SprintFunc()
 
 		fmt.Fprintln(os.Stderr, red("error:")+" "+err.Error())
 
+		var apiErr *keygenext.APIError
+		if errors.As(err, &apiErr) {
+			if hint := explainAPIError(apiErr, cmd); hint != "" {
+				fmt.Fprintln(os.Stderr, hint)
+			}
+		}
+
 		os.Exit(1)
 	}
 }
+
+// Run runs the CLI with args in a new session bound to ctx, so that it can be
+// embedded. Runs must not be made concurrently, since the --host and output
+// settings are process-wide.
+func Run(ctx context.Context, args []string) error {
+	_, err := run(ctx, args)
+
+	return err
+}
+
+// run runs the CLI like Run, returning the command which was run, or the
+// root command when none was found.
+func run(ctx context.Context, args []string) (*cobra.Command, error) {
+	s := newSession(ctx)
+	defer func() { s.cancel() }()
+
+	root := newRootCmd(s)
+	root.SetArgs(args)
+
+	cmd, err := root.ExecuteC()
+	if cmd == nil {
+		cmd = root
+	}
+
+	return cmd, s.formatDeadlineError(err)
+}
+
+// addAccountFlags adds the --account and --token flags to commands which talk
+// to the API, falling back to their respective environment variables.
+func addAccountFlags(cmd *cobra.Command, s *session) {
+	cmd.Flags().StringVar(&s.client.Account, "account", "", "your keygen.sh account identifier [$KEYGEN_ACCOUNT_ID=<id>] (required)")
+	cmd.Flags().StringVar(&s.client.Token, "token", "", "your keygen.sh product token [$KEYGEN_PRODUCT_TOKEN] (required)")
+
+	bindEnv(cmd.Flags(), "account", "KEYGEN_ACCOUNT_ID")
+	bindEnv(cmd.Flags(), "token", "KEYGEN_PRODUCT_TOKEN")
+
+	cmd.MarkFlagRequired("account")
+	cmd.MarkFlagRequired("token")
+}
+
+// addProductFlag adds the --product flag, falling back to its environment
+// variable.
+func addProductFlag(cmd *cobra.Command, s *session) {
+	cmd.Flags().StringVar(&s.productID, "product", "", "your keygen.sh product identifier [$KEYGEN_PRODUCT_ID=<id>] (required)")
+
+	bindEnv(cmd.Flags(), "product", "KEYGEN_PRODUCT_ID")
+
+	cmd.MarkFlagRequired("product")
+}
+
+// formatAPIError formats an API error for display, leaving other errors as-is.
+func formatAPIError(err error) error {
+	e, ok := err.(*keygenext.APIError)
+	if !ok {
+		return err
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+	code := e.Code
+	if code == "" {
+		code = "API_ERROR"
+	}
+
+	return &displayedAPIError{message: fmt.Sprintf("%s - %s: %s", italic(code), e.Title, e.Detail), err: e}
+}
+
+// externalTransport pools connections to third-party services separately from
+// keygenext's transport, so that they're never recorded or replayed alongside
+// API interactions.
+var externalTransport = keygenext.NewTransport(keygenext.TransportOptions{})
+
+// newExternalClient returns an HTTP client for third-party services.
+func newExternalClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: externalTransport, Timeout: timeout}
+}
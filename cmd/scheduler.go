@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// publishAtMetadataKey is the metadata key a scheduled release's publish time
+// is kept in, as an RFC3339 timestamp, until the scheduler publishes it.
+const publishAtMetadataKey = "publishAt"
+
+func newSchedulerCmd(s *session) *cobra.Command {
+	runOpts := s.newOptions()
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "publish draft releases scheduled by dist --publish-at once they're due",
+		Example: `  keygen scheduler run --interval 1m
+
+Without --interval, due releases are published once, e.g. from cron.
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return schedulerRunRun(runOpts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(runCmd, s)
+	addProductFlag(runCmd, s)
+
+	runCmd.Flags().DurationVar(&runOpts.interval, "interval", 0, "keep running, checking for due releases at an interval, e.g. 1m (default checks once)")
+	runCmd.Flags().BoolVar(&runOpts.overrideFreeze, "override-freeze", false, "publish due releases to the stable channel even while publishing is frozen by keygen freeze")
+
+	cmd := &cobra.Command{
+		Use:   "scheduler",
+		Short: "publish scheduled releases",
+	}
+
+	cmd.AddCommand(runCmd)
+
+	return cmd
+}
+
+// parsePublishAt parses the time a release is scheduled for, given as an
+// RFC3339 timestamp with or without seconds, e.g. 2024-06-01T09:00Z.
+func parsePublishAt(v string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			if !t.After(time.Now()) {
+				return time.Time{}, fmt.Errorf(`publish time "%s" is not acceptable (must be in the future)`, v)
+			}
+
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf(`publish time "%s" is not acceptable (must be an RFC3339 timestamp, e.g. 2024-06-01T09:00Z)`, v)
+}
+
+// scheduleRelease creates the release as a draft, along with the time it's
+// scheduled for, when dist is given --publish-at.
+func scheduleRelease(opts *CommandOptions, release *keygenext.Release) {
+	if opts.publishAt == "" {
+		return
+	}
+
+	if release.Metadata == nil {
+		release.Metadata = map[string]interface{}{}
+	}
+
+	release.Status = "DRAFT"
+	release.Metadata[publishAtMetadataKey] = opts.publishAt
+}
+
+// checkScheduledRelease makes sure a scheduled release is still a draft once
+// it's upserted, since the requested status isn't guaranteed to be kept, e.g.
+// when upserting over a published release. A published release is yanked
+// before its artifact is uploaded, so that it's not offered as an upgrade
+// before its publish time.
+func checkScheduledRelease(opts *CommandOptions, release *keygenext.Release) error {
+	if opts.publishAt == "" || release.Status == "DRAFT" {
+		return nil
+	}
+
+	if err := opts.client.YankRelease(opts.ctx, release); err != nil {
+		return fmt.Errorf(`release "%s" was published rather than scheduled, and could not be yanked (%w)`, release.ID, formatAPIError(err))
+	}
+
+	return fmt.Errorf(`release "%s" was published rather than scheduled (status %s), so it was yanked`, release.ID, release.Status)
+}
+
+func schedulerRunRun(opts *CommandOptions) error {
+	if opts.interval < 0 {
+		return fmt.Errorf(`interval "%s" is not acceptable (must not be negative)`, opts.interval)
+	}
+
+	for {
+		err := publishDueReleases(opts)
+		if opts.interval == 0 {
+			return err
+		}
+
+		// Keep running through failures, e.g. while the API is unreachable
+		if err != nil {
+			yellow := color.New(color.FgYellow).SprintFunc()
+
+			fmt.Fprintln(os.Stderr, yellow("warning:")+" scheduled releases could not be published ("+err.Error()+")")
+		}
+
+		select {
+		case <-opts.ctx.Done():
+			return nil
+		case <-time.After(opts.interval):
+		}
+	}
+}
+
+// publishDueReleases publishes the product's scheduled drafts whose publish
+// time has passed. Drafts which weren't scheduled are left alone, and ones to
+// the stable channel are left scheduled while publishing is frozen.
+func publishDueReleases(opts *CommandOptions) error {
+	// The freeze may be enabled between checks
+	opts.unfrozen = nil
+
+	drafts, err := opts.client.ListReleases(opts.ctx, &keygenext.ReleaseFilter{
+		Product: opts.productID,
+		Status:  "DRAFT",
+		Paging:  keygenext.Paging{All: true},
+	})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+	now := time.Now()
+
+	for i := range drafts {
+		release := &drafts[i]
+
+		v, ok := release.Metadata[publishAtMetadataKey].(string)
+		if !ok {
+			continue
+		}
+
+		at, err := time.Parse(time.RFC3339, v)
+		if err != nil || at.After(now) {
+			continue
+		}
+
+		if err := checkFreeze(opts, release.Channel); err != nil {
+			yellow := color.New(color.FgYellow).SprintFunc()
+
+			fmt.Fprintln(os.Stderr, yellow("warning:")+" release "+release.ID+" is due but was not published ("+err.Error()+")")
+
+			continue
+		}
+
+		if err := opts.client.PublishRelease(opts.ctx, release); err != nil {
+			return fmt.Errorf(`release "%s" could not be published (%w)`, release.ID, formatAPIError(err))
+		}
+
+		fmt.Println("published release " + italic(release.ID) + " (v" + release.Version + ", " + release.Filename + ") scheduled for " + v)
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/fatih/color"
+)
+
+var (
+	// strictSemverRegex matches versions which follow the semver 2.0.0 spec
+	// exactly, see https://semver.org/#is-there-a-suggested-regular-expression-regex-to-check-a-semver-string
+	strictSemverRegex = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+	// coerceSemverRegex matches the first version-like sequence in a string
+	coerceSemverRegex = regexp.MustCompile(`(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+)
+
+// parseVersion parses a release version according to --semver-strict and
+// --semver-coerce, reporting when the version was normalized. Build metadata
+// is preserved.
+func parseVersion(opts *CommandOptions, v string) (*semver.Version, error) {
+	version, err := checkVersion(opts, v)
+	if err != nil {
+		return nil, err
+	}
+
+	if s := version.String(); s != v {
+		italic := color.New(color.Italic).SprintFunc()
+
+		fmt.Fprintln(os.Stderr, "normalized version "+italic(v)+" to "+italic(s))
+	}
+
+	return version, nil
+}
+
+// checkVersion parses a release version like parseVersion, without reporting
+// normalization, e.g. to validate a version as it's entered.
+func checkVersion(opts *CommandOptions, v string) (*semver.Version, error) {
+	if opts.semverStrict && !strictSemverRegex.MatchString(v) {
+		return nil, fmt.Errorf(`version "%s" is not acceptable (must be a strict semantic version, e.g. 1.2.3-rc.1+build.45)`, v)
+	}
+
+	version, err := semver.NewVersion(v)
+	if err != nil && opts.semverCoerce {
+		version, err = coerceVersion(v)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf(`version "%s" is not acceptable (%s)`, v, strings.ToLower(err.Error()))
+	}
+
+	return version, nil
+}
+
+// coerceVersion extracts a version from a loose version string, e.g. 1.2.3 for
+// "release-1.2.3.4", keeping any prerelease or build metadata that follows.
+func coerceVersion(v string) (*semver.Version, error) {
+	loc := coerceSemverRegex.FindStringSubmatchIndex(v)
+	if loc == nil {
+		return nil, semver.ErrInvalidSemVer
+	}
+
+	parts := []string{"0", "0", "0"}
+	for i := range parts {
+		if start := loc[2+i*2]; start != -1 {
+			parts[i] = v[start:loc[3+i*2]]
+		}
+	}
+
+	coerced := strings.Join(parts, ".")
+
+	if rest := v[loc[1]:]; strings.HasPrefix(rest, "-") || strings.HasPrefix(rest, "+") {
+		if version, err := semver.NewVersion(coerced + rest); err == nil {
+			return version, nil
+		}
+	}
+
+	return semver.NewVersion(coerced)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/keygen-sh/keygen-cli/keygenext"
+)
+
+// session is the state of a single command invocation, i.e. its global flags
+// and the API client and context derived from them. Every command tree built
+// by newRootCmd has its own session, and every command its own options, so
+// that commands can be executed one after another from the same process. The
+// --host, --no-color, --ascii and --units are process-wide, since keygen-go's
+// host and the output settings are globals, so sessions can't be executed
+// concurrently.
+type session struct {
+	// root are the global flags, e.g. --host and --deadline.
+	root *CommandOptions
+
+	// client makes API requests on behalf of the --account and --token.
+	client *keygenext.Client
+
+	// productID is the --product whose releases are managed.
+	productID string
+
+	// ctx is the context API requests are bound to. It's canceled once the
+	// command's --deadline passes, or when a long-running command such as
+	// dist --watch is interrupted, aborting any in-flight requests.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// publicKeys caches the public keys published in the product's metadata,
+	// so that every artifact can be checked without refetching them.
+	publicKeys map[string]string
+
+	// warnings are reported while publishing, and included in dist's JSON
+	// output.
+	warnings []map[string]string
+
+	// bundled are the releases prepared by --prepare-only, written to the
+	// bundle once every artifact is prepared.
+	bundled []*queueEntry
+
+	// published are the releases published by dist, which --manifest lists.
+	published []*keygenext.Release
+
+	// collected, when not nil, collects dist's JSON output for the releases
+	// published to a product of --products, so that every product's releases
+	// are printed in a single document.
+	collected []interface{}
+
+	// summarized are the releases and manifests published by dist, which are
+	// written to its --summary-file.
+	summarized          []*distSummaryRelease
+	summarizedManifests []*distSummaryRelease
+
+	// unfrozen are the accounts whose publish freeze was checked by
+	// checkFreeze, and which may be published to.
+	unfrozen map[string]bool
+
+	// artifactHostChecked is whether the --artifact-host was checked to serve
+	// the account's API.
+	artifactHostChecked bool
+
+	// unpublishedKeyWarned is whether preflightSigningKey warned that the
+	// product has no published public key to check the signing key against.
+	unpublishedKeyWarned bool
+
+	// signingContext overrides the Ed25519ph context releases are signed
+	// with, which is the product ID by default. It may be empty.
+	signingContext *string
+}
+
+func newSession(ctx context.Context) *session {
+	s := &session{
+		client:   &keygenext.Client{UserAgent: "cli/" + Version},
+		ctx:      ctx,
+		cancel:   func() {},
+		warnings: []map[string]string{},
+		bundled:  []*queueEntry{},
+	}
+
+	s.root = s.newOptions()
+
+	return s
+}
+
+// ed25519phContext returns the context releases are signed with using
+// Ed25519ph.
+func (s *session) ed25519phContext() string {
+	if s.signingContext != nil {
+		return *s.signingContext
+	}
+
+	return s.productID
+}
+
+// newOptions returns options for one of the session's commands.
+func (s *session) newOptions() *CommandOptions {
+	return &CommandOptions{session: s}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/keygen-sh/keygen-go"
+)
+
+// newTestOptions returns options for a command of a new session, whose API
+// requests are served by handler for the account "acct".
+func newTestOptions(t *testing.T, handler http.HandlerFunc) *CommandOptions {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	url := keygen.APIURL
+	keygen.APIURL = server.URL
+	t.Cleanup(func() { keygen.APIURL = url })
+
+	s := newSession(context.Background())
+	s.client.Account = "acct"
+	s.client.Token = "token"
+
+	return s.newOptions()
+}
+
+// captureOutput returns what fn writes to stream, e.g. &os.Stderr.
+func captureOutput(t *testing.T, stream **os.File, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Drain the pipe while fn runs, since its buffer fills up long before
+	// e.g. a JSON listing does
+	out := make(chan []byte)
+	go func() {
+		b, _ := ioutil.ReadAll(r)
+		r.Close()
+
+		out <- b
+	}()
+
+	f := *stream
+	*stream = w
+
+	defer func() { *stream = f }()
+
+	fn()
+
+	w.Close()
+
+	return string(<-out)
+}
+
+// writeJSONAPI responds with a JSON:API document.
+func writeJSONAPI(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"crypto"
+	stded25519 "crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+	"golang.org/x/crypto/ssh"
+)
+
+// loadSigner loads the signing key from either a path or its raw value. Paths
+// prefixed with agent:// delegate signing to a running ssh-agent, PKCS#11 URIs
+// delegate signing to a hardware token, and vault:// references delegate
+// signing to Vault's transit engine. Requests to an agent or Vault are bound to
+// the session's context.
+func (s *session) loadSigner(path string, key string) (crypto.Signer, error) {
+	switch {
+	case strings.HasPrefix(path, "agent://"):
+		return newAgentSigner(s.ctx, strings.TrimPrefix(path, "agent://"))
+	case strings.HasPrefix(path, "pkcs11:"):
+		return newPKCS11Signer(path)
+	case strings.HasPrefix(path, "vault://"):
+		return newVaultSigner(s.ctx, path)
+	}
+
+	if path != "" {
+		p, err := homedir.Expand(path)
+		if err != nil {
+			return nil, fmt.Errorf(`signing-key path is not expandable (%s)`, err)
+		}
+
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf(`signing-key path is not readable (%s)`, err)
+		}
+
+		key = string(b)
+	}
+
+	return parseSigningKey(key)
+}
+
+// parseSigningKey decodes an ed25519 private key, auto-detecting its format.
+// Supported formats are hex (as generated by genkey) or base64 encoded keys
+// and seeds, PEM-encoded PKCS#8 (as generated by openssl), and OpenSSH keys
+// (as generated by ssh-keygen), which may be encrypted using the passphrase
+// from $KEYGEN_SIGNING_KEY_PASSPHRASE.
+func parseSigningKey(encSigningKey string) (ed25519.PrivateKey, error) {
+	enc := strings.TrimSpace(encSigningKey)
+
+	if block, _ := pem.Decode([]byte(enc)); block != nil {
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("bad signing key (%s)", err)
+			}
+
+			k, ok := key.(stded25519.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("bad signing key (expected ed25519 got %T)", key)
+			}
+
+			return ed25519.PrivateKey(k), nil
+		case "OPENSSH PRIVATE KEY":
+			var key interface{}
+			var err error
+
+			if passphrase := os.Getenv("KEYGEN_SIGNING_KEY_PASSPHRASE"); passphrase != "" {
+				key, err = ssh.ParseRawPrivateKeyWithPassphrase([]byte(enc), []byte(passphrase))
+			} else {
+				key, err = ssh.ParseRawPrivateKey([]byte(enc))
+			}
+
+			var missing *ssh.PassphraseMissingError
+			if errors.As(err, &missing) {
+				return nil, errors.New("bad signing key (key is encrypted, set $KEYGEN_SIGNING_KEY_PASSPHRASE)")
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("bad signing key (%s)", err)
+			}
+
+			k, ok := key.(*stded25519.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("bad signing key (expected ed25519 got %T)", key)
+			}
+
+			return ed25519.PrivateKey(*k), nil
+		default:
+			return nil, fmt.Errorf(`bad signing key (unsupported PEM type "%s")`, block.Type)
+		}
+	}
+
+	dec, err := hex.DecodeString(enc)
+	if err != nil {
+		// Fall back to base64, with or without padding
+		dec, err = base64.StdEncoding.DecodeString(enc)
+		if err != nil {
+			dec, err = base64.RawStdEncoding.DecodeString(enc)
+		}
+
+		if err != nil {
+			return nil, errors.New("bad signing key (expected hex, base64, PKCS#8 or OpenSSH format)")
+		}
+	}
+
+	switch l := len(dec); l {
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(dec), nil
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(dec), nil
+	default:
+		return nil, fmt.Errorf("bad signing key length (got %d expected %d or %d)", l, ed25519.PrivateKeySize, ed25519.SeedSize)
+	}
+}
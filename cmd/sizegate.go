@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+)
+
+var sizeRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-zA-Z]*)$`)
+
+// sizeUnits are the accepted size units, where KB, MB and GB are decimal and
+// KiB, MiB and GiB are binary.
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1000,
+	"mb":  1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"kib": 1024,
+	"mib": 1024 * 1024,
+	"gib": 1024 * 1024 * 1024,
+}
+
+// parseSize parses a human-readable size, e.g. 150MB or 1.5GiB, into bytes.
+func parseSize(size string) (int64, error) {
+	m := sizeRegex.FindStringSubmatch(strings.TrimSpace(size))
+	if m == nil {
+		return 0, fmt.Errorf(`size "%s" is not acceptable (e.g. 150MB or 1.5GiB)`, size)
+	}
+
+	unit, ok := sizeUnits[strings.ToLower(m[2])]
+	if !ok {
+		return 0, fmt.Errorf(`size "%s" is not acceptable (unit "%s" is not supported)`, size, m[2])
+	}
+
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf(`size "%s" is not acceptable (%s)`, size, err)
+	}
+
+	return int64(n * unit), nil
+}
+
+// checkSizeGate fails when the release is larger than --max-size, or grew by
+// more than --max-size-increase (a percentage or a size) compared to the
+// previous release for the same platform and channel.
+func checkSizeGate(opts *CommandOptions, release *keygenext.Release) error {
+	if s := opts.maxSize; s != "" {
+		max, err := parseSize(s)
+		if err != nil {
+			return err
+		}
+
+		if release.Filesize > max {
+			return fmt.Errorf("release size %s exceeds --max-size %s", formatBytes(release.Filesize), s)
+		}
+	}
+
+	increase := opts.maxSizeIncrease
+	if increase == "" {
+		return nil
+	}
+
+	previous, err := opts.previousRelease(release)
+	if err != nil {
+		// Don't block queueing releases while the API is unreachable
+		if isNetworkError(err) {
+			return nil
+		}
+
+		return formatAPIError(err)
+	}
+
+	if previous == nil {
+		yellow := color.New(color.FgYellow).SprintFunc()
+
+		fmt.Fprintln(os.Stderr, yellow("warning:")+" no previous release to compare the size against")
+
+		return nil
+	}
+
+	growth := release.Filesize - previous.Filesize
+
+	if strings.HasSuffix(increase, "%") {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(increase, "%"), 64)
+		if err != nil {
+			return fmt.Errorf(`size increase "%s" is not acceptable (e.g. 10%% or 5MB)`, increase)
+		}
+
+		if previous.Filesize > 0 && float64(growth)/float64(previous.Filesize)*100 > pct {
+			return fmt.Errorf("release size grew %s from %s (%s %s %s), exceeding --max-size-increase %s", formatSizeDelta(previous.Filesize, release.Filesize), previous.Version, formatBytes(previous.Filesize), glyph("→"), formatBytes(release.Filesize), increase)
+		}
+
+		return nil
+	}
+
+	max, err := parseSize(increase)
+	if err != nil {
+		return err
+	}
+
+	if growth > max {
+		return fmt.Errorf("release size grew %s from %s (%s %s %s), exceeding --max-size-increase %s", formatSizeDelta(previous.Filesize, release.Filesize), previous.Version, formatBytes(previous.Filesize), glyph("→"), formatBytes(release.Filesize), increase)
+	}
+
+	return nil
+}
+
+// previousRelease finds the release with the highest version below the given
+// release's version, for the same platform, channel and filetype.
+func (s *session) previousRelease(release *keygenext.Release) (*keygenext.Release, error) {
+	current, err := semver.NewVersion(release.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Releases are listed newest first rather than by version, so a backport's
+	// predecessor may be past the first page
+	releases, err := s.client.ListReleases(s.ctx, &keygenext.ReleaseFilter{
+		Product:  release.ProductID,
+		Platform: release.Platform,
+		Channel:  release.Channel,
+		Filetype: release.Filetype,
+		Paging:   keygenext.Paging{All: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var previous *keygenext.Release
+	var previousVersion *semver.Version
+
+	for i, r := range releases {
+		// An empty platform filter matches every platform, so skip platform
+		// releases when looking for a platformless release's predecessor
+		if release.Platform == "" && !matchPlatform(r, noPlatform) {
+			continue
+		}
+
+		v, err := semver.NewVersion(r.Version)
+		if err != nil || !v.LessThan(current) {
+			continue
+		}
+
+		if previousVersion == nil || v.GreaterThan(previousVersion) {
+			previous = &releases[i]
+			previousVersion = v
+		}
+	}
+
+	return previous, nil
+}
@@ -0,0 +1,768 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// accountSnapshot is an account's licensing configuration, as written by
+// snapshot and reconciled by apply.
+type accountSnapshot struct {
+	Entitlements []*entitlementSnapshot `yaml:"entitlements"`
+	Products     []*productSnapshot     `yaml:"products"`
+}
+
+// entitlementSnapshot is an entitlement, identified by its code.
+type entitlementSnapshot struct {
+	Code     string                 `yaml:"code"`
+	Name     string                 `yaml:"name"`
+	Metadata map[string]interface{} `yaml:"metadata,omitempty"`
+}
+
+// productSnapshot is a product and its policies. Products are identified by
+// their ID, or by their name when the ID isn't found, e.g. when applying a
+// snapshot of one account to another.
+type productSnapshot struct {
+	ID        string                 `yaml:"id,omitempty"`
+	Name      string                 `yaml:"name"`
+	URL       string                 `yaml:"url,omitempty"`
+	Platforms []string               `yaml:"platforms,omitempty"`
+	Metadata  map[string]interface{} `yaml:"metadata,omitempty"`
+	Policies  []*policySnapshot      `yaml:"policies"`
+}
+
+// policySnapshot is a policy, identified like products but within its product.
+// Entitlements are codes, where leaving them out leaves the policy's
+// entitlements as-is.
+type policySnapshot struct {
+	ID                string                 `yaml:"id,omitempty"`
+	Name              string                 `yaml:"name"`
+	Duration          *int                   `yaml:"duration"`
+	Strict            bool                   `yaml:"strict"`
+	Floating          bool                   `yaml:"floating"`
+	Protected         bool                   `yaml:"protected"`
+	MaxMachines       *int                   `yaml:"maxMachines"`
+	MaxUses           *int                   `yaml:"maxUses"`
+	RequireHeartbeat  bool                   `yaml:"requireHeartbeat"`
+	HeartbeatDuration *int                   `yaml:"heartbeatDuration"`
+	Entitlements      []string               `yaml:"entitlements"`
+	Metadata          map[string]interface{} `yaml:"metadata,omitempty"`
+}
+
+func newSnapshotCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "export products, policies and entitlements to a file",
+		Example: `  keygen snapshot --out account.yml
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return snapshotRun(opts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+
+	cmd.Flags().StringVar(&opts.out, "out", "", "path to write the snapshot to (default stdout)")
+
+	return cmd
+}
+
+func newApplyCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "apply <path>",
+		Short: "create and update products, policies and entitlements to match a snapshot",
+		Example: `  keygen apply account.yml --dry-run
+  keygen apply account.yml
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: applyArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return applyRun(opts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "print the changes which would be made without making them")
+
+	return cmd
+}
+
+func applyArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("snapshot path is required")
+	}
+
+	return nil
+}
+
+// accountState is an account's current licensing configuration.
+type accountState struct {
+	entitlements       keygenext.Entitlements
+	products           keygenext.Products
+	policies           keygenext.Policies
+	policyEntitlements map[string][]string
+}
+
+// fetchAccountState retrieves every product, policy and entitlement, along
+// with the entitlement IDs attached to each policy.
+func fetchAccountState(opts *CommandOptions) (*accountState, error) {
+	all := keygenext.Paging{All: true}
+
+	entitlements, err := opts.client.ListEntitlements(opts.ctx, &keygenext.ListParams{Paging: all})
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := opts.client.ListProducts(opts.ctx, &keygenext.ListParams{Paging: all})
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := opts.client.ListPolicies(opts.ctx, &keygenext.PolicyFilter{Paging: all})
+	if err != nil {
+		return nil, err
+	}
+
+	state := &accountState{
+		entitlements:       entitlements,
+		products:           products,
+		policies:           policies,
+		policyEntitlements: map[string][]string{},
+	}
+
+	for i := range policies {
+		attached, err := opts.client.ListPolicyEntitlements(opts.ctx, &policies[i])
+		if err != nil {
+			return nil, err
+		}
+
+		ids := []string{}
+		for _, e := range attached {
+			ids = append(ids, e.ID)
+		}
+
+		state.policyEntitlements[policies[i].ID] = ids
+	}
+
+	return state, nil
+}
+
+func snapshotRun(opts *CommandOptions) error {
+	state, err := fetchAccountState(opts)
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	snapshot := &accountSnapshot{
+		Entitlements: []*entitlementSnapshot{},
+		Products:     []*productSnapshot{},
+	}
+
+	codes := map[string]string{}
+	for _, e := range state.entitlements {
+		codes[e.ID] = e.Code
+
+		snapshot.Entitlements = append(snapshot.Entitlements, &entitlementSnapshot{Code: e.Code, Name: e.Name, Metadata: e.Metadata})
+	}
+
+	sort.Slice(snapshot.Entitlements, func(i, j int) bool {
+		return snapshot.Entitlements[i].Code < snapshot.Entitlements[j].Code
+	})
+
+	for _, p := range state.products {
+		product := &productSnapshot{
+			ID:        p.ID,
+			Name:      p.Name,
+			URL:       p.URL,
+			Platforms: p.Platforms,
+			Metadata:  p.Metadata,
+			Policies:  []*policySnapshot{},
+		}
+
+		for _, pol := range state.policies {
+			if pol.ProductID != p.ID {
+				continue
+			}
+
+			policy := &policySnapshot{
+				ID:                pol.ID,
+				Name:              pol.Name,
+				Duration:          pol.Duration,
+				Strict:            pol.Strict,
+				Floating:          pol.Floating,
+				Protected:         pol.Protected,
+				MaxMachines:       pol.MaxMachines,
+				MaxUses:           pol.MaxUses,
+				RequireHeartbeat:  pol.RequireHeartbeat,
+				HeartbeatDuration: pol.HeartbeatDuration,
+				Entitlements:      []string{},
+				Metadata:          pol.Metadata,
+			}
+
+			for _, id := range state.policyEntitlements[pol.ID] {
+				policy.Entitlements = append(policy.Entitlements, codes[id])
+			}
+
+			sort.Strings(policy.Entitlements)
+
+			product.Policies = append(product.Policies, policy)
+		}
+
+		sort.SliceStable(product.Policies, func(i, j int) bool {
+			return product.Policies[i].Name < product.Policies[j].Name
+		})
+
+		snapshot.Products = append(snapshot.Products, product)
+	}
+
+	sort.SliceStable(snapshot.Products, func(i, j int) bool {
+		return snapshot.Products[i].Name < snapshot.Products[j].Name
+	})
+
+	b, err := yaml.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	if opts.out == "" || opts.out == "-" {
+		_, err := os.Stdout.Write(b)
+
+		return err
+	}
+
+	p, err := homedir.Expand(opts.out)
+	if err != nil {
+		return fmt.Errorf(`path "%s" is not expandable (%s)`, opts.out, err)
+	}
+
+	if err := ioutil.WriteFile(p, b, 0644); err != nil {
+		return fmt.Errorf(`path "%s" is not writable (%s)`, opts.out, err.(*os.PathError).Err)
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("wrote " + strconv.Itoa(len(snapshot.Products)) + " products and " + strconv.Itoa(len(snapshot.Entitlements)) + " entitlements to " + italic(opts.out))
+
+	return nil
+}
+
+// readSnapshot reads and validates a snapshot. Unknown keys are rejected, so
+// that a misspelled attribute isn't silently reset.
+func readSnapshot(path string) (*accountSnapshot, error) {
+	var b []byte
+
+	if path == "-" {
+		in, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf(`snapshot is not readable (%s)`, err)
+		}
+
+		b = in
+	} else {
+		p, err := homedir.Expand(path)
+		if err != nil {
+			return nil, fmt.Errorf(`path "%s" is not expandable (%s)`, path, err)
+		}
+
+		in, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf(`path "%s" is not readable (%s)`, path, err.(*os.PathError).Err)
+		}
+
+		b = in
+	}
+
+	snapshot := &accountSnapshot{}
+	if err := yaml.UnmarshalStrict(b, snapshot); err != nil {
+		return nil, fmt.Errorf(`snapshot "%s" is not valid (%s)`, path, err)
+	}
+
+	codes := map[string]bool{}
+	for i, e := range snapshot.Entitlements {
+		switch {
+		case e.Code == "" || e.Name == "":
+			return nil, fmt.Errorf(`snapshot "%s" is not valid (entitlement %d must have a code and name)`, path, i+1)
+		case codes[e.Code]:
+			return nil, fmt.Errorf(`snapshot "%s" is not valid (entitlement "%s" is listed more than once)`, path, e.Code)
+		}
+
+		codes[e.Code] = true
+		e.Metadata = normalizeYAMLMap(e.Metadata)
+	}
+
+	for i, p := range snapshot.Products {
+		if p.Name == "" {
+			return nil, fmt.Errorf(`snapshot "%s" is not valid (product %d must have a name)`, path, i+1)
+		}
+
+		p.Metadata = normalizeYAMLMap(p.Metadata)
+
+		// Policies are found by name, so two with the same name would both
+		// update the same policy
+		names := map[string]bool{}
+		for j, pol := range p.Policies {
+			switch {
+			case pol.Name == "":
+				return nil, fmt.Errorf(`snapshot "%s" is not valid (policy %d of product "%s" must have a name)`, path, j+1, p.Name)
+			case names[pol.Name]:
+				return nil, fmt.Errorf(`snapshot "%s" is not valid (policy "%s" of product "%s" is listed more than once)`, path, pol.Name, p.Name)
+			}
+
+			names[pol.Name] = true
+
+			pol.Metadata = normalizeYAMLMap(pol.Metadata)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// applyChange is a create or update made by apply.
+type applyChange struct {
+	action  string
+	kind    string
+	name    string
+	details []string
+	run     func() error
+}
+
+func (c *applyChange) String() string {
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	var line string
+	if c.action == "create" {
+		line = green("+ " + c.kind + " " + c.name)
+	} else {
+		line = yellow("~ " + c.kind + " " + c.name)
+	}
+
+	for _, d := range c.details {
+		line += "\n    " + d
+	}
+
+	return line
+}
+
+func applyRun(opts *CommandOptions, args []string) error {
+	snapshot, err := readSnapshot(args[0])
+	if err != nil {
+		return err
+	}
+
+	state, err := fetchAccountState(opts)
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	changes, err := planApply(opts, snapshot, state)
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("no changes")
+
+		return nil
+	}
+
+	affected := []string{}
+	for _, c := range changes {
+		affected = append(affected, c.String())
+	}
+
+	if opts.dryRun {
+		for _, a := range affected {
+			fmt.Println(a)
+		}
+
+		return nil
+	}
+
+	if err := opts.confirmAction("apply "+strconv.Itoa(len(changes))+" changes", affected, ""); err != nil {
+		return err
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	for i, c := range changes {
+		if err := c.run(); err != nil {
+			return fmt.Errorf("%s %s %s failed, after applying %d of %d changes (%w)", c.action, c.kind, c.name, i, len(changes), formatAPIError(err))
+		}
+
+		fmt.Println(c.action + "d " + c.kind + " " + italic(c.name))
+	}
+
+	return nil
+}
+
+// planApply compares a snapshot against the account's state, returning the
+// changes which reconcile them in the order they must be made: entitlements,
+// then products, then their policies. Nothing is deleted.
+func planApply(opts *CommandOptions, snapshot *accountSnapshot, state *accountState) ([]*applyChange, error) {
+	changes := []*applyChange{}
+
+	entitlements := map[string]*keygenext.Entitlement{}
+	for i := range state.entitlements {
+		entitlements[state.entitlements[i].Code] = &state.entitlements[i]
+	}
+
+	for _, want := range snapshot.Entitlements {
+		want := want
+
+		e, ok := entitlements[want.Code]
+		if !ok {
+			e = &keygenext.Entitlement{Code: want.Code, Name: want.Name, Metadata: want.Metadata}
+			entitlements[want.Code] = e
+
+			changes = append(changes, &applyChange{action: "create", kind: "entitlement", name: want.Code, run: func() error {
+				return opts.client.CreateEntitlement(opts.ctx, e)
+			}})
+
+			continue
+		}
+
+		details := []string{}
+		details = appendChange(details, "name", e.Name, want.Name)
+		details = appendChange(details, "metadata", e.Metadata, want.Metadata)
+
+		if len(details) > 0 {
+			changes = append(changes, &applyChange{action: "update", kind: "entitlement", name: want.Code, details: details, run: func() error {
+				e.Name, e.Metadata = want.Name, want.Metadata
+
+				return opts.client.UpdateEntitlement(opts.ctx, e)
+			}})
+		}
+	}
+
+	// entitlementIDs resolves codes once any entitlements have been created
+	entitlementIDs := func(codes []string) []string {
+		ids := []string{}
+		for _, code := range codes {
+			ids = append(ids, entitlements[code].ID)
+		}
+
+		return ids
+	}
+
+	for _, want := range snapshot.Products {
+		want := want
+
+		p, err := findProduct(state.products, want)
+		if err != nil {
+			return nil, err
+		}
+
+		if p == nil {
+			p = &keygenext.ProductObject{Name: want.Name, URL: want.URL, Platforms: want.Platforms, Metadata: want.Metadata}
+
+			changes = append(changes, &applyChange{action: "create", kind: "product", name: want.Name, run: func() error {
+				return opts.client.CreateProduct(opts.ctx, p)
+			}})
+		} else {
+			details := []string{}
+			details = appendChange(details, "name", p.Name, want.Name)
+			details = appendChange(details, "url", p.URL, want.URL)
+			details = appendChange(details, "platforms", p.Platforms, want.Platforms)
+			details = appendChange(details, "metadata", p.Metadata, want.Metadata)
+
+			if len(details) > 0 {
+				changes = append(changes, &applyChange{action: "update", kind: "product", name: want.Name, details: details, run: func() error {
+					p.Name, p.URL, p.Platforms, p.Metadata = want.Name, want.URL, want.Platforms, want.Metadata
+
+					return opts.client.UpdateProduct(opts.ctx, p)
+				}})
+			}
+		}
+
+		for _, wantPolicy := range want.Policies {
+			wantPolicy := wantPolicy
+
+			for _, code := range wantPolicy.Entitlements {
+				if _, ok := entitlements[code]; !ok {
+					return nil, fmt.Errorf(`entitlement "%s" of policy "%s" is not found (add it to the snapshot's entitlements)`, code, wantPolicy.Name)
+				}
+			}
+
+			pol, err := findPolicy(state.policies, p, wantPolicy)
+			if err != nil {
+				return nil, err
+			}
+
+			name := wantPolicy.Name + " (" + want.Name + ")"
+
+			if pol == nil {
+				pol = &keygenext.Policy{}
+				applyPolicySnapshot(pol, wantPolicy)
+
+				details := []string{}
+				if len(wantPolicy.Entitlements) > 0 {
+					details = append(details, "entitlements  "+strings.Join(wantPolicy.Entitlements, ", "))
+				}
+
+				changes = append(changes, &applyChange{action: "create", kind: "policy", name: name, details: details, run: func() error {
+					pol.ProductID = p.ID
+
+					if err := opts.client.CreatePolicy(opts.ctx, pol); err != nil {
+						return err
+					}
+
+					if len(wantPolicy.Entitlements) == 0 {
+						return nil
+					}
+
+					return opts.client.AttachPolicyEntitlements(opts.ctx, pol, entitlementIDs(wantPolicy.Entitlements))
+				}})
+
+				continue
+			}
+
+			details := []string{}
+			details = appendChange(details, "name", pol.Name, wantPolicy.Name)
+			details = appendChange(details, "duration", pol.Duration, wantPolicy.Duration)
+			details = appendChange(details, "strict", pol.Strict, wantPolicy.Strict)
+			details = appendChange(details, "floating", pol.Floating, wantPolicy.Floating)
+			details = appendChange(details, "protected", pol.Protected, wantPolicy.Protected)
+			details = appendChange(details, "maxMachines", pol.MaxMachines, wantPolicy.MaxMachines)
+			details = appendChange(details, "maxUses", pol.MaxUses, wantPolicy.MaxUses)
+			details = appendChange(details, "requireHeartbeat", pol.RequireHeartbeat, wantPolicy.RequireHeartbeat)
+			details = appendChange(details, "heartbeatDuration", pol.HeartbeatDuration, wantPolicy.HeartbeatDuration)
+			details = appendChange(details, "metadata", pol.Metadata, wantPolicy.Metadata)
+
+			attributesChanged := len(details) > 0
+
+			// Entitlements are compared by code, since new ones have no ID yet
+			var attach, detach []string
+			if wantPolicy.Entitlements != nil {
+				current := map[string]bool{}
+				for _, id := range state.policyEntitlements[pol.ID] {
+					for code, e := range entitlements {
+						if e.ID == id {
+							current[code] = true
+						}
+					}
+				}
+
+				wanted := map[string]bool{}
+				for _, code := range wantPolicy.Entitlements {
+					wanted[code] = true
+
+					if !current[code] {
+						attach = append(attach, code)
+					}
+				}
+
+				for code := range current {
+					if !wanted[code] {
+						detach = append(detach, code)
+					}
+				}
+
+				sort.Strings(detach)
+			}
+
+			if len(attach) > 0 || len(detach) > 0 {
+				green := color.New(color.FgGreen).SprintFunc()
+				red := color.New(color.FgRed).SprintFunc()
+
+				line := "entitlements"
+				for _, code := range attach {
+					line += " " + green("+"+code)
+				}
+
+				for _, code := range detach {
+					line += " " + red("-"+code)
+				}
+
+				details = append(details, line)
+			}
+
+			if len(details) == 0 {
+				continue
+			}
+
+			changes = append(changes, &applyChange{action: "update", kind: "policy", name: name, details: details, run: func() error {
+				if attributesChanged {
+					applyPolicySnapshot(pol, wantPolicy)
+
+					if err := opts.client.UpdatePolicy(opts.ctx, pol); err != nil {
+						return err
+					}
+				}
+
+				if len(attach) > 0 {
+					if err := opts.client.AttachPolicyEntitlements(opts.ctx, pol, entitlementIDs(attach)); err != nil {
+						return err
+					}
+				}
+
+				if len(detach) > 0 {
+					return opts.client.DetachPolicyEntitlements(opts.ctx, pol, entitlementIDs(detach))
+				}
+
+				return nil
+			}})
+		}
+	}
+
+	return changes, nil
+}
+
+// findProduct finds a snapshot's product by ID, falling back to its name.
+func findProduct(products keygenext.Products, want *productSnapshot) (*keygenext.ProductObject, error) {
+	var found *keygenext.ProductObject
+
+	for i := range products {
+		if want.ID != "" && products[i].ID == want.ID {
+			return &products[i], nil
+		}
+
+		if products[i].Name == want.Name {
+			if found != nil {
+				return nil, fmt.Errorf(`product name "%s" is not unique (add the product's id to the snapshot)`, want.Name)
+			}
+
+			found = &products[i]
+		}
+	}
+
+	return found, nil
+}
+
+// findPolicy finds a snapshot's policy within its product by ID, falling back
+// to its name. Policies of a product which doesn't exist yet are never found.
+func findPolicy(policies keygenext.Policies, product *keygenext.ProductObject, want *policySnapshot) (*keygenext.Policy, error) {
+	if product.ID == "" {
+		return nil, nil
+	}
+
+	var found *keygenext.Policy
+
+	for i := range policies {
+		if policies[i].ProductID != product.ID {
+			continue
+		}
+
+		if want.ID != "" && policies[i].ID == want.ID {
+			return &policies[i], nil
+		}
+
+		if policies[i].Name == want.Name {
+			if found != nil {
+				return nil, fmt.Errorf(`policy name "%s" is not unique (add the policy's id to the snapshot)`, want.Name)
+			}
+
+			found = &policies[i]
+		}
+	}
+
+	return found, nil
+}
+
+func applyPolicySnapshot(p *keygenext.Policy, want *policySnapshot) {
+	p.Name = want.Name
+	p.Duration = want.Duration
+	p.Strict = want.Strict
+	p.Floating = want.Floating
+	p.Protected = want.Protected
+	p.MaxMachines = want.MaxMachines
+	p.MaxUses = want.MaxUses
+	p.RequireHeartbeat = want.RequireHeartbeat
+	p.HeartbeatDuration = want.HeartbeatDuration
+	p.Metadata = want.Metadata
+}
+
+// appendChange appends a line describing an attribute's change, unless its
+// current and wanted values are the same. Values are compared as JSON, so that
+// e.g. a number read from YAML equals the same number read from the API.
+func appendChange(details []string, attr string, current interface{}, wanted interface{}) []string {
+	from, to := formatSnapshotValue(current), formatSnapshotValue(wanted)
+	if from == to {
+		return details
+	}
+
+	return append(details, attr+"  "+from+" "+glyph("→")+" "+to)
+}
+
+func formatSnapshotValue(v interface{}) string {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return "{}"
+		}
+	case []string:
+		if len(v) == 0 {
+			return "[]"
+		}
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
+
+// normalizeYAMLMap converts the nested maps decoded by yaml.v2, which have
+// interface{} keys, into maps which can be encoded as JSON.
+func normalizeYAMLMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+
+	out := map[string]interface{}{}
+	for k, v := range m {
+		out[k] = normalizeYAMLValue(v)
+	}
+
+	return out
+}
+
+func normalizeYAMLValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		out := map[string]interface{}{}
+		for k, value := range v {
+			out[fmt.Sprint(k)] = normalizeYAMLValue(value)
+		}
+
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, value := range v {
+			out[i] = normalizeYAMLValue(value)
+		}
+
+		return out
+	}
+
+	return v
+}
@@ -0,0 +1,401 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-go"
+	"github.com/spf13/cobra"
+)
+
+// snippetTemplates are the upgrade snippets for each --lang. Apps other than
+// Go's call the upgrade endpoint themselves, verifying the download's SHA-512
+// checksum and signature before it's used.
+var snippetTemplates = map[string]*template.Template{
+	"go": template.Must(template.New("go").Parse(`// Checks for an upgrade to the app and installs it, e.g. at startup.
+//
+//	go get github.com/keygen-sh/keygen-go
+package main
+
+import "github.com/keygen-sh/keygen-go"
+
+func upgrade(currentVersion string, licenseToken string) error {
+{{- if .SelfHosted }}
+	keygen.APIURL = "{{ .APIURL }}"
+{{- end }}
+	keygen.Account = "{{ .Account }}"
+	keygen.Product = "{{ .Product }}"
+	keygen.Token = licenseToken
+	keygen.UpgradeKey = "{{ .PublicKey }}"
+	keygen.Channel = "{{ .Channel }}"
+{{- if .Filetype }}
+	keygen.Filetype = "{{ .Filetype }}"
+{{- end }}
+
+	release, err := keygen.Upgrade(currentVersion)
+	switch {
+	case err == keygen.ErrUpgradeNotAvailable:
+		return nil
+	case err != nil:
+		return err
+	}
+
+	// Verifies the checksum and signature before replacing the executable
+	return release.Install()
+}
+`)),
+	"js": template.Must(template.New("js").Parse(`// Checks for an upgrade to the app and downloads it, verified (Node 18+).
+//
+//   npm install @noble/curves
+import { createHash } from 'node:crypto'
+import { {{ .Algorithm }} } from '@noble/curves/ed25519'
+
+const API_URL = '{{ .APIURL }}/v1/accounts/{{ .Account }}'
+const PRODUCT = '{{ .Product }}'
+const PUBLIC_KEY = '{{ .PublicKey }}'
+const CHANNEL = '{{ .Channel }}'
+const PLATFORM = '{{ .Platform }}'
+{{- if .Prehashed }}
+const SIGNING_CONTEXT = '{{ .Context }}'
+{{- end }}
+
+export async function upgrade(currentVersion, licenseKey) {
+  const headers = { Accept: 'application/vnd.api+json', Authorization: ` + "`License ${licenseKey}`" + ` }
+  const params = new URLSearchParams({ product: PRODUCT, version: currentVersion, channel: CHANNEL, platform: PLATFORM })
+
+  const res = await fetch(` + "`${API_URL}/releases/actions/upgrade?${params}`" + `, { headers, redirect: 'manual' })
+  if (res.status === 204) {
+    return null // up to date
+  }
+
+  const { data: artifact } = await res.json()
+  const { data: release } = await fetch(` + "`${API_URL}/releases/${artifact.relationships.release.data.id}`" + `, { headers }).then(r => r.json())
+  const { version, checksum, signature } = release.attributes
+
+  const file = Buffer.from(await fetch(res.headers.get('location')).then(r => r.arrayBuffer()))
+
+  const digest = createHash('sha512').update(file).digest('base64')
+  if (digest.replace(/=+$/, '') !== checksum.replace(/=+$/, '')) {
+    throw new Error('upgrade checksum does not match')
+  }
+{{ if .Prehashed }}
+  if (!ed25519ph.verify(Buffer.from(signature, 'base64'), file, PUBLIC_KEY, { context: Buffer.from(SIGNING_CONTEXT) })) {
+{{- else }}
+  if (!ed25519.verify(Buffer.from(signature, 'base64'), file, PUBLIC_KEY)) {
+{{- end }}
+    throw new Error('upgrade signature does not verify')
+  }
+
+  return { version, file }
+}
+`)),
+	"rust": template.Must(template.New("rust").Parse(`// Checks for an upgrade to the app and downloads it, verified.
+//
+//   [dependencies]
+//   base64 = "0.21"
+//   ed25519-dalek = { version = "2", features = ["digest"] }
+//   hex = "0.4"
+//   reqwest = { version = "0.11", features = ["blocking", "json"] }
+//   serde_json = "1"
+//   sha2 = "0.10"
+use base64::{engine::general_purpose::STANDARD_NO_PAD, Engine};
+use ed25519_dalek::{Signature, {{ if not .Prehashed }}Verifier, {{ end }}VerifyingKey};
+use reqwest::{blocking::Client, header, redirect::Policy, StatusCode};
+use sha2::{Digest, Sha512};
+
+const API_URL: &str = "{{ .APIURL }}/v1/accounts/{{ .Account }}";
+const PRODUCT: &str = "{{ .Product }}";
+const PUBLIC_KEY: &str = "{{ .PublicKey }}";
+const CHANNEL: &str = "{{ .Channel }}";
+const PLATFORM: &str = "{{ .Platform }}";
+{{- if .Prehashed }}
+const SIGNING_CONTEXT: &str = "{{ .Context }}";
+{{- end }}
+
+pub fn upgrade(current_version: &str, license_key: &str) -> Result<Option<(String, Vec<u8>)>, Box<dyn std::error::Error>> {
+    let client = Client::builder().redirect(Policy::none()).build()?;
+    let auth = format!("License {}", license_key);
+
+    let res = client
+        .get(format!("{}/releases/actions/upgrade", API_URL))
+        .query(&[("product", PRODUCT), ("version", current_version), ("channel", CHANNEL), ("platform", PLATFORM)])
+        .header(header::ACCEPT, "application/vnd.api+json")
+        .header(header::AUTHORIZATION, &auth)
+        .send()?;
+    if res.status() == StatusCode::NO_CONTENT {
+        return Ok(None); // up to date
+    }
+
+    let location = res.headers()[header::LOCATION].to_str()?.to_string();
+    let artifact: serde_json::Value = res.json()?;
+    let release: serde_json::Value = client
+        .get(format!("{}/releases/{}", API_URL, artifact["data"]["relationships"]["release"]["data"]["id"].as_str().unwrap_or_default()))
+        .header(header::ACCEPT, "application/vnd.api+json")
+        .header(header::AUTHORIZATION, &auth)
+        .send()?
+        .json()?;
+    let attrs = &release["data"]["attributes"];
+
+    let file = client.get(location).send()?.bytes()?.to_vec();
+
+    let checksum = attrs["checksum"].as_str().unwrap_or_default().trim_end_matches('=');
+    if STANDARD_NO_PAD.encode(Sha512::digest(&file)) != checksum {
+        return Err("upgrade checksum does not match".into());
+    }
+
+    let key: [u8; 32] = hex::decode(PUBLIC_KEY)?.try_into().map_err(|_| "public key is not valid")?;
+    let key = VerifyingKey::from_bytes(&key)?;
+    let signature = Signature::from_slice(&STANDARD_NO_PAD.decode(attrs["signature"].as_str().unwrap_or_default().trim_end_matches('='))?)?;
+{{- if .Prehashed }}
+    key.verify_prehashed(Sha512::new_with_prefix(&file), Some(SIGNING_CONTEXT.as_bytes()), &signature)?;
+{{- else }}
+    key.verify(&file, &signature)?;
+{{- end }}
+
+    Ok(Some((attrs["version"].as_str().unwrap_or_default().to_string(), file)))
+}
+`)),
+	"swift": template.Must(template.New("swift").Parse(`// Checks for an upgrade to the app and downloads it, verified using CryptoKit.
+import CryptoKit
+import Foundation
+
+let apiURL = "{{ .APIURL }}/v1/accounts/{{ .Account }}"
+let product = "{{ .Product }}"
+let publicKey = "{{ .PublicKey }}"
+let channel = "{{ .Channel }}"
+let platform = "{{ .Platform }}"
+
+enum UpgradeError: Error {
+    case invalidResponse, checksumMismatch, signatureInvalid
+}
+
+struct Document: Decodable {
+    struct Resource: Decodable {
+        struct Attributes: Decodable {
+            let version: String?
+            let checksum: String?
+            let signature: String?
+        }
+
+        struct Relationships: Decodable {
+            struct Release: Decodable {
+                struct Identifier: Decodable { let id: String }
+                let data: Identifier
+            }
+
+            let release: Release?
+        }
+
+        let attributes: Attributes
+        let relationships: Relationships?
+    }
+
+    let data: Resource
+}
+
+final class NoRedirects: NSObject, URLSessionTaskDelegate {
+    func urlSession(_ session: URLSession, task: URLSessionTask, willPerformHTTPRedirection response: HTTPURLResponse, newRequest request: URLRequest, completionHandler: @escaping (URLRequest?) -> Void) {
+        completionHandler(nil)
+    }
+}
+
+func decodeBase64(_ s: String) -> Data? {
+    let trimmed = s.trimmingCharacters(in: CharacterSet(charactersIn: "="))
+    return Data(base64Encoded: trimmed.padding(toLength: (trimmed.count + 3) / 4 * 4, withPad: "=", startingAt: 0))
+}
+
+func upgrade(currentVersion: String, licenseKey: String) async throws -> (version: String, file: Data)? {
+    let session = URLSession(configuration: .default, delegate: NoRedirects(), delegateQueue: nil)
+
+    func get(_ url: URL) async throws -> (Data, HTTPURLResponse) {
+        var request = URLRequest(url: url)
+        request.setValue("application/vnd.api+json", forHTTPHeaderField: "Accept")
+        request.setValue("License \(licenseKey)", forHTTPHeaderField: "Authorization")
+
+        let (data, response) = try await session.data(for: request)
+        guard let res = response as? HTTPURLResponse else { throw UpgradeError.invalidResponse }
+
+        return (data, res)
+    }
+
+    var components = URLComponents(string: "\(apiURL)/releases/actions/upgrade")!
+    components.queryItems = [
+        URLQueryItem(name: "product", value: product),
+        URLQueryItem(name: "version", value: currentVersion),
+        URLQueryItem(name: "channel", value: channel),
+        URLQueryItem(name: "platform", value: platform),
+    ]
+
+    let (body, res) = try await get(components.url!)
+    if res.statusCode == 204 {
+        return nil // up to date
+    }
+
+    guard let location = res.value(forHTTPHeaderField: "Location").flatMap(URL.init(string:)),
+          let releaseID = try JSONDecoder().decode(Document.self, from: body).data.relationships?.release?.data.id
+    else { throw UpgradeError.invalidResponse }
+
+    let release = try JSONDecoder().decode(Document.self, from: try await get(URL(string: "\(apiURL)/releases/\(releaseID)")!).0).data.attributes
+    let (file, _) = try await URLSession.shared.data(from: location)
+
+    guard let checksum = release.checksum.flatMap(decodeBase64), Data(SHA512.hash(data: file)) == checksum else {
+        throw UpgradeError.checksumMismatch
+    }
+
+    let keyBytes = stride(from: 0, to: publicKey.count, by: 2).compactMap {
+        UInt8(publicKey.dropFirst($0).prefix(2), radix: 16)
+    }
+    let key = try Curve25519.Signing.PublicKey(rawRepresentation: keyBytes)
+
+    guard let signature = release.signature.flatMap(decodeBase64), key.isValidSignature(signature, for: file) else {
+        throw UpgradeError.signatureInvalid
+    }
+
+    return (release.version ?? "", file)
+}
+`)),
+}
+
+func newSnippetsCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "snippets",
+		Short: "print upgrade code for an app, filled in with a release's account, product, public key and channel",
+		Example: `  keygen snippets --release 1fddcec8-8dd3-4d8d-9b16-215cac0f9b52 --lang go
+  keygen snippets --release 1fddcec8-8dd3-4d8d-9b16-215cac0f9b52 --lang rust --out upgrade.rs
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return snippetsRun(opts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+	addProductFlag(cmd, s)
+
+	cmd.Flags().StringVar(&opts.release, "release", "", "release, by ID, whose channel, platform and signature the snippet is for (required)")
+	cmd.Flags().StringVar(&opts.lang, "lang", "", "language of the snippet, one of: go, js, rust, swift (required)")
+	cmd.Flags().StringVar(&opts.out, "out", "", "write the snippet to the specified file (defaults to stdout)")
+	cmd.Flags().StringVar(&opts.signingAlgorithm, "signing-algorithm", "", "the signing algorithm the release was signed using, one of: ed25519ph, ed25519 (default the one recorded in its metadata, or ed25519ph)")
+
+	cmd.MarkFlagRequired("release")
+	cmd.MarkFlagRequired("lang")
+
+	return cmd
+}
+
+func snippetsRun(opts *CommandOptions) error {
+	tmpl, ok := snippetTemplates[opts.lang]
+	if !ok {
+		return fmt.Errorf(`language "%s" is not supported (must be one of: %s)`, opts.lang, strings.Join(snippetLangs(), ", "))
+	}
+
+	switch opts.signingAlgorithm {
+	case "", "ed25519ph", "ed25519":
+	default:
+		return fmt.Errorf(`signing algorithm "%s" is not supported (must be one of: ed25519ph, ed25519)`, opts.signingAlgorithm)
+	}
+
+	release, err := opts.client.GetRelease(opts.ctx, opts.release)
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	keys, err := opts.publishedPublicKeys()
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	key, ok := keys["publicKey"]
+	if !ok {
+		return errors.New("product has no public key in its metadata (use keygen keys check --publish to publish one)")
+	}
+
+	if release.Signature == "" {
+		return fmt.Errorf(`release "%s" is not acceptable (it's unsigned, but snippets verify signatures, so publish it using --signing-key)`, release.ID)
+	}
+
+	// Releases signed using ed25519ph record their context, other than ones
+	// given a --signature or published before it was recorded, which are
+	// assumed to use dist's defaults, i.e. ed25519ph and the product ID
+	algorithm, context := "ed25519ph", opts.productID
+	if v, ok := release.Metadata["signingContext"].(string); ok {
+		context = v
+	}
+
+	switch opts.signingAlgorithm {
+	case "":
+		if _, ok := release.Metadata["signingContext"]; !ok {
+			yellow := color.New(color.FgYellow).SprintFunc()
+
+			fmt.Fprintln(os.Stderr, yellow("warning:")+fmt.Sprintf(` release "%s" has no signing context in its metadata, so it's assumed to be signed using ed25519ph with the product ID (use --signing-algorithm to override)`, release.ID))
+		}
+	case "ed25519":
+		algorithm, context = "ed25519", ""
+	}
+
+	switch {
+	case opts.lang == "go" && (algorithm != "ed25519ph" || context != opts.productID):
+		return fmt.Errorf(`release "%s" is not acceptable for go (keygen-go only verifies ed25519ph signatures whose context is the product ID)`, release.ID)
+	case opts.lang == "swift" && algorithm != "ed25519":
+		return fmt.Errorf(`release "%s" is not acceptable for swift (CryptoKit can't verify ed25519ph signatures, so publish it using --signing-algorithm ed25519)`, release.ID)
+	}
+
+	var buf bytes.Buffer
+
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"APIURL":     strings.TrimSuffix(keygen.APIURL, "/"),
+		"SelfHosted": isSelfHosted(),
+		"Account":    opts.client.Account,
+		"Product":    opts.productID,
+		"PublicKey":  key,
+		"Channel":    release.Channel,
+		"Platform":   release.Platform,
+		"Filetype":   release.Filetype,
+		"Algorithm":  algorithm,
+		"Prehashed":  algorithm == "ed25519ph",
+		"Context":    context,
+	})
+	if err != nil {
+		return err
+	}
+
+	if opts.out != "" {
+		if err := ioutil.WriteFile(opts.out, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf(`snippet could not be written (%s)`, err)
+		}
+
+		fmt.Println("wrote snippet to " + opts.out)
+
+		return nil
+	}
+
+	fmt.Print(buf.String())
+
+	return nil
+}
+
+// snippetLangs returns the supported --lang values, sorted.
+func snippetLangs() []string {
+	langs := []string{}
+	for lang := range snippetTemplates {
+		langs = append(langs, lang)
+	}
+
+	sort.Strings(langs)
+
+	return langs
+}
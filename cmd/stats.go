@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// maxEventLogPages limits how many pages of event logs of each event are read,
+// so windowed counts beyond maxEventLogPages*100 events are truncated.
+const maxEventLogPages = 100
+
+func newReleasesStatsCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "summarize downloads, upgrades and unique licenses per release",
+		Example: `  keygen releases stats --version 1.2.3 --since 7d
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return releasesStatsRun(opts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addAccountFlags(cmd, s)
+	addProductFlag(cmd, s)
+
+	cmd.Flags().StringVar(&opts.version, "version", "", "only summarize releases for a version (default summarizes the product's releases)")
+	cmd.Flags().StringVar(&opts.channel, "channel", "", "only summarize releases for a channel")
+	cmd.Flags().StringVar(&opts.since, "since", "30d", "start of the time window, as a duration (e.g. 7d, 12h) or a date (e.g. 2021-11-01)")
+	cmd.Flags().StringVar(&opts.until, "until", "", "end of the time window, as a date (default today)")
+	cmd.Flags().IntVar(&opts.limit, "limit", 100, "number of releases to summarize")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "table", renderOutputUsage+", csv")
+
+	return cmd
+}
+
+// releaseStats are a release's usage within the time window.
+type releaseStats struct {
+	ID             string `json:"id"`
+	Version        string `json:"version"`
+	Channel        string `json:"channel"`
+	Platform       string `json:"platform"`
+	Filetype       string `json:"filetype"`
+	Downloads      int64  `json:"downloads"`
+	Upgrades       int64  `json:"upgrades"`
+	Licenses       int    `json:"unique_licenses"`
+	TotalDownloads int64  `json:"total_downloads"`
+	TotalUpgrades  int64  `json:"total_upgrades"`
+
+	licenses map[string]bool
+}
+
+func releasesStatsRun(opts *CommandOptions) error {
+	if o := opts.output; o != "csv" {
+		if err := validateRenderOutput(o); err != nil {
+			return err
+		}
+	}
+
+	start, err := parseSince(opts.since)
+	if err != nil {
+		return err
+	}
+
+	end := time.Now().UTC()
+	if u := opts.until; u != "" {
+		end, err = time.Parse("2006-01-02", u)
+		if err != nil {
+			return fmt.Errorf(`until "%s" is not acceptable (must be a date, e.g. 2021-11-30)`, u)
+		}
+	}
+
+	releases, err := opts.client.ListReleases(opts.ctx, &keygenext.ReleaseFilter{Product: opts.productID, Version: opts.version, Channel: opts.channel, Limit: opts.limit})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	stats := map[string]*releaseStats{}
+	for _, r := range releases {
+		stats[r.ID] = &releaseStats{
+			ID:             r.ID,
+			Version:        r.Version,
+			Channel:        r.Channel,
+			Platform:       r.Platform,
+			Filetype:       r.Filetype,
+			TotalDownloads: r.Downloads,
+			TotalUpgrades:  r.Upgrades,
+			licenses:       map[string]bool{},
+		}
+	}
+
+	// The events of every release are read at once and matched to the listed
+	// releases, up to maxEventLogPages, so busy windows are truncated
+	truncated := false
+
+	for _, event := range []string{"release.downloaded", "release.upgraded"} {
+		for page := 1; page <= maxEventLogPages; page++ {
+			logs, err := opts.client.ListEventLogs(opts.ctx, &keygenext.EventLogFilter{
+				Event:        event,
+				Start:        start.Format("2006-01-02"),
+				End:          end.Format("2006-01-02"),
+				ResourceType: "releases",
+				PageSize:     100,
+				PageNumber:   page,
+			})
+			if err != nil {
+				return formatAPIError(err)
+			}
+
+			for _, l := range logs {
+				s, ok := stats[l.ResourceID]
+				if !ok {
+					continue
+				}
+
+				if event == "release.downloaded" {
+					s.Downloads++
+				} else {
+					s.Upgrades++
+				}
+
+				if l.WhodunnitType == "licenses" {
+					s.licenses[l.WhodunnitID] = true
+				}
+			}
+
+			if len(logs) < 100 {
+				break
+			}
+
+			if page == maxEventLogPages {
+				truncated = true
+			}
+		}
+	}
+
+	if truncated {
+		yellow := color.New(color.FgYellow).SprintFunc()
+
+		fmt.Fprintln(os.Stderr, yellow("warning:")+fmt.Sprintf(" downloads and upgrades may be undercounted, since at most %d event logs of each are read (use a shorter window with --since and --until)", maxEventLogPages*100))
+	}
+
+	rows := []*releaseStats{}
+	for _, s := range stats {
+		s.Licenses = len(s.licenses)
+		rows = append(rows, s)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Version != rows[j].Version {
+			return compareVersions(rows[i].Version, rows[j].Version) > 0
+		}
+
+		return rows[i].Platform < rows[j].Platform
+	})
+
+	switch {
+	case isStructuredOutput(opts.output):
+		return render(opts.output, rendering{value: map[string]interface{}{
+			"since":     start.Format("2006-01-02"),
+			"until":     end.Format("2006-01-02"),
+			"releases":  rows,
+			"truncated": truncated,
+		}})
+	case opts.output == "csv":
+		table := [][]string{}
+		for _, s := range rows {
+			table = append(table, []string{s.ID, s.Version, s.Channel, s.Platform, s.Filetype, strconv.FormatInt(s.Downloads, 10), strconv.FormatInt(s.Upgrades, 10), strconv.Itoa(s.Licenses), strconv.FormatInt(s.TotalDownloads, 10), strconv.FormatInt(s.TotalUpgrades, 10)})
+		}
+
+		return printCSV([]string{"id", "version", "channel", "platform", "filetype", "downloads", "upgrades", "unique_licenses", "total_downloads", "total_upgrades"}, table)
+	}
+
+	var downloads, upgrades int64
+
+	// Licenses may have downloaded several releases, so they're counted again
+	// for the totals
+	licenses := map[string]bool{}
+
+	table := [][]string{}
+	for _, s := range rows {
+		table = append(table, []string{s.Version, s.Platform, s.Filetype, strconv.FormatInt(s.Downloads, 10), strconv.FormatInt(s.Upgrades, 10), strconv.Itoa(s.Licenses), strconv.FormatInt(s.TotalDownloads, 10), s.Channel, strconv.FormatInt(s.TotalUpgrades, 10), s.ID})
+
+		downloads += s.Downloads
+		upgrades += s.Upgrades
+
+		for id := range s.licenses {
+			licenses[id] = true
+		}
+	}
+
+	table = append(table, []string{"total", "", "", strconv.FormatInt(downloads, 10), strconv.FormatInt(upgrades, 10), strconv.Itoa(len(licenses)), "", "", "", ""})
+
+	fmt.Println("from " + start.Format("2006-01-02") + " to " + end.Format("2006-01-02"))
+	fmt.Println()
+
+	return render(opts.output, rendering{
+		headers: []string{"VERSION", "PLATFORM", "FILETYPE", "DOWNLOADS", "UPGRADES", "LICENSES", "ALL-TIME DOWNLOADS", "CHANNEL", "ALL-TIME UPGRADES", "ID"},
+		rows:    table,
+		wide:    3,
+	})
+}
+
+// parseSince parses the start of a time window, given as a date, a duration
+// or a number of days, e.g. 2021-11-01, 12h or 7d.
+func parseSince(since string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", since); err == nil {
+		return t, nil
+	}
+
+	if strings.HasSuffix(since, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(since, "d")); err == nil && days > 0 {
+			return time.Now().UTC().AddDate(0, 0, -days), nil
+		}
+	}
+
+	if d, err := time.ParseDuration(since); err == nil && d > 0 {
+		return time.Now().UTC().Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf(`since "%s" is not acceptable (must be a duration, e.g. 7d, or a date, e.g. 2021-11-01)`, since)
+}
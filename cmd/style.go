@@ -0,0 +1,52 @@
+package cmd
+
+import "strings"
+
+// asciiOutput replaces unicode symbols, e.g. arrows and spinners, with ASCII
+// for terminals and log collectors which garble them. It's set by the global
+// --ascii flag, and is the default for dumb terminals. Like colors, it applies
+// to the whole process.
+var asciiOutput bool
+
+// glyphs are the ASCII replacements for unicode symbols used in output.
+var glyphs = map[string]string{
+	"→": "->",
+	"›": ">",
+	"…": "...",
+	"·": "|",
+	"↑": "up",
+	"↓": "down",
+}
+
+// glyph returns a unicode symbol, or its ASCII replacement in ASCII mode.
+func glyph(symbol string) string {
+	if asciiOutput {
+		if ascii, ok := glyphs[symbol]; ok {
+			return ascii
+		}
+	}
+
+	return symbol
+}
+
+// asciiText replaces every unicode symbol in s when in ASCII mode.
+func asciiText(s string) string {
+	if !asciiOutput {
+		return s
+	}
+
+	for symbol, ascii := range glyphs {
+		s = strings.ReplaceAll(s, symbol, ascii)
+	}
+
+	return s
+}
+
+// spinnerFrames returns the frames of a progress spinner.
+func spinnerFrames() []string {
+	if asciiOutput {
+		return []string{"|", "/", "-", "\\"}
+	}
+
+	return []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/mitchellh/go-homedir"
+)
+
+// distSummary is the --summary-file written once dist finishes, for later
+// pipeline stages, e.g. a deployment, to consume without parsing dist's output.
+type distSummary struct {
+	Status     string                `json:"status"`
+	Error      string                `json:"error,omitempty"`
+	Version    string                `json:"version"`
+	Channel    string                `json:"channel"`
+	Started    time.Time             `json:"started"`
+	Finished   time.Time             `json:"finished"`
+	Duration   float64               `json:"duration_seconds"`
+	Releases   []*distSummaryRelease `json:"releases"`
+	Manifests  []*distSummaryRelease `json:"manifests,omitempty"`
+	Warnings   []map[string]string   `json:"warnings"`
+	CLIVersion string                `json:"cli_version"`
+}
+
+// distSummaryRelease is a release published by dist, along with its upload
+// telemetry.
+type distSummaryRelease struct {
+	ID          string           `json:"id"`
+	ArtifactID  string           `json:"artifact_id"`
+	Product     string           `json:"product"`
+	Version     string           `json:"version"`
+	Channel     string           `json:"channel"`
+	Platform    string           `json:"platform"`
+	Filename    string           `json:"filename"`
+	Filesize    int64            `json:"filesize"`
+	Filetype    string           `json:"filetype"`
+	Status      string           `json:"status"`
+	URL         string           `json:"url"`
+	Checksum    string           `json:"checksum"`
+	Signature   string           `json:"signature"`
+	Telemetry   *uploadTelemetry `json:"telemetry,omitempty"`
+	PublishedAt time.Time        `json:"published_at"`
+}
+
+// summarizeRelease records a published release for the --summary-file.
+func (s *session) summarizeRelease(release *keygenext.Release, telemetry *uploadTelemetry) *distSummaryRelease {
+	return &distSummaryRelease{
+		ID:          release.ID,
+		ArtifactID:  release.ArtifactID,
+		Product:     release.ProductID,
+		Version:     release.Version,
+		Channel:     release.Channel,
+		Platform:    release.Platform,
+		Filename:    release.Filename,
+		Filesize:    release.Filesize,
+		Filetype:    release.Filetype,
+		Status:      release.Status,
+		URL:         s.client.ReleaseArtifactURL(release),
+		Checksum:    release.Checksum,
+		Signature:   release.Signature,
+		Telemetry:   telemetry,
+		PublishedAt: time.Now().UTC(),
+	}
+}
+
+// writeSummary writes the --summary-file, including when dist failed part way
+// through, so that later stages can tell what was published.
+func writeSummary(opts *CommandOptions, path string, started time.Time, err error) error {
+	finished := time.Now()
+
+	summary := &distSummary{
+		Status:     "published",
+		Version:    opts.version,
+		Channel:    opts.channel,
+		Started:    started.UTC(),
+		Finished:   finished.UTC(),
+		Duration:   finished.Sub(started).Seconds(),
+		Releases:   opts.summarized,
+		Manifests:  opts.summarizedManifests,
+		Warnings:   opts.warnings,
+		CLIVersion: Version,
+	}
+
+	if err != nil {
+		summary.Status = "failed"
+		summary.Error = err.Error()
+	}
+
+	// Versions are normalized when they're published, e.g. v1.2 to 1.2.0
+	if len(opts.summarized) != 0 {
+		summary.Version = opts.summarized[0].Version
+	} else {
+		summary.Releases = []*distSummaryRelease{}
+	}
+
+	b, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return fmt.Errorf(`summary path "%s" is not expandable (%s)`, path, err)
+	}
+
+	if err := ioutil.WriteFile(p, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf(`summary path "%s" is not writable (%s)`, path, err)
+	}
+
+	return nil
+}
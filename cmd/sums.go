@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// sumsEntry is a file listed in a SUMS manifest, along with its SHA-512 digest
+// and, for manifests published by dist, its filesize (otherwise -1).
+type sumsEntry struct {
+	filename string
+	sum      []byte
+	size     int64
+}
+
+// parseSums parses a SHA-512 SUMS manifest in the format written by sha512sum,
+// i.e. "<hex digest>  <filename>" lines (or " *<filename>" in binary mode), or
+// the BSD format written by `shasum --tag`, i.e. "SHA512 (<filename>) = <hex>".
+// Comments are skipped, except for the filesizes listed by dist's manifests.
+func parseSums(b []byte) ([]*sumsEntry, error) {
+	entries := []*sumsEntry{}
+	seen := map[string]bool{}
+	sizes := map[string]int64{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			if strings.HasPrefix(line, manifestSizePrefix) {
+				parts := strings.SplitN(strings.TrimPrefix(line, manifestSizePrefix), "  ", 2)
+
+				size, err := strconv.ParseInt(parts[0], 10, 64)
+				if len(parts) != 2 || err != nil || size < 0 {
+					return nil, fmt.Errorf(`line %d is not acceptable (must be "%s<bytes>  <filename>")`, n, manifestSizePrefix)
+				}
+
+				sizes[parts[1]] = size
+			}
+
+			continue
+		}
+
+		var digest, filename string
+
+		switch {
+		case strings.HasPrefix(line, "SHA512 ("):
+			i := strings.LastIndex(line, ") = ")
+			if i < 0 {
+				return nil, fmt.Errorf(`line %d is not acceptable (must be "SHA512 (<filename>) = <digest>")`, n)
+			}
+
+			filename, digest = line[len("SHA512 ("):i], line[i+len(") = "):]
+		default:
+			parts := strings.SplitN(line, " ", 2)
+			if len(parts) != 2 || len(parts[1]) < 2 || (parts[1][0] != ' ' && parts[1][0] != '*') {
+				return nil, fmt.Errorf(`line %d is not acceptable (must be "<digest>  <filename>")`, n)
+			}
+
+			digest, filename = parts[0], parts[1][1:]
+		}
+
+		sum, err := hex.DecodeString(digest)
+		if err != nil || len(sum) != 64 {
+			return nil, fmt.Errorf(`digest for "%s" is not acceptable (must be a hex encoded SHA-512 digest)`, filename)
+		}
+
+		if seen[filename] {
+			return nil, fmt.Errorf(`filename "%s" is listed more than once`, filename)
+		}
+		seen[filename] = true
+
+		entries = append(entries, &sumsEntry{filename: filename, sum: sum, size: -1})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if size, ok := sizes[e.filename]; ok {
+			e.size = size
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("no files are listed")
+	}
+
+	return entries, nil
+}
+
+// sumsArtifacts returns an artifact for every file listed in the SUMS manifest
+// at path, relative to dir (default the manifest's directory), using the
+// listed digest as its checksum.
+func sumsArtifacts(opts *CommandOptions, path string, dir string) ([]*distArtifact, error) {
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return nil, fmt.Errorf(`sums path "%s" is not expandable (%s)`, path, err)
+	}
+
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf(`sums path "%s" is not readable (%s)`, path, err.(*os.PathError).Err)
+	}
+
+	entries, err := parseSums(b)
+	if err != nil {
+		return nil, fmt.Errorf(`sums file "%s" is not acceptable (%s)`, path, err)
+	}
+
+	if dir == "" {
+		dir = filepath.Dir(p)
+	}
+
+	artifacts := []*distArtifact{}
+	for _, e := range entries {
+		a := newDistArtifact(opts, filepath.Join(dir, filepath.FromSlash(e.filename)))
+		a.checksum = base64.RawStdEncoding.EncodeToString(e.sum)
+
+		artifacts = append(artifacts, a)
+	}
+
+	return artifacts, nil
+}
+
+// verifySums checks the listed checksum of every artifact from a SUMS manifest
+// against its file, hashing them concurrently, so that a stale manifest fails
+// before anything is uploaded. Every mismatch is reported at once.
+func verifySums(opts *CommandOptions, artifacts []*distArtifact) error {
+	prehashArtifacts(artifacts, opts.extraChecksums, opts.noCache)
+
+	mismatched := []string{}
+	for _, a := range artifacts {
+		path, err := homedir.Expand(a.path)
+		if err != nil {
+			return fmt.Errorf(`path "%s" is not expandable (%s)`, a.path, err)
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf(`path "%s" is not readable (%s)`, path, err.(*os.PathError).Err)
+		}
+
+		digest, err := hashArtifact(path, file, opts.extraChecksums, opts.noCache)
+		file.Close()
+		if err != nil {
+			return err
+		}
+
+		if digest.checksum() != a.checksum {
+			mismatched = append(mismatched, path)
+		}
+	}
+
+	if len(mismatched) != 0 {
+		return fmt.Errorf("checksums do not match the listed digests for: %s", strings.Join(mismatched, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/mitchellh/go-homedir"
+)
+
+// symbolsFilename returns the filename a release's debug symbols are published
+// as, e.g. "App.exe.symbols.pdb", which is unique since the release's is.
+func symbolsFilename(filename string, filetype string) string {
+	return filename + ".symbols." + filetype
+}
+
+// openSymbols opens debug symbols for upload, returning the file along with
+// its filetype. Directories, e.g. a macOS .dSYM bundle, are archived into a
+// temporary tarball, which the caller must remove. A non-nil epoch archives
+// them reproducibly.
+func openSymbols(path string, epoch *time.Time) (*os.File, string, bool, error) {
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return nil, "", false, fmt.Errorf(`symbols path "%s" is not expandable (%s)`, path, err)
+	}
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, "", false, fmt.Errorf(`symbols path "%s" is not readable (%s)`, path, err.(*os.PathError).Err)
+	}
+
+	if !info.IsDir() {
+		file, err := os.Open(p)
+		if err != nil {
+			return nil, "", false, fmt.Errorf(`symbols path "%s" is not readable (%s)`, path, err.(*os.PathError).Err)
+		}
+
+		return file, filetypeFromExtension(info.Name()), false, nil
+	}
+
+	tmp, err := ioutil.TempFile("", "keygen-*.tar.gz")
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	if err := archiveDir(tmp, p, epoch); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return nil, "", false, fmt.Errorf(`symbols path "%s" is not readable (%s)`, path, err)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return nil, "", false, err
+	}
+
+	return tmp, "tar.gz", true, nil
+}
+
+// archiveDir writes a gzipped tarball of the directory, with entries relative
+// to its parent so that e.g. App.dSYM/ is kept when extracted. A non-nil epoch
+// normalizes every entry's header to it, for --reproducible.
+func archiveDir(w io.Writer, dir string, epoch *time.Time) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	parent := filepath.Dir(dir)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(parent, path)
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+
+		header.Name = filepath.ToSlash(name)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if epoch != nil {
+			normalizeTarHeader(header, *epoch)
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// publishSymbols publishes debug symbols as a companion release of the
+// release, so that crash reports can be symbolicated by version. Symbols are
+// created as a draft and yanked once uploaded, so they're never offered to
+// upgrading clients, even while uploading, but remain downloadable.
+func publishSymbols(opts *CommandOptions, release *keygenext.Release, path string) (*keygenext.Release, error) {
+	var epoch *time.Time
+	if opts.reproducible {
+		epoch = &opts.sourceDate
+	}
+
+	file, filetype, archived, err := openSymbols(path, epoch)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if archived {
+		defer os.Remove(file.Name())
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, _, err := calculateChecksum(file, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	companion := &keygenext.Release{
+		Name:        release.Name,
+		Version:     release.Version,
+		Filename:    symbolsFilename(release.Filename, filetype),
+		Filesize:    info.Size(),
+		Filetype:    filetype,
+		Platform:    release.Platform,
+		Channel:     release.Channel,
+		Checksum:    checksum,
+		Status:      "DRAFT",
+		ContentType: contentTypeForFiletype(filetype),
+		ProductID:   release.ProductID,
+		Constraints: release.Constraints,
+		Metadata: map[string]interface{}{
+			"symbolsFor":    release.Filename,
+			"distributable": false,
+		},
+	}
+
+	if _, err := publishRelease(opts, companion, file); err != nil {
+		return nil, err
+	}
+
+	if err := opts.client.YankRelease(opts.ctx, companion); err != nil {
+		return nil, formatAPIError(err)
+	}
+
+	return companion, nil
+}
+
+// symbolsFiletype returns the filetype debug symbols are published with,
+// without opening them, so that the release can link to them up front.
+func symbolsFiletype(path string) (string, error) {
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return "", fmt.Errorf(`symbols path "%s" is not expandable (%s)`, path, err)
+	}
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return "", fmt.Errorf(`symbols path "%s" is not readable (%s)`, path, err.(*os.PathError).Err)
+	}
+
+	if info.IsDir() {
+		return "tar.gz", nil
+	}
+
+	return filetypeFromExtension(info.Name()), nil
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// oidSHA512 identifies SHA-512 in a timestamp's message imprint.
+var oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+// timestampRequest is an RFC 3161 TimeStampReq.
+type timestampRequest struct {
+	Version        int
+	MessageImprint messageImprint
+	Nonce          *big.Int `asn1:"optional"`
+	CertReq        bool     `asn1:"optional,default:false"`
+}
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+// timestampResponse is an RFC 3161 TimeStampResp, where the token is a CMS
+// SignedData ContentInfo.
+type timestampResponse struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0"`
+}
+
+// signedData is the start of a CMS SignedData, up to the signed content.
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo encapContentInfo
+}
+
+type encapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+// tstInfo is the content signed by the timestamp authority.
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+	Accuracy       accuracy  `asn1:"optional"`
+	Ordering       bool      `asn1:"optional,default:false"`
+	Nonce          *big.Int  `asn1:"optional"`
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+// timestampSignature obtains an RFC 3161 timestamp token over the SHA-512
+// digest of a signature, as published, from a timestamp authority. The token
+// proves the signature existed at its time, so that it remains verifiable
+// after the signing key is rotated. The authority's certificate isn't
+// verified here, but the token's imprint and nonce are checked against the
+// request.
+func timestampSignature(url string, signature string) ([]byte, time.Time, error) {
+	digest := sha512.Sum512([]byte(signature))
+
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	req, err := asn1.Marshal(timestampRequest{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA512, Parameters: asn1.NullRawValue},
+			HashedMessage: digest[:],
+		},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	res, err := newExternalClient(time.Minute).Post(url, "application/timestamp-query", bytes.NewReader(req))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("timestamp authority responded with status %d", res.StatusCode)
+	}
+
+	var resp timestampResponse
+	if _, err := asn1.Unmarshal(body, &resp); err != nil {
+		return nil, time.Time{}, fmt.Errorf("timestamp response is not valid (%s)", err)
+	}
+
+	// 0 is granted, and 1 is granted with modifications
+	if s := resp.Status.Status; s != 0 && s != 1 {
+		return nil, time.Time{}, fmt.Errorf("timestamp was rejected (status %d %v)", s, resp.Status.StatusString)
+	}
+
+	token := resp.TimeStampToken.FullBytes
+	if len(token) == 0 {
+		return nil, time.Time{}, errors.New("timestamp response has no token")
+	}
+
+	info, err := parseTimestampToken(token)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("timestamp token is not valid (%s)", err)
+	}
+
+	if !info.MessageImprint.HashAlgorithm.Algorithm.Equal(oidSHA512) || !bytes.Equal(info.MessageImprint.HashedMessage, digest[:]) {
+		return nil, time.Time{}, errors.New("timestamp token is not valid (imprint does not match the signature)")
+	}
+
+	if info.Nonce == nil || info.Nonce.Cmp(nonce) != 0 {
+		return nil, time.Time{}, errors.New("timestamp token is not valid (nonce does not match the request)")
+	}
+
+	return token, info.GenTime, nil
+}
+
+// parseTimestampToken extracts the signed TSTInfo from a timestamp token.
+func parseTimestampToken(token []byte) (*tstInfo, error) {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(token, &ci); err != nil {
+		return nil, err
+	}
+
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, err
+	}
+
+	if len(sd.EncapContentInfo.EContent) == 0 {
+		return nil, errors.New("token has no content")
+	}
+
+	info := &tstInfo{}
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent, info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
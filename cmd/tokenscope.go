@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/keygen-sh/keygen-cli/keygenext"
+)
+
+// publishPermissions are the permissions a token needs to publish releases,
+// and constraintPermissions the ones it additionally needs to attach
+// entitlement constraints to them.
+var (
+	publishPermissions    = []string{"release.create", "release.update", "release.upload"}
+	constraintPermissions = []string{"entitlement.read", "release.constraints.attach"}
+)
+
+// readOnlyRoles are user roles which can't publish releases.
+var readOnlyRoles = map[string]bool{
+	"read-only":     true,
+	"sales-agent":   true,
+	"support-agent": true,
+	"user":          true,
+}
+
+// checkTokenScope inspects what the token authenticates as before anything is
+// published, so that a misconfigured pipeline fails up front rather than with
+// a 403 part way through an upload. Tokens which can't publish are rejected,
+// while over-privileged tokens, e.g. an admin's, and tokens missing a
+// permission are warned about, or rejected with --strict-auth.
+func checkTokenScope(opts *CommandOptions, constrained bool) error {
+	bearer, err := opts.client.GetBearer(opts.ctx)
+	if err != nil {
+		if _, ok := err.(*keygenext.APIError); ok {
+			return fmt.Errorf("token could not be inspected (%w)", formatAPIError(err))
+		}
+
+		// Leave it to the publish to fail (or queue) when unreachable
+		return nil
+	}
+
+	products := opts.products
+	if len(products) == 0 {
+		products = []string{opts.productID}
+	}
+
+	warnings := [][2]string{}
+
+	switch bearer.Type {
+	case "products":
+		for _, p := range products {
+			if p != bearer.ID {
+				return fmt.Errorf(`token is not acceptable (it's a product token for "%s", which can't publish to product "%s")`, bearer.ID, p)
+			}
+		}
+	case "users":
+		if readOnlyRoles[bearer.Role] {
+			return fmt.Errorf(`token is not acceptable (it belongs to %s, whose %s role can't publish releases)`, bearer.Email, bearer.Role)
+		}
+
+		warnings = append(warnings, [2]string{"ADMIN_TOKEN", fmt.Sprintf("token belongs to %s, whose %s role can do far more than publish when a product token suffices", bearer.Email, bearer.Role)})
+	case "licenses":
+		return errors.New("token is not acceptable (it's a license token, which can't publish releases)")
+	}
+
+	// Permissions are only given by editions of Keygen which support them
+	if len(bearer.Permissions) != 0 {
+		required := publishPermissions
+		if constrained {
+			required = append(append([]string{}, required...), constraintPermissions...)
+		}
+
+		if missing := missingPermissions(bearer.Permissions, required); len(missing) != 0 {
+			warnings = append(warnings, [2]string{"MISSING_PERMISSIONS", "token is missing permissions: " + strings.Join(missing, ", ")})
+		}
+	}
+
+	for _, w := range warnings {
+		if opts.strictAuth {
+			return fmt.Errorf("%s (%s, refused by --strict-auth)", w[1], w[0])
+		}
+
+		opts.distWarning(w[0], w[1])
+	}
+
+	return nil
+}
+
+// missingPermissions returns the required permissions which aren't granted,
+// where "*" grants every permission.
+func missingPermissions(granted []string, required []string) []string {
+	has := map[string]bool{}
+	for _, p := range granted {
+		has[p] = true
+	}
+
+	if has["*"] {
+		return nil
+	}
+
+	missing := []string{}
+	for _, p := range required {
+		if !has[p] {
+			missing = append(missing, p)
+		}
+	}
+
+	return missing
+}
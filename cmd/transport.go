@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/mitchellh/go-homedir"
+)
+
+// configureTransport configures the connections API and storage requests are
+// made using from the --proxy, --ca-cert and --response-timeout. Since
+// connections are pooled for the life of the process, only the first session
+// configures them.
+func (s *session) configureTransport() error {
+	opts := keygenext.TransportOptions{}
+
+	if p := s.root.proxy; p != "" {
+		u, err := url.Parse(p)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http" && u.Scheme != "socks5") || u.Host == "" {
+			return fmt.Errorf(`proxy "%s" is not acceptable (must be a URL, e.g. http://proxy.example.com:3128)`, p)
+		}
+
+		opts.Proxy = http.ProxyURL(u)
+	}
+
+	if p := s.root.caCert; p != "" {
+		expanded, err := homedir.Expand(p)
+		if err != nil {
+			return fmt.Errorf(`ca cert path "%s" is not expandable (%s)`, p, err)
+		}
+
+		b, err := ioutil.ReadFile(expanded)
+		if err != nil {
+			return fmt.Errorf(`ca cert path "%s" is not readable (%s)`, p, err)
+		}
+
+		// The CA is trusted in addition to the system's, e.g. so that storage
+		// providers with public certificates still work
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(b) {
+			return fmt.Errorf(`ca cert path "%s" is not acceptable (must contain PEM-encoded certificates)`, p)
+		}
+
+		opts.TLSConfig = &tls.Config{RootCAs: pool}
+	}
+
+	if t := s.root.responseTimeout; t < 0 {
+		return fmt.Errorf(`response timeout "%s" is not acceptable (must be positive)`, t)
+	}
+
+	opts.ResponseHeaderTimeout = s.root.responseTimeout
+
+	keygenext.ConfigureTransport(opts)
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/vbauerster/mpb/v7/decor"
+)
+
+// byteUnits are the units sizes and speeds are formatted in: "binary", e.g.
+// 1.5 MiB, "decimal", e.g. 1.6 MB, or "raw", i.e. exact byte counts such as
+// 1572864 B for reports which need them. It's set by the global --units flag,
+// and applies to the whole process.
+var byteUnits = "binary"
+
+// units are the accepted values of --units.
+var units = []string{"binary", "decimal", "raw"}
+
+// configureUnits validates and applies the --units flag.
+func (s *session) configureUnits() error {
+	for _, u := range units {
+		if s.root.units == u {
+			byteUnits = u
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf(`units "%s" is not acceptable (must be one of: binary, decimal, raw)`, s.root.units)
+}
+
+// formatBytes formats a byte count in the configured units, e.g. 1.5 MiB.
+func formatBytes(n int64) string {
+	unit, suffix := int64(1024), "iB"
+	switch byteUnits {
+	case "raw":
+		return fmt.Sprintf("%d B", n)
+	case "decimal":
+		unit, suffix = 1000, "B"
+	}
+
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := unit, 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	// Decimal kilobytes are a lowercase "kB", unlike binary's "KiB"
+	prefixes := "KMGTPE"
+	if byteUnits == "decimal" {
+		prefixes = "kMGTPE"
+	}
+
+	return fmt.Sprintf("%.1f %c%s", float64(n)/float64(div), prefixes[exp], suffix)
+}
+
+// byteCountersDecorator shows a progress bar's bytes done and total in the
+// configured units.
+func byteCountersDecorator() decor.Decorator {
+	switch byteUnits {
+	case "raw":
+		return decor.Counters(0, "%d B / %d B")
+	case "decimal":
+		return decor.CountersKiloByte("% .2f / % .2f")
+	}
+
+	return decor.CountersKibiByte("% .2f / % .2f")
+}
+
+// byteSpeedDecorator shows a progress bar's speed in the configured units.
+func byteSpeedDecorator() decor.Decorator {
+	switch byteUnits {
+	case "raw":
+		return decor.EwmaSpeed(0, "%.0f B/s", 60)
+	case "decimal":
+		return decor.EwmaSpeed(decor.UnitKB, "% .2f", 60)
+	}
+
+	return decor.EwmaSpeed(decor.UnitKiB, "% .2f", 60)
+}
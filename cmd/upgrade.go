@@ -1,16 +1,25 @@
 package cmd
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/eiannone/keyboard"
 	"github.com/fatih/color"
+	"github.com/keygen-sh/go-update"
 	"github.com/keygen-sh/keygen-go"
 	"github.com/mattn/go-isatty"
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
 	"github.com/spf13/cobra"
 	"github.com/vbauerster/mpb/v7"
 	"github.com/vbauerster/mpb/v7/decor"
@@ -23,23 +32,14 @@ var (
 	KeyCodeY     KeyCode = 121
 )
 
-var (
-	upgradeCmd = &cobra.Command{
-		Use:   "upgrade",
-		Short: "check if a CLI upgrade is available",
-		Args:  cobra.NoArgs,
-		RunE:  upgradeRun,
-
-		SilenceUsage: true,
-		Hidden:       true,
-	}
-)
+// cliProduct is keygen.sh's product for the CLI, whose releases are upgrades.
+const cliProduct = "0d5f0b57-3102-4ddf-beb9-f652cf8e24b7"
 
 func init() {
 	keygen.UpgradeKey = "5ec69b78d4b5d4b624699cef5faf3347dc4b06bb807ed4a2c6740129f1db7159"
 	keygen.PublicKey = "b8f3eb4cd260135f67a5096e8dc1c9b9dcb81ee9fe50d12cdcd941f6607a9031"
 	keygen.Account = "5cc3b5a2-0d08-4291-940b-41c21f0ba6ab"
-	keygen.Product = "0d5f0b57-3102-4ddf-beb9-f652cf8e24b7"
+	keygen.Product = cliProduct
 
 	switch {
 	case strings.Contains(Version, "-rc."):
@@ -54,51 +54,61 @@ func init() {
 		keygen.Channel = "stable"
 	}
 
-	rootCmd.AddCommand(upgradeCmd)
 }
 
-func upgradeRun(cmd *cobra.Command, args []string) error {
-	if !isatty.IsTerminal(os.Stdout.Fd()) && !isatty.IsCygwinTerminal(os.Stdout.Fd()) {
+func newUpgradeCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "check if a CLI upgrade is available",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return upgradeRun(opts)
+		},
+
+		SilenceUsage: true,
+		Hidden:       true,
+	}
+
+	cmd.Flags().BoolVar(&opts.verifyOnly, "verify-only", false, "download and verify the upgrade without installing it")
+	cmd.Flags().StringVar(&opts.checksum, "checksum", "", "pin the upgrade to a base64 encoded SHA-512 checksum, e.g. from the release notes")
+
+	return cmd
+}
+
+func upgradeRun(opts *CommandOptions) error {
+	if !opts.verifyOnly && !isatty.IsTerminal(os.Stdout.Fd()) && !isatty.IsCygwinTerminal(os.Stdout.Fd()) {
 		return nil
 	}
 
-	// When the upgrade command is not called directly, we only want to
-	// check periodically. To do so, we'll try to use a /tmp lockfile.
-	if cmd == nil {
-		path := filepath.Join(os.TempDir(), "keygen-auto-upgrade.lock")
-		info, err := os.Stat(path)
-		if err != nil {
-			f, _ := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
-			defer f.Close()
-		} else {
-			// Check for upgrades at most once per day
-			if time.Since(info.ModTime()) < time.Duration(24)*time.Hour {
-				return nil
-			}
-
-			// Touch the lockfile
-			os.Chtimes(path, time.Now(), time.Now())
-		}
+	// Self-hosted instances only serve upgrades when they mirror the CLI
+	if isSelfHosted() && (keygen.Account == "" || keygen.Product == "") {
+		return errors.New("upgrades for self-hosted instances require $KEYGEN_UPGRADE_ACCOUNT and $KEYGEN_UPGRADE_PRODUCT")
 	}
 
 	release, err := keygen.Upgrade(Version)
 	switch {
 	case err == keygen.ErrUpgradeNotAvailable:
-		if cmd != nil {
-			fmt.Println("all up to date!")
-		}
+		fmt.Println("all up to date!")
 
 		return nil
 	case err != nil:
-		if cmd != nil {
+		return err
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	if opts.verifyOnly {
+		if _, err := downloadUpgrade(opts, release); err != nil {
 			return err
 		}
 
+		fmt.Println("verified " + italic("v"+release.Version) + " (checksum " + release.Checksum + ")")
+
 		return nil
 	}
 
-	italic := color.New(color.Italic).SprintFunc()
-
 	fmt.Printf("an upgrade is available! would you like to install " + italic("v"+release.Version) + " now? Y/n ")
 
 	key, _, err := keyboard.GetSingleKey()
@@ -109,17 +119,14 @@ func upgradeRun(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	if k := KeyCode(key); k != KeyCodeEnter && k != KeyCodeY {
-		if cmd != nil {
-			yellow := color.New(color.FgYellow).SprintFunc()
+		yellow := color.New(color.FgYellow).SprintFunc()
 
-			fmt.Println(yellow("warning:") + " upgrade aborted")
-		}
+		fmt.Println(yellow("warning:") + " upgrade aborted")
 
 		return nil
 	}
 
-	frames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
-	style := mpb.SpinnerStyle(frames...)
+	style := mpb.SpinnerStyle(spinnerFrames()...)
 	style.PositionLeft()
 
 	progress := mpb.New(mpb.WithWidth(1), mpb.WithRefreshRate(180*time.Millisecond))
@@ -131,16 +138,106 @@ func upgradeRun(cmd *cobra.Command, args []string) error {
 		),
 	)
 
-	if err := release.Install(); err != nil {
+	// Verify the upgrade ourselves rather than using release.Install(), which
+	// skips verification when a release is missing a signature or checksum
+	data, err := downloadUpgrade(opts, release)
+	if err != nil {
+		return err
+	}
+
+	if err := update.Apply(bytes.NewReader(data), update.Options{}); err != nil {
 		return err
 	}
 
 	spinner.Increment()
 	progress.Wait()
 
-	if cmd != nil {
-		fmt.Println("install complete! now on " + italic("v"+release.Version))
-	}
+	fmt.Println("install complete! now on " + italic("v"+release.Version))
 
 	return nil
 }
+
+// upgradeProduct returns the product whose releases are upgrades, i.e. the
+// CLI's product, or for self-hosted instances their mirror of it given by
+// $KEYGEN_UPGRADE_PRODUCT. Upgrades are signed using it as their ed25519ph
+// context.
+func upgradeProduct() string {
+	if isSelfHosted() {
+		return os.Getenv("KEYGEN_UPGRADE_PRODUCT")
+	}
+
+	return cliProduct
+}
+
+// upgradeKey returns the hex-encoded public key used to verify CLI upgrades,
+// which $KEYGEN_CLI_PUBLIC_KEY overrides, e.g. for a self-hosted mirror.
+func upgradeKey() (string, error) {
+	if v := os.Getenv("KEYGEN_CLI_PUBLIC_KEY"); v != "" {
+		return readPublicKey(v)
+	}
+
+	return keygen.UpgradeKey, nil
+}
+
+// downloadUpgrade downloads a CLI upgrade, verifying its SHA-512 checksum and
+// ed25519ph signature before it's returned. Unsigned upgrades are refused.
+func downloadUpgrade(opts *CommandOptions, release *keygen.Release) ([]byte, error) {
+	if release.Location == "" {
+		return nil, keygen.ErrReleaseLocationMissing
+	}
+
+	if release.Checksum == "" {
+		return nil, fmt.Errorf(`upgrade "%s" is not acceptable (missing checksum)`, release.Version)
+	}
+
+	if release.Signature == "" {
+		return nil, fmt.Errorf(`upgrade "%s" is not acceptable (missing signature)`, release.Version)
+	}
+
+	if c := opts.checksum; c != "" && c != release.Checksum {
+		return nil, fmt.Errorf("upgrade verification failed (expected pinned checksum %s got %s)", c, release.Checksum)
+	}
+
+	key, err := upgradeKey()
+	if err != nil {
+		return nil, err
+	}
+
+	verifyKey, err := hex.DecodeString(key)
+	if err != nil || len(verifyKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf(`public key "%s" is not acceptable (must be a hex-encoded Ed25519 public key)`, key)
+	}
+
+	sig, err := base64.RawStdEncoding.DecodeString(release.Signature)
+	if err != nil {
+		return nil, fmt.Errorf(`signature "%s" is not acceptable (%s)`, release.Signature, err)
+	}
+
+	res, err := newExternalClient(10 * time.Minute).Get(release.Location)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upgrade download failed (status %d)", res.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha512.Sum512(data)
+
+	if checksum := base64.RawStdEncoding.EncodeToString(digest[:]); checksum != release.Checksum {
+		return nil, fmt.Errorf("upgrade verification failed (expected checksum %s got %s)", release.Checksum, checksum)
+	}
+
+	verifyOpts := &ed25519.Options{Hash: crypto.SHA512, Context: upgradeProduct()}
+	if !ed25519.VerifyWithOptions(verifyKey, digest[:], sig, verifyOpts) {
+		return nil, errors.New("upgrade verification failed (signature does not match the public key)")
+	}
+
+	return data, nil
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+func newUpgradeCheckCmd(s *session) *cobra.Command {
+	opts := s.newOptions()
+
+	cmd := &cobra.Command{
+		Use:   "upgrade-check",
+		Short: "check which upgrade an app would be offered, using the same endpoint apps call",
+		Example: `  keygen upgrade-check --current 1.1.0 --platform darwin/arm64 --channel stable \
+      --license-key 'XXXX-XXXX-XXXX-XXXX'
+  keygen upgrade-check --current 1.1.0 --arch arm64
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return upgradeCheckRun(opts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	// Apps don't use a product token, so only the account is required
+	cmd.Flags().StringVar(&s.client.Account, "account", "", "your keygen.sh account identifier [$KEYGEN_ACCOUNT_ID=<id>] (required)")
+	cmd.Flags().StringVar(&s.client.LicenseKey, "license-key", "", "license key the app is licensed with (default unauthenticated, e.g. for an open product) [$KEYGEN_LICENSE_KEY]")
+
+	bindEnv(cmd.Flags(), "account", "KEYGEN_ACCOUNT_ID")
+	bindEnv(cmd.Flags(), "license-key", "KEYGEN_LICENSE_KEY")
+
+	cmd.MarkFlagRequired("account")
+
+	addProductFlag(cmd, s)
+
+	cmd.Flags().StringVar(&opts.version, "current", "", "version the app is currently on, e.g. 1.1.0 (required)")
+	cmd.Flags().StringVar(&opts.platform, "platform", "", "platform the app is running on, e.g. darwin/arm64")
+	cmd.Flags().StringVar(&opts.arch, "arch", "", "check the upgrade offered on every platform of an arch, e.g. arm64")
+	cmd.Flags().StringVar(&opts.channel, "channel", "stable", "channel the app receives upgrades from, one of: stable, rc, beta, alpha, dev")
+	cmd.Flags().StringVar(&opts.filetype, "filetype", "", "filetype the app upgrades using, e.g. tar.gz")
+	cmd.Flags().StringVar(&opts.constraint, "constraint", "", "only offer upgrades matching a version constraint, e.g. 1.0 for 1.x")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "text", "output format, one of: text, json, yaml, go-template=<template>")
+
+	cmd.MarkFlagRequired("current")
+
+	return cmd
+}
+
+func upgradeCheckRun(opts *CommandOptions) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	if _, ok := upgradeChannels[opts.channel]; !ok {
+		return fmt.Errorf(`channel "%s" is not supported (must be one of: stable, rc, beta, alpha, dev)`, opts.channel)
+	}
+
+	if err := opts.checkArtifactHost(); err != nil {
+		return err
+	}
+
+	if opts.arch != "" {
+		if opts.platform != "" {
+			return errors.New(`flag "--arch" cannot be used together with "--platform"`)
+		}
+
+		return upgradeCheckArchRun(opts)
+	}
+
+	release, artifact, err := upgradeCheckOffer(opts, opts.platform)
+	if err != nil {
+		return err
+	}
+
+	if isStructuredOutput(opts.output) {
+		return render(opts.output, rendering{value: upgradeOfferValue(opts, release, artifact)})
+	}
+
+	printUpgradeOffer(opts, release, artifact)
+
+	return nil
+}
+
+// upgradeCheckArchRun checks the upgrade offered on each platform of an arch,
+// e.g. linux/arm64 and darwin/arm64 for arm64, since the upgrade endpoint
+// only accepts a single platform.
+func upgradeCheckArchRun(opts *CommandOptions) error {
+	releases, err := opts.client.ListReleases(opts.ctx, &keygenext.ReleaseFilter{
+		Product: opts.productID,
+		Paging:  keygenext.Paging{All: true},
+	})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	platforms := []string{}
+	seen := map[string]bool{}
+
+	for _, r := range releases {
+		if r.Yanked != nil || !matchArch(r, opts.arch) || seen[r.Platform] {
+			continue
+		}
+
+		seen[r.Platform] = true
+		platforms = append(platforms, r.Platform)
+	}
+
+	if len(platforms) == 0 {
+		return fmt.Errorf(`no release is available for arch "%s"`, opts.arch)
+	}
+
+	sort.Strings(platforms)
+
+	offers := []map[string]interface{}{}
+
+	for i, platform := range platforms {
+		release, artifact, err := upgradeCheckOffer(opts, platform)
+		if err != nil {
+			return fmt.Errorf("upgrade for platform %s could not be checked (%s)", platform, err)
+		}
+
+		if isStructuredOutput(opts.output) {
+			offer := upgradeOfferValue(opts, release, artifact)
+			offer["platform"] = platform
+			offers = append(offers, offer)
+
+			continue
+		}
+
+		if i > 0 {
+			fmt.Println()
+		}
+
+		fmt.Println(platform + ":")
+		printUpgradeOffer(opts, release, artifact)
+	}
+
+	if isStructuredOutput(opts.output) {
+		return render(opts.output, rendering{value: map[string]interface{}{
+			"current":  opts.version,
+			"arch":     normalizeArch(opts.arch),
+			"upgrades": offers,
+		}})
+	}
+
+	return nil
+}
+
+// upgradeCheckOffer returns the upgrade offered to an app on a platform, or a
+// nil release when it's already up to date.
+func upgradeCheckOffer(opts *CommandOptions, platform string) (*keygenext.Release, *keygenext.Artifact, error) {
+	release, artifact, err := opts.client.Upgrade(opts.ctx, &keygenext.UpgradeParams{
+		Product:    opts.productID,
+		Version:    opts.version,
+		Platform:   platform,
+		Channel:    opts.channel,
+		Filetype:   opts.filetype,
+		Constraint: opts.constraint,
+	})
+	if err != nil && err != keygenext.ErrUpgradeNotAvailable {
+		return nil, nil, formatAPIError(err)
+	}
+
+	return release, artifact, nil
+}
+
+// upgradeOfferValue returns the structured output of an upgrade offer.
+func upgradeOfferValue(opts *CommandOptions, release *keygenext.Release, artifact *keygenext.Artifact) map[string]interface{} {
+	if release == nil {
+		return map[string]interface{}{
+			"current":   opts.version,
+			"available": false,
+		}
+	}
+
+	return map[string]interface{}{
+		"current":   opts.version,
+		"available": true,
+		"release": map[string]interface{}{
+			"id":        release.ID,
+			"version":   release.Version,
+			"channel":   release.Channel,
+			"platform":  release.Platform,
+			"filetype":  release.Filetype,
+			"filename":  release.Filename,
+			"filesize":  release.Filesize,
+			"checksum":  release.Checksum,
+			"signature": release.Signature,
+		},
+		"artifact": map[string]interface{}{
+			"id":  artifact.ID,
+			"url": opts.client.ArtifactDownloadURL(artifact),
+		},
+	}
+}
+
+// printUpgradeOffer prints an upgrade offer, or that none was offered.
+func printUpgradeOffer(opts *CommandOptions, release *keygenext.Release, artifact *keygenext.Artifact) {
+	italic := color.New(color.Italic).SprintFunc()
+
+	if release == nil {
+		fmt.Println("no upgrade offered for " + italic("v"+opts.version) + " (already up to date)")
+
+		return
+	}
+
+	fmt.Println("upgrade offered for " + italic("v"+opts.version) + " " + glyph("→") + " " + italic("v"+release.Version) + " (" + release.Channel + ")")
+	fmt.Printf("    %-12s %s\n", "release", release.ID)
+	fmt.Printf("    %-12s %s (%s, %s)\n", "artifact", release.Filename, formatPlatform(release.Platform), formatBytes(release.Filesize))
+	fmt.Printf("    %-12s %s\n", "url", opts.client.ArtifactDownloadURL(artifact))
+	fmt.Printf("    %-12s %s\n", "checksum", release.Checksum)
+	fmt.Printf("    %-12s %s\n", "signature", release.Signature)
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/mattn/go-isatty"
+)
+
+// conflictStrategies are the supported --on-conflict strategies.
+var conflictStrategies = []string{"replace", "skip", "update", "fail"}
+
+// existingRelease looks up the live releases of the release's version, i.e.
+// ones which weren't published by this run, returning how many there are and
+// the one for the same artifact, if any. Locked releases are never published
+// over, which publishRelease checks again, including when the API couldn't be
+// reached here.
+func existingRelease(opts *CommandOptions, release *keygenext.Release) (int, *keygenext.Release, error) {
+	existing, err := opts.client.ListReleases(opts.ctx, &keygenext.ReleaseFilter{Product: release.ProductID, Version: release.Version, Limit: 100})
+	switch {
+	case isNetworkError(err) && opts.onConflict == "fail":
+		// Publishing can't be refused without knowing whether it exists
+		return 0, nil, fmt.Errorf("existing releases could not be checked for --on-conflict fail (%w)", err)
+	case isNetworkError(err):
+		// Leave it to the publish to fail (or queue) when unreachable
+		return 0, nil, nil
+	case err != nil:
+		return 0, nil, formatAPIError(err)
+	}
+
+	// Artifacts published by this run, e.g. the other artifacts of a
+	// multi-artifact release, aren't changes to a live release
+	published := map[string]bool{}
+	for _, r := range opts.published {
+		published[r.ID] = true
+	}
+
+	live := 0
+
+	var match *keygenext.Release
+
+	for i := range existing {
+		r := &existing[i]
+		if published[r.ID] {
+			continue
+		}
+
+		live++
+
+		if isReleaseLocked(r) {
+			return 0, nil, fmt.Errorf(`release "%s" is locked (v%s, %s), run keygen releases unlock %s to publish over it`, r.ID, r.Version, r.Filename, r.ID)
+		}
+
+		if r.Filename == release.Filename {
+			match = r
+		}
+	}
+
+	return live, match, nil
+}
+
+// resolveConflict handles a release whose artifact already exists using the
+// --on-conflict strategy, other than replace: skip leaves the existing release
+// as-is, update merges the given metadata keys into its metadata, and fail
+// refuses to publish. Skipped and updated releases are still included in the
+// run's manifest.
+func resolveConflict(opts *CommandOptions, existing *keygenext.Release, metadata map[string]interface{}) error {
+	switch opts.onConflict {
+	case "fail":
+		return fmt.Errorf(`release "%s" already exists (v%s, %s), use --on-conflict to skip, update or replace it`, existing.ID, existing.Version, existing.Filename)
+	case "update":
+		// The API replaces metadata as a whole, so keys which weren't given
+		// are kept as they are
+		if len(metadata) != 0 {
+			merged := map[string]interface{}{}
+			for k, v := range existing.Metadata {
+				merged[k] = v
+			}
+
+			for k, v := range metadata {
+				merged[k] = v
+			}
+
+			if err := opts.client.UpdateReleaseMetadata(opts.ctx, existing, merged); err != nil {
+				return formatAPIError(err)
+			}
+		}
+	}
+
+	opts.published = append(opts.published, existing)
+
+	skipped := opts.onConflict == "skip"
+
+	if isGitHubActions() {
+		err := writeGitHubOutput(map[string]string{
+			"release-id": existing.ID,
+			"version":    existing.Version,
+			"channel":    existing.Channel,
+			"platform":   existing.Platform,
+			"skipped":    strconv.FormatBool(skipped),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.output == "json" {
+		return opts.printDistJSON(map[string]interface{}{
+			"id":          existing.ID,
+			"artifact_id": existing.ArtifactID,
+			"version":     existing.Version,
+			"channel":     existing.Channel,
+			"platform":    existing.Platform,
+			"filename":    existing.Filename,
+			"filesize":    existing.Filesize,
+			"checksum":    existing.Checksum,
+			"signature":   existing.Signature,
+			"metadata":    existing.Metadata,
+			"status":      existing.Status,
+			"skipped":     skipped,
+			"updated":     !skipped,
+			"warnings":    opts.warnings,
+		})
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	if skipped {
+		fmt.Println("skipped release " + italic(existing.ID) + " (v" + existing.Version + ", " + existing.Filename + " already exists)")
+	} else {
+		fmt.Println("updated metadata of release " + italic(existing.ID) + " (v" + existing.Version + ", " + existing.Filename + " already exists)")
+	}
+
+	return nil
+}
+
+// confirmUpsert shows what publishing will change when the release's version
+// already exists, i.e. an artifact which will be added to the version or one
+// which will be replaced, and asks to confirm, so that a misconfigured
+// pipeline can't quietly mutate a live release. --force skips the check.
+func confirmUpsert(opts *CommandOptions, release *keygenext.Release, live int, match *keygenext.Release) error {
+	if live == 0 || opts.force {
+		return nil
+	}
+
+	var from *releaseSummary
+
+	if match != nil {
+		constraints, err := opts.client.ListReleaseConstraints(opts.ctx, match)
+		if err != nil {
+			return formatAPIError(err)
+		}
+
+		from = summarizeRelease(match, constraints)
+	}
+
+	d := diffReleases(releaseDiffKey(release), from, summarizeRelease(release, release.Constraints))
+	if d.Status == "unchanged" {
+		return nil
+	}
+
+	action := "replace an artifact of release " + release.Version
+	if d.Status == "added" {
+		action = "add an artifact to release " + release.Version
+	}
+
+	// Show what would change in CI logs, rather than only refusing
+	if !opts.root.yes && !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		yellow := color.New(color.FgYellow).SprintFunc()
+
+		fmt.Fprintln(os.Stderr, yellow("warning:")+" release "+release.Version+" already exists:")
+
+		for _, line := range formatReleaseDiff(d) {
+			fmt.Fprintln(os.Stderr, "  "+line)
+		}
+
+		return fmt.Errorf("refusing to %s without confirmation (use --yes or --force in non-interactive environments)", action)
+	}
+
+	return opts.confirmAction(action, formatReleaseDiff(d), "")
+}
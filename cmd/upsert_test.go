@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/keygen-sh/keygen-cli/keygenext"
+)
+
+func TestResolveConflict(t *testing.T) {
+	tests := []struct {
+		name       string
+		onConflict string
+		metadata   map[string]interface{}
+		patched    map[string]interface{}
+		err        string
+	}{
+		{name: "fail", onConflict: "fail", err: "already exists"},
+		{name: "skip", onConflict: "skip"},
+		{name: "skip ignores metadata", onConflict: "skip", metadata: map[string]interface{}{"commit": "def"}},
+		{name: "update without metadata", onConflict: "update"},
+		{
+			name:       "update merges metadata",
+			onConflict: "update",
+			metadata:   map[string]interface{}{"commit": "def", "build": "2"},
+			patched:    map[string]interface{}{"commit": "def", "build": "2", "team": "core"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_ACTIONS", "")
+
+			var patched map[string]interface{}
+
+			opts := newTestOptions(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPatch || r.URL.Path != "/v1/accounts/acct/releases/r1" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					writeJSONAPI(w, http.StatusNotFound, `{"errors":[{"title":"Not found","code":"NOT_FOUND"}]}`)
+
+					return
+				}
+
+				var body struct {
+					Data struct {
+						Attributes struct {
+							Metadata map[string]interface{} `json:"metadata"`
+						} `json:"attributes"`
+					} `json:"data"`
+				}
+
+				b, _ := ioutil.ReadAll(r.Body)
+				if err := json.Unmarshal(b, &body); err != nil {
+					t.Errorf("request body is not acceptable: %s", err)
+				}
+
+				patched = body.Data.Attributes.Metadata
+
+				writeJSONAPI(w, http.StatusOK, `{"data":{"id":"r1","type":"releases","attributes":{"version":"1.0.0","filename":"app.zip"}}}`)
+			})
+
+			opts.onConflict = tt.onConflict
+			opts.output = "json"
+
+			existing := &keygenext.Release{
+				ID:       "r1",
+				Version:  "1.0.0",
+				Filename: "app.zip",
+				Metadata: map[string]interface{}{"commit": "abc", "team": "core"},
+			}
+
+			var err error
+
+			stdout := captureOutput(t, &os.Stdout, func() {
+				err = resolveConflict(opts, existing, tt.metadata)
+			})
+
+			switch {
+			case tt.err != "":
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("resolveConflict() error = %v, want %q", err, tt.err)
+				}
+
+				if len(opts.published) != 0 {
+					t.Error("release was included in the manifest")
+				}
+
+				return
+			case err != nil:
+				t.Fatalf("resolveConflict() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(patched, tt.patched) {
+				t.Errorf("patched metadata = %v, want %v", patched, tt.patched)
+			}
+
+			if len(opts.published) != 1 || opts.published[0] != existing {
+				t.Errorf("published = %v, want the existing release", opts.published)
+			}
+
+			var out map[string]interface{}
+			if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+				t.Fatalf("output is not JSON: %q", stdout)
+			}
+
+			if skipped := tt.onConflict == "skip"; out["skipped"] != skipped || out["updated"] != !skipped {
+				t.Errorf("output skipped = %v, updated = %v, want skipped = %v", out["skipped"], out["updated"], skipped)
+			}
+		})
+	}
+}
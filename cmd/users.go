@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/spf13/cobra"
+)
+
+// usersSortFields are the fields users can be sorted by.
+var usersSortFields = []string{"email", "role", "status", "created", "updated"}
+
+func newUsersCmd(s *session) *cobra.Command {
+	listOpts := s.newOptions()
+	listCmd := &cobra.Command{
+		Use:   "ls",
+		Short: "list users",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return usersListRun(listOpts)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	addListFlags(listCmd, listOpts, 10, usersSortFields...)
+	listCmd.Flags().StringVarP(&listOpts.output, "output", "o", "table", renderOutputUsage)
+
+	inviteOpts := s.newOptions()
+	inviteCmd := &cobra.Command{
+		Use:   "invite <email>",
+		Short: "create a user and email them an invite to set their password",
+		Example: `  keygen users invite jane@example.com \
+      --first-name 'Jane' \
+      --last-name 'Doe' \
+      --role 'support-agent'
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: usersEmailArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return usersInviteRun(inviteOpts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	inviteCmd.Flags().StringVar(&inviteOpts.firstName, "first-name", "", "first name of the user")
+	inviteCmd.Flags().StringVar(&inviteOpts.lastName, "last-name", "", "last name of the user")
+	inviteCmd.Flags().StringVar(&inviteOpts.role, "role", "", "role to assign the user, e.g. admin, developer, sales-agent, support-agent, read-only (default user)")
+	inviteCmd.Flags().StringVar(&inviteOpts.group, "group", "", "group to add the user to")
+	inviteCmd.Flags().BoolVar(&inviteOpts.invite, "send-invite", true, "email the user an invite to set their password")
+	inviteCmd.Flags().StringVarP(&inviteOpts.output, "output", "o", "text", "output format, one of: text, json, yaml, go-template=<template>")
+
+	roleOpts := s.newOptions()
+	roleCmd := &cobra.Command{
+		Use:   "role <id> <role>",
+		Short: "assign a role to a user",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return usersRoleRun(roleOpts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	deleteOpts := s.newOptions()
+	deleteCmd := &cobra.Command{
+		Use:   "delete <id>",
+		Short: "delete a user",
+		Args:  usersIDArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return usersDeleteRun(deleteOpts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	attachOpts := s.newOptions()
+	attachCmd := &cobra.Command{
+		Use:   "attach <id>",
+		Short: "attach licenses to a user",
+		Example: `  keygen users attach jane@example.com --licenses <id>,<id>
+
+Docs:
+  https://keygen.sh/docs/cli/`,
+		Args: usersIDArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return usersAttachRun(attachOpts, args)
+		},
+
+		// Encountering an error should not display usage
+		SilenceUsage: true,
+	}
+
+	attachCmd.Flags().StringSliceVar(&attachOpts.licenses, "licenses", []string{}, "comma seperated list of license IDs to attach (required)")
+
+	attachCmd.MarkFlagRequired("licenses")
+
+	cmd := &cobra.Command{
+		Use:   "users",
+		Short: "manage users",
+	}
+
+	for _, c := range []*cobra.Command{listCmd, inviteCmd, roleCmd, deleteCmd, attachCmd} {
+		addAccountFlags(c, s)
+
+		cmd.AddCommand(c)
+	}
+
+	return cmd
+}
+
+func usersEmailArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("email is required")
+	}
+
+	return nil
+}
+
+func usersIDArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("user ID or email is required")
+	}
+
+	return nil
+}
+
+func usersListRun(opts *CommandOptions) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	if err := validateListFlags(opts, usersSortFields...); err != nil {
+		return err
+	}
+
+	limit, paging := opts.limit, listPaging(opts)
+
+	// Results are sorted locally, so every page is listed
+	if sortsLocally(opts) {
+		limit, paging = localPaging(opts)
+	}
+
+	users, err := opts.client.ListUsers(opts.ctx, &keygenext.ListParams{Limit: limit, Paging: paging})
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	sortList(opts, users, func(i, j int) bool {
+		switch opts.sort {
+		case "email":
+			return users[i].Email < users[j].Email
+		case "role":
+			return users[i].Role < users[j].Role
+		case "status":
+			return users[i].Status < users[j].Status
+		case "updated":
+			return users[i].Updated.Before(users[j].Updated)
+		default:
+			return users[i].Created.Before(users[j].Created)
+		}
+	})
+
+	if sortsLocally(opts) {
+		users = users[:truncateList(opts, len(users))]
+	}
+
+	rows := [][]string{}
+	for _, u := range users {
+		rows = append(rows, []string{u.ID, u.Email, u.FullName, u.Role, u.Status, u.Created.Format(time.RFC3339), u.Updated.Format(time.RFC3339)})
+	}
+
+	r, err := selectList(opts, rendering{
+		value:   usersJSON(users...),
+		headers: []string{"ID", "EMAIL", "NAME", "ROLE", "STATUS", "CREATED", "UPDATED"},
+		rows:    rows,
+		wide:    1,
+	})
+	if err != nil {
+		return err
+	}
+
+	return render(opts.output, r)
+}
+
+func usersInviteRun(opts *CommandOptions, args []string) error {
+	if err := validateRenderOutput(opts.output); err != nil {
+		return err
+	}
+
+	user := &keygenext.User{Email: args[0], FirstName: opts.firstName, LastName: opts.lastName}
+
+	if err := opts.client.CreateUser(opts.ctx, user); err != nil {
+		return formatAPIError(err)
+	}
+
+	// Roles can only be assigned after the user has been created
+	if r := opts.role; r != "" && r != user.Role {
+		if err := opts.client.UpdateUserRole(opts.ctx, user, r); err != nil {
+			return formatAPIError(err)
+		}
+	}
+
+	if g := opts.group; g != "" {
+		group := &keygenext.Group{ID: g}
+		if err := opts.client.AttachGroupUser(opts.ctx, group, user.ID); err != nil {
+			return formatAPIError(err)
+		}
+
+		user.GroupID = g
+	}
+
+	if opts.invite {
+		if err := opts.client.InviteUser(opts.ctx, user); err != nil {
+			return formatAPIError(err)
+		}
+	}
+
+	if isStructuredOutput(opts.output) {
+		return render(opts.output, rendering{value: usersJSON(*user)[0]})
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	if opts.invite {
+		fmt.Println("invited user " + italic(user.ID) + " (" + user.Email + ")")
+	} else {
+		fmt.Println("created user " + italic(user.ID) + " (" + user.Email + ")")
+	}
+
+	return nil
+}
+
+func usersRoleRun(opts *CommandOptions, args []string) error {
+	user, err := opts.client.GetUser(opts.ctx, args[0])
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	if err := opts.client.UpdateUserRole(opts.ctx, user, args[1]); err != nil {
+		return formatAPIError(err)
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("assigned role " + italic(user.Role) + " to user " + italic(user.ID))
+
+	return nil
+}
+
+func usersDeleteRun(opts *CommandOptions, args []string) error {
+	user, err := opts.client.GetUser(opts.ctx, args[0])
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	if err := opts.confirmAction("delete a user", []string{user.Email + " (" + user.ID + ")"}, user.Email); err != nil {
+		return err
+	}
+
+	if err := opts.client.DeleteUser(opts.ctx, user); err != nil {
+		return formatAPIError(err)
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	fmt.Println("deleted user " + italic(user.ID))
+
+	return nil
+}
+
+func usersAttachRun(opts *CommandOptions, args []string) error {
+	user, err := opts.client.GetUser(opts.ctx, args[0])
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+
+	for _, id := range opts.licenses {
+		if err := opts.client.AttachUserLicense(opts.ctx, user, id); err != nil {
+			return formatAPIError(err)
+		}
+
+		fmt.Println("attached license " + italic(id) + " to user " + italic(user.ID))
+	}
+
+	return nil
+}
+
+func usersJSON(users ...keygenext.User) []map[string]interface{} {
+	out := []map[string]interface{}{}
+
+	for _, u := range users {
+		out = append(out, map[string]interface{}{
+			"id":         u.ID,
+			"email":      u.Email,
+			"first_name": u.FirstName,
+			"last_name":  u.LastName,
+			"role":       u.Role,
+			"status":     u.Status,
+			"group":      u.GroupID,
+			"metadata":   u.Metadata,
+			"created":    u.Created,
+			"updated":    u.Updated,
+		})
+	}
+
+	return out
+}
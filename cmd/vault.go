@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+)
+
+// maxVaultMessageSize keeps requests below Vault's default 32 MiB request size
+// limit, accounting for base64 encoding.
+const maxVaultMessageSize = 24 * 1024 * 1024
+
+// vaultSigner signs messages using an ed25519 key held by HashiCorp Vault's
+// transit secrets engine, authenticating via $VAULT_ADDR and $VAULT_TOKEN.
+// Requests to Vault are aborted once its context is done.
+type vaultSigner struct {
+	ctx       context.Context
+	addr      string
+	token     string
+	namespace string
+	mount     string
+	name      string
+}
+
+// newVaultSigner parses a Vault key reference, e.g. "vault://transit/keys/release"
+// where "transit" is the engine's mount path and "release" is the key name.
+func newVaultSigner(ctx context.Context, ref string) (*vaultSigner, error) {
+	path := strings.Trim(strings.TrimPrefix(ref, "vault://"), "/")
+
+	i := strings.LastIndex(path, "/keys/")
+	if i == -1 {
+		return nil, fmt.Errorf(`vault key "%s" is not acceptable (expected vault://<mount>/keys/<name>)`, ref)
+	}
+
+	s := &vaultSigner{
+		ctx:       ctx,
+		addr:      strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/"),
+		token:     os.Getenv("VAULT_TOKEN"),
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		mount:     path[:i],
+		name:      path[i+len("/keys/"):],
+	}
+
+	if s.addr == "" {
+		return nil, errors.New("vault signing requires $VAULT_ADDR")
+	}
+
+	if s.token == "" {
+		return nil, errors.New("vault signing requires $VAULT_TOKEN")
+	}
+
+	return s, nil
+}
+
+func (s *vaultSigner) do(method string, path string, body interface{}, out interface{}) error {
+	var in io.Reader
+
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		in = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(s.ctx, method, s.addr+"/v1/"+s.mount+path, in)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-Vault-Token", s.token)
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+
+	client := newExternalClient(5 * time.Minute)
+
+	res, err := client.Do(req)
+	if err != nil {
+		if e := s.ctx.Err(); e != nil {
+			return e
+		}
+
+		return fmt.Errorf("vault is not available (%s)", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+
+		json.NewDecoder(res.Body).Decode(&e)
+
+		return fmt.Errorf("vault request failed with %d (%s)", res.StatusCode, strings.Join(e.Errors, "; "))
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func (s *vaultSigner) Public() crypto.PublicKey {
+	var res struct {
+		Data struct {
+			Type          string `json:"type"`
+			LatestVersion int    `json:"latest_version"`
+			Keys          map[string]struct {
+				PublicKey string `json:"public_key"`
+			} `json:"keys"`
+		} `json:"data"`
+	}
+
+	if err := s.do("GET", "/keys/"+s.name, nil, &res); err != nil {
+		return nil
+	}
+
+	key, ok := res.Data.Keys[strconv.Itoa(res.Data.LatestVersion)]
+	if res.Data.Type != "ed25519" || !ok {
+		return nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(key.PublicKey)
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil
+	}
+
+	return ed25519.PublicKey(b)
+}
+
+// Sign signs the message using the latest version of the transit key. Vault
+// only produces pure ed25519 signatures without a context, so ed25519ph is
+// not supported.
+func (s *vaultSigner) Sign(_ io.Reader, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts != nil && opts.HashFunc() != crypto.Hash(0) {
+		return nil, errors.New("vault does not support ed25519ph signatures (use --signing-algorithm ed25519)")
+	}
+
+	if o, ok := opts.(*ed25519.Options); ok && o.Context != "" {
+		return nil, errors.New("vault does not support ed25519 signature contexts")
+	}
+
+	if l := len(message); l > maxVaultMessageSize {
+		return nil, fmt.Errorf("file is too large to be signed by vault (got %d bytes max %d)", l, maxVaultMessageSize)
+	}
+
+	var res struct {
+		Data struct {
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}
+
+	err := s.do("POST", "/sign/"+s.name, map[string]string{"input": base64.StdEncoding.EncodeToString(message)}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	// Signatures are formatted as "vault:v<version>:<base64>"
+	parts := strings.SplitN(res.Data.Signature, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return nil, fmt.Errorf(`vault returned an unexpected signature format "%s"`, res.Data.Signature)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("vault returned a bad signature (%s)", err)
+	}
+
+	if l := len(sig); l != ed25519.SignatureSize {
+		return nil, fmt.Errorf("vault returned a bad signature length (got %d expected %d)", l, ed25519.SignatureSize)
+	}
+
+	return sig, nil
+}
@@ -11,17 +11,13 @@ var (
 	Version string
 )
 
-var (
-	versionCmd = &cobra.Command{
+func newVersionCmd() *cobra.Command {
+	return &cobra.Command{
 		Use:   "version",
 		Short: "print the current CLI version",
 		Args:  cobra.NoArgs,
 		Run:   versionRun,
 	}
-)
-
-func init() {
-	rootCmd.AddCommand(versionCmd)
 }
 
 func versionRun(cmd *cobra.Command, args []string) {
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+)
+
+// waitEventInterval is how often the event logs are polled for an event.
+const waitEventInterval = 5 * time.Second
+
+var eventRegex = regexp.MustCompile(`^[a-z-]+(\.[a-z-]+)+$`)
+
+// validateWaitEvent checks an event given to --wait-for-event, which must be a
+// release or artifact event, e.g. release.published or artifact.uploaded.
+func validateWaitEvent(event string) error {
+	if !eventRegex.MatchString(event) || !(strings.HasPrefix(event, "release.") || strings.HasPrefix(event, "artifact.")) {
+		return fmt.Errorf(`event "%s" is not acceptable (must be a release or artifact event, e.g. release.published)`, event)
+	}
+
+	return nil
+}
+
+// waitForEvent blocks until the event is logged for the release, or its
+// artifact, after since, e.g. so that a pipeline's follow-up steps only run
+// once the release has propagated. Event logs are polled, since the CLI has
+// no address to receive webhooks on. Network errors are retried until the
+// timeout.
+func waitForEvent(opts *CommandOptions, release *keygenext.Release, event string, since time.Time) error {
+	resource := release.ID
+	if strings.HasPrefix(event, "artifact.") {
+		resource = release.ArtifactID
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+	deadline := time.Now().Add(opts.waitTimeout)
+
+	fmt.Fprintln(os.Stderr, "waiting for event "+italic(event)+" (timeout "+opts.waitTimeout.String()+")")
+
+	for {
+		logs, err := opts.client.ListEventLogs(opts.ctx, &keygenext.EventLogFilter{
+			Event:      event,
+			ResourceID: resource,
+			PageSize:   100,
+			PageNumber: 1,
+		})
+		if err != nil && !isNetworkError(err) {
+			return fmt.Errorf(`event "%s" could not be checked (%w)`, event, formatAPIError(err))
+		}
+
+		for _, l := range logs {
+			if l.Event == event && l.ResourceID == resource && !l.Created.Before(since) {
+				fmt.Fprintln(os.Stderr, "received event "+italic(event)+" ("+l.ID+")")
+
+				return nil
+			}
+		}
+
+		if !time.Now().Add(waitEventInterval).Before(deadline) {
+			return fmt.Errorf(`event "%s" was not received for release "%s" within %s`, event, release.ID, opts.waitTimeout)
+		}
+
+		select {
+		case <-opts.ctx.Done():
+			return opts.ctx.Err()
+		case <-time.After(waitEventInterval):
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/fatih/color"
+	"github.com/keygen-sh/keygen-cli/keygenext"
+	"github.com/mitchellh/go-homedir"
+)
+
+// distWatchInterval is how often watched files are checked for changes.
+const distWatchInterval = 500 * time.Millisecond
+
+// watchedFile is the state used to detect a file change.
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+}
+
+// distWatch watches a file or directory, publishing each changed file as a
+// new dev prerelease of --version, e.g. 1.2.3-dev.4, until interrupted.
+func distWatch(opts *CommandOptions, arg string) error {
+	root, err := homedir.Expand(arg)
+	if err != nil {
+		return fmt.Errorf(`path "%s" is not expandable (%s)`, arg, err)
+	}
+
+	base, err := parseVersion(opts, opts.version)
+	if err != nil {
+		return err
+	}
+
+	n, err := nextDevNumber(opts, base)
+	if err != nil {
+		return formatAPIError(err)
+	}
+
+	// Files which exist before watching are not published until they change
+	seen, err := snapshotWatchPath(root)
+	if err != nil {
+		return err
+	}
+
+	italic := color.New(color.Italic).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	if opts.output != "json" {
+		fmt.Println("watching " + italic(root) + " for changes (press ctrl+c to stop)")
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	// Abort an in-flight publish when interrupted
+	go func() {
+		<-interrupt
+		opts.cancel()
+	}()
+
+	ticker := time.NewTicker(distWatchInterval)
+	defer ticker.Stop()
+
+	pending := map[string]time.Time{}
+
+	for {
+		select {
+		case <-opts.ctx.Done():
+			if errors.Is(opts.ctx.Err(), context.DeadlineExceeded) {
+				return opts.ctx.Err()
+			}
+
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := snapshotWatchPath(root)
+		if err != nil {
+			return err
+		}
+
+		// Restart the debounce window on every change, so that a file is only
+		// published once it's done being written.
+		for path, f := range current {
+			if prev, ok := seen[path]; !ok || prev != f {
+				pending[path] = time.Now()
+			}
+		}
+
+		seen = current
+
+		for path, changed := range pending {
+			if time.Since(changed) < opts.debounce {
+				continue
+			}
+
+			delete(pending, path)
+
+			if _, ok := current[path]; !ok {
+				continue
+			}
+
+			version := fmt.Sprintf("%d.%d.%d-dev.%d", base.Major(), base.Minor(), base.Patch(), n)
+			n++
+
+			if opts.output != "json" {
+				fmt.Println("publishing " + italic(filepath.Base(path)) + " as " + italic(version))
+			}
+
+			if err := distPublish(opts, newDistArtifact(opts, path), version); err != nil {
+				fmt.Fprintln(os.Stderr, red("error:")+" "+err.Error())
+			}
+		}
+	}
+}
+
+// snapshotWatchPath returns the state of the file at path, or every file under
+// it if it's a directory. Hidden files are ignored.
+func snapshotWatchPath(root string) (map[string]watchedFile, error) {
+	files := map[string]watchedFile{}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Files may disappear mid-walk while a build is writing them
+			if os.IsNotExist(err) && path != root {
+				return nil
+			}
+
+			return err
+		}
+
+		if path != root && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if info.Mode().IsRegular() {
+			files[path] = watchedFile{size: info.Size(), modTime: info.ModTime()}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf(`path "%s" is not readable (%s)`, root, err)
+	}
+
+	return files, nil
+}
+
+// nextDevNumber returns the next dev prerelease number for the base version,
+// following the highest one already published to the dev channel.
+func nextDevNumber(opts *CommandOptions, base *semver.Version) (int, error) {
+	releases, err := opts.client.ListReleases(opts.ctx, &keygenext.ReleaseFilter{Product: opts.productID, Channel: opts.channel, Limit: 100})
+	if err != nil {
+		return 0, err
+	}
+
+	n := 1
+
+	for _, r := range releases {
+		v, err := semver.NewVersion(r.Version)
+		if err != nil || v.Major() != base.Major() || v.Minor() != base.Minor() || v.Patch() != base.Patch() {
+			continue
+		}
+
+		pre := v.Prerelease()
+		if !strings.HasPrefix(pre, "dev.") {
+			continue
+		}
+
+		if i, err := strconv.Atoi(strings.TrimPrefix(pre, "dev.")); err == nil && i >= n {
+			n = i + 1
+		}
+	}
+
+	return n, nil
+}
@@ -1,9 +0,0 @@
-package keygenext
-
-var (
-	Account   string
-	Product   string
-	Token     string
-	PublicKey string
-	UserAgent string
-)
@@ -0,0 +1,74 @@
+package keygenext
+
+import (
+	"context"
+)
+
+// Account represents the Keygen account the Client belongs to.
+type Account struct {
+	ID       string                 `json:"-"`
+	Type     string                 `json:"-"`
+	Name     string                 `json:"name"`
+	Slug     string                 `json:"slug"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+func (a *Account) SetID(id string) error {
+	a.ID = id
+	return nil
+}
+
+func (a *Account) SetType(t string) error {
+	a.Type = t
+	return nil
+}
+
+func (a *Account) SetData(to func(target interface{}) error) error {
+	return to(a)
+}
+
+// GetAccount retrieves the Client's account.
+func (c *Client) GetAccount(ctx context.Context) (*Account, error) {
+	client := c.newClient(ctx)
+
+	account := &Account{}
+
+	res, err := client.Get("", nil, account)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	return account, nil
+}
+
+// UpdateAccountMetadata replaces the account's metadata.
+func (c *Client) UpdateAccountMetadata(ctx context.Context, a *Account, metadata map[string]interface{}) error {
+	client := c.newClient(ctx)
+
+	params := accountMetadata{ID: a.ID, Metadata: metadata}
+
+	res, err := client.Patch("", params, a)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// accountMetadata is used to update only an account's metadata.
+type accountMetadata struct {
+	ID       string                 `json:"-"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+func (a accountMetadata) GetID() string {
+	return a.ID
+}
+
+func (a accountMetadata) GetType() string {
+	return "accounts"
+}
+
+func (a accountMetadata) GetData() interface{} {
+	return a
+}
@@ -0,0 +1,208 @@
+package keygenext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keygen-sh/jsonapi-go"
+	"github.com/keygen-sh/keygen-go"
+)
+
+var (
+	ErrArtifactLocationMissing = errors.New("artifact has no download location")
+	ErrRangeNotSupported       = errors.New("storage provider does not support range requests")
+)
+
+// Artifact represents a Keygen artifact object.
+type Artifact struct {
+	ID            string    `json:"-"`
+	Type          string    `json:"-"`
+	Key           string    `json:"key"`
+	Filename      string    `json:"filename"`
+	Filesize      int64     `json:"filesize"`
+	Status        string    `json:"status"`
+	Created       time.Time `json:"created"`
+	Updated       time.Time `json:"updated"`
+	Location      string    `json:"-"`
+	ContentLength int64     `json:"-"`
+	ContentType   string    `json:"-"`
+	ReleaseID     string    `json:"-"`
+}
+
+func (a *Artifact) SetID(id string) error {
+	a.ID = id
+	return nil
+}
+
+func (a *Artifact) SetType(t string) error {
+	a.Type = t
+	return nil
+}
+
+func (a *Artifact) SetData(to func(target interface{}) error) error {
+	return to(a)
+}
+
+func (a *Artifact) SetRelationships(relationships map[string]interface{}) error {
+	if relationship, ok := relationships["release"]; ok {
+		if r, ok := relationship.(*jsonapi.ResourceObjectIdentifier); ok && r != nil {
+			a.ReleaseID = r.ID
+		}
+	}
+
+	return nil
+}
+
+func (a *Artifact) Upload(ctx context.Context, reader io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, "PUT", a.Location, reader)
+	if err != nil {
+		return err
+	}
+
+	// This must be set otherwise the Go http package sends a Transfer-Encoding
+	// header, which S3 does not support.
+	req.ContentLength = a.ContentLength
+
+	// Set the content type that the storage provider serves the file with
+	if a.ContentType != "" {
+		req.Header.Set("Content-Type", a.ContentType)
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// Drain the response so that its connection is reused
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to upload to storage provider (status %d)", res.StatusCode)
+	}
+
+	return nil
+}
+
+// Download requests the artifact's file from the storage provider. When length
+// is greater than zero, only the given byte range is requested. The total size
+// of the stored file is returned alongside the body, which must be closed.
+func (a *Artifact) Download(ctx context.Context, offset int64, length int64) (io.ReadCloser, int64, error) {
+	if a.Location == "" {
+		return nil, 0, ErrArtifactLocationMissing
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", a.Location, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if length > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch {
+	case length <= 0 && res.StatusCode == http.StatusOK:
+		return res.Body, res.ContentLength, nil
+	case length > 0 && res.StatusCode == http.StatusPartialContent:
+		// Content-Range is formatted as "bytes <start>-<end>/<size>"
+		r := res.Header.Get("Content-Range")
+		i := strings.LastIndex(r, "/")
+		if i == -1 {
+			res.Body.Close()
+
+			return nil, 0, ErrRangeNotSupported
+		}
+
+		size, err := strconv.ParseInt(r[i+1:], 10, 64)
+		if err != nil {
+			res.Body.Close()
+
+			return nil, 0, ErrRangeNotSupported
+		}
+
+		return res.Body, size, nil
+	case length > 0 && res.StatusCode == http.StatusOK:
+		res.Body.Close()
+
+		return nil, 0, ErrRangeNotSupported
+	default:
+		res.Body.Close()
+
+		return nil, 0, errors.New("failed to download from storage provider")
+	}
+}
+
+type artifactParams struct {
+	TTL int64 `url:"ttl,omitempty"`
+}
+
+// GetArtifact retrieves an artifact by its ID, including a temporary download
+// location that expires after the given TTL (or the server default when zero).
+func (c *Client) GetArtifact(ctx context.Context, id string, ttl time.Duration) (*Artifact, error) {
+	client := c.newClient(ctx)
+
+	params := &artifactParams{TTL: int64(ttl.Seconds())}
+	artifact := &Artifact{}
+
+	res, err := client.Get("artifacts/"+id, params, artifact)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	artifact.Location = res.Headers.Get("Location")
+
+	return artifact, nil
+}
+
+// Artifacts represents a collection of Keygen artifact objects.
+type Artifacts []Artifact
+
+func (a *Artifacts) SetData(to func(target interface{}) error) error {
+	return to(a)
+}
+
+// ListArtifacts retrieves the account's artifacts, including ones which no
+// release references, e.g. from failed uploads.
+func (c *Client) ListArtifacts(ctx context.Context, params *ListParams) (Artifacts, error) {
+	client := c.newClient(ctx)
+
+	artifacts := Artifacts{}
+
+	err := paginate(&params.Limit, &params.Paging, func() (*keygen.Response, int, error) {
+		page := Artifacts{}
+		res, err := client.Get("artifacts", params, &page)
+		artifacts = append(artifacts, page...)
+
+		return res, len(page), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return artifacts, nil
+}
+
+// DeleteArtifact deletes an artifact, along with its uploaded file.
+func (c *Client) DeleteArtifact(ctx context.Context, a *Artifact) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Delete("artifacts/"+a.ID, nil, nil)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
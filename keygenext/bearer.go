@@ -0,0 +1,44 @@
+package keygenext
+
+import (
+	"context"
+)
+
+// Bearer represents the Keygen object a token authenticates as, e.g. a
+// product, or a user such as an admin.
+type Bearer struct {
+	ID          string   `json:"-"`
+	Type        string   `json:"-"`
+	Name        string   `json:"name"`
+	Email       string   `json:"email"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+func (b *Bearer) SetID(id string) error {
+	b.ID = id
+	return nil
+}
+
+func (b *Bearer) SetType(t string) error {
+	b.Type = t
+	return nil
+}
+
+func (b *Bearer) SetData(to func(target interface{}) error) error {
+	return to(b)
+}
+
+// GetBearer retrieves the object the Client's token authenticates as.
+func (c *Client) GetBearer(ctx context.Context) (*Bearer, error) {
+	client := c.newClient(ctx)
+
+	bearer := &Bearer{}
+
+	res, err := client.Get("me", nil, bearer)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	return bearer, nil
+}
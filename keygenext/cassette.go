@@ -0,0 +1,244 @@
+package keygenext
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/keygen-sh/keygen-go"
+)
+
+// signedParam matches the query parameters of presigned URLs which carry a
+// live signature or credential, e.g. an artifact's storage Location.
+var signedParam = regexp.MustCompile(`(?i)((?:x-amz-signature|x-amz-credential|x-amz-security-token|x-goog-signature|x-goog-credential|signature|sig|token)=)[^&"'\s\\]+`)
+
+// Interaction is a single recorded API request and its response. Request
+// bodies and headers are never recorded, signatures of presigned URLs are
+// redacted, and bodies other than JSON are replaced by a placeholder, so that
+// tokens, live download links and artifacts don't end up in cassettes.
+type Interaction struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+
+	replayed bool
+}
+
+// Cassette is an http.RoundTripper which either records API interactions with
+// the underlying transport to a file, or replays previously recorded
+// interactions from a file without touching the network. Only requests to the
+// API host, i.e. keygen.APIURL, are recorded. Other requests, e.g. uploads to
+// and downloads from the storage provider, are passed through when recording,
+// and answered with an empty 200 response when replaying.
+type Cassette struct {
+	Path         string
+	Interactions []*Interaction
+
+	replay bool
+	mu     sync.Mutex
+}
+
+// Record installs a cassette which records the API interactions to path.
+func Record(path string) error {
+	c := &Cassette{Path: path, Interactions: []*Interaction{}}
+	if err := c.save(); err != nil {
+		return err
+	}
+
+	installCassette(c)
+
+	return nil
+}
+
+// Replay installs a cassette which replays the HTTP interactions recorded at
+// path, in order. Requests which were not recorded fail.
+func Replay(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	c := &Cassette{Path: path, replay: true}
+	if err := json.Unmarshal(b, &c.Interactions); err != nil {
+		return fmt.Errorf(`cassette "%s" is not readable (%s)`, path, err)
+	}
+
+	installCassette(c)
+
+	return nil
+}
+
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	if c.replay {
+		return c.play(req)
+	}
+
+	if !isAPIRequest(req) {
+		return baseTransport().RoundTrip(req)
+	}
+
+	res, err := baseTransport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	header := res.Header.Clone()
+	header.Del("Set-Cookie")
+
+	if header.Get("Authorization") != "" {
+		header.Set("Authorization", "REDACTED")
+	}
+
+	for k, values := range header {
+		for i, v := range values {
+			header[k][i] = redactSignatures(v)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Interactions = append(c.Interactions, &Interaction{
+		Method: req.Method,
+		URL:    redactSignatures(req.URL.String()),
+		Status: res.StatusCode,
+		Header: header,
+		Body:   recordableBody(res.Header.Get("Content-Type"), body),
+	})
+
+	if err := c.save(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Cassette) play(req *http.Request) (*http.Response, error) {
+	// Drain the request body as the real transport would have, e.g. so that
+	// upload progress is still reported.
+	if req.Body != nil {
+		io.Copy(ioutil.Discard, req.Body)
+		req.Body.Close()
+	}
+
+	if !isAPIRequest(req) {
+		return &http.Response{
+			Status:     "200 OK",
+			StatusCode: http.StatusOK,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+			Request:    req,
+		}, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	url := redactSignatures(req.URL.String())
+
+	for _, i := range c.Interactions {
+		if i.replayed || i.Method != req.Method || i.URL != url {
+			continue
+		}
+
+		i.replayed = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", i.Status, http.StatusText(i.Status)),
+			StatusCode:    i.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        i.Header.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewBufferString(i.Body)),
+			ContentLength: int64(len(i.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf(`cassette "%s" has no recorded interaction for %s %s`, c.Path, req.Method, url)
+}
+
+// isAPIRequest reports whether req is made to the API host, rather than e.g.
+// to the storage provider.
+func isAPIRequest(req *http.Request) bool {
+	u, err := url.Parse(keygen.APIURL)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(req.URL.Host, u.Host)
+}
+
+// redactSignatures replaces the signatures and credentials of presigned URLs
+// in s, since they grant access until they expire.
+func redactSignatures(s string) string {
+	return signedParam.ReplaceAllString(s, "${1}REDACTED")
+}
+
+// recordableBody returns a response body as it's recorded, where anything but
+// JSON, e.g. a downloaded artifact, is replaced by a placeholder.
+func recordableBody(contentType string, body []byte) string {
+	if !strings.Contains(contentType, "json") || !utf8.Valid(body) {
+		return fmt.Sprintf("<%d bytes of %s omitted>", len(body), contentType)
+	}
+
+	return redactSignatures(string(body))
+}
+
+func (c *Cassette) save() error {
+	b, err := json.MarshalIndent(c.Interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(c.Path, b, 0600)
+}
+
+var (
+	useCassette    sync.Once
+	useCassetteErr error
+)
+
+// UseCassetteFromEnv installs a recording or replaying cassette when the
+// KEYGEN_RECORD or KEYGEN_REPLAY environment variables are set. The cassette
+// is only installed once per process, since it wraps the package's transport.
+func UseCassetteFromEnv() error {
+	useCassette.Do(func() {
+		record, replay := os.Getenv("KEYGEN_RECORD"), os.Getenv("KEYGEN_REPLAY")
+
+		switch {
+		case record != "" && replay != "":
+			useCassetteErr = errors.New("KEYGEN_RECORD and KEYGEN_REPLAY cannot be used together")
+		case record != "":
+			useCassetteErr = Record(record)
+		case replay != "":
+			useCassetteErr = Replay(replay)
+		}
+	})
+
+	return useCassetteErr
+}
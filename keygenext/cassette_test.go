@@ -0,0 +1,179 @@
+package keygenext
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/keygen-sh/keygen-go"
+)
+
+// useAPI points the package at an API served by handler, and uninstalls any
+// cassette once the test is done.
+func useAPI(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	url := keygen.APIURL
+	keygen.APIURL = server.URL
+
+	t.Cleanup(func() {
+		keygen.APIURL = url
+		installCassette(nil)
+	})
+
+	return server
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write([]byte("PK"))
+	}))
+	defer storage.Close()
+
+	location := storage.URL + "/app.zip?X-Amz-Credential=cred&X-Amz-Signature=secret"
+
+	api := useAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+
+		switch r.URL.Path {
+		case "/v1/accounts/acct/releases/r1":
+			w.Header().Set("Set-Cookie", "session=secret")
+			w.Write([]byte(`{"data":{"id":"r1","type":"releases","attributes":{"version":"1.0.0","filename":"app.zip"}}}`))
+		case "/v1/accounts/acct/releases/r1/artifact":
+			w.Header().Set("Location", location)
+			w.WriteHeader(http.StatusSeeOther)
+			w.Write([]byte(`{"data":{"id":"a1","type":"artifacts","attributes":{"key":"app.zip"},"links":{"redirect":"` + location + `"}}}`))
+		case "/v1/accounts/acct/releases/r1/app.zip":
+			w.Header().Set("Content-Type", "application/zip")
+			w.Write([]byte("PK"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[{"title":"Not found","code":"NOT_FOUND"}]}`))
+		}
+	})
+
+	ctx := context.Background()
+	client := &Client{Account: "acct", Token: "prod-secret"}
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	// run makes the same requests when recording and when replaying
+	run := func() {
+		t.Helper()
+
+		release, err := client.GetRelease(ctx, "r1")
+		if err != nil {
+			t.Fatalf("GetRelease() error = %v", err)
+		}
+
+		if release.Version != "1.0.0" {
+			t.Errorf("release version = %s, want 1.0.0", release.Version)
+		}
+
+		artifact, err := client.GetReleaseArtifact(ctx, release)
+		if err != nil {
+			t.Fatalf("GetReleaseArtifact() error = %v", err)
+		}
+
+		if !strings.HasPrefix(artifact.Location, storage.URL+"/app.zip?") {
+			t.Errorf("artifact location = %s, want the storage URL", artifact.Location)
+		}
+
+		if _, err := client.GetRelease(ctx, "r2"); err == nil {
+			t.Error("GetRelease() found a missing release")
+		}
+
+		res, err := apiHTTPClient.Get(api.URL + "/v1/accounts/acct/releases/r1/app.zip")
+		if err != nil {
+			t.Fatalf("download error = %v", err)
+		}
+		res.Body.Close()
+
+		res, err = httpClient.Get(location)
+		if err != nil {
+			t.Fatalf("storage error = %v", err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("storage status = %d, want 200", res.StatusCode)
+		}
+	}
+
+	if err := Record(path); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	run()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var interactions []*Interaction
+	if err := json.Unmarshal(b, &interactions); err != nil {
+		t.Fatalf("cassette is not readable: %s", err)
+	}
+
+	// Requests to the storage provider are passed through, not recorded
+	if len(interactions) != 4 {
+		t.Fatalf("recorded %d interactions, want 4", len(interactions))
+	}
+
+	for _, secret := range []string{"prod-secret", "session=secret", "X-Amz-Signature=secret", "=cred", "PK"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("cassette contains %q", secret)
+		}
+	}
+
+	if got := interactions[1].Header.Get("Location"); !strings.Contains(got, "X-Amz-Signature=REDACTED") {
+		t.Errorf("recorded location = %s, want its signature redacted", got)
+	}
+
+	if got := interactions[3].Body; got != "<2 bytes of application/zip omitted>" {
+		t.Errorf("recorded download body = %q, want a placeholder", got)
+	}
+
+	// Replay without the network
+	api.Close()
+
+	if err := Replay(path); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+
+	storage.Close()
+
+	run()
+
+	// Each interaction is replayed once, and others aren't made up
+	for _, id := range []string{"r1", "r3"} {
+		_, err := client.GetRelease(ctx, id)
+		if err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+			t.Errorf("GetRelease(%s) error = %v, want no recorded interaction", id, err)
+		}
+	}
+}
+
+func TestReplayUnreadableCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := ioutil.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Replay(path); err == nil || !strings.Contains(err.Error(), "is not readable") {
+		t.Errorf("Replay() error = %v, want the cassette to be unreadable", err)
+	}
+
+	if err := Replay(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Replay() accepted a missing cassette")
+	}
+}
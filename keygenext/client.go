@@ -0,0 +1,210 @@
+package keygenext
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/google/go-querystring/query"
+	"github.com/keygen-sh/jsonapi-go"
+	"github.com/keygen-sh/keygen-go"
+)
+
+// sdkUserAgent identifies requests the same way keygen-go does.
+var sdkUserAgent = "keygen/" + keygen.APIVersion + " sdk/" + keygen.SDKVersion + " go/" + runtime.Version() + " " + runtime.GOOS + "/" + runtime.GOARCH
+
+// apiHTTPClient makes API requests using the package's transport. Redirects
+// aren't followed, since e.g. an artifact's download location is read from
+// the redirect's Location header.
+var apiHTTPClient = &http.Client{
+	Transport: sharedTransport{},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+var reportedMu sync.Mutex
+
+// apiClient makes a Client's requests to the API, the same way keygen-go's
+// client does, except that they're bound to a context and made using the
+// package's transport rather than the default transport.
+type apiClient struct {
+	client *Client
+	ctx    context.Context
+
+	// Token authenticates requests, unless the Client has a license key.
+	Token string
+}
+
+// newClient returns an API client whose requests are bound to ctx, so that
+// they're aborted once ctx is canceled or its deadline passes.
+func (c *Client) newClient(ctx context.Context) *apiClient {
+	return &apiClient{client: c, ctx: ctx, Token: c.Token}
+}
+
+func (a *apiClient) Post(path string, params interface{}, model interface{}) (*keygen.Response, error) {
+	return a.send(http.MethodPost, path, params, model)
+}
+
+func (a *apiClient) Get(path string, params interface{}, model interface{}) (*keygen.Response, error) {
+	return a.send(http.MethodGet, path, params, model)
+}
+
+func (a *apiClient) Put(path string, params interface{}, model interface{}) (*keygen.Response, error) {
+	return a.send(http.MethodPut, path, params, model)
+}
+
+func (a *apiClient) Patch(path string, params interface{}, model interface{}) (*keygen.Response, error) {
+	return a.send(http.MethodPatch, path, params, model)
+}
+
+func (a *apiClient) Delete(path string, params interface{}, model interface{}) (*keygen.Response, error) {
+	return a.send(http.MethodDelete, path, params, model)
+}
+
+// send makes a request, where params are sent as the query of a GET request
+// and as the body of others, and unmarshals the response into model. Errors
+// are reported the same way as keygen-go, e.g. keygen.ErrNotFound.
+func (a *apiClient) send(method string, path string, params interface{}, model interface{}) (*keygen.Response, error) {
+	url := keygen.APIURL + "/" + keygen.APIVersion + "/accounts/" + a.client.Account + "/" + path
+	in := []byte{}
+
+	if params != nil {
+		switch {
+		case method == http.MethodGet:
+			values, err := query.Values(params)
+			if err != nil {
+				return nil, err
+			}
+
+			url += "?" + values.Encode()
+		default:
+			serialized, err := jsonapi.Marshal(params)
+			if err != nil {
+				return nil, err
+			}
+
+			in = serialized
+		}
+	}
+
+	req, err := http.NewRequestWithContext(a.ctx, method, url, bytes.NewReader(in))
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+
+	req.Header.Set("Content-Type", jsonapi.ContentType)
+	req.Header.Set("Accept", jsonapi.ContentType)
+
+	a.client.prepareRequest(req)
+
+	res, err := apiHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	a.client.reportVersion(res)
+
+	response := &keygen.Response{
+		ID:      res.Header.Get("x-request-id"),
+		Method:  method,
+		URL:     url,
+		Status:  res.StatusCode,
+		Headers: res.Header,
+		Size:    len(out),
+		Body:    out,
+	}
+
+	if response.Status >= http.StatusInternalServerError {
+		return response, fmt.Errorf("an error occurred: id=%s status=%d size=%d body=%s", response.ID, response.Status, response.Size, tldr(out))
+	}
+
+	if k := a.client.PublicKey; k != "" {
+		if err := verifyRawResponse(k, method, req, res, out); err != nil {
+			return response, err
+		}
+	}
+
+	if response.Status == http.StatusNoContent || response.Size == 0 {
+		return response, nil
+	}
+
+	doc, err := jsonapi.Unmarshal(out, model)
+	if err != nil {
+		return response, err
+	}
+
+	response.Document = doc
+
+	if response.Status == http.StatusForbidden {
+		return response, keygen.ErrNotAuthorized
+	}
+
+	if len(doc.Errors) > 0 {
+		switch keygen.ErrorCode(doc.Errors[0].Code) {
+		case keygen.ErrorCodeFingerprintTaken:
+			return response, keygen.ErrMachineAlreadyActivated
+		case keygen.ErrorCodeMachineLimitExceeded:
+			return response, keygen.ErrMachineLimitExceeded
+		case keygen.ErrorCodeTokenInvalid:
+			return response, keygen.ErrLicenseTokenInvalid
+		case keygen.ErrorCodeMachineHeartbeatDead:
+			return response, keygen.ErrMachineHeartbeatDead
+		case keygen.ErrorCodeNotFound:
+			return response, keygen.ErrNotFound
+		default:
+			return response, fmt.Errorf("an error occurred: id=%s status=%d size=%d body=%s", response.ID, response.Status, response.Size, out)
+		}
+	}
+
+	return response, nil
+}
+
+// prepareRequest identifies a request made on behalf of the Client, pins it to
+// the Client's API version and authenticates it using its license key.
+func (c *Client) prepareRequest(req *http.Request) {
+	req.Header.Set("User-Agent", strings.TrimSpace(sdkUserAgent+" "+c.UserAgent))
+
+	if v := c.APIVersion; v != "" {
+		req.Header.Set("Keygen-Version", v)
+	}
+
+	if k := c.LicenseKey; k != "" {
+		req.Header.Set("Authorization", "License "+k)
+	}
+}
+
+// reportVersion reports the API version a response was served using.
+func (c *Client) reportVersion(res *http.Response) {
+	if v := res.Header.Get("Keygen-Version"); v != "" && c.VersionReported != nil {
+		reportedMu.Lock()
+		c.VersionReported(v)
+		reportedMu.Unlock()
+	}
+}
+
+// tldr truncates a response body for an error message, in case it's an
+// unexpected response, e.g. from a proxy.
+func tldr(body []byte) string {
+	s := string(body)
+	if len(s) > 500 {
+		s = s[:500] + "..."
+	}
+
+	return strings.Replace(s, "\n", "\\n", -1)
+}
@@ -8,6 +8,30 @@ type Constraint struct {
 	EntitlementID string `json:"-"`
 }
 
+func (c *Constraint) SetID(id string) error {
+	c.ID = id
+	return nil
+}
+
+func (c *Constraint) SetType(t string) error {
+	c.Type = t
+	return nil
+}
+
+func (c *Constraint) SetData(to func(target interface{}) error) error {
+	return to(c)
+}
+
+func (c *Constraint) SetRelationships(relationships map[string]interface{}) error {
+	if relationship, ok := relationships["entitlement"]; ok {
+		if r, ok := relationship.(*jsonapi.ResourceObjectIdentifier); ok && r != nil {
+			c.EntitlementID = r.ID
+		}
+	}
+
+	return nil
+}
+
 func (c Constraint) GetID() string {
 	return c.ID
 }
@@ -41,6 +65,10 @@ func (c Constraints) GetData() interface{} {
 	return c
 }
 
+func (c *Constraints) SetData(to func(target interface{}) error) error {
+	return to(c)
+}
+
 func (c Constraints) From(entitlements []string) Constraints {
 	for _, entitlement := range entitlements {
 		c = append(c, Constraint{EntitlementID: entitlement})
@@ -0,0 +1,133 @@
+package keygenext
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/keygen-sh/keygen-go"
+)
+
+// Entitlement represents a Keygen entitlement object.
+type Entitlement struct {
+	ID       string                 `json:"-"`
+	Type     string                 `json:"-"`
+	Name     string                 `json:"name"`
+	Code     string                 `json:"code"`
+	Metadata map[string]interface{} `json:"metadata"`
+	Created  time.Time              `json:"created"`
+	Updated  time.Time              `json:"updated"`
+}
+
+func (e *Entitlement) SetID(id string) error {
+	e.ID = id
+	return nil
+}
+
+func (e *Entitlement) SetType(t string) error {
+	e.Type = t
+	return nil
+}
+
+func (e *Entitlement) SetData(to func(target interface{}) error) error {
+	return to(e)
+}
+
+// Entitlements represents a collection of Keygen entitlement objects.
+type Entitlements []Entitlement
+
+func (e *Entitlements) SetData(to func(target interface{}) error) error {
+	return to(e)
+}
+
+// ListEntitlements retrieves the account's entitlements.
+func (c *Client) ListEntitlements(ctx context.Context, params *ListParams) (Entitlements, error) {
+	client := c.newClient(ctx)
+
+	entitlements := Entitlements{}
+
+	err := paginate(&params.Limit, &params.Paging, func() (*keygen.Response, int, error) {
+		page := Entitlements{}
+		res, err := client.Get("entitlements", params, &page)
+		entitlements = append(entitlements, page...)
+
+		return res, len(page), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entitlements, nil
+}
+
+// GetEntitlement retrieves an entitlement by its ID or code.
+func (c *Client) GetEntitlement(ctx context.Context, id string) (*Entitlement, error) {
+	client := c.newClient(ctx)
+
+	entitlement := &Entitlement{}
+
+	res, err := client.Get("entitlements/"+url.PathEscape(id), nil, entitlement)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	return entitlement, nil
+}
+
+// entitlementAttributes are the writable attributes of an entitlement. The
+// metadata is always sent, so that removing it is too.
+type entitlementAttributes struct {
+	ID       string                 `json:"-"`
+	Name     string                 `json:"name"`
+	Code     string                 `json:"code"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+func newEntitlementAttributes(e *Entitlement) entitlementAttributes {
+	params := entitlementAttributes{ID: e.ID, Name: e.Name, Code: e.Code, Metadata: e.Metadata}
+	if params.Metadata == nil {
+		params.Metadata = map[string]interface{}{}
+	}
+
+	return params
+}
+
+func (e entitlementAttributes) GetID() string {
+	return e.ID
+}
+
+func (e entitlementAttributes) GetType() string {
+	return "entitlements"
+}
+
+func (e entitlementAttributes) GetData() interface{} {
+	return e
+}
+
+// CreateEntitlement creates an entitlement.
+func (c *Client) CreateEntitlement(ctx context.Context, e *Entitlement) error {
+	client := c.newClient(ctx)
+
+	params := newEntitlementAttributes(e)
+
+	res, err := client.Post("entitlements", params, e)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// UpdateEntitlement replaces an entitlement's name, code and metadata.
+func (c *Client) UpdateEntitlement(ctx context.Context, e *Entitlement) error {
+	client := c.newClient(ctx)
+
+	params := newEntitlementAttributes(e)
+
+	res, err := client.Patch("entitlements/"+e.ID, params, e)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package keygenext
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/keygen-sh/keygen-go"
+)
+
+type APIError struct {
+	Title  string
+	Detail string
+	Code   string
+	Source string
+	Status int
+	Err    error
+
+	// RetryAfter is how long to wait before retrying a rate limited request.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return e.Code + " - " + e.Title + ": " + e.Detail
+	} else {
+		return e.Title + ": " + e.Detail
+	}
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// newAPIError wraps err with the first error object from the response
+// document, if there is one.
+func newAPIError(res *keygen.Response, err error) error {
+	if res != nil && res.Document != nil && len(res.Document.Errors) > 0 {
+		e := res.Document.Errors[0]
+
+		apiErr := &APIError{Title: e.Title, Detail: e.Detail, Source: e.Source.Pointer, Code: e.Code, Status: res.Status, Err: err}
+		if secs, err := strconv.Atoi(res.Headers.Get("Retry-After")); err == nil && secs > 0 {
+			apiErr.RetryAfter = time.Duration(secs) * time.Second
+		}
+
+		return apiErr
+	}
+
+	return err
+}
+
+// IsRateLimited reports whether err is an API error for a rate limited
+// request, which should be retried once its RetryAfter passes.
+func IsRateLimited(err error) bool {
+	e, ok := err.(*APIError)
+
+	return ok && e.Status == http.StatusTooManyRequests
+}
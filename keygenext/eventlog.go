@@ -0,0 +1,86 @@
+package keygenext
+
+import (
+	"context"
+	"time"
+
+	"github.com/keygen-sh/jsonapi-go"
+)
+
+// EventLog represents a Keygen event log object.
+type EventLog struct {
+	ID            string                 `json:"-"`
+	Type          string                 `json:"-"`
+	Event         string                 `json:"event"`
+	Metadata      map[string]interface{} `json:"metadata"`
+	Created       time.Time              `json:"created"`
+	ResourceType  string                 `json:"-"`
+	ResourceID    string                 `json:"-"`
+	WhodunnitType string                 `json:"-"`
+	WhodunnitID   string                 `json:"-"`
+}
+
+func (e *EventLog) SetID(id string) error {
+	e.ID = id
+	return nil
+}
+
+func (e *EventLog) SetType(t string) error {
+	e.Type = t
+	return nil
+}
+
+func (e *EventLog) SetData(to func(target interface{}) error) error {
+	return to(e)
+}
+
+func (e *EventLog) SetRelationships(relationships map[string]interface{}) error {
+	if relationship, ok := relationships["resource"]; ok {
+		if r, ok := relationship.(*jsonapi.ResourceObjectIdentifier); ok && r != nil {
+			e.ResourceType = r.Type
+			e.ResourceID = r.ID
+		}
+	}
+
+	if relationship, ok := relationships["whodunnit"]; ok {
+		if r, ok := relationship.(*jsonapi.ResourceObjectIdentifier); ok && r != nil {
+			e.WhodunnitType = r.Type
+			e.WhodunnitID = r.ID
+		}
+	}
+
+	return nil
+}
+
+// EventLogs represents a collection of Keygen event log objects.
+type EventLogs []EventLog
+
+func (e *EventLogs) SetData(to func(target interface{}) error) error {
+	return to(e)
+}
+
+// EventLogFilter narrows down the event logs returned by ListEventLogs.
+type EventLogFilter struct {
+	Event        string `url:"event,omitempty"`
+	Start        string `url:"date[start],omitempty"`
+	End          string `url:"date[end],omitempty"`
+	ResourceType string `url:"resource[type],omitempty"`
+	ResourceID   string `url:"resource[id],omitempty"`
+	PageSize     int    `url:"page[size],omitempty"`
+	PageNumber   int    `url:"page[number],omitempty"`
+}
+
+// ListEventLogs retrieves a page of the event logs matching the given filter.
+// Event logs are only available to accounts with the event logs feature.
+func (c *Client) ListEventLogs(ctx context.Context, filter *EventLogFilter) (EventLogs, error) {
+	client := c.newClient(ctx)
+
+	logs := EventLogs{}
+
+	res, err := client.Get("event-logs", filter, &logs)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	return logs, nil
+}
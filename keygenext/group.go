@@ -0,0 +1,147 @@
+package keygenext
+
+import (
+	"context"
+	"time"
+
+	"github.com/keygen-sh/keygen-go"
+)
+
+// Group represents a Keygen group object.
+type Group struct {
+	ID          string                 `json:"-"`
+	Type        string                 `json:"-"`
+	Name        string                 `json:"name"`
+	MaxLicenses *int                   `json:"maxLicenses"`
+	MaxMachines *int                   `json:"maxMachines"`
+	MaxUsers    *int                   `json:"maxUsers"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	Created     time.Time              `json:"created"`
+	Updated     time.Time              `json:"updated"`
+}
+
+func (g *Group) SetID(id string) error {
+	g.ID = id
+	return nil
+}
+
+func (g *Group) SetType(t string) error {
+	g.Type = t
+	return nil
+}
+
+func (g *Group) SetData(to func(target interface{}) error) error {
+	return to(g)
+}
+
+// Groups represents a collection of Keygen group objects.
+type Groups []Group
+
+func (g *Groups) SetData(to func(target interface{}) error) error {
+	return to(g)
+}
+
+// groupAttributes are the writable attributes of a group.
+type groupAttributes struct {
+	Name        string                 `json:"name"`
+	MaxLicenses *int                   `json:"maxLicenses,omitempty"`
+	MaxMachines *int                   `json:"maxMachines,omitempty"`
+	MaxUsers    *int                   `json:"maxUsers,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+func (g groupAttributes) GetID() string {
+	return ""
+}
+
+func (g groupAttributes) GetType() string {
+	return "groups"
+}
+
+func (g groupAttributes) GetData() interface{} {
+	return g
+}
+
+// ListGroups retrieves the account's groups.
+func (c *Client) ListGroups(ctx context.Context, params *ListParams) (Groups, error) {
+	client := c.newClient(ctx)
+
+	groups := Groups{}
+
+	err := paginate(&params.Limit, &params.Paging, func() (*keygen.Response, int, error) {
+		page := Groups{}
+		res, err := client.Get("groups", params, &page)
+		groups = append(groups, page...)
+
+		return res, len(page), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// GetGroup retrieves a group by ID.
+func (c *Client) GetGroup(ctx context.Context, id string) (*Group, error) {
+	client := c.newClient(ctx)
+
+	group := &Group{}
+
+	res, err := client.Get("groups/"+id, nil, group)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	return group, nil
+}
+
+// CreateGroup creates a group.
+func (c *Client) CreateGroup(ctx context.Context, g *Group) error {
+	client := c.newClient(ctx)
+
+	params := groupAttributes{Name: g.Name, MaxLicenses: g.MaxLicenses, MaxMachines: g.MaxMachines, MaxUsers: g.MaxUsers, Metadata: g.Metadata}
+
+	res, err := client.Post("groups", params, g)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// DeleteGroup deletes a group.
+func (c *Client) DeleteGroup(ctx context.Context, g *Group) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Delete("groups/"+g.ID, nil, nil)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// AttachGroupLicense moves a license into a group.
+func (c *Client) AttachGroupLicense(ctx context.Context, g *Group, licenseID string) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Put("licenses/"+licenseID+"/group", identifier{ID: g.ID, Type: "groups"}, &Group{})
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// AttachGroupUser moves a user into a group.
+func (c *Client) AttachGroupUser(ctx context.Context, g *Group, userID string) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Put("users/"+userID+"/group", identifier{ID: g.ID, Type: "groups"}, &Group{})
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
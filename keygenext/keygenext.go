@@ -0,0 +1,41 @@
+// Package keygenext extends keygen-go with the API primitives used to publish
+// and manage releases, e.g. upserting a release and uploading its artifact.
+//
+// Requests are made like keygen-go's, so the API host is configured using
+// keygen.APIURL. Every request is made using the package's own transport,
+// which pools connections and is configured using ConfigureTransport, rather
+// than the default transport.
+package keygenext
+
+// Client makes API requests on behalf of an account, authenticated using a
+// product token.
+type Client struct {
+	// Account is the account's ID or slug.
+	Account string
+
+	// Token is a product token for the account.
+	Token string
+
+	// LicenseKey, when given, authenticates requests as a license instead of
+	// using Token, e.g. to make the same requests as a licensed app.
+	LicenseKey string
+
+	// PublicKey is the account's hex-encoded Ed25519 public key. When given,
+	// the signature of every API response is verified using it.
+	PublicKey string
+
+	// UserAgent is appended to the User-Agent header of every request.
+	UserAgent string
+
+	// APIVersion pins requests to an API version using the Keygen-Version
+	// header. When empty, the account's default version is used.
+	APIVersion string
+
+	// VersionReported, when given, is called with the API version each
+	// response was served using.
+	VersionReported func(version string)
+
+	// ArtifactHost, when given, replaces the API host of artifact URLs, e.g.
+	// with a custom domain fronting artifact downloads.
+	ArtifactHost string
+}
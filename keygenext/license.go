@@ -0,0 +1,261 @@
+package keygenext
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/keygen-sh/jsonapi-go"
+	"github.com/keygen-sh/keygen-go"
+)
+
+// License represents a Keygen license object.
+type License struct {
+	ID          string                 `json:"-"`
+	Type        string                 `json:"-"`
+	Name        string                 `json:"name"`
+	Key         string                 `json:"key"`
+	Status      string                 `json:"status"`
+	Expiry      *time.Time             `json:"expiry"`
+	Uses        int                    `json:"uses"`
+	MaxMachines *int                   `json:"maxMachines"`
+	MaxUses     *int                   `json:"maxUses"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	Created     time.Time              `json:"created"`
+	Updated     time.Time              `json:"updated"`
+	PolicyID    string                 `json:"-"`
+	UserID      string                 `json:"-"`
+	GroupID     string                 `json:"-"`
+}
+
+func (l *License) SetID(id string) error {
+	l.ID = id
+	return nil
+}
+
+func (l *License) SetType(t string) error {
+	l.Type = t
+	return nil
+}
+
+func (l *License) SetData(to func(target interface{}) error) error {
+	return to(l)
+}
+
+func (l *License) SetRelationships(relationships map[string]interface{}) error {
+	if relationship, ok := relationships["policy"]; ok {
+		if r, ok := relationship.(*jsonapi.ResourceObjectIdentifier); ok && r != nil {
+			l.PolicyID = r.ID
+		}
+	}
+
+	if relationship, ok := relationships["user"]; ok {
+		if r, ok := relationship.(*jsonapi.ResourceObjectIdentifier); ok && r != nil {
+			l.UserID = r.ID
+		}
+	}
+
+	if relationship, ok := relationships["group"]; ok {
+		if r, ok := relationship.(*jsonapi.ResourceObjectIdentifier); ok && r != nil {
+			l.GroupID = r.ID
+		}
+	}
+
+	return nil
+}
+
+// Licenses represents a collection of Keygen license objects.
+type Licenses []License
+
+func (l *Licenses) SetData(to func(target interface{}) error) error {
+	return to(l)
+}
+
+// licenseAttributes are the writable attributes of a license, along with the
+// policy, user and group it's created for.
+type licenseAttributes struct {
+	Name     string                 `json:"name,omitempty"`
+	Key      string                 `json:"key,omitempty"`
+	Expiry   *time.Time             `json:"expiry,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	PolicyID string                 `json:"-"`
+	UserID   string                 `json:"-"`
+	GroupID  string                 `json:"-"`
+}
+
+func (l licenseAttributes) GetID() string {
+	return ""
+}
+
+func (l licenseAttributes) GetType() string {
+	return "licenses"
+}
+
+func (l licenseAttributes) GetData() interface{} {
+	return l
+}
+
+func (l licenseAttributes) GetRelationships() map[string]interface{} {
+	relationships := make(map[string]interface{})
+
+	relationships["policy"] = jsonapi.ResourceObjectIdentifier{Type: "policies", ID: l.PolicyID}
+
+	if l.UserID != "" {
+		relationships["user"] = jsonapi.ResourceObjectIdentifier{Type: "users", ID: l.UserID}
+	}
+
+	if l.GroupID != "" {
+		relationships["group"] = jsonapi.ResourceObjectIdentifier{Type: "groups", ID: l.GroupID}
+	}
+
+	return relationships
+}
+
+// LicenseFilter narrows down the licenses returned by ListLicenses.
+type LicenseFilter struct {
+	Status   string         `url:"status,omitempty"`
+	Policy   string         `url:"policy,omitempty"`
+	User     string         `url:"user,omitempty"`
+	Group    string         `url:"group,omitempty"`
+	Metadata MetadataFilter `url:"metadata,omitempty"`
+	Limit    int            `url:"limit,omitempty"`
+	Paging
+}
+
+// MetadataFilter matches resources whose metadata has the given values, e.g.
+// metadata[customer]=acme.
+type MetadataFilter map[string]string
+
+func (m MetadataFilter) EncodeValues(key string, v *url.Values) error {
+	for k, value := range m {
+		v.Set(key+"["+k+"]", value)
+	}
+
+	return nil
+}
+
+// ListLicenses retrieves the licenses matching the given filter.
+func (c *Client) ListLicenses(ctx context.Context, filter *LicenseFilter) (Licenses, error) {
+	client := c.newClient(ctx)
+
+	licenses := Licenses{}
+
+	err := paginate(&filter.Limit, &filter.Paging, func() (*keygen.Response, int, error) {
+		page := Licenses{}
+		res, err := client.Get("licenses", filter, &page)
+		licenses = append(licenses, page...)
+
+		return res, len(page), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return licenses, nil
+}
+
+// GetLicense retrieves a license by its ID or key.
+func (c *Client) GetLicense(ctx context.Context, id string) (*License, error) {
+	client := c.newClient(ctx)
+
+	license := &License{}
+
+	res, err := client.Get("licenses/"+id, nil, license)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	return license, nil
+}
+
+// CreateLicense creates a license for its policy. The key is generated by the
+// server unless one is given, e.g. when importing existing licenses.
+func (c *Client) CreateLicense(ctx context.Context, l *License) error {
+	client := c.newClient(ctx)
+
+	params := licenseAttributes{
+		Name:     l.Name,
+		Key:      l.Key,
+		Expiry:   l.Expiry,
+		Metadata: l.Metadata,
+		PolicyID: l.PolicyID,
+		UserID:   l.UserID,
+		GroupID:  l.GroupID,
+	}
+
+	res, err := client.Post("licenses", params, l)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// licenseExpiry is used to update only a license's expiry.
+type licenseExpiry struct {
+	ID     string     `json:"-"`
+	Expiry *time.Time `json:"expiry"`
+}
+
+func (l licenseExpiry) GetID() string {
+	return l.ID
+}
+
+func (l licenseExpiry) GetType() string {
+	return "licenses"
+}
+
+func (l licenseExpiry) GetData() interface{} {
+	return l
+}
+
+// SuspendLicense suspends a license, e.g. for non-payment, so that it fails
+// validation until it's reinstated.
+func (c *Client) SuspendLicense(ctx context.Context, l *License) error {
+	return c.licenseAction(ctx, l, "suspend")
+}
+
+// ReinstateLicense reinstates a suspended license.
+func (c *Client) ReinstateLicense(ctx context.Context, l *License) error {
+	return c.licenseAction(ctx, l, "reinstate")
+}
+
+// RenewLicense extends a license's expiry by its policy's duration.
+func (c *Client) RenewLicense(ctx context.Context, l *License) error {
+	return c.licenseAction(ctx, l, "renew")
+}
+
+// RevokeLicense permanently revokes, i.e. deletes, a license.
+func (c *Client) RevokeLicense(ctx context.Context, l *License) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Delete("licenses/"+l.ID+"/actions/revoke", nil, nil)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// UpdateLicenseExpiry changes a license's expiry, where nil never expires.
+func (c *Client) UpdateLicenseExpiry(ctx context.Context, l *License, expiry *time.Time) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Patch("licenses/"+l.ID, licenseExpiry{ID: l.ID, Expiry: expiry}, l)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+func (c *Client) licenseAction(ctx context.Context, l *License, action string) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Post("licenses/"+l.ID+"/actions/"+action, nil, l)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package keygenext
+
+import (
+	"context"
+	"time"
+
+	"github.com/keygen-sh/jsonapi-go"
+	"github.com/keygen-sh/keygen-go"
+)
+
+// Machine represents a Keygen machine object, i.e. a license activation.
+type Machine struct {
+	ID                string                 `json:"-"`
+	Type              string                 `json:"-"`
+	Fingerprint       string                 `json:"fingerprint"`
+	Name              string                 `json:"name"`
+	Hostname          string                 `json:"hostname"`
+	Platform          string                 `json:"platform"`
+	IP                string                 `json:"ip"`
+	RequireHeartbeat  bool                   `json:"requireHeartbeat"`
+	HeartbeatStatus   string                 `json:"heartbeatStatus"`
+	HeartbeatDuration *int                   `json:"heartbeatDuration"`
+	LastHeartbeat     *time.Time             `json:"lastHeartbeat"`
+	NextHeartbeat     *time.Time             `json:"nextHeartbeat"`
+	Metadata          map[string]interface{} `json:"metadata"`
+	Created           time.Time              `json:"created"`
+	Updated           time.Time              `json:"updated"`
+	LicenseID         string                 `json:"-"`
+}
+
+func (m *Machine) SetID(id string) error {
+	m.ID = id
+	return nil
+}
+
+func (m *Machine) SetType(t string) error {
+	m.Type = t
+	return nil
+}
+
+func (m *Machine) SetData(to func(target interface{}) error) error {
+	return to(m)
+}
+
+func (m *Machine) SetRelationships(relationships map[string]interface{}) error {
+	if relationship, ok := relationships["license"]; ok {
+		if r, ok := relationship.(*jsonapi.ResourceObjectIdentifier); ok && r != nil {
+			m.LicenseID = r.ID
+		}
+	}
+
+	return nil
+}
+
+// Machines represents a collection of Keygen machine objects.
+type Machines []Machine
+
+func (m *Machines) SetData(to func(target interface{}) error) error {
+	return to(m)
+}
+
+// MachineFilter narrows down the machines returned by ListMachines.
+type MachineFilter struct {
+	License string `url:"license,omitempty"`
+	Policy  string `url:"policy,omitempty"`
+	Limit   int    `url:"limit,omitempty"`
+	Paging
+}
+
+// ListMachines retrieves the machines matching the given filter.
+func (c *Client) ListMachines(ctx context.Context, filter *MachineFilter) (Machines, error) {
+	client := c.newClient(ctx)
+
+	machines := Machines{}
+
+	err := paginate(&filter.Limit, &filter.Paging, func() (*keygen.Response, int, error) {
+		page := Machines{}
+		res, err := client.Get("machines", filter, &page)
+		machines = append(machines, page...)
+
+		return res, len(page), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return machines, nil
+}
+
+// DeleteMachine deletes a machine, freeing up its license's seat.
+func (c *Client) DeleteMachine(ctx context.Context, m *Machine) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Delete("machines/"+m.ID, nil, nil)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,293 @@
+package keygenext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// maxPartAttempts is how many times a part of a multipart upload is attempted.
+const maxPartAttempts = 3
+
+// MultipartUpload is an artifact upload split into parts, each with its own
+// presigned URL, e.g. an S3 multipart upload. This isn't part of Keygen's API,
+// and keygen.sh doesn't support it, but self-hosted instances extended to
+// support it offer one in the artifact's meta when asked to.
+type MultipartUpload struct {
+	Parts       []UploadPart `json:"parts"`
+	CompleteURL string       `json:"completeUrl"`
+	AbortURL    string       `json:"abortUrl"`
+}
+
+// UploadPart is a byte range of the file, uploaded using a ranged PUT.
+type UploadPart struct {
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+}
+
+// UploadResult describes how an artifact was uploaded, or how much of it has
+// been uploaded so far when it's reported during the upload.
+type UploadResult struct {
+	Parts    int
+	Uploaded int
+	Retries  int
+}
+
+// UploadReleaseConcurrently uploads the file to the release's artifact using
+// parallel ranged PUTs when the server offers a multipart upload, falling back
+// to a single PUT of the whole file when it doesn't. Every reader uploaded is
+// passed through wrap, e.g. to report progress, and report, when given, is
+// called whenever a part is uploaded or retried. It's unsupported by
+// keygen.sh, so it should only be used with self-hosted instances.
+func (c *Client) UploadReleaseConcurrently(ctx context.Context, r *Release, file io.ReaderAt, concurrency int, wrap func(io.Reader) io.Reader, report func(UploadResult)) (*UploadResult, error) {
+	client := c.newClient(ctx)
+
+	artifact := &Artifact{}
+
+	// Servers which don't support multipart uploads ignore the param, and
+	// redirect to a location for a single upload as usual
+	res, err := client.Put("releases/"+r.ID+"/artifact?upload=multipart", nil, artifact)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	artifact.ContentLength = r.Filesize
+	artifact.ContentType = r.ContentType
+	artifact.Location = res.Headers.Get("Location")
+	r.ArtifactID = artifact.ID
+
+	var doc struct {
+		Meta struct {
+			Upload *MultipartUpload `json:"upload"`
+		} `json:"meta"`
+	}
+
+	if err := json.Unmarshal(res.Body, &doc); err != nil || doc.Meta.Upload == nil || len(doc.Meta.Upload.Parts) == 0 {
+		if err := artifact.Upload(ctx, wrap(io.NewSectionReader(file, 0, r.Filesize))); err != nil {
+			return nil, err
+		}
+
+		return &UploadResult{Parts: 1, Uploaded: 1}, nil
+	}
+
+	upload := doc.Meta.Upload
+
+	retries, err := upload.upload(ctx, artifact, file, concurrency, wrap, report)
+	if err != nil {
+		upload.abort()
+
+		return nil, err
+	}
+
+	return &UploadResult{Parts: len(upload.Parts), Uploaded: len(upload.Parts), Retries: retries}, nil
+}
+
+func (u *MultipartUpload) upload(ctx context.Context, artifact *Artifact, file io.ReaderAt, concurrency int, wrap func(io.Reader) io.Reader, report func(UploadResult)) (int, error) {
+	if u.CompleteURL == "" {
+		return 0, errors.New("multipart upload has no completion location")
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parts := make(chan UploadPart)
+	etags := map[int]string{}
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	var retries, uploaded int64
+	var failed error
+	var once sync.Once
+
+	// Reports are made one at a time, so that they're never out of order
+	reportMu := sync.Mutex{}
+	progress := func() {
+		if report == nil {
+			return
+		}
+
+		reportMu.Lock()
+		defer reportMu.Unlock()
+
+		report(UploadResult{Parts: len(u.Parts), Uploaded: int(atomic.LoadInt64(&uploaded)), Retries: int(atomic.LoadInt64(&retries))})
+	}
+
+	retried := func() {
+		atomic.AddInt64(&retries, 1)
+		progress()
+	}
+
+	progress()
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for part := range parts {
+				etag, err := uploadPart(ctx, artifact.ContentType, part, file, wrap, retried)
+				if err != nil {
+					once.Do(func() {
+						failed = fmt.Errorf("part %d could not be uploaded (%s)", part.Number, err)
+						cancel()
+					})
+
+					continue
+				}
+
+				mu.Lock()
+				etags[part.Number] = etag
+				mu.Unlock()
+
+				atomic.AddInt64(&uploaded, 1)
+				progress()
+			}
+		}()
+	}
+
+	for _, part := range u.Parts {
+		select {
+		case parts <- part:
+		case <-ctx.Done():
+		}
+	}
+
+	close(parts)
+	wg.Wait()
+
+	if failed != nil {
+		return int(retries), failed
+	}
+
+	if err := ctx.Err(); err != nil {
+		return int(retries), err
+	}
+
+	return int(retries), u.complete(ctx, etags)
+}
+
+// uploadPart uploads a part, retrying when the storage provider fails, and
+// returns its ETag. The retried func is called before each retry.
+func uploadPart(ctx context.Context, contentType string, part UploadPart, file io.ReaderAt, wrap func(io.Reader) io.Reader, retried func()) (string, error) {
+	var err error
+
+	for attempt := 1; attempt <= maxPartAttempts; attempt++ {
+		if attempt > 1 {
+			retried()
+		}
+
+		var req *http.Request
+
+		req, err = http.NewRequestWithContext(ctx, http.MethodPut, part.URL, wrap(io.NewSectionReader(file, part.Offset, part.Size)))
+		if err != nil {
+			return "", err
+		}
+
+		req.ContentLength = part.Size
+
+		var res *http.Response
+
+		res, err = httpClient.Do(req)
+		if err == nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+
+			if res.StatusCode == http.StatusOK {
+				return res.Header.Get("ETag"), nil
+			}
+
+			err = fmt.Errorf("storage provider responded with status %d", res.StatusCode)
+		}
+
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+	}
+
+	return "", err
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// complete assembles the uploaded parts into the artifact's file.
+func (u *MultipartUpload) complete(ctx context.Context, etags map[int]string) error {
+	body := completeMultipartUpload{}
+	for number, etag := range etags {
+		body.Parts = append(body.Parts, completedPart{PartNumber: number, ETag: etag})
+	}
+
+	sort.Slice(body.Parts, func(i, j int) bool {
+		return body.Parts[i].PartNumber < body.Parts[j].PartNumber
+	})
+
+	b, err := xml.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.CompleteURL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/xml")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	out, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	// S3 may report a failure to complete the upload with a 200 status
+	if res.StatusCode != http.StatusOK || bytes.Contains(out, []byte("<Error>")) {
+		return errors.New("failed to complete upload to storage provider")
+	}
+
+	return nil
+}
+
+// abort discards the uploaded parts, so that the storage provider doesn't keep
+// them around. Failing to abort is ignored.
+func (u *MultipartUpload) abort() {
+	if u.AbortURL == "" {
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, u.AbortURL, nil)
+	if err != nil {
+		return
+	}
+
+	if res, err := httpClient.Do(req); err == nil {
+		res.Body.Close()
+	}
+}
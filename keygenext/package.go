@@ -0,0 +1,61 @@
+package keygenext
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/keygen-sh/jsonapi-go"
+)
+
+// Package represents a Keygen package object, which groups a product's
+// releases for a distribution engine, e.g. pypi or tauri.
+type Package struct {
+	ID        string                 `json:"-"`
+	Type      string                 `json:"-"`
+	Name      string                 `json:"name"`
+	Key       string                 `json:"key"`
+	Engine    *string                `json:"engine"`
+	Metadata  map[string]interface{} `json:"metadata"`
+	Created   time.Time              `json:"created"`
+	Updated   time.Time              `json:"updated"`
+	ProductID string                 `json:"-"`
+}
+
+func (p *Package) SetID(id string) error {
+	p.ID = id
+	return nil
+}
+
+func (p *Package) SetType(t string) error {
+	p.Type = t
+	return nil
+}
+
+func (p *Package) SetData(to func(target interface{}) error) error {
+	return to(p)
+}
+
+func (p *Package) SetRelationships(relationships map[string]interface{}) error {
+	if relationship, ok := relationships["product"]; ok {
+		if r, ok := relationship.(*jsonapi.ResourceObjectIdentifier); ok && r != nil {
+			p.ProductID = r.ID
+		}
+	}
+
+	return nil
+}
+
+// GetPackage retrieves a package by its ID or key.
+func (c *Client) GetPackage(ctx context.Context, id string) (*Package, error) {
+	client := c.newClient(ctx)
+
+	pkg := &Package{}
+
+	res, err := client.Get("packages/"+url.PathEscape(id), nil, pkg)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	return pkg, nil
+}
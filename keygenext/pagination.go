@@ -0,0 +1,83 @@
+package keygenext
+
+import (
+	"encoding/json"
+
+	"github.com/keygen-sh/keygen-go"
+)
+
+// maxPageSize is the largest page size supported by the API.
+const maxPageSize = 100
+
+// ListParams are common parameters for listing resources.
+type ListParams struct {
+	Limit int `url:"limit,omitempty"`
+	Paging
+}
+
+// Paging selects a page of resources to list. Without a page, the first Limit
+// resources are listed. With a page, Limit is used as the page size. When All
+// is set, every page from the given page onwards is listed.
+type Paging struct {
+	Page int  `url:"page[number],omitempty"`
+	Size int  `url:"page[size],omitempty"`
+	All  bool `url:"-"`
+}
+
+// paginate calls fetch for each page selected by paging, where fetch appends
+// the page's resources and returns the response along with the number of
+// resources on the page. Pages are followed until the API no longer links to
+// a next page.
+func paginate(limit *int, paging *Paging, fetch func() (*keygen.Response, int, error)) error {
+	if paging.Page == 0 && !paging.All {
+		res, _, err := fetch()
+		if err != nil {
+			return newAPIError(res, err)
+		}
+
+		return nil
+	}
+
+	// Page params can't be combined with a limit, so use it as the size
+	size := *limit
+	if paging.All || size <= 0 || size > maxPageSize {
+		size = maxPageSize
+	}
+
+	defer func(l int, p Paging) { *limit, *paging = l, p }(*limit, *paging)
+
+	*limit = 0
+	paging.Size = size
+
+	if paging.Page == 0 {
+		paging.Page = 1
+	}
+
+	for {
+		res, n, err := fetch()
+		if err != nil {
+			return newAPIError(res, err)
+		}
+
+		if !paging.All || n < size || !hasNextPage(res) {
+			return nil
+		}
+
+		paging.Page++
+	}
+}
+
+// hasNextPage reports whether the response links to a next page.
+func hasNextPage(res *keygen.Response) bool {
+	var doc struct {
+		Links struct {
+			Next *string `json:"next"`
+		} `json:"links"`
+	}
+
+	if err := json.Unmarshal(res.Body, &doc); err != nil {
+		return false
+	}
+
+	return doc.Links.Next != nil && *doc.Links.Next != ""
+}
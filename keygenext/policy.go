@@ -0,0 +1,234 @@
+package keygenext
+
+import (
+	"context"
+	"time"
+
+	"github.com/keygen-sh/jsonapi-go"
+	"github.com/keygen-sh/keygen-go"
+)
+
+// Policy represents a Keygen policy object.
+type Policy struct {
+	ID                string                 `json:"-"`
+	Type              string                 `json:"-"`
+	Name              string                 `json:"name"`
+	Duration          *int                   `json:"duration"`
+	Strict            bool                   `json:"strict"`
+	Floating          bool                   `json:"floating"`
+	Protected         bool                   `json:"protected"`
+	MaxMachines       *int                   `json:"maxMachines"`
+	MaxUses           *int                   `json:"maxUses"`
+	RequireHeartbeat  bool                   `json:"requireHeartbeat"`
+	HeartbeatDuration *int                   `json:"heartbeatDuration"`
+	Metadata          map[string]interface{} `json:"metadata"`
+	Created           time.Time              `json:"created"`
+	Updated           time.Time              `json:"updated"`
+	ProductID         string                 `json:"-"`
+}
+
+func (p *Policy) SetID(id string) error {
+	p.ID = id
+	return nil
+}
+
+func (p *Policy) SetType(t string) error {
+	p.Type = t
+	return nil
+}
+
+func (p *Policy) SetData(to func(target interface{}) error) error {
+	return to(p)
+}
+
+func (p *Policy) SetRelationships(relationships map[string]interface{}) error {
+	if relationship, ok := relationships["product"]; ok {
+		if r, ok := relationship.(*jsonapi.ResourceObjectIdentifier); ok && r != nil {
+			p.ProductID = r.ID
+		}
+	}
+
+	return nil
+}
+
+// Policies represents a collection of Keygen policy objects.
+type Policies []Policy
+
+func (p *Policies) SetData(to func(target interface{}) error) error {
+	return to(p)
+}
+
+// policyAttributes are the writable attributes of a policy. Limits are always
+// sent, since null means unlimited, and so is the metadata, so that removing
+// it is too.
+type policyAttributes struct {
+	ID                string                 `json:"-"`
+	Name              string                 `json:"name"`
+	Duration          *int                   `json:"duration"`
+	Strict            bool                   `json:"strict"`
+	Floating          bool                   `json:"floating"`
+	Protected         bool                   `json:"protected"`
+	MaxMachines       *int                   `json:"maxMachines"`
+	MaxUses           *int                   `json:"maxUses"`
+	RequireHeartbeat  bool                   `json:"requireHeartbeat"`
+	HeartbeatDuration *int                   `json:"heartbeatDuration"`
+	Metadata          map[string]interface{} `json:"metadata"`
+	ProductID         string                 `json:"-"`
+}
+
+func newPolicyAttributes(p *Policy) policyAttributes {
+	params := policyAttributes{
+		ID:                p.ID,
+		Name:              p.Name,
+		Duration:          p.Duration,
+		Strict:            p.Strict,
+		Floating:          p.Floating,
+		Protected:         p.Protected,
+		MaxMachines:       p.MaxMachines,
+		MaxUses:           p.MaxUses,
+		RequireHeartbeat:  p.RequireHeartbeat,
+		HeartbeatDuration: p.HeartbeatDuration,
+		Metadata:          p.Metadata,
+		ProductID:         p.ProductID,
+	}
+
+	if params.Metadata == nil {
+		params.Metadata = map[string]interface{}{}
+	}
+
+	return params
+}
+
+func (p policyAttributes) GetID() string {
+	return p.ID
+}
+
+func (p policyAttributes) GetType() string {
+	return "policies"
+}
+
+func (p policyAttributes) GetData() interface{} {
+	return p
+}
+
+func (p policyAttributes) GetRelationships() map[string]interface{} {
+	relationships := make(map[string]interface{})
+
+	// The product can't be changed once a policy is created
+	if p.ID == "" {
+		relationships["product"] = jsonapi.ResourceObjectIdentifier{Type: "products", ID: p.ProductID}
+	}
+
+	return relationships
+}
+
+// PolicyFilter narrows down the policies returned by ListPolicies.
+type PolicyFilter struct {
+	Product string `url:"product,omitempty"`
+	Limit   int    `url:"limit,omitempty"`
+	Paging
+}
+
+// ListPolicies retrieves the policies matching the given filter.
+func (c *Client) ListPolicies(ctx context.Context, filter *PolicyFilter) (Policies, error) {
+	client := c.newClient(ctx)
+
+	policies := Policies{}
+
+	err := paginate(&filter.Limit, &filter.Paging, func() (*keygen.Response, int, error) {
+		page := Policies{}
+		res, err := client.Get("policies", filter, &page)
+		policies = append(policies, page...)
+
+		return res, len(page), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// GetPolicy retrieves a policy by its ID.
+func (c *Client) GetPolicy(ctx context.Context, id string) (*Policy, error) {
+	client := c.newClient(ctx)
+
+	policy := &Policy{}
+
+	res, err := client.Get("policies/"+id, nil, policy)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	return policy, nil
+}
+
+// CreatePolicy creates a policy for its product.
+func (c *Client) CreatePolicy(ctx context.Context, p *Policy) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Post("policies", newPolicyAttributes(p), p)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// UpdatePolicy replaces a policy's writable attributes.
+func (c *Client) UpdatePolicy(ctx context.Context, p *Policy) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Patch("policies/"+p.ID, newPolicyAttributes(p), p)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// ListPolicyEntitlements retrieves the entitlements attached to a policy.
+func (c *Client) ListPolicyEntitlements(ctx context.Context, p *Policy) (Entitlements, error) {
+	client := c.newClient(ctx)
+
+	entitlements := Entitlements{}
+	params := &ListParams{Paging: Paging{All: true}}
+
+	err := paginate(&params.Limit, &params.Paging, func() (*keygen.Response, int, error) {
+		page := Entitlements{}
+		res, err := client.Get("policies/"+p.ID+"/entitlements", params, &page)
+		entitlements = append(entitlements, page...)
+
+		return res, len(page), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entitlements, nil
+}
+
+// AttachPolicyEntitlements attaches entitlements to a policy, so that its
+// licenses are entitled to them.
+func (c *Client) AttachPolicyEntitlements(ctx context.Context, p *Policy, entitlementIDs []string) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Post("policies/"+p.ID+"/entitlements", identifiers{}.From("entitlements", entitlementIDs), &Entitlements{})
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// DetachPolicyEntitlements detaches entitlements from a policy.
+func (c *Client) DetachPolicyEntitlements(ctx context.Context, p *Policy, entitlementIDs []string) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Delete("policies/"+p.ID+"/entitlements", identifiers{}.From("entitlements", entitlementIDs), nil)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
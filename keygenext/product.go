@@ -0,0 +1,185 @@
+package keygenext
+
+import (
+	"context"
+	"time"
+
+	"github.com/keygen-sh/keygen-go"
+)
+
+// ProductObject represents a Keygen product object.
+type ProductObject struct {
+	ID                   string                 `json:"-"`
+	Type                 string                 `json:"-"`
+	Name                 string                 `json:"name"`
+	URL                  string                 `json:"url"`
+	DistributionStrategy string                 `json:"distributionStrategy"`
+	Platforms            []string               `json:"platforms"`
+	Metadata             map[string]interface{} `json:"metadata"`
+	Created              time.Time              `json:"created"`
+	Updated              time.Time              `json:"updated"`
+}
+
+func (p *ProductObject) SetID(id string) error {
+	p.ID = id
+	return nil
+}
+
+func (p *ProductObject) SetType(t string) error {
+	p.Type = t
+	return nil
+}
+
+func (p *ProductObject) SetData(to func(target interface{}) error) error {
+	return to(p)
+}
+
+// Products represents a collection of Keygen product objects.
+type Products []ProductObject
+
+func (p *Products) SetData(to func(target interface{}) error) error {
+	return to(p)
+}
+
+// ListProducts retrieves the account's products.
+func (c *Client) ListProducts(ctx context.Context, params *ListParams) (Products, error) {
+	client := c.newClient(ctx)
+
+	products := Products{}
+
+	err := paginate(&params.Limit, &params.Paging, func() (*keygen.Response, int, error) {
+		page := Products{}
+		res, err := client.Get("products", params, &page)
+		products = append(products, page...)
+
+		return res, len(page), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// GetProduct retrieves a product by ID.
+func (c *Client) GetProduct(ctx context.Context, id string) (*ProductObject, error) {
+	client := c.newClient(ctx)
+
+	product := &ProductObject{}
+
+	res, err := client.Get("products/"+id, nil, product)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	return product, nil
+}
+
+// productMetadata is used to update only a product's metadata.
+type productMetadata struct {
+	ID       string                 `json:"-"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+func (p productMetadata) GetID() string {
+	return p.ID
+}
+
+func (p productMetadata) GetType() string {
+	return "products"
+}
+
+func (p productMetadata) GetData() interface{} {
+	return p
+}
+
+// UpdateProductMetadata replaces a product's metadata.
+func (c *Client) UpdateProductMetadata(ctx context.Context, p *ProductObject, metadata map[string]interface{}) error {
+	client := c.newClient(ctx)
+
+	params := productMetadata{ID: p.ID, Metadata: metadata}
+
+	res, err := client.Patch("products/"+p.ID, params, p)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// productAttributes are the writable attributes of a product. The URL,
+// platforms and metadata are always sent, so that removing them is too, where
+// an empty URL is sent as null.
+type productAttributes struct {
+	ID                   string                 `json:"-"`
+	Name                 string                 `json:"name"`
+	URL                  *string                `json:"url"`
+	DistributionStrategy string                 `json:"distributionStrategy,omitempty"`
+	Platforms            []string               `json:"platforms"`
+	Metadata             map[string]interface{} `json:"metadata"`
+}
+
+func newProductAttributes(p *ProductObject) productAttributes {
+	params := productAttributes{
+		ID:                   p.ID,
+		Name:                 p.Name,
+		DistributionStrategy: p.DistributionStrategy,
+		Platforms:            p.Platforms,
+		Metadata:             p.Metadata,
+	}
+
+	if p.URL != "" {
+		params.URL = &p.URL
+	}
+
+	if params.Platforms == nil {
+		params.Platforms = []string{}
+	}
+
+	if params.Metadata == nil {
+		params.Metadata = map[string]interface{}{}
+	}
+
+	return params
+}
+
+func (p productAttributes) GetID() string {
+	return p.ID
+}
+
+func (p productAttributes) GetType() string {
+	return "products"
+}
+
+func (p productAttributes) GetData() interface{} {
+	return p
+}
+
+// CreateProduct creates a product.
+func (c *Client) CreateProduct(ctx context.Context, p *ProductObject) error {
+	client := c.newClient(ctx)
+
+	params := newProductAttributes(p)
+
+	res, err := client.Post("products", params, p)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// UpdateProduct replaces a product's name, URL, distribution strategy,
+// platforms and metadata.
+func (c *Client) UpdateProduct(ctx context.Context, p *ProductObject) error {
+	client := c.newClient(ctx)
+
+	params := newProductAttributes(p)
+
+	res, err := client.Patch("products/"+p.ID, params, p)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package keygenext
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keygen-sh/jsonapi-go"
+	"github.com/keygen-sh/keygen-go"
+)
+
+// maxRawAttempts is how many times Do attempts a retryable request.
+const maxRawAttempts = 3
+
+// RawResponse is the response to a request made by Do.
+type RawResponse struct {
+	Status  int
+	Headers http.Header
+	Body    []byte
+}
+
+// Do makes a request to an arbitrary endpoint, e.g. one which the package has
+// no method for, authenticated and versioned like the Client's other requests.
+// The path is relative to the account, e.g. "releases?limit=5", and the body
+// is sent as-is. When the Client has a public key, the response's signature
+// is verified. Idempotent requests are retried when they're rate limited or
+// the server is unavailable. Error statuses are returned as a response rather
+// than an error.
+func (c *Client) Do(ctx context.Context, method string, path string, header http.Header, body []byte) (*RawResponse, error) {
+	url := keygen.APIURL + "/" + keygen.APIVersion + "/accounts/" + c.Account + "/" + strings.TrimPrefix(path, "/")
+	retryable := method == http.MethodGet || method == http.MethodHead || method == http.MethodPut || method == http.MethodDelete
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		if c.Token != "" && req.Header.Get("Authorization") == "" {
+			req.Header.Set("Authorization", "Bearer "+c.Token)
+		}
+
+		if req.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", jsonapi.ContentType)
+		}
+
+		if req.Header.Get("Accept") == "" {
+			req.Header.Set("Accept", jsonapi.ContentType)
+		}
+
+		c.prepareRequest(req)
+
+		res, err := httpClient.Do(req)
+		if err == nil {
+			var out []byte
+
+			out, err = ioutil.ReadAll(res.Body)
+			res.Body.Close()
+
+			c.reportVersion(res)
+
+			if err == nil && (!retryable || attempt == maxRawAttempts || !isRetryableStatus(res.StatusCode)) {
+				if c.PublicKey != "" {
+					if err := verifyRawResponse(c.PublicKey, method, req, res, out); err != nil {
+						return nil, err
+					}
+				}
+
+				return &RawResponse{Status: res.StatusCode, Headers: res.Header, Body: out}, nil
+			}
+		}
+
+		if ctx.Err() != nil || !retryable || attempt == maxRawAttempts {
+			if err == nil {
+				err = ctx.Err()
+			}
+
+			return nil, err
+		}
+
+		wait := time.Duration(attempt) * time.Second
+		if res != nil {
+			if s, e := strconv.Atoi(res.Header.Get("Retry-After")); e == nil && s > 0 {
+				wait = time.Duration(s) * time.Second
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// isRetryableStatus reports whether a request which failed with the status
+// may succeed when retried.
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// verifyRawResponse verifies a response's Keygen-Signature header using the
+// account's public key, the same way keygen-go verifies its responses.
+func verifyRawResponse(publicKey string, method string, req *http.Request, res *http.Response, body []byte) error {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return keygen.ErrPublicKeyInvalid
+	}
+
+	sum := sha256.Sum256(body)
+	digest := "sha-256=" + base64.StdEncoding.EncodeToString(sum[:])
+
+	switch d := res.Header.Get("Digest"); {
+	case d == "":
+		return keygen.ErrResponseDigestMissing
+	case d != digest:
+		return keygen.ErrResponseDigestInvalid
+	}
+
+	date := res.Header.Get("Date")
+
+	t, err := time.Parse(time.RFC1123, date)
+	if err != nil {
+		return keygen.ErrResponseDateInvalid
+	}
+
+	if time.Since(t) > 5*time.Minute {
+		return keygen.ErrResponseDateTooOld
+	}
+
+	var sig string
+	for _, param := range strings.Split(res.Header.Get("Keygen-Signature"), ",") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) == 2 && kv[0] == "signature" {
+			sig = strings.Trim(kv[1], `"`)
+		}
+	}
+
+	if sig == "" {
+		return keygen.ErrResponseSignatureMissing
+	}
+
+	sigBytes, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return keygen.ErrResponseSignatureInvalid
+	}
+
+	target := req.URL.Path
+	if q := req.URL.RawQuery; q != "" {
+		target += "?" + q
+	}
+
+	msg := fmt.Sprintf("(request-target): %s %s\nhost: %s\ndate: %s\ndigest: %s", strings.ToLower(method), target, req.URL.Host, date, digest)
+
+	if !ed25519.Verify(key, []byte(msg), sigBytes) {
+		return keygen.ErrResponseSignatureInvalid
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package keygenext
+
+// identifier is a resource identifier used to change a relationship, e.g. to
+// change a license's group via PUT /licenses/:id/group.
+type identifier struct {
+	ID   string `json:"-"`
+	Type string `json:"-"`
+}
+
+func (i identifier) GetID() string {
+	return i.ID
+}
+
+func (i identifier) GetType() string {
+	return i.Type
+}
+
+func (i identifier) GetData() interface{} {
+	return i
+}
+
+// identifiers are resource identifiers used to change a to-many relationship,
+// e.g. to attach entitlements via POST /policies/:id/entitlements.
+type identifiers []identifier
+
+func (i identifiers) GetData() interface{} {
+	return i
+}
+
+func (i identifiers) From(t string, ids []string) identifiers {
+	for _, id := range ids {
+		i = append(i, identifier{ID: id, Type: t})
+	}
+
+	return i
+}
@@ -0,0 +1,293 @@
+package keygenext
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/keygen-sh/jsonapi-go"
+	"github.com/keygen-sh/keygen-go"
+)
+
+type Release struct {
+	ID          string                 `json:"-"`
+	Type        string                 `json:"-"`
+	Name        *string                `json:"name"`
+	Description *string                `json:"description"`
+	Version     string                 `json:"version"`
+	Filename    string                 `json:"filename"`
+	Filetype    string                 `json:"filetype"`
+	Filesize    int64                  `json:"filesize"`
+	Platform    string                 `json:"platform,omitempty"`
+	Channel     string                 `json:"channel"`
+	Status      string                 `json:"status,omitempty"`
+	Signature   string                 `json:"signature"`
+	Checksum    string                 `json:"checksum"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	Downloads   int64                  `json:"downloadCount,omitempty"`
+	Upgrades    int64                  `json:"upgradeCount,omitempty"`
+	Yanked      *time.Time             `json:"yanked,omitempty"`
+	Created     *time.Time             `json:"created,omitempty"`
+	ArtifactID  string                 `json:"-"`
+	ContentType string                 `json:"-"`
+	ProductID   string                 `json:"-"`
+	PackageID   string                 `json:"-"`
+	Constraints Constraints            `json:"-"`
+}
+
+func (r *Release) SetID(id string) error {
+	r.ID = id
+	return nil
+}
+
+func (r *Release) SetType(t string) error {
+	r.Type = t
+	return nil
+}
+
+func (r *Release) SetData(to func(target interface{}) error) error {
+	return to(r)
+}
+
+func (r Release) GetID() string {
+	return r.ID
+}
+
+func (r Release) GetType() string {
+	return "releases"
+}
+
+func (r Release) GetData() interface{} {
+	return r
+}
+
+func (r Release) GetRelationships() map[string]interface{} {
+	relationships := make(map[string]interface{})
+
+	relationships["constraints"] = r.Constraints
+	relationships["product"] = jsonapi.ResourceObjectIdentifier{
+		Type: "products",
+		ID:   r.ProductID,
+	}
+
+	if r.PackageID != "" {
+		relationships["package"] = jsonapi.ResourceObjectIdentifier{
+			Type: "packages",
+			ID:   r.PackageID,
+		}
+	}
+
+	return relationships
+}
+
+func (c *Client) UpsertRelease(ctx context.Context, r *Release) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Put("releases", r, r)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+func (c *Client) UploadRelease(ctx context.Context, r *Release, reader io.Reader) error {
+	client := c.newClient(ctx)
+
+	artifact := &Artifact{}
+
+	res, err := client.Put("releases/"+r.ID+"/artifact", nil, artifact)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	artifact.ContentLength = r.Filesize
+	artifact.ContentType = r.ContentType
+	artifact.Location = res.Headers.Get("Location")
+	r.ArtifactID = artifact.ID
+
+	err = artifact.Upload(ctx, reader)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetRelease retrieves a release by its ID.
+func (c *Client) GetRelease(ctx context.Context, id string) (*Release, error) {
+	client := c.newClient(ctx)
+
+	release := &Release{}
+
+	res, err := client.Get("releases/"+id, nil, release)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	return release, nil
+}
+
+// GetReleaseArtifact retrieves a release's artifact, including a temporary
+// download location for the uploaded file.
+func (c *Client) GetReleaseArtifact(ctx context.Context, r *Release) (*Artifact, error) {
+	client := c.newClient(ctx)
+
+	artifact := &Artifact{}
+
+	res, err := client.Get("releases/"+r.ID+"/artifact", nil, artifact)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	artifact.ContentLength = r.Filesize
+	artifact.Location = res.Headers.Get("Location")
+
+	return artifact, nil
+}
+
+// Releases represents a collection of Keygen release objects.
+type Releases []Release
+
+func (r *Releases) SetData(to func(target interface{}) error) error {
+	return to(r)
+}
+
+// ReleaseFilter narrows down the releases returned by ListReleases.
+type ReleaseFilter struct {
+	Product  string `url:"product,omitempty"`
+	Version  string `url:"version,omitempty"`
+	Platform string `url:"platform,omitempty"`
+	Channel  string `url:"channel,omitempty"`
+	Filetype string `url:"filetype,omitempty"`
+	Status   string `url:"status,omitempty"`
+	Limit    int    `url:"limit,omitempty"`
+	Paging
+}
+
+// ListReleases retrieves the releases matching the given filter.
+func (c *Client) ListReleases(ctx context.Context, filter *ReleaseFilter) (Releases, error) {
+	client := c.newClient(ctx)
+
+	releases := Releases{}
+
+	err := paginate(&filter.Limit, &filter.Paging, func() (*keygen.Response, int, error) {
+		page := Releases{}
+		res, err := client.Get("releases", filter, &page)
+		releases = append(releases, page...)
+
+		return res, len(page), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return releases, nil
+}
+
+// ReleaseArtifactURL returns the API URL which redirects to a release's
+// artifact, using the Client's ArtifactHost when given.
+func (c *Client) ReleaseArtifactURL(r *Release) string {
+	host := keygen.APIURL
+	if c.ArtifactHost != "" {
+		host = c.ArtifactHost
+	}
+
+	return host + "/" + keygen.APIVersion + "/accounts/" + c.Account + "/releases/" + r.ID + "/artifact"
+}
+
+// ArtifactDownloadURL returns the URL to download an artifact from, which is
+// its temporary download location, or the API URL which redirects to it on
+// the Client's ArtifactHost when given, since a download location is signed
+// for the storage provider's host.
+func (c *Client) ArtifactDownloadURL(a *Artifact) string {
+	if c.ArtifactHost == "" {
+		return a.Location
+	}
+
+	return c.ArtifactHost + "/" + keygen.APIVersion + "/accounts/" + c.Account + "/artifacts/" + a.ID
+}
+
+// releaseMetadata is used to update only a release's metadata.
+type releaseMetadata struct {
+	ID       string                 `json:"-"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+func (r releaseMetadata) GetID() string {
+	return r.ID
+}
+
+func (r releaseMetadata) GetType() string {
+	return "releases"
+}
+
+func (r releaseMetadata) GetData() interface{} {
+	return r
+}
+
+// UpdateReleaseMetadata replaces a release's metadata.
+func (c *Client) UpdateReleaseMetadata(ctx context.Context, r *Release, metadata map[string]interface{}) error {
+	client := c.newClient(ctx)
+
+	params := releaseMetadata{ID: r.ID, Metadata: metadata}
+
+	res, err := client.Patch("releases/"+r.ID, params, r)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// YankRelease marks a release as yanked, so that it is no longer offered as
+// an upgrade. The artifact remains downloadable for existing installs.
+func (c *Client) YankRelease(ctx context.Context, r *Release) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Post("releases/"+r.ID+"/actions/yank", nil, r)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// PublishRelease publishes a draft release, so that it's offered as an
+// upgrade.
+func (c *Client) PublishRelease(ctx context.Context, r *Release) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Post("releases/"+r.ID+"/actions/publish", nil, r)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// DeleteRelease deletes a release along with its artifact.
+func (c *Client) DeleteRelease(ctx context.Context, r *Release) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Delete("releases/"+r.ID, nil, nil)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// ListReleaseConstraints retrieves a release's entitlement constraints.
+func (c *Client) ListReleaseConstraints(ctx context.Context, r *Release) (Constraints, error) {
+	client := c.newClient(ctx)
+
+	constraints := Constraints{}
+
+	res, err := client.Get("releases/"+r.ID+"/constraints", &ListParams{Limit: 100}, &constraints)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	return constraints, nil
+}
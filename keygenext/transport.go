@@ -0,0 +1,125 @@
+package keygenext
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// maxIdleConnsPerHost is how many idle connections are kept per host, which
+// covers the most parts uploaded at once. The standard library keeps only 2,
+// so concurrent uploads and bulk commands would otherwise dial (and handshake)
+// a new connection for most requests.
+const maxIdleConnsPerHost = 32
+
+// TransportOptions configures a transport from NewTransport.
+type TransportOptions struct {
+	// Proxy returns the proxy for a request. When nil, the proxy is taken from
+	// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// TLSConfig, when given, is used for TLS connections, e.g. to trust the
+	// private CA of a self-hosted instance.
+	TLSConfig *tls.Config
+
+	// ResponseHeaderTimeout, when given, limits how long to wait for a
+	// response's headers once a request is sent. Bodies, e.g. downloads, are
+	// not limited.
+	ResponseHeaderTimeout time.Duration
+
+	// MaxConnsPerHost, when given, limits the connections per host, including
+	// ones in use.
+	MaxConnsPerHost int
+}
+
+var (
+	transport   http.RoundTripper
+	cassette    http.RoundTripper
+	transportMu sync.Mutex
+	installPool sync.Once
+)
+
+// NewTransport returns a transport which pools connections, so that they're
+// reused across requests, and which negotiates HTTP/2 where supported. It's
+// safe for concurrent use, and should be shared rather than created per
+// request.
+func NewTransport(opts TransportOptions) *http.Transport {
+	proxy := opts.Proxy
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       opts.TLSConfig,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		MaxConnsPerHost:       opts.MaxConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		ResponseHeaderTimeout: opts.ResponseHeaderTimeout,
+	}
+}
+
+// ConfigureTransport installs a transport from NewTransport as the package's
+// transport, which every request is made using. The default transport is left
+// alone. The transport is shared for the life of the process so that its
+// connections are reused, so only the first call has an effect. It must be
+// called before a Client is used, which otherwise installs one using the
+// default options.
+func ConfigureTransport(opts TransportOptions) {
+	installPool.Do(func() {
+		transportMu.Lock()
+		defer transportMu.Unlock()
+
+		transport = NewTransport(opts)
+	})
+}
+
+// baseTransport returns the package's transport, installing one using the
+// default options when none was configured.
+func baseTransport() http.RoundTripper {
+	ConfigureTransport(TransportOptions{})
+
+	transportMu.Lock()
+	defer transportMu.Unlock()
+
+	return transport
+}
+
+// installCassette makes every request using a cassette, which wraps the package's
+// transport when recording.
+func installCassette(c http.RoundTripper) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+
+	cassette = c
+}
+
+// sharedTransport makes requests using the installed cassette, if any, and
+// otherwise using the package's transport.
+type sharedTransport struct{}
+
+func (sharedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transportMu.Lock()
+	c := cassette
+	transportMu.Unlock()
+
+	if c != nil {
+		return c.RoundTrip(req)
+	}
+
+	return baseTransport().RoundTrip(req)
+}
+
+// httpClient is used for requests to the storage provider and arbitrary API
+// endpoints, so that they share the API's connections.
+var httpClient = &http.Client{Transport: sharedTransport{}}
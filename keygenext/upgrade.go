@@ -0,0 +1,56 @@
+package keygenext
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var ErrUpgradeNotAvailable = errors.New("no upgrade is available")
+
+// UpgradeParams describes an app checking for an upgrade, using the same
+// parameters as the upgrade endpoint.
+type UpgradeParams struct {
+	Product    string `url:"product"`
+	Version    string `url:"version"`
+	Platform   string `url:"platform,omitempty"`
+	Channel    string `url:"channel,omitempty"`
+	Filetype   string `url:"filetype,omitempty"`
+	Constraint string `url:"constraint,omitempty"`
+}
+
+// Upgrade retrieves the release an app would be offered as an upgrade, along
+// with the artifact it would download, returning ErrUpgradeNotAvailable when
+// the app is up to date. Requests are authenticated by the Client's license
+// key, like the app's own, or are otherwise unauthenticated.
+func (c *Client) Upgrade(ctx context.Context, params *UpgradeParams) (*Release, *Artifact, error) {
+	client := c.newClient(ctx)
+
+	// Only the license key is used, since a product token could be offered
+	// upgrades which the app wouldn't be
+	client.Token = ""
+
+	artifact := &Artifact{}
+
+	res, err := client.Get("releases/actions/upgrade", params, artifact)
+	if err != nil {
+		return nil, nil, newAPIError(res, err)
+	}
+
+	if res.Status == http.StatusNoContent {
+		return nil, nil, ErrUpgradeNotAvailable
+	}
+
+	artifact.Location = res.Headers.Get("Location")
+
+	release := &Release{}
+
+	res, err = client.Get("releases/"+artifact.ReleaseID, nil, release)
+	if err != nil {
+		return nil, nil, newAPIError(res, err)
+	}
+
+	artifact.ContentLength = release.Filesize
+
+	return release, artifact, nil
+}
@@ -0,0 +1,185 @@
+package keygenext
+
+import (
+	"context"
+	"time"
+
+	"github.com/keygen-sh/jsonapi-go"
+	"github.com/keygen-sh/keygen-go"
+)
+
+// User represents a Keygen user object.
+type User struct {
+	ID        string                 `json:"-"`
+	Type      string                 `json:"-"`
+	Email     string                 `json:"email"`
+	FirstName string                 `json:"firstName"`
+	LastName  string                 `json:"lastName"`
+	FullName  string                 `json:"fullName"`
+	Role      string                 `json:"role"`
+	Status    string                 `json:"status"`
+	Metadata  map[string]interface{} `json:"metadata"`
+	Created   time.Time              `json:"created"`
+	Updated   time.Time              `json:"updated"`
+	GroupID   string                 `json:"-"`
+}
+
+func (u *User) SetID(id string) error {
+	u.ID = id
+	return nil
+}
+
+func (u *User) SetType(t string) error {
+	u.Type = t
+	return nil
+}
+
+func (u *User) SetData(to func(target interface{}) error) error {
+	return to(u)
+}
+
+func (u *User) SetRelationships(relationships map[string]interface{}) error {
+	if relationship, ok := relationships["group"]; ok {
+		if r, ok := relationship.(*jsonapi.ResourceObjectIdentifier); ok && r != nil {
+			u.GroupID = r.ID
+		}
+	}
+
+	return nil
+}
+
+// Users represents a collection of Keygen user objects.
+type Users []User
+
+func (u *Users) SetData(to func(target interface{}) error) error {
+	return to(u)
+}
+
+// userAttributes are the writable attributes of a user.
+type userAttributes struct {
+	ID        string                 `json:"-"`
+	Email     string                 `json:"email,omitempty"`
+	FirstName string                 `json:"firstName,omitempty"`
+	LastName  string                 `json:"lastName,omitempty"`
+	Role      string                 `json:"role,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+func (u userAttributes) GetID() string {
+	return u.ID
+}
+
+func (u userAttributes) GetType() string {
+	return "users"
+}
+
+func (u userAttributes) GetData() interface{} {
+	return u
+}
+
+// passwordReset requests a password reset email, which doubles as an invite
+// for users created without a password.
+type passwordReset struct {
+	Email   string `json:"email"`
+	Deliver bool   `json:"deliver"`
+}
+
+func (p passwordReset) GetMeta() interface{} {
+	return p
+}
+
+// ListUsers retrieves the account's users.
+func (c *Client) ListUsers(ctx context.Context, params *ListParams) (Users, error) {
+	client := c.newClient(ctx)
+
+	users := Users{}
+
+	err := paginate(&params.Limit, &params.Paging, func() (*keygen.Response, int, error) {
+		page := Users{}
+		res, err := client.Get("users", params, &page)
+		users = append(users, page...)
+
+		return res, len(page), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// GetUser retrieves a user by its ID or email.
+func (c *Client) GetUser(ctx context.Context, id string) (*User, error) {
+	client := c.newClient(ctx)
+
+	user := &User{}
+
+	res, err := client.Get("users/"+id, nil, user)
+	if err != nil {
+		return nil, newAPIError(res, err)
+	}
+
+	return user, nil
+}
+
+// CreateUser creates a user without a password.
+func (c *Client) CreateUser(ctx context.Context, u *User) error {
+	client := c.newClient(ctx)
+
+	params := userAttributes{Email: u.Email, FirstName: u.FirstName, LastName: u.LastName, Metadata: u.Metadata}
+
+	res, err := client.Post("users", params, u)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// InviteUser emails a user a link to set their password.
+func (c *Client) InviteUser(ctx context.Context, u *User) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Post("passwords", passwordReset{Email: u.Email, Deliver: true}, nil)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// UpdateUserRole changes a user's role, e.g. to admin or support-agent.
+func (c *Client) UpdateUserRole(ctx context.Context, u *User, role string) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Patch("users/"+u.ID, userAttributes{ID: u.ID, Role: role}, u)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// DeleteUser deletes a user.
+func (c *Client) DeleteUser(ctx context.Context, u *User) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Delete("users/"+u.ID, nil, nil)
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}
+
+// AttachUserLicense transfers ownership of a license to a user.
+func (c *Client) AttachUserLicense(ctx context.Context, u *User, licenseID string) error {
+	client := c.newClient(ctx)
+
+	res, err := client.Put("licenses/"+licenseID+"/user", identifier{ID: u.ID, Type: "users"}, &User{})
+	if err != nil {
+		return newAPIError(res, err)
+	}
+
+	return nil
+}